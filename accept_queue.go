@@ -0,0 +1,141 @@
+package tinytcp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// acceptQueue decouples the accept loop from the handling of new connections (see ServerConfig.AcceptQueueSize).
+// Connections are passed to the handler by a dedicated goroutine, so a momentarily slow ForkingStrategy doesn't stall
+// the accept loop. Connections that don't fit into the queue are dropped.
+type acceptQueue struct {
+	size    int
+	handler func(net.Conn)
+	onDrop  func()
+	dropped uint64
+
+	connections chan net.Conn
+	closed      bool
+	m           sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func newAcceptQueue(size int, handler func(net.Conn), onDrop func()) *acceptQueue {
+	return &acceptQueue{
+		size:    size,
+		handler: handler,
+		onDrop:  onDrop,
+		closed:  true,
+	}
+}
+
+func (q *acceptQueue) Start() {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	q.connections = make(chan net.Conn, q.size)
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	q.closed = false
+
+	go q.run(q.connections, q.stop, q.done)
+}
+
+// Push adds the connection to the queue. It returns false if the queue is full (the connection is counted as dropped),
+// or it has been stopped. Connections that haven't been pushed should be closed by the caller.
+func (q *acceptQueue) Push(conn net.Conn) bool {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.closed {
+		return false
+	}
+
+	select {
+	case q.connections <- conn:
+		return true
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+		q.onDrop()
+		return false
+	}
+}
+
+// Stop stops the goroutine handling the connections, and closes the connections left in the queue.
+func (q *acceptQueue) Stop() {
+	q.Close()
+	q.Wait()
+}
+
+// Close makes the queue stop accepting and handling the connections, without waiting for the connection
+// being currently handled (see Wait).
+func (q *acceptQueue) Close() {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+
+	close(q.stop)
+}
+
+// Wait waits until the goroutine handling the connections finishes, and closes the connections left in the queue.
+// Handler of the current connection might be blocked on the sockets (eg. InlineHandler), so they should be closed
+// before calling Wait.
+func (q *acceptQueue) Wait() {
+	q.m.Lock()
+	done := q.done
+	q.m.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	<-done
+
+	for {
+		select {
+		case conn := <-q.connections:
+			_ = conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// Len returns a number of connections waiting in the queue.
+func (q *acceptQueue) Len() int {
+	q.m.Lock()
+	defer q.m.Unlock()
+
+	return len(q.connections)
+}
+
+// Dropped returns a total number of connections dropped because the queue was full.
+func (q *acceptQueue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+func (q *acceptQueue) run(connections <-chan net.Conn, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	for {
+		// stop takes precedence over the queued connections, they're closed by Stop()
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		select {
+		case conn := <-connections:
+			q.handler(conn)
+		case <-stop:
+			return
+		}
+	}
+}
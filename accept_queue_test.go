@@ -0,0 +1,89 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAcceptQueue(t *testing.T) {
+	// given
+	release := make(chan struct{})
+	handled := make(chan net.Conn, 3)
+
+	var drops int
+	queue := newAcceptQueue(1, func(conn net.Conn) {
+		handled <- conn
+		<-release
+	}, func() {
+		drops++
+	})
+	queue.Start()
+
+	first, firstPeer := net.Pipe()
+	second, secondPeer := net.Pipe()
+	third, thirdPeer := net.Pipe()
+	defer firstPeer.Close()
+	defer secondPeer.Close()
+	defer thirdPeer.Close()
+
+	// when
+	pushedFirst := queue.Push(first)
+	<-handled
+
+	pushedSecond := queue.Push(second)
+	pushedThird := queue.Push(third)
+	length := queue.Len()
+
+	close(release)
+	<-handled
+	queue.Stop()
+
+	pushedAfterStop := queue.Push(third)
+
+	// then
+	assert.True(t, pushedFirst, "first connection should be pushed")
+	assert.True(t, pushedSecond, "second connection should be queued")
+	assert.False(t, pushedThird, "third connection should be dropped")
+	assert.False(t, pushedAfterStop, "connection should not be pushed after stop")
+	assert.Equal(t, 1, length, "queue length should match")
+	assert.Equal(t, uint64(1), queue.Dropped(), "dropped connections should match")
+	assert.Equal(t, 1, drops, "drop handler should be called")
+}
+
+func TestAcceptQueueStopClosesQueued(t *testing.T) {
+	// given
+	release := make(chan struct{})
+	handled := make(chan struct{}, 1)
+
+	queue := newAcceptQueue(1, func(_ net.Conn) {
+		handled <- struct{}{}
+		<-release
+	}, func() {})
+	queue.Start()
+
+	first, firstPeer := net.Pipe()
+	second, secondPeer := net.Pipe()
+	defer first.Close()
+	defer firstPeer.Close()
+
+	queue.Push(first)
+	<-handled
+	queue.Push(second)
+
+	// when
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	queue.Stop()
+
+	// then
+	assert.Equal(t, 0, len(queue.connections), "queue should be emptied")
+
+	_ = secondPeer.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := secondPeer.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err, "queued connection should be closed")
+}
@@ -0,0 +1,397 @@
+package admintinytcp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// CommandHandler handles a single command of the console. Output written to w is sent to the operator.
+// Returned error is reported as "ERR <error>", otherwise the command is followed by "OK".
+type CommandHandler func(w io.Writer, args []string) error
+
+// Config holds a configuration for NewConsole.
+type Config struct {
+	// ServerConfig is an optional configuration of the console server, eg. to listen on a unix socket.
+	ServerConfig *tinytcp.ServerConfig
+}
+
+func mergeConfig(provided *Config) *Config {
+	config := &Config{}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.ServerConfig != nil {
+		config.ServerConfig = provided.ServerConfig
+	}
+
+	return config
+}
+
+type command struct {
+	help    string
+	handler CommandHandler
+}
+
+// Console is an administrative console of a tinytcp server. Operators connect to it with tools like netcat
+// and issue commands, one per line. Built-in commands are:
+//
+//	help               lists the available commands
+//	list               lists the connections of the managed server
+//	kick <id>          closes the connection with given ID
+//	ban <ip>           rejects new connections from given IP, and closes the existing ones
+//	unban <ip>         removes the ban
+//	bans               lists the banned IPs
+//	drain              rejects all the new connections, leaving the existing ones open
+//	resume             accepts new connections again
+//	metrics            dumps the metrics of the managed server
+//	quit               closes the console connection
+//
+// Bans and draining take effect only if the Console is set as the AdmissionController of the managed server
+// (it can be combined with other controllers using tinytcp.AdmitAll).
+// Console conforms to the tinytcp.Service interface, so it can be started with tinytcp.StartAndBlock.
+type Console struct {
+	server   *tinytcp.Server
+	target   *tinytcp.Server
+	commands map[string]*command
+	bans     map[string]struct{}
+	draining uint32
+	m        sync.RWMutex
+}
+
+// NewConsole creates new Console listening under given address. Server to manage is set with Manage().
+func NewConsole(address string, config ...*Config) *Console {
+	var providedConfig *Config
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeConfig(providedConfig)
+
+	console := &Console{
+		server:   tinytcp.NewServer(address, c.ServerConfig),
+		commands: make(map[string]*command),
+		bans:     make(map[string]struct{}),
+	}
+
+	console.server.ForkingStrategy(tinytcp.GoroutinePerConnection(
+		tinytcp.PacketFramingHandler(
+			tinytcp.SplitBySeparator([]byte{'\n'}),
+			console.serve,
+		),
+	))
+
+	console.registerBuiltinCommands()
+
+	return console
+}
+
+// Manage sets the server managed by the console.
+func (c *Console) Manage(server *tinytcp.Server) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.target = server
+}
+
+// Command registers a custom command. Built-in commands can be overridden.
+func (c *Console) Command(name string, help string, handler CommandHandler) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.commands[name] = &command{help: help, handler: handler}
+}
+
+// Admit conforms to the tinytcp.AdmissionController interface. It rejects the connections from the banned IPs,
+// and all the connections while draining.
+func (c *Console) Admit(conn net.Conn) bool {
+	if atomic.LoadUint32(&c.draining) == 1 {
+		return false
+	}
+
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	_, banned := c.bans[remoteIP(conn.RemoteAddr())]
+	return !banned
+}
+
+// Ban rejects the new connections from given IP, and closes the existing ones.
+func (c *Console) Ban(ip string) {
+	c.m.Lock()
+	c.bans[ip] = struct{}{}
+	target := c.target
+	c.m.Unlock()
+
+	if target == nil {
+		return
+	}
+
+	var refs []*tinytcp.SocketRef
+	target.IterateRefs(func(ref *tinytcp.SocketRef) {
+		if ref.RemoteAddress() == ip {
+			refs = append(refs, ref)
+		}
+	})
+
+	for _, ref := range refs {
+		_ = ref.Close()
+	}
+}
+
+// Unban removes the ban of given IP.
+func (c *Console) Unban(ip string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	delete(c.bans, ip)
+}
+
+// Drain makes the console reject all the new connections (draining=true) or accept them again (draining=false).
+func (c *Console) Drain(draining bool) {
+	if draining {
+		atomic.StoreUint32(&c.draining, 1)
+	} else {
+		atomic.StoreUint32(&c.draining, 0)
+	}
+}
+
+// Start starts the console server and blocks until Stop() is called.
+func (c *Console) Start() error {
+	return c.server.Start()
+}
+
+// Stop stops the console server.
+func (c *Console) Stop() error {
+	return c.server.Stop()
+}
+
+// Port returns a port number of the console server. Only returns a valid value after Start().
+func (c *Console) Port() int {
+	return c.server.Port()
+}
+
+func (c *Console) serve(socket *tinytcp.Socket) tinytcp.PacketHandler {
+	var output bytes.Buffer
+
+	return func(packet []byte) {
+		fields := strings.Fields(string(packet))
+		if len(fields) == 0 {
+			return
+		}
+
+		if fields[0] == "quit" {
+			_ = socket.Close()
+			return
+		}
+
+		output.Reset()
+
+		if err := c.execute(&output, fields[0], fields[1:]); err != nil {
+			_, _ = fmt.Fprintf(&output, "ERR %v\n", err)
+		} else {
+			output.WriteString("OK\n")
+		}
+
+		_ = tinytcp.WriteBytes(socket, output.Bytes())
+	}
+}
+
+func (c *Console) execute(w io.Writer, name string, args []string) error {
+	c.m.RLock()
+	cmd, ok := c.commands[name]
+	c.m.RUnlock()
+
+	if !ok {
+		return errors.New("unknown command: " + name)
+	}
+
+	return cmd.handler(w, args)
+}
+
+func (c *Console) managed() (*tinytcp.Server, error) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if c.target == nil {
+		return nil, errors.New("no managed server")
+	}
+
+	return c.target, nil
+}
+
+func (c *Console) registerBuiltinCommands() {
+	c.Command("help", "lists the available commands", c.help)
+	c.Command("list", "lists the connections", c.list)
+	c.Command("kick", "kick <id> - closes the connection with given ID", c.kick)
+	c.Command("ban", "ban <ip> - rejects new connections from given IP, and closes the existing ones", c.ban)
+	c.Command("unban", "unban <ip> - removes the ban", c.unban)
+	c.Command("bans", "lists the banned IPs", c.listBans)
+	c.Command("drain", "rejects all the new connections", c.drain)
+	c.Command("resume", "accepts new connections again", c.resume)
+	c.Command("metrics", "dumps the server metrics", c.metrics)
+	c.Command("quit", "closes the console connection", func(_ io.Writer, _ []string) error {
+		return nil
+	})
+}
+
+func (c *Console) help(w io.Writer, _ []string) error {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	names := make([]string, 0, len(c.commands))
+	for name := range c.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "%-10s %s\n", name, c.commands[name].help)
+	}
+
+	return nil
+}
+
+func (c *Console) list(w io.Writer, _ []string) error {
+	target, err := c.managed()
+	if err != nil {
+		return err
+	}
+
+	for _, connection := range target.DumpConnections() {
+		_, _ = fmt.Fprintf(
+			w,
+			"%d %s connected=%s read=%d written=%d state=%s\n",
+			connection.ID,
+			connection.RemoteAddress,
+			time.UnixMilli(connection.ConnectedAt).UTC().Format(time.RFC3339),
+			connection.TotalRead,
+			connection.TotalWritten,
+			connection.State,
+		)
+	}
+
+	return nil
+}
+
+func (c *Console) kick(_ io.Writer, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: kick <id>")
+	}
+
+	id, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return errors.New("invalid connection ID")
+	}
+
+	target, err := c.managed()
+	if err != nil {
+		return err
+	}
+
+	var ref *tinytcp.SocketRef
+	target.IterateRefs(func(r *tinytcp.SocketRef) {
+		if r.ID() == id {
+			ref = r
+		}
+	})
+
+	if ref == nil {
+		return errors.New("connection not found")
+	}
+
+	return ref.Close()
+}
+
+func (c *Console) ban(_ io.Writer, args []string) error {
+	if len(args) != 1 || net.ParseIP(args[0]) == nil {
+		return errors.New("usage: ban <ip>")
+	}
+
+	c.Ban(args[0])
+	return nil
+}
+
+func (c *Console) unban(_ io.Writer, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: unban <ip>")
+	}
+
+	c.Unban(args[0])
+	return nil
+}
+
+func (c *Console) listBans(w io.Writer, _ []string) error {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	bans := make([]string, 0, len(c.bans))
+	for ip := range c.bans {
+		bans = append(bans, ip)
+	}
+	sort.Strings(bans)
+
+	for _, ip := range bans {
+		_, _ = fmt.Fprintln(w, ip)
+	}
+
+	return nil
+}
+
+func (c *Console) drain(_ io.Writer, _ []string) error {
+	c.Drain(true)
+	return nil
+}
+
+func (c *Console) resume(_ io.Writer, _ []string) error {
+	c.Drain(false)
+	return nil
+}
+
+func (c *Console) metrics(w io.Writer, _ []string) error {
+	target, err := c.managed()
+	if err != nil {
+		return err
+	}
+
+	metrics := target.Metrics()
+
+	_, _ = fmt.Fprintf(w, "connections %d\n", metrics.Connections)
+	_, _ = fmt.Fprintf(w, "connections_peak %d\n", metrics.PeakConnections)
+	_, _ = fmt.Fprintf(w, "accepted_total %d\n", metrics.TotalAccepted)
+	_, _ = fmt.Fprintf(w, "rejected_total %d\n", metrics.TotalRejected)
+	_, _ = fmt.Fprintf(w, "tarpitted_total %d\n", metrics.TotalTarpitted)
+	_, _ = fmt.Fprintf(w, "closed_by_server_total %d\n", metrics.TotalClosedByServer)
+	_, _ = fmt.Fprintf(w, "closed_by_client_total %d\n", metrics.TotalClosedByClient)
+	_, _ = fmt.Fprintf(w, "read_bytes_total %d\n", metrics.TotalRead)
+	_, _ = fmt.Fprintf(w, "written_bytes_total %d\n", metrics.TotalWritten)
+	_, _ = fmt.Fprintf(w, "goroutines %d\n", metrics.Goroutines)
+	_, _ = fmt.Fprintf(w, "draining %t\n", atomic.LoadUint32(&c.draining) == 1)
+
+	return nil
+}
+
+func remoteIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return addr.String()
+		}
+
+		return host
+	}
+}
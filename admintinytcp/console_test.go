@@ -0,0 +1,110 @@
+package admintinytcp
+
+import (
+	"fmt"
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsole(t *testing.T) {
+	// given
+	console := NewConsole("127.0.0.1:0", &Config{
+		ServerConfig: &tinytcp.ServerConfig{MaxClients: -1},
+	})
+	console.Command("greet", "greet <name>", func(w io.Writer, args []string) error {
+		_, err := fmt.Fprintf(w, "Hello %s\n", strings.Join(args, " "))
+		return err
+	})
+
+	server := tinytcp.NewServer("127.0.0.1:0", &tinytcp.ServerConfig{
+		MaxClients:          -1,
+		AdmissionController: console,
+	})
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(socket *tinytcp.Socket) {
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+	console.Manage(server)
+
+	startService(t, server)
+	startService(t, console)
+
+	serverAddress := fmt.Sprintf("127.0.0.1:%d", server.Port())
+	conn, err := net.Dial("tcp", serverAddress)
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool {
+		return len(server.DumpConnections()) == 1
+	}, time.Second, time.Millisecond, "connection should be visible")
+
+	client, err := tinytcptest.DialScriptedClient(fmt.Sprintf("127.0.0.1:%d", console.Port()))
+	assert.Nil(t, err, "err should be nil")
+	defer client.Close()
+
+	// when
+	err = client.Run(
+		tinytcptest.SendString("greet operator\n"),
+		tinytcptest.ExpectString("Hello operator\nOK\n"),
+		tinytcptest.SendString("list\n"),
+		tinytcptest.Expect(tinytcptest.Regexp(`^\d+ 127\.0\.0\.1 connected=\S+ read=0 written=0 state=open\nOK\n`)),
+		tinytcptest.SendString("ban 127.0.0.1\n"),
+		tinytcptest.ExpectString("OK\n"),
+		tinytcptest.SendString("bans\n"),
+		tinytcptest.ExpectString("127.0.0.1\nOK\n"),
+		tinytcptest.SendString("unban 127.0.0.1\n"),
+		tinytcptest.ExpectString("OK\n"),
+		tinytcptest.SendString("drain\n"),
+		tinytcptest.ExpectString("OK\n"),
+		tinytcptest.SendString("metrics\n"),
+		tinytcptest.Expect(tinytcptest.Regexp(`^connections \d+\n(?s:.*)draining true\nOK\n`)),
+		tinytcptest.SendString("unknown\n"),
+		tinytcptest.ExpectString("ERR unknown command: unknown\n"),
+		tinytcptest.SendString("quit\n"),
+		tinytcptest.ExpectClose(),
+	)
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, kickedErr := conn.Read(make([]byte, 1))
+
+	rejectedConn, _ := net.Dial("tcp", serverAddress)
+	_ = rejectedConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, rejectedErr := rejectedConn.Read(make([]byte, 1))
+	_ = rejectedConn.Close()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, io.EOF, kickedErr, "banned connection should be closed")
+	assert.Equal(t, io.EOF, rejectedErr, "new connections should be rejected while draining")
+}
+
+func startService(t *testing.T, service interface {
+	tinytcp.Service
+	Port() int
+}) {
+	started := make(chan struct{})
+	go func() {
+		_ = service.Start()
+	}()
+	t.Cleanup(func() {
+		_ = service.Stop()
+	})
+
+	go func() {
+		for service.Port() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		close(started)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("service failed to start")
+	}
+}
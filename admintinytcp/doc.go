@@ -0,0 +1,5 @@
+/*
+Package admintinytcp provides an administrative console for tinytcp servers, served over a line-based protocol
+on a separate port or unix socket.
+*/
+package admintinytcp
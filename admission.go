@@ -0,0 +1,183 @@
+package tinytcp
+
+import (
+	"math"
+	"net"
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// admissionSampleInterval is a minimal interval between the samples of runtime metrics taken by the built-in
+// admission controllers, so they don't slow down the accept loop under heavy load.
+const admissionSampleInterval = 100 * time.Millisecond
+
+// AdmissionController decides whether a newly accepted connection should be served (see ServerConfig).
+// It allows the server to shed new connections gracefully under overload, instead of degrading all the clients.
+// Rejected connections are closed immediately and counted in ServerMetrics.TotalRejected.
+type AdmissionController interface {
+	// Admit is called by the accept loop for each new connection, so it needs to be fast.
+	// Returning false rejects the connection.
+	Admit(conn net.Conn) bool
+}
+
+// AdmissionFunc is an adapter allowing to use an ordinary function as AdmissionController.
+type AdmissionFunc func(conn net.Conn) bool
+
+// Admit conforms to the AdmissionController interface.
+func (f AdmissionFunc) Admit(conn net.Conn) bool {
+	return f(conn)
+}
+
+// AdmitAll returns an AdmissionController admitting a connection only if all the given controllers admit it.
+func AdmitAll(controllers ...AdmissionController) AdmissionController {
+	return AdmissionFunc(func(conn net.Conn) bool {
+		for _, controller := range controllers {
+			if !controller.Admit(conn) {
+				return false
+			}
+		}
+
+		return true
+	})
+}
+
+// MaxGoroutines returns an AdmissionController rejecting new connections while the process runs more than limit
+// goroutines.
+func MaxGoroutines(limit int) AdmissionController {
+	return AdmissionFunc(func(_ net.Conn) bool {
+		return runtime.NumGoroutine() <= limit
+	})
+}
+
+// MemoryWatermarks returns an AdmissionController rejecting new connections once the memory mapped by the Go
+// runtime exceeds high watermark (in bytes), until it drops below low watermark. Memory usage is sampled
+// at most every 100ms.
+func MemoryWatermarks(high, low uint64) AdmissionController {
+	c := &memoryWatermarks{
+		high: high,
+		low:  low,
+	}
+	c.sample.Name = "/memory/classes/total:bytes"
+
+	return c
+}
+
+type memoryWatermarks struct {
+	high     uint64
+	low      uint64
+	sampler  admissionSampler
+	sample   metrics.Sample
+	shedding atomic.Bool
+}
+
+func (c *memoryWatermarks) Admit(_ net.Conn) bool {
+	c.sampler.maybe(func() {
+		samples := []metrics.Sample{c.sample}
+		metrics.Read(samples)
+		if samples[0].Value.Kind() != metrics.KindUint64 {
+			return
+		}
+
+		used := samples[0].Value.Uint64()
+		if used > c.high {
+			c.shedding.Store(true)
+		} else if used < c.low {
+			c.shedding.Store(false)
+		}
+	})
+
+	return !c.shedding.Load()
+}
+
+// SchedulingLatency returns an AdmissionController rejecting new connections while the 99th percentile
+// of the time goroutines spend waiting to be scheduled exceeds threshold. Under CPU overload, it's the scheduling
+// latency that delays accepting and serving the connections, so it reacts before the clients start timing out.
+// Latency is sampled at most every 100ms, from the observations made since the previous sample.
+func SchedulingLatency(threshold time.Duration) AdmissionController {
+	c := &schedulingLatency{
+		threshold: threshold.Seconds(),
+	}
+	c.sample.Name = "/sched/latencies:seconds"
+
+	return c
+}
+
+type schedulingLatency struct {
+	threshold float64
+	sampler   admissionSampler
+	sample    metrics.Sample
+	previous  []uint64
+	shedding  atomic.Bool
+}
+
+func (c *schedulingLatency) Admit(_ net.Conn) bool {
+	c.sampler.maybe(func() {
+		samples := []metrics.Sample{c.sample}
+		metrics.Read(samples)
+		if samples[0].Value.Kind() != metrics.KindFloat64Histogram {
+			return
+		}
+
+		histogram := samples[0].Value.Float64Histogram()
+		c.shedding.Store(c.percentile(histogram, 0.99) > c.threshold)
+	})
+
+	return !c.shedding.Load()
+}
+
+// percentile calculates the percentile of the observations made since the previous call.
+func (c *schedulingLatency) percentile(histogram *metrics.Float64Histogram, p float64) float64 {
+	if len(c.previous) != len(histogram.Counts) {
+		c.previous = make([]uint64, len(histogram.Counts))
+	}
+
+	var total uint64
+	for i, count := range histogram.Counts {
+		total += count - c.previous[i]
+	}
+
+	var (
+		rank       = uint64(float64(total) * p)
+		cumulative uint64
+		result     float64
+		found      bool
+	)
+
+	for i, count := range histogram.Counts {
+		cumulative += count - c.previous[i]
+		c.previous[i] = count
+
+		if total > 0 && !found && cumulative > rank {
+			// lower bound of the bucket, as the upper one might be infinite
+			result = math.Max(histogram.Buckets[i], 0)
+			found = true
+		}
+	}
+
+	return result
+}
+
+// admissionSampler limits the frequency of sampling runtime metrics by the admission controllers.
+type admissionSampler struct {
+	lastSample atomic.Int64
+	m          sync.Mutex
+}
+
+func (s *admissionSampler) maybe(sample func()) {
+	now := time.Now().UnixNano()
+	if now-s.lastSample.Load() < int64(admissionSampleInterval) {
+		return
+	}
+
+	if !s.m.TryLock() {
+		// another goroutine is sampling right now
+		return
+	}
+	defer s.m.Unlock()
+
+	s.lastSample.Store(now)
+	sample()
+}
@@ -0,0 +1,65 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestBuiltinAdmissionControllers(t *testing.T) {
+	// given
+	conn := &ConnMock{}
+
+	// when
+	admitted := AdmitAll(
+		MaxGoroutines(math.MaxInt),
+		MemoryWatermarks(math.MaxUint64, math.MaxUint64),
+		SchedulingLatency(time.Hour),
+	).Admit(conn)
+	tooManyGoroutines := MaxGoroutines(0).Admit(conn)
+	tooMuchMemory := MemoryWatermarks(0, 0).Admit(conn)
+
+	// then
+	assert.True(t, admitted, "connection should be admitted")
+	assert.False(t, tooManyGoroutines, "connection should be rejected due to goroutines limit")
+	assert.False(t, tooMuchMemory, "connection should be rejected due to memory watermark")
+}
+
+func TestServerAdmissionController(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients: -1,
+		AdmissionController: AdmissionFunc(func(_ net.Conn) bool {
+			return false
+		}),
+	})
+
+	var handled bool
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {
+		handled = true
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, readErr := conn.Read(make([]byte, 1))
+
+	// then
+	assert.Equal(t, io.EOF, readErr, "connection should be closed")
+	assert.False(t, handled, "connection should not be handled")
+}
@@ -0,0 +1,138 @@
+package tinytcp
+
+import (
+	"math/bits"
+	"sync"
+)
+
+const (
+	minAllocatorClass = 6  // 64 B
+	maxAllocatorClass = 24 // 16 MiB
+)
+
+// Allocator provides byte buffers used by the framing layer (see PacketFramingConfig) and PacketWriter
+// (see NewPacketWriterWithAllocator). It allows latency-sensitive deployments to control the GC pressure,
+// eg. by serving the buffers from an arena or an off-heap pool. Allocator is called concurrently from multiple
+// goroutines, so it needs to be safe for concurrent use.
+type Allocator interface {
+	// Allocate returns a buffer of given length. Capacity of the buffer might be larger than the length.
+	Allocate(size int) []byte
+
+	// Free returns the buffer obtained from Allocate, once it's no longer used. It receives the buffer with its length
+	// possibly changed, but with the capacity intact.
+	Free(buffer []byte)
+}
+
+// PoolAllocator returns the default Allocator, backed by sync.Pool. Buffers are rounded up to the nearest power
+// of two (between 64 B and 16 MiB), each size has its own pool. Larger buffers are not pooled.
+// Returned Allocator is shared by the whole process.
+func PoolAllocator() Allocator {
+	return defaultAllocator
+}
+
+var defaultAllocator = &poolAllocator{}
+
+type poolAllocator struct {
+	pools [maxAllocatorClass - minAllocatorClass + 1]sync.Pool
+}
+
+func (a *poolAllocator) Allocate(size int) []byte {
+	class := allocatorClass(size)
+	if class > maxAllocatorClass {
+		return make([]byte, size)
+	}
+
+	if buffer, ok := a.pools[class-minAllocatorClass].Get().([]byte); ok {
+		return buffer[:size]
+	}
+
+	return make([]byte, size, 1<<class)
+}
+
+func (a *poolAllocator) Free(buffer []byte) {
+	c := cap(buffer)
+	class := allocatorClass(c)
+	if class > maxAllocatorClass || c != 1<<class {
+		// not allocated by the pool
+		return
+	}
+
+	a.pools[class-minAllocatorClass].Put(buffer[:0])
+}
+
+// allocatorClass returns the exponent of the smallest power of two not lower than size (at least minAllocatorClass).
+func allocatorClass(size int) int {
+	if size <= 1<<minAllocatorClass {
+		return minAllocatorClass
+	}
+
+	return bits.Len(uint(size - 1))
+}
+
+// allocatedBuffer is a growable buffer, similar to bytes.Buffer, which obtains its memory from the Allocator.
+type allocatedBuffer struct {
+	allocator Allocator
+	buffer    []byte
+	offset    int
+}
+
+func (b *allocatedBuffer) Len() int {
+	return len(b.buffer) - b.offset
+}
+
+func (b *allocatedBuffer) Bytes() []byte {
+	return b.buffer[b.offset:]
+}
+
+func (b *allocatedBuffer) Write(p []byte) {
+	if len(b.buffer)+len(p) > cap(b.buffer) {
+		b.grow(len(p))
+	}
+
+	b.buffer = append(b.buffer, p...)
+}
+
+// Next discards the next n bytes of the buffer.
+func (b *allocatedBuffer) Next(n int) {
+	b.offset += n
+	if b.offset == len(b.buffer) {
+		b.buffer = b.buffer[:0]
+		b.offset = 0
+	}
+}
+
+func (b *allocatedBuffer) Reset() {
+	b.buffer = b.buffer[:0]
+	b.offset = 0
+}
+
+// Free returns the memory to the Allocator.
+func (b *allocatedBuffer) Free() {
+	if b.buffer != nil {
+		b.allocator.Free(b.buffer)
+	}
+
+	b.buffer = nil
+	b.offset = 0
+}
+
+func (b *allocatedBuffer) grow(n int) {
+	data := b.buffer[b.offset:]
+
+	if len(data)+n <= cap(b.buffer)/2 {
+		// enough space after sliding the data down
+		b.buffer = b.buffer[:copy(b.buffer, data)]
+		b.offset = 0
+		return
+	}
+
+	grown := b.allocator.Allocate(2*cap(b.buffer) + n)
+	grown = grown[:copy(grown, data)]
+
+	if b.buffer != nil {
+		b.allocator.Free(b.buffer)
+	}
+
+	b.buffer = grown
+	b.offset = 0
+}
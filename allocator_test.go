@@ -0,0 +1,121 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+)
+
+type countingAllocator struct {
+	allocated int
+	freed     int
+	m         sync.Mutex
+}
+
+func (a *countingAllocator) Allocate(size int) []byte {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.allocated++
+	return make([]byte, size)
+}
+
+func (a *countingAllocator) Free(_ []byte) {
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	a.freed++
+}
+
+func TestPoolAllocator(t *testing.T) {
+	// given
+	allocator := PoolAllocator()
+
+	// when
+	small := allocator.Allocate(10)
+	medium := allocator.Allocate(1000)
+	huge := allocator.Allocate(1<<maxAllocatorClass + 1)
+
+	// then
+	assert.Equal(t, 10, len(small), "length should match")
+	assert.Equal(t, 64, cap(small), "capacity should be rounded up to the smallest class")
+	assert.Equal(t, 1000, len(medium), "length should match")
+	assert.Equal(t, 1024, cap(medium), "capacity should be rounded up to the power of two")
+	assert.Equal(t, 1<<maxAllocatorClass+1, cap(huge), "huge buffer should not be rounded")
+
+	allocator.Free(small)
+	allocator.Free(medium)
+	allocator.Free(huge)
+	allocator.Free(make([]byte, 100))
+}
+
+func TestAllocatedBuffer(t *testing.T) {
+	// given
+	allocator := &countingAllocator{}
+	buffer := allocatedBuffer{allocator: allocator}
+
+	// when
+	buffer.Write([]byte("Hello "))
+	buffer.Write([]byte("world!"))
+	buffer.Next(6)
+	buffer.Write(bytes.Repeat([]byte{'x'}, 100))
+
+	// then
+	assert.Equal(t, 106, buffer.Len(), "length should match")
+	assert.Equal(t, []byte("world!"), buffer.Bytes()[:6], "data should be retained")
+
+	buffer.Free()
+	assert.Equal(t, allocator.allocated, allocator.freed, "all the buffers should be freed")
+}
+
+func TestFramingHandlerAllocator(t *testing.T) {
+	// given
+	in := bytes.NewBuffer(generateTestPayloadWithSeparator(1024))
+	socket := MockSocket(in, io.Discard)
+	allocator := &countingAllocator{}
+
+	// when
+	var receivedPackets int
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(packet []byte) {
+				receivedPackets++
+				assert.True(t, validateTestPayload(1024, packet), "packet should be valid")
+			}
+		},
+		&PacketFramingConfig{
+			ReadBufferSize: 512,
+			MinReadSpace:   256,
+			Allocator:      allocator,
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, 1, receivedPackets, "received packets count must match")
+	assert.Greater(t, allocator.allocated, 1, "receive buffer should be allocated")
+	assert.Equal(t, allocator.allocated, allocator.freed, "all the buffers should be freed")
+}
+
+func TestPacketWriterAllocator(t *testing.T) {
+	// given
+	allocator := &countingAllocator{}
+	writer := NewPacketWriterWithAllocator(allocator, 4)
+
+	// when
+	writer.Uint32(1).Uint32(2).Text("Hello world!")
+	packet := append([]byte(nil), writer.Bytes()...)
+	writer.Release()
+
+	// then
+	reader := NewPacketReader(packet)
+	assert.Equal(t, uint32(1), reader.Uint32(), "value should match")
+	assert.Equal(t, uint32(2), reader.Uint32(), "value should match")
+	assert.Equal(t, "Hello world!", reader.Text(), "value should match")
+	assert.Nil(t, reader.Err(), "err should be nil")
+	assert.Greater(t, allocator.allocated, 1, "buffer should be grown")
+	assert.Equal(t, allocator.allocated, allocator.freed, "all the buffers should be freed")
+}
@@ -5,7 +5,9 @@ import (
 	"crypto/rand"
 	"fmt"
 	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
 	"io"
+	"net"
 	"os"
 	"testing"
 )
@@ -13,8 +15,8 @@ import (
 var payload = preparePayload(1024)
 
 func BenchmarkSingleClient(b *testing.B) {
-	listener := newMockListener()
-	server := createEchoServer(listener)
+	listener := tinytcptest.NewPipeListener()
+	server := createEchoServer(listener, goroutinePerConnection)
 	defer server.Stop()
 
 	buffer := make([]byte, len(payload))
@@ -37,8 +39,8 @@ func BenchmarkSingleClient(b *testing.B) {
 }
 
 func BenchmarkConcurrentClients(b *testing.B) {
-	listener := newMockListener()
-	server := createEchoServer(listener)
+	listener := tinytcptest.NewPipeListener()
+	server := createEchoServer(listener, goroutinePerConnection)
 	defer server.Stop()
 
 	b.ResetTimer()
@@ -61,7 +63,115 @@ func BenchmarkConcurrentClients(b *testing.B) {
 	})
 }
 
-func createEchoServer(listener *mockListener) *tinytcp.Server {
+// BenchmarkPipelinedEcho sends the packets without waiting for the responses, so the server constantly has
+// more than one packet buffered and the framing layer extracts them in batches.
+func BenchmarkPipelinedEcho(b *testing.B) {
+	listener := tinytcptest.NewPipeListener()
+	server := createEchoServer(listener, goroutinePerConnection)
+	defer server.Stop()
+
+	client := listener.Connect()
+	defer client.Close()
+
+	// echo server responds with the packet, without the length prefix
+	packetSize, _ := tinytcp.ReadVarInt(bytes.NewReader(payload))
+	buffer := make([]byte, packetSize)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+
+	go func() {
+		for i := 0; i < b.N; i++ {
+			if _, err := client.Write(payload); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := io.ReadFull(client, buffer); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkManyClients keeps 10k echo clients connected at once, so the sockets don't fit into CPU caches
+// and the benchmark is sensitive to the layout of Socket (false sharing between hot and cold fields).
+func BenchmarkManyClients(b *testing.B) {
+	const clientsCount = 10_000
+
+	listener := tinytcptest.NewPipeListener()
+	server := createEchoServer(listener, goroutinePerConnection)
+	defer server.Stop()
+
+	clients := make(chan net.Conn, clientsCount)
+	for i := 0; i < clientsCount; i++ {
+		clients <- listener.Connect()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		buffer := make([]byte, len(payload))
+
+		for pb.Next() {
+			client := <-clients
+
+			_, err := client.Write(payload)
+			if err == nil {
+				_, _ = client.Read(buffer)
+			}
+
+			clients <- client
+		}
+	})
+}
+
+func BenchmarkConnectionChurn(b *testing.B) {
+	b.Run("GoroutinePerConnection", func(b *testing.B) {
+		benchmarkConnectionChurn(b, goroutinePerConnection)
+	})
+
+	b.Run("ReusedGoroutines", func(b *testing.B) {
+		benchmarkConnectionChurn(b, func(handler tinytcp.SocketHandler) tinytcp.ForkingStrategy {
+			return tinytcp.ReusedGoroutines(handler)
+		})
+	})
+}
+
+func benchmarkConnectionChurn(b *testing.B, strategy func(tinytcp.SocketHandler) tinytcp.ForkingStrategy) {
+	listener := tinytcptest.NewPipeListener()
+	server := createEchoServer(listener, strategy)
+	defer server.Stop()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		buffer := make([]byte, len(payload))
+
+		for pb.Next() {
+			client := listener.Connect()
+
+			_, err := client.Write(payload)
+			if err == nil {
+				_, _ = client.Read(buffer)
+			}
+
+			_ = client.Close()
+		}
+	})
+}
+
+func goroutinePerConnection(handler tinytcp.SocketHandler) tinytcp.ForkingStrategy {
+	return tinytcp.GoroutinePerConnection(handler)
+}
+
+func createEchoServer(
+	listener *tinytcptest.PipeListener,
+	strategy func(tinytcp.SocketHandler) tinytcp.ForkingStrategy,
+) *tinytcp.Server {
 	server := tinytcp.NewServer("fakeaddress")
 	server.Listener(listener)
 
@@ -71,7 +181,7 @@ func createEchoServer(listener *mockListener) *tinytcp.Server {
 		ch <- struct{}{}
 	})
 
-	server.ForkingStrategy(tinytcp.GoroutinePerConnection(
+	server.ForkingStrategy(strategy(
 		tinytcp.PacketFramingHandler(
 			tinytcp.LengthPrefixedFraming(tinytcp.PrefixVarInt),
 			func(socket *tinytcp.Socket) tinytcp.PacketHandler {
@@ -0,0 +1,277 @@
+// Command loadgen generates load against an echo server and reports the throughput and the distribution
+// of round-trip latencies. The load is spread among multiple worker processes, so the load generator itself
+// isn't limited by a single Go runtime (scheduler, GC) and the measurements are not skewed by it.
+//
+// Usage:
+//
+//	$ cd benchmarks && go run ./cmd/loadgen -serve -processes 4 -connections 64 -duration 30s
+//
+// Each connection sends a request of given size and waits for the response, before sending the next one.
+// The target server is expected to echo back everything it receives (see examples/echo). With -serve, such
+// a server is started by the load generator itself.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mkorman9/tinytcp"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type options struct {
+	address     string
+	processes   int
+	connections int
+	duration    time.Duration
+	size        int
+	serve       bool
+	worker      bool
+}
+
+// workerResult is passed from the workers to the parent process through the standard output.
+type workerResult struct {
+	Requests  uint64                 `json:"requests"`
+	Errors    uint64                 `json:"errors"`
+	Histogram *hdrhistogram.Snapshot `json:"histogram"`
+}
+
+func main() {
+	var opts options
+
+	flag.StringVar(&opts.address, "address", "127.0.0.1:7000", "address of the echo server")
+	flag.IntVar(&opts.processes, "processes", runtime.NumCPU(), "number of worker processes")
+	flag.IntVar(&opts.connections, "connections", 16, "number of connections opened by each worker process")
+	flag.DurationVar(&opts.duration, "duration", 10*time.Second, "duration of the test")
+	flag.IntVar(&opts.size, "size", 1024, "size of the request (in bytes)")
+	flag.BoolVar(&opts.serve, "serve", false, "start an echo server listening on -address")
+	flag.BoolVar(&opts.worker, "worker", false, "run as a worker process (used internally)")
+	flag.Parse()
+
+	if opts.worker {
+		if err := runWorker(&opts); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Worker error: %v\n", err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if opts.serve {
+		server, err := startEchoServer(opts.address)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Error while starting the server: %v\n", err)
+			os.Exit(1)
+		}
+
+		defer server.Stop()
+	}
+
+	if err := runParent(&opts); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runParent starts the worker processes, waits for them to finish, and merges their results.
+func runParent(opts *options) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var (
+		results = make([]workerResult, opts.processes)
+		errs    = make([]error, opts.processes)
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < opts.processes; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			var stdout bytes.Buffer
+
+			cmd := exec.Command(
+				executable,
+				"-worker",
+				"-address", opts.address,
+				"-connections", strconv.Itoa(opts.connections),
+				"-duration", opts.duration.String(),
+				"-size", strconv.Itoa(opts.size),
+			)
+			cmd.Stdout = &stdout
+			cmd.Stderr = os.Stderr
+
+			if err := cmd.Run(); err != nil {
+				errs[i] = fmt.Errorf("worker %d: %w", i, err)
+				return
+			}
+
+			if err := json.Unmarshal(stdout.Bytes(), &results[i]); err != nil {
+				errs[i] = fmt.Errorf("worker %d: %w", i, err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	var (
+		histogram = newHistogram()
+		requests  uint64
+		failures  uint64
+	)
+
+	for _, result := range results {
+		requests += result.Requests
+		failures += result.Errors
+		histogram.Merge(hdrhistogram.Import(result.Histogram))
+	}
+
+	printSummary(opts, requests, failures, histogram)
+	return nil
+}
+
+// runWorker generates the load over opts.connections connections, and writes the result to the standard output.
+func runWorker(opts *options) error {
+	var (
+		result    workerResult
+		histogram = newHistogram()
+		m         sync.Mutex
+		wg        sync.WaitGroup
+		deadline  = time.Now().Add(opts.duration)
+	)
+
+	for i := 0; i < opts.connections; i++ {
+		conn, err := net.Dial("tcp", opts.address)
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			local := newHistogram()
+			requests, failed := generateLoad(conn, opts.size, deadline, local)
+
+			m.Lock()
+			defer m.Unlock()
+
+			result.Requests += requests
+			if failed {
+				result.Errors++
+			}
+			histogram.Merge(local)
+		}()
+	}
+
+	wg.Wait()
+
+	result.Histogram = histogram.Export()
+	return json.NewEncoder(os.Stdout).Encode(&result)
+}
+
+// generateLoad sends the requests until the deadline, recording the round-trip latencies.
+// It returns the number of completed requests and whether the connection has failed.
+func generateLoad(conn net.Conn, size int, deadline time.Time, histogram *hdrhistogram.Histogram) (uint64, bool) {
+	request := make([]byte, size)
+	response := make([]byte, size)
+	var requests uint64
+
+	_ = conn.SetDeadline(deadline.Add(time.Second))
+
+	for time.Now().Before(deadline) {
+		start := time.Now()
+
+		if _, err := conn.Write(request); err != nil {
+			return requests, true
+		}
+		if _, err := io.ReadFull(conn, response); err != nil {
+			return requests, true
+		}
+
+		_ = histogram.RecordValue(int64(time.Since(start)))
+		requests++
+	}
+
+	return requests, false
+}
+
+func startEchoServer(address string) (*tinytcp.Server, error) {
+	server := tinytcp.NewServer(address)
+	started := make(chan struct{})
+	failed := make(chan error, 1)
+
+	server.OnStart(func() {
+		close(started)
+	})
+
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(socket *tinytcp.Socket) {
+		var buffer [4096]byte
+
+		for {
+			n, err := socket.Read(buffer[:])
+			if err != nil {
+				return
+			}
+
+			if _, err := socket.Write(buffer[:n]); err != nil {
+				return
+			}
+		}
+	}))
+
+	go func() {
+		failed <- server.Start()
+	}()
+
+	select {
+	case <-started:
+		return server, nil
+	case err := <-failed:
+		return nil, err
+	}
+}
+
+// newHistogram creates a histogram tracking the latencies between 1 µs and 1 minute, with 3 significant digits.
+func newHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(int64(time.Microsecond), int64(time.Minute), 3)
+}
+
+func printSummary(opts *options, requests, failures uint64, histogram *hdrhistogram.Histogram) {
+	fmt.Printf(
+		"%d processes, %d connections, %d B requests, %v\n",
+		opts.processes,
+		opts.processes*opts.connections,
+		opts.size,
+		opts.duration,
+	)
+	fmt.Printf("Requests:   %d (%.0f req/s)\n", requests, float64(requests)/opts.duration.Seconds())
+	fmt.Printf("Failures:   %d connections\n", failures)
+	fmt.Printf("Latency:\n")
+
+	for _, quantile := range []float64{50, 90, 99, 99.9, 99.99} {
+		fmt.Printf("  p%-6v %v\n", quantile, time.Duration(histogram.ValueAtQuantile(quantile)))
+	}
+
+	fmt.Printf("  max     %v\n", time.Duration(histogram.Max()))
+}
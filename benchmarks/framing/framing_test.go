@@ -0,0 +1,188 @@
+package framing
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"io"
+	"testing"
+)
+
+type protocol struct {
+	name    string
+	framing tinytcp.FramingProtocol
+	encode  func(packet []byte) []byte
+}
+
+var protocols = []protocol{
+	{
+		name:    "SplitBySeparator",
+		framing: tinytcp.SplitBySeparator([]byte{'\n'}),
+		encode:  separated([]byte{'\n'}),
+	},
+	{
+		name:    "SplitByLongSeparator",
+		framing: tinytcp.SplitBySeparator([]byte("\r\n\r\n")),
+		encode:  separated([]byte("\r\n\r\n")),
+	},
+	{
+		name:    "LengthPrefixedVarInt",
+		framing: tinytcp.LengthPrefixedFraming(tinytcp.PrefixVarInt),
+		encode:  prefixed(tinytcp.PrefixVarInt),
+	},
+	{
+		name:    "LengthPrefixedInt32",
+		framing: tinytcp.LengthPrefixedFraming(tinytcp.PrefixInt32_BE),
+		encode:  prefixed(tinytcp.PrefixInt32_BE),
+	},
+}
+
+// packet sizes double as separator densities for separator-based protocols
+var packetSizes = []int{16, 512, 8192}
+
+// fragment sizes simulate the data arriving in chunks of given size, 0 means whole packets
+var fragmentSizes = []int{0, 7, 1400}
+
+func BenchmarkExtractPacket(b *testing.B) {
+	for _, p := range protocols {
+		for _, size := range packetSizes {
+			b.Run(fmt.Sprintf("%s/%dB", p.name, size), func(b *testing.B) {
+				source := p.encode(preparePacket(size))
+
+				b.ReportAllocs()
+				b.SetBytes(int64(len(source)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					if _, _, extracted := p.framing.ExtractPacket(source); !extracted {
+						b.Fatal("packet not extracted")
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkPacketFramingHandler(b *testing.B) {
+	for _, p := range protocols {
+		for _, size := range packetSizes {
+			for _, fragment := range fragmentSizes {
+				name := fmt.Sprintf("%s/%dB/Whole", p.name, size)
+				if fragment > 0 {
+					name = fmt.Sprintf("%s/%dB/Fragments%d", p.name, size, fragment)
+				}
+
+				b.Run(name, func(b *testing.B) {
+					benchmarkPacketFramingHandler(b, p, size, fragment)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkPacketFramingHandler(b *testing.B, p protocol, size int, fragment int) {
+	encoded := p.encode(preparePacket(size))
+	if fragment == 0 {
+		fragment = len(encoded)
+	}
+
+	var received int
+	handler := tinytcp.PacketFramingHandler(
+		p.framing,
+		func(_ *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(_ []byte) {
+				received++
+			}
+		},
+	)
+
+	reader := &cyclicReader{
+		stream:    bytes.Repeat(encoded, 64),
+		fragment:  fragment,
+		remaining: b.N * len(encoded),
+	}
+	socket := tinytcptest.NewSocket(reader, nil)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(encoded)))
+	b.ResetTimer()
+
+	handler(socket)
+
+	b.StopTimer()
+	if received != b.N {
+		b.Fatalf("received %d packets, expected %d", received, b.N)
+	}
+}
+
+func TestExtractPacketAllocations(t *testing.T) {
+	for _, p := range protocols {
+		for _, size := range packetSizes {
+			source := p.encode(preparePacket(size))
+
+			allocs := testing.AllocsPerRun(100, func() {
+				_, _, _ = p.framing.ExtractPacket(source)
+			})
+
+			if allocs != 0 {
+				t.Errorf("%s/%dB: ExtractPacket allocates %v times per packet", p.name, size, allocs)
+			}
+		}
+	}
+}
+
+// cyclicReader serves the stream of packets in fragments of given size, in a loop, until the limit is reached.
+type cyclicReader struct {
+	stream    []byte
+	position  int
+	fragment  int
+	remaining int
+}
+
+func (r *cyclicReader) Read(b []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+
+	n := r.fragment
+	if n > len(b) {
+		n = len(b)
+	}
+	if n > r.remaining {
+		n = r.remaining
+	}
+	if n > len(r.stream)-r.position {
+		n = len(r.stream) - r.position
+	}
+
+	copy(b, r.stream[r.position:r.position+n])
+	r.position = (r.position + n) % len(r.stream)
+	r.remaining -= n
+
+	return n, nil
+}
+
+func separated(separator []byte) func([]byte) []byte {
+	return func(packet []byte) []byte {
+		return append(append([]byte(nil), packet...), separator...)
+	}
+}
+
+func prefixed(prefix tinytcp.PrefixType) func([]byte) []byte {
+	return func(packet []byte) []byte {
+		var buffer bytes.Buffer
+		_ = tinytcp.WritePrefixedByteArray(&buffer, packet, prefix)
+		return buffer.Bytes()
+	}
+}
+
+func preparePacket(size int) []byte {
+	packet := make([]byte, size)
+	for i := range packet {
+		// printable bytes, so the packet never contains a separator
+		packet[i] = 'a' + byte(i%26)
+	}
+
+	return packet
+}
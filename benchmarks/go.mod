@@ -0,0 +1,10 @@
+module github.com/mkorman9/tinytcp/benchmarks
+
+go 1.20
+
+require (
+	github.com/HdrHistogram/hdrhistogram-go v1.1.2
+	github.com/mkorman9/tinytcp v0.0.0
+)
+
+replace github.com/mkorman9/tinytcp => ../
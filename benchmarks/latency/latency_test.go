@@ -0,0 +1,196 @@
+package latency
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+var payloadSizes = []int{16, 1024, 8192}
+
+// BenchmarkRoundTripLatency measures the latency of a single request/response exchange with the echo server.
+// Apart from the mean (ns/op), it reports the percentiles of the round-trip time (p50-ns, p99-ns, p999-ns),
+// which are much more sensitive to the scheduling hiccups and GC pauses than the throughput.
+func BenchmarkRoundTripLatency(b *testing.B) {
+	for _, transport := range transports {
+		for _, size := range payloadSizes {
+			b.Run(fmt.Sprintf("%s/%dB", transport.name, size), func(b *testing.B) {
+				connect, stop := transport.start(b)
+				defer stop()
+
+				client := connect()
+				defer client.Close()
+
+				request := preparePayload(size)
+				response := make([]byte, size)
+				histogram := newHistogram()
+
+				b.ReportAllocs()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					start := time.Now()
+
+					if _, err := client.Write(request); err != nil {
+						b.Fatal(err)
+					}
+					if _, err := io.ReadFull(client, response); err != nil {
+						b.Fatal(err)
+					}
+
+					_ = histogram.RecordValue(int64(time.Since(start)))
+				}
+
+				b.StopTimer()
+				reportPercentiles(b, histogram)
+			})
+		}
+	}
+}
+
+// BenchmarkConcurrentRoundTripLatency measures the round-trip latency with multiple clients competing
+// for the server (see -cpu and b.SetParallelism).
+func BenchmarkConcurrentRoundTripLatency(b *testing.B) {
+	for _, transport := range transports {
+		b.Run(transport.name, func(b *testing.B) {
+			connect, stop := transport.start(b)
+			defer stop()
+
+			var (
+				histogram = newHistogram()
+				m         sync.Mutex
+			)
+
+			b.ReportAllocs()
+			b.SetParallelism(4)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				client := connect()
+				defer client.Close()
+
+				request := preparePayload(1024)
+				response := make([]byte, 1024)
+				local := newHistogram()
+
+				for pb.Next() {
+					start := time.Now()
+
+					if _, err := client.Write(request); err != nil {
+						break
+					}
+					if _, err := io.ReadFull(client, response); err != nil {
+						break
+					}
+
+					_ = local.RecordValue(int64(time.Since(start)))
+				}
+
+				m.Lock()
+				histogram.Merge(local)
+				m.Unlock()
+			})
+
+			b.StopTimer()
+			reportPercentiles(b, histogram)
+		})
+	}
+}
+
+type transport struct {
+	name  string
+	start func(b *testing.B) (connect func() net.Conn, stop func() error)
+}
+
+var transports = []transport{
+	{
+		name: "Pipe",
+		start: func(b *testing.B) (func() net.Conn, func() error) {
+			listener := tinytcptest.NewPipeListener()
+			server := startEchoServer(b, tinytcp.NewServer("fakeaddress"), listener)
+
+			return listener.Connect, server.Stop
+		},
+	},
+	{
+		name: "TCP",
+		start: func(b *testing.B) (func() net.Conn, func() error) {
+			server := startEchoServer(b, tinytcp.NewServer("127.0.0.1:0"), nil)
+			address := fmt.Sprintf("127.0.0.1:%d", server.Port())
+
+			connect := func() net.Conn {
+				conn, err := net.Dial("tcp", address)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				return conn
+			}
+
+			return connect, server.Stop
+		},
+	},
+}
+
+func startEchoServer(b *testing.B, server *tinytcp.Server, listener tinytcp.Listener) *tinytcp.Server {
+	if listener != nil {
+		server.Listener(listener)
+	}
+
+	ch := make(chan struct{})
+
+	server.OnStart(func() {
+		close(ch)
+	})
+
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(
+		tinytcp.PacketFramingHandler(
+			tinytcp.LengthPrefixedFraming(tinytcp.PrefixVarInt),
+			func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+				return func(packet []byte) {
+					_, _ = socket.Write(packet)
+				}
+			},
+		),
+	))
+
+	go func() {
+		if err := server.Start(); err != nil {
+			b.Error(err)
+		}
+	}()
+
+	<-ch
+
+	return server
+}
+
+// newHistogram creates a histogram tracking the latencies between 1 ns and 10 s, with 3 significant digits.
+func newHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(1, int64(10*time.Second), 3)
+}
+
+func reportPercentiles(b *testing.B, histogram *hdrhistogram.Histogram) {
+	b.ReportMetric(float64(histogram.ValueAtQuantile(50)), "p50-ns")
+	b.ReportMetric(float64(histogram.ValueAtQuantile(99)), "p99-ns")
+	b.ReportMetric(float64(histogram.ValueAtQuantile(99.9)), "p999-ns")
+}
+
+// preparePayload returns a random packet of given size, prefixed with its length.
+func preparePayload(size int) []byte {
+	packet := make([]byte, size)
+	_, _ = rand.Read(packet)
+
+	writer := tinytcp.NewPacketWriter()
+	_ = tinytcp.WriteVarInt(writer, size)
+	_, _ = writer.Write(packet)
+
+	return writer.Bytes()
+}
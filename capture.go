@@ -0,0 +1,70 @@
+package tinytcp
+
+import (
+	"encoding/hex"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnableCapture starts copying all the raw data read from and written to the socket into sink, in hexdump format.
+// Each chunk is preceded by a header line with a timestamp, remote address, direction ("<" for inbound data,
+// ">" for outbound data) and size. In TLS mode, captured data is already decrypted.
+// It's a debugging tool, meant to be enabled only for selected connections, as it slows down all the socket operations.
+// It must not be called concurrently with Read() or Write(), preferably right at the start of the handler.
+func (s *Socket) EnableCapture(sink io.Writer) {
+	c := &capture{
+		sink:       sink,
+		remoteAddr: s.remoteAddr,
+	}
+
+	s.meteredReader.reader = &captureReader{reader: s.meteredReader.reader, capture: c}
+	s.meteredWriter.writer = &captureWriter{writer: s.meteredWriter.writer, capture: c}
+}
+
+type capture struct {
+	sink       io.Writer
+	remoteAddr string
+	m          sync.Mutex
+}
+
+func (c *capture) record(direction string, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	header := time.Now().UTC().Format(time.RFC3339Nano) + " " + c.remoteAddr + " " + direction + " " +
+		strconv.Itoa(len(b)) + " bytes\n"
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	_, _ = io.WriteString(c.sink, header)
+
+	dumper := hex.Dumper(c.sink)
+	_, _ = dumper.Write(b)
+	_ = dumper.Close()
+}
+
+type captureReader struct {
+	reader  io.Reader
+	capture *capture
+}
+
+func (r *captureReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.capture.record("<", b[:n])
+	return n, err
+}
+
+type captureWriter struct {
+	writer  io.Writer
+	capture *capture
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	w.capture.record(">", b[:n])
+	return n, err
+}
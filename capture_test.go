@@ -0,0 +1,42 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSocketCapture(t *testing.T) {
+	// given
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	socket := NewSocket(serverConn)
+	defer socket.Close()
+
+	var sink bytes.Buffer
+	socket.EnableCapture(&sink)
+
+	go func() {
+		_, _ = clientConn.Write([]byte("ping"))
+		_, _ = clientConn.Read(make([]byte, 4))
+	}()
+
+	// when
+	packet := make([]byte, 4)
+	_, readErr := socket.Read(packet)
+	_, writeErr := socket.Write([]byte("pong"))
+
+	// then
+	assert.Nil(t, readErr, "readErr should be nil")
+	assert.Nil(t, writeErr, "writeErr should be nil")
+
+	lines := strings.Split(strings.TrimSpace(sink.String()), "\n")
+	assert.Len(t, lines, 4, "two chunks should be captured")
+	assert.True(t, strings.HasSuffix(lines[0], "< 4 bytes"), "inbound header should match")
+	assert.Contains(t, lines[1], "70 69 6e 67", "inbound data should be dumped")
+	assert.True(t, strings.HasSuffix(lines[2], "> 4 bytes"), "outbound header should match")
+	assert.Contains(t, lines[3], "|pong|", "outbound data should be dumped")
+}
@@ -0,0 +1,105 @@
+package tinytcp
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrCallTimeout is returned by Client.Call when the response does not arrive in time.
+var ErrCallTimeout = errors.New("call timed out")
+
+type callResult struct {
+	response []byte
+	err      error
+}
+
+// Call writes payload to the connection and waits for the response, extracted according to given FramingProtocol.
+// Payload is expected to be already framed. Calls can be pipelined - multiple goroutines can call concurrently,
+// and responses are matched with requests in the order the requests were written.
+// Background read loop is started on the first call, so all the calls should use the same FramingProtocol,
+// and Client should not be read from directly afterwards. The value of timeout of 0 or less means no timeout.
+func (c *Client) Call(payload []byte, framingProtocol FramingProtocol, timeout time.Duration) ([]byte, error) {
+	c.callsOnce.Do(func() {
+		c.OnClose(c.failPendingCalls)
+		c.OnPacket(framingProtocol, c.handleCallResponse)
+	})
+
+	// buffered channel lets the response be delivered even if the caller timed out
+	resultChannel := make(chan callResult, 1)
+
+	if err := c.writeCall(payload, resultChannel); err != nil {
+		return nil, err
+	}
+
+	if timeout <= 0 {
+		result := <-resultChannel
+		return result.response, result.err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-resultChannel:
+		return result.response, result.err
+	case <-timer.C:
+		return nil, ErrCallTimeout
+	}
+}
+
+func (c *Client) writeCall(payload []byte, resultChannel chan callResult) error {
+	// order of writes must match the order of pending calls
+	c.callsWriteMutex.Lock()
+	defer c.callsWriteMutex.Unlock()
+
+	c.pendingCallsMutex.Lock()
+	if c.callsClosed {
+		c.pendingCallsMutex.Unlock()
+		return io.EOF
+	}
+	c.pendingCalls = append(c.pendingCalls, resultChannel)
+	c.pendingCallsMutex.Unlock()
+
+	if err := WriteBytes(c, payload); err != nil {
+		c.pendingCallsMutex.Lock()
+		if n := len(c.pendingCalls); n > 0 && c.pendingCalls[n-1] == resultChannel {
+			c.pendingCalls = c.pendingCalls[:n-1]
+		}
+		c.pendingCallsMutex.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) handleCallResponse(packet []byte) {
+	c.pendingCallsMutex.Lock()
+	defer c.pendingCallsMutex.Unlock()
+
+	if len(c.pendingCalls) == 0 {
+		// unsolicited response
+		return
+	}
+
+	resultChannel := c.pendingCalls[0]
+	c.pendingCalls = c.pendingCalls[1:]
+
+	response := make([]byte, len(packet))
+	copy(response, packet)
+
+	resultChannel <- callResult{response: response}
+}
+
+func (c *Client) failPendingCalls(_ CloseReason) {
+	c.pendingCallsMutex.Lock()
+	defer c.pendingCallsMutex.Unlock()
+
+	c.callsClosed = true
+
+	for _, resultChannel := range c.pendingCalls {
+		resultChannel <- callResult{err: io.EOF}
+	}
+	c.pendingCalls = nil
+}
@@ -0,0 +1,74 @@
+package tinytcp
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientCall(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+	defer client.Close()
+
+	go func() {
+		reader := bufio.NewReader(server)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			_, _ = server.Write(line)
+		}
+	}()
+
+	// when
+	var wg sync.WaitGroup
+	responses := make([][]byte, 10)
+	errs := make([]error, 10)
+
+	for i := range responses {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = client.Call([]byte(strconv.Itoa(i)+"\n"), SplitBySeparator([]byte{'\n'}), time.Second)
+		}(i)
+	}
+
+	wg.Wait()
+
+	// then
+	for i := range responses {
+		assert.Nil(t, errs[i], "err should be nil")
+		assert.Equal(t, []byte(strconv.Itoa(i)), responses[i], "response should match the request")
+	}
+}
+
+func TestClientCallTimeout(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+	defer client.Close()
+
+	go func() {
+		buffer := make([]byte, 64)
+		for {
+			if _, err := server.Read(buffer); err != nil {
+				return
+			}
+		}
+	}()
+
+	// when
+	_, err := client.Call([]byte("ping\n"), SplitBySeparator([]byte{'\n'}), 10*time.Millisecond)
+
+	// then
+	assert.Equal(t, ErrCallTimeout, err, "err should be equal to ErrCallTimeout")
+}
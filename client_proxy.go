@@ -0,0 +1,262 @@
+package tinytcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	socks5Version          = 0x05
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthNoAcceptable = 0xFF
+	socks5CommandConnect   = 0x01
+	socks5AddressIPv4      = 0x01
+	socks5AddressDomain    = 0x03
+	socks5AddressIPv6      = 0x04
+
+	maxHTTPConnectResponseSize = 8 * 1024 // 8 KiB
+)
+
+func dialProxy(ctx context.Context, dialer *net.Dialer, address string, options *DialOptions) (net.Conn, error) {
+	proxyURL, err := url.Parse(options.Proxy)
+	if err != nil {
+		return nil, err
+	}
+
+	var handshake func(net.Conn, string, *url.Userinfo) error
+
+	switch proxyURL.Scheme {
+	case "socks5":
+		address, err = resolveAddress(ctx, dialer, options.Network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		handshake = socks5Handshake
+	case "socks5h":
+		handshake = socks5Handshake
+	case "http":
+		handshake = httpConnectHandshake
+	default:
+		return nil, errors.New("unsupported proxy scheme: " + proxyURL.Scheme)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := handshake(conn, address, proxyURL.User); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// resolveAddress replaces the host name in address with one of its IP addresses.
+func resolveAddress(ctx context.Context, dialer *net.Dialer, network string, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return address, nil
+	}
+
+	resolver := dialer.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	ipNetwork := "ip"
+	switch network {
+	case "tcp4":
+		ipNetwork = "ip4"
+	case "tcp6":
+		ipNetwork = "ip6"
+	}
+
+	ips, err := resolver.LookupIP(ctx, ipNetwork, host)
+	if err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(ips[0].String(), port), nil
+}
+
+func socks5Handshake(conn net.Conn, address string, user *url.Userinfo) error {
+	host, portRaw, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.ParseUint(portRaw, 10, 16)
+	if err != nil {
+		return errors.New("invalid port: " + portRaw)
+	}
+
+	// method negotiation
+	method := byte(socks5AuthNone)
+	if user != nil {
+		method = socks5AuthPassword
+	}
+
+	if err := WriteBytes(conn, []byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	var response [2]byte
+	if _, err := io.ReadFull(conn, response[:]); err != nil {
+		return err
+	}
+	if response[0] != socks5Version {
+		return errors.New("invalid SOCKS5 server version")
+	}
+	if response[1] == socks5AuthNoAcceptable || response[1] != method {
+		return errors.New("no acceptable SOCKS5 authentication method")
+	}
+
+	// username/password authentication (RFC 1929)
+	if method == socks5AuthPassword {
+		username := user.Username()
+		password, _ := user.Password()
+		if len(username) > 255 || len(password) > 255 {
+			return errors.New("SOCKS5 credentials too long")
+		}
+
+		var request bytes.Buffer
+		request.WriteByte(1)
+		request.WriteByte(byte(len(username)))
+		request.WriteString(username)
+		request.WriteByte(byte(len(password)))
+		request.WriteString(password)
+
+		if err := WriteBytes(conn, request.Bytes()); err != nil {
+			return err
+		}
+
+		if _, err := io.ReadFull(conn, response[:]); err != nil {
+			return err
+		}
+		if response[1] != 0 {
+			return errors.New("SOCKS5 authentication failed")
+		}
+	}
+
+	// connect command
+	var request bytes.Buffer
+	request.Write([]byte{socks5Version, socks5CommandConnect, 0})
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			request.WriteByte(socks5AddressIPv4)
+			request.Write(ip4)
+		} else {
+			request.WriteByte(socks5AddressIPv6)
+			request.Write(ip.To16())
+		}
+	} else {
+		if len(host) > 255 {
+			return errors.New("SOCKS5 host name too long")
+		}
+
+		request.WriteByte(socks5AddressDomain)
+		request.WriteByte(byte(len(host)))
+		request.WriteString(host)
+	}
+
+	_ = binary.Write(&request, binary.BigEndian, uint16(port))
+
+	if err := WriteBytes(conn, request.Bytes()); err != nil {
+		return err
+	}
+
+	var reply [4]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[1] != 0 {
+		return errors.New("SOCKS5 connect failed with code " + strconv.Itoa(int(reply[1])))
+	}
+
+	// skip bound address
+	var boundAddressSize int
+	switch reply[3] {
+	case socks5AddressIPv4:
+		boundAddressSize = net.IPv4len
+	case socks5AddressIPv6:
+		boundAddressSize = net.IPv6len
+	case socks5AddressDomain:
+		size, err := ReadByte(conn)
+		if err != nil {
+			return err
+		}
+
+		boundAddressSize = int(size)
+	default:
+		return errors.New("invalid SOCKS5 address type")
+	}
+
+	_, err = io.CopyN(io.Discard, conn, int64(boundAddressSize+2))
+	return err
+}
+
+func httpConnectHandshake(conn net.Conn, address string, user *url.Userinfo) error {
+	var request strings.Builder
+	request.WriteString("CONNECT " + address + " HTTP/1.1\r\n")
+	request.WriteString("Host: " + address + "\r\n")
+
+	if user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		request.WriteString("Proxy-Authorization: Basic " + credentials + "\r\n")
+	}
+
+	request.WriteString("\r\n")
+
+	if err := WriteBytes(conn, []byte(request.String())); err != nil {
+		return err
+	}
+
+	// response is read byte by byte, so no data sent by the target after the headers is consumed
+	var response bytes.Buffer
+	for !bytes.HasSuffix(response.Bytes(), []byte("\r\n\r\n")) {
+		if response.Len() >= maxHTTPConnectResponseSize {
+			return errors.New("HTTP CONNECT response too long")
+		}
+
+		b, err := ReadByte(conn)
+		if err != nil {
+			return err
+		}
+
+		response.WriteByte(b)
+	}
+
+	statusLine, _, _ := strings.Cut(response.String(), "\r\n")
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "HTTP/") {
+		return errors.New("invalid HTTP CONNECT response")
+	}
+	if fields[1] != "200" {
+		return errors.New("HTTP CONNECT failed: " + strings.Join(fields[1:], " "))
+	}
+
+	return nil
+}
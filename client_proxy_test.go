@@ -0,0 +1,189 @@
+package tinytcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSocks5Handshake(t *testing.T) {
+	// given
+	proxy, conn := net.Pipe()
+	requestChannel := make(chan []byte, 1)
+
+	go func() {
+		greeting := make([]byte, 3)
+		_, _ = io.ReadFull(proxy, greeting)
+		_, _ = proxy.Write([]byte{socks5Version, socks5AuthPassword})
+
+		auth := make([]byte, 1+1+4+1+6)
+		_, _ = io.ReadFull(proxy, auth)
+		_, _ = proxy.Write([]byte{1, 0})
+
+		request := make([]byte, 4+1+len("example.com")+2)
+		_, _ = io.ReadFull(proxy, request)
+		requestChannel <- request
+
+		_, _ = proxy.Write([]byte{socks5Version, 0, 0, socks5AddressIPv4, 127, 0, 0, 1, 0, 80})
+	}()
+
+	// when
+	err := socks5Handshake(conn, "example.com:80", url.UserPassword("user", "secret"))
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(
+		t,
+		append(append([]byte{socks5Version, socks5CommandConnect, 0, socks5AddressDomain, 11}, "example.com"...), 0, 80),
+		<-requestChannel,
+		"connect request should match",
+	)
+}
+
+func TestHTTPConnectHandshake(t *testing.T) {
+	// given
+	proxy, conn := net.Pipe()
+	requestChannel := make(chan *http.Request, 1)
+
+	go func() {
+		request, err := http.ReadRequest(bufio.NewReader(proxy))
+		if err != nil {
+			return
+		}
+		requestChannel <- request
+
+		_, _ = proxy.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	}()
+
+	// when
+	err := httpConnectHandshake(conn, "example.com:443", url.UserPassword("user", "secret"))
+	request := <-requestChannel
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, http.MethodConnect, request.Method, "method should match")
+	assert.Equal(t, "example.com:443", request.Host, "host should match")
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", request.Header.Get("Proxy-Authorization"), "credentials should match")
+}
+
+func TestHTTPConnectHandshakeRejected(t *testing.T) {
+	// given
+	proxy, conn := net.Pipe()
+
+	go func() {
+		_, _ = http.ReadRequest(bufio.NewReader(proxy))
+		_, _ = proxy.Write([]byte("HTTP/1.1 407 Proxy Authentication Required\r\n\r\n"))
+	}()
+
+	// when
+	err := httpConnectHandshake(conn, "example.com:443", nil)
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
+
+func TestDialProxySocks5ResolvesLocally(t *testing.T) {
+	for scheme, expected := range map[string][]byte{
+		"socks5":  {socks5AddressIPv4, 127, 0, 0, 1, 0, 80},
+		"socks5h": append([]byte{socks5AddressDomain, 9}, append([]byte("localhost"), 0, 80)...),
+	} {
+		t.Run(scheme, func(t *testing.T) {
+			// given
+			listener := startSocks5Proxy(t)
+			defer listener.Close()
+
+			// when
+			client, err := DialContext(context.Background(), "localhost:80", &DialOptions{
+				Network: "tcp4",
+				Timeout: time.Second,
+				Proxy:   scheme + "://" + listener.Addr().String(),
+			})
+			request := <-listener.requests
+
+			// then
+			assert.Nil(t, err, "err should be nil")
+			assert.Equal(t, expected, request, "target address should match")
+			_ = client.Close()
+		})
+	}
+}
+
+func TestDialProxyTimeoutTLSHandshake(t *testing.T) {
+	// given
+	listener := startSocks5Proxy(t)
+	defer listener.Close()
+
+	// when
+	start := time.Now()
+	_, err := DialContext(context.Background(), "127.0.0.1:443", &DialOptions{
+		Timeout:   100 * time.Millisecond,
+		Proxy:     "socks5://" + listener.Addr().String(),
+		TLSConfig: &tls.Config{},
+	})
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+	assert.Less(t, time.Since(start), time.Second, "timeout should cover the TLS handshake")
+}
+
+type socks5ProxyListener struct {
+	net.Listener
+	requests chan []byte
+}
+
+// startSocks5Proxy starts a SOCKS5 proxy that accepts all the connect requests and never forwards any data.
+func startSocks5Proxy(t *testing.T) *socks5ProxyListener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	proxy := &socks5ProxyListener{
+		Listener: listener,
+		requests: make(chan []byte, 1),
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				greeting := make([]byte, 3)
+				_, _ = io.ReadFull(conn, greeting)
+				_, _ = conn.Write([]byte{socks5Version, socks5AuthNone})
+
+				header := make([]byte, 5)
+				_, _ = io.ReadFull(conn, header)
+
+				addressSize := int(header[4]) + 2
+				switch header[3] {
+				case socks5AddressIPv4:
+					addressSize = net.IPv4len - 1 + 2
+				case socks5AddressIPv6:
+					addressSize = net.IPv6len - 1 + 2
+				}
+
+				address := make([]byte, addressSize)
+				_, _ = io.ReadFull(conn, address)
+				proxy.requests <- append(header[3:], address...)
+
+				_, _ = conn.Write([]byte{socks5Version, 0, 0, socks5AddressIPv4, 127, 0, 0, 1, 0, 80})
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+
+	return proxy
+}
@@ -0,0 +1,317 @@
+package tinytcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ClientServiceConfig holds a configuration for NewClientService.
+type ClientServiceConfig struct {
+	// DialOptions are options used to establish each connection.
+	DialOptions *DialOptions
+
+	// ReconnectDelay is a delay before reconnecting after the connection is lost or cannot be established.
+	// Delay is doubled after each failed attempt (default: 1s).
+	ReconnectDelay time.Duration
+
+	// MaxReconnectDelay is a maximal delay between reconnect attempts (default: 30s).
+	MaxReconnectDelay time.Duration
+
+	// OnDialError is a handler called when the connection cannot be established.
+	OnDialError func(error)
+
+	// ResolveInterval enables periodic re-resolution of the target host name. When the set of addresses changes,
+	// the connection is closed and established again, so the clients of DNS-load-balanced backends follow the changes.
+	// TTL returned by the Resolver takes precedence over the interval. The value of 0 disables re-resolution
+	// (default: 0).
+	ResolveInterval time.Duration
+
+	// Resolver is a function returning addresses of given host, along with their TTL (0 if unknown).
+	// (default: net.DefaultResolver.LookupHost, which doesn't report TTLs).
+	Resolver func(ctx context.Context, host string) (addresses []string, ttl time.Duration, err error)
+}
+
+// ClientService keeps a long-lived outbound connection alive. It conforms to the Service interface,
+// so it can be supervised by StartAndBlock alongside the servers.
+// Connection is established on Start(), re-established whenever it's lost, and closed on Stop().
+type ClientService struct {
+	address string
+	config  *ClientServiceConfig
+	handler func(*Client)
+
+	client      *Client
+	clientMutex sync.Mutex
+	stopChannel chan struct{}
+	stopOnce    sync.Once
+}
+
+func mergeClientServiceConfig(provided *ClientServiceConfig) *ClientServiceConfig {
+	config := &ClientServiceConfig{
+		ReconnectDelay:    1 * time.Second,
+		MaxReconnectDelay: 30 * time.Second,
+		OnDialError:       func(_ error) {},
+		Resolver:          lookupHost,
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.DialOptions != nil {
+		config.DialOptions = provided.DialOptions
+	}
+	if provided.ReconnectDelay > 0 {
+		config.ReconnectDelay = provided.ReconnectDelay
+	}
+	if provided.MaxReconnectDelay > 0 {
+		config.MaxReconnectDelay = provided.MaxReconnectDelay
+	}
+	if provided.OnDialError != nil {
+		config.OnDialError = provided.OnDialError
+	}
+	if provided.ResolveInterval > 0 {
+		config.ResolveInterval = provided.ResolveInterval
+	}
+	if provided.Resolver != nil {
+		config.Resolver = provided.Resolver
+	}
+
+	return config
+}
+
+// NewClientService returns new ClientService instance.
+// Handler is called for each established connection. The connection is considered in use until it's closed,
+// so handler can either block while using the client, or register callbacks (like OnPacket) and return.
+func NewClientService(address string, handler func(*Client), config ...*ClientServiceConfig) *ClientService {
+	var providedConfig *ClientServiceConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+
+	return &ClientService{
+		address:     address,
+		config:      mergeClientServiceConfig(providedConfig),
+		handler:     handler,
+		stopChannel: make(chan struct{}),
+	}
+}
+
+// Client returns currently connected client, or nil if the service is not connected.
+func (s *ClientService) Client() *Client {
+	s.clientMutex.Lock()
+	defer s.clientMutex.Unlock()
+
+	return s.client
+}
+
+// Start connects to the server and blocks until Stop() is called, reconnecting whenever the connection is lost.
+func (s *ClientService) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		<-s.stopChannel
+		cancel()
+	}()
+
+	delay := s.config.ReconnectDelay
+
+	for {
+		client, err := DialContext(ctx, s.address, s.config.DialOptions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			s.config.OnDialError(err)
+
+			if !s.wait(delay) {
+				return nil
+			}
+
+			delay *= 2
+			if delay > s.config.MaxReconnectDelay {
+				delay = s.config.MaxReconnectDelay
+			}
+
+			continue
+		}
+
+		delay = s.config.ReconnectDelay
+
+		if !s.serve(client) {
+			return nil
+		}
+	}
+}
+
+// Stop closes the connection and unblocks the Start() method.
+func (s *ClientService) Stop() error {
+	s.stopOnce.Do(func() {
+		close(s.stopChannel)
+	})
+
+	s.clientMutex.Lock()
+	client := s.client
+	s.clientMutex.Unlock()
+
+	if client != nil {
+		return client.Close()
+	}
+
+	return nil
+}
+
+// serve runs handler for given client and waits until the connection is closed.
+// Returns false if the service has been stopped in the meantime.
+func (s *ClientService) serve(client *Client) bool {
+	closed := make(chan struct{})
+	client.OnClose(func(_ CloseReason) {
+		close(closed)
+	})
+
+	addressesChanged := make(chan struct{})
+	if s.config.ResolveInterval > 0 {
+		go s.watchAddresses(client, closed, addressesChanged)
+	}
+
+	s.clientMutex.Lock()
+	s.client = client
+	s.clientMutex.Unlock()
+
+	defer func() {
+		s.clientMutex.Lock()
+		s.client = nil
+		s.clientMutex.Unlock()
+	}()
+
+	select {
+	case <-s.stopChannel:
+		_ = client.Close()
+		return false
+	default:
+	}
+
+	s.handler(client)
+
+	select {
+	case <-closed:
+	case <-s.stopChannel:
+		_ = client.Close()
+		return false
+	}
+
+	select {
+	case <-addressesChanged:
+		// not a failure, reconnect right away
+		return true
+	default:
+	}
+
+	return s.wait(s.config.ReconnectDelay)
+}
+
+// watchAddresses periodically resolves the target host and closes the client when its addresses change.
+func (s *ClientService) watchAddresses(client *Client, closed <-chan struct{}, changed chan<- struct{}) {
+	host, _, err := net.SplitHostPort(s.address)
+	if err != nil || net.ParseIP(host) != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-closed:
+		case <-s.stopChannel:
+		}
+		cancel()
+	}()
+
+	known, ttl, err := s.config.Resolver(ctx, host)
+	if err != nil {
+		known = nil
+	}
+
+	for {
+		interval := s.config.ResolveInterval
+		if ttl > 0 {
+			interval = ttl
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		var addresses []string
+		addresses, ttl, err = s.config.Resolver(ctx, host)
+		if err != nil {
+			// keep the connection, the resolver may be temporarily unavailable
+			continue
+		}
+
+		if known == nil {
+			known = addresses
+			continue
+		}
+
+		if !sameAddresses(known, addresses) {
+			close(changed)
+			_ = client.Close()
+			return
+		}
+	}
+}
+
+func (s *ClientService) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stopChannel:
+		return false
+	}
+}
+
+// Ready returns an error if the service is not connected. It conforms to the ReadinessChecker interface.
+func (s *ClientService) Ready() error {
+	if s.Client() == nil {
+		return errors.New("client is not connected")
+	}
+
+	return nil
+}
+
+func lookupHost(ctx context.Context, host string) ([]string, time.Duration, error) {
+	addresses, err := net.DefaultResolver.LookupHost(ctx, host)
+	return addresses, 0, err
+}
+
+func sameAddresses(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := make(map[string]struct{}, len(a))
+	for _, address := range a {
+		set[address] = struct{}{}
+	}
+
+	for _, address := range b {
+		if _, ok := set[address]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
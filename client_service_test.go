@@ -0,0 +1,103 @@
+package tinytcp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientServiceReconnect(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			_ = conn.Close()
+		}
+	}()
+
+	connections := make(chan struct{}, 16)
+	service := NewClientService(listener.Addr().String(), func(client *Client) {
+		connections <- struct{}{}
+		_, _ = client.Read(make([]byte, 1))
+	}, &ClientServiceConfig{
+		ReconnectDelay: time.Millisecond,
+	})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- service.Start()
+	}()
+
+	// when
+	<-connections
+	<-connections
+	_ = service.Stop()
+
+	// then
+	assert.Nil(t, <-stopped, "service should stop without error")
+}
+
+func TestClientServiceResolveInterval(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			defer conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	var resolved uint32
+	connections := make(chan struct{}, 16)
+	service := NewClientService(net.JoinHostPort("localhost", port), func(client *Client) {
+		connections <- struct{}{}
+	}, &ClientServiceConfig{
+		ReconnectDelay:  time.Hour,
+		ResolveInterval: time.Millisecond,
+		DialOptions:     &DialOptions{Network: "tcp4"},
+		Resolver: func(_ context.Context, _ string) ([]string, time.Duration, error) {
+			if atomic.AddUint32(&resolved, 1) < 3 {
+				return []string{"10.0.0.1"}, 0, nil
+			}
+
+			return []string{"10.0.0.2"}, time.Hour, nil
+		},
+	})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- service.Start()
+	}()
+
+	// when
+	<-connections
+	<-connections
+	_ = service.Stop()
+
+	// then
+	assert.Nil(t, <-stopped, "service should stop without error")
+}
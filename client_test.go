@@ -0,0 +1,219 @@
+package tinytcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClientOnPacket(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+	packets := make(chan []byte, 2)
+
+	// when
+	client.OnPacket(SplitBySeparator([]byte{'\n'}), func(packet []byte) {
+		p := make([]byte, len(packet))
+		copy(p, packet)
+		packets <- p
+	})
+
+	_, _ = server.Write([]byte("Hello\nwor"))
+	_, _ = server.Write([]byte("ld\n"))
+	_ = server.Close()
+
+	// then
+	assert.Equal(t, []byte("Hello"), <-packets, "packets should match")
+	assert.Equal(t, []byte("world"), <-packets, "packets should match")
+}
+
+func TestDialContext(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// when
+	client, err := DialContext(context.Background(), listener.Addr().String(), &DialOptions{
+		Timeout: time.Second,
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.NotNil(t, client, "client should be returned")
+	_ = client.Close()
+}
+
+func TestDialContextCancelled(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	_, err := DialContext(ctx, "127.0.0.1:1")
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
+
+func TestClientCloseReason(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+
+	var closeReason CloseReason = -1
+	client.OnClose(func(reason CloseReason) {
+		closeReason = reason
+	})
+
+	// when
+	_ = server.Close()
+	_, err := client.Read(make([]byte, 1))
+
+	// then
+	assert.Equal(t, io.EOF, err, "err should be equal to io.EOF")
+	assert.Equal(t, CloseReasonServer, closeReason, "close reason should be correct")
+}
+
+func TestClientClose(t *testing.T) {
+	// given
+	_, conn := net.Pipe()
+	client := &Client{connection: conn}
+
+	var closeReasons []CloseReason
+	client.OnClose(func(reason CloseReason) {
+		closeReasons = append(closeReasons, reason)
+	})
+
+	// when
+	var closer io.Closer = client
+	err := closer.Close()
+	_ = client.CloseWithReason(CloseReasonServer)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, []CloseReason{CloseReasonClient}, closeReasons, "close handlers should be called once")
+}
+
+func TestClientCloseWithReason(t *testing.T) {
+	// given
+	_, conn := net.Pipe()
+	client := &Client{connection: conn}
+
+	var closeReason CloseReason = -1
+	client.OnClose(func(reason CloseReason) {
+		closeReason = reason
+	})
+
+	// when
+	err := client.CloseWithReason(CloseReasonServer)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, CloseReasonServer, closeReason, "close reason should be correct")
+}
+
+func TestDialContextFallback(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// when
+	client, err := DialContext(context.Background(), "127.0.0.1:1", &DialOptions{
+		FallbackAddresses: []string{listener.Addr().String()},
+		FallbackDelay:     time.Minute,
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, listener.Addr().String(), client.Unwrap().RemoteAddr().String(), "fallback address should be used")
+	_ = client.Close()
+}
+
+func TestDialContextSocketOptions(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	// when
+	client, err := DialContext(context.Background(), listener.Addr().String(), &DialOptions{
+		DisableNoDelay:    true,
+		ReceiveBufferSize: 64 * 1024,
+		SendBufferSize:    64 * 1024,
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	_ = client.Close()
+}
+
+func TestClientUpgradeTLS(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+	payload := []byte("Hello world!")
+
+	go func() {
+		tlsServer := tls.Server(server, &tls.Config{Certificates: []tls.Certificate{cert}})
+		_, _ = tlsServer.Write(payload)
+	}()
+
+	// when
+	err := client.UpgradeTLS(&tls.Config{InsecureSkipVerify: true})
+
+	buffer := make([]byte, len(payload))
+	_, readErr := io.ReadFull(client, buffer)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, payload, buffer, "payloads should match")
+
+	_, isTLS := client.UnwrapTLS()
+	assert.True(t, isTLS, "connection should be TLS secured")
+}
+
+func generateTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
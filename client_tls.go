@@ -0,0 +1,130 @@
+package tinytcp
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+)
+
+// ErrTLSPinMismatch is returned by Dial functions when none of the server certificates matches TLSPinnedKeys.
+var ErrTLSPinMismatch = errors.New("server certificate doesn't match any of the pinned keys")
+
+// SPKIHash returns base64 encoded SHA-256 hash of the Subject Public Key Info of given certificate,
+// in the format expected by DialOptions.TLSPinnedKeys. Hash can also be computed from the PEM file with:
+// openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIHash(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// TLSConnectionState returns the state of the TLS connection, or false if the connection is not TLS secured.
+func (c *Client) TLSConnectionState() (tls.ConnectionState, bool) {
+	conn, ok := c.UnwrapTLS()
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+
+	return conn.ConnectionState(), true
+}
+
+func usesTLSHelpers(options *DialOptions) bool {
+	return options.TLSRootCAs != "" || options.TLSPinnedKeys != nil || options.TLSInsecureSkipVerify
+}
+
+// clientTLSConfig returns TLSConfig from options, extended with the verification helpers.
+func clientTLSConfig(address string, options *DialOptions) (*tls.Config, error) {
+	if !usesTLSHelpers(options) {
+		return options.TLSConfig, nil
+	}
+
+	config := &tls.Config{}
+	if options.TLSConfig != nil {
+		config = options.TLSConfig.Clone()
+	}
+
+	if options.TLSRootCAs != "" {
+		pool, err := loadCertPool(options.TLSRootCAs)
+		if err != nil {
+			return nil, err
+		}
+
+		config.RootCAs = pool
+	}
+
+	serverName := config.ServerName
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+
+		serverName = host
+	}
+
+	var (
+		insecure         = options.TLSInsecureSkipVerify
+		pins             = options.TLSPinnedKeys
+		onInsecure       = options.OnInsecureTLS
+		roots            = config.RootCAs
+		verifyConnection = config.VerifyConnection
+	)
+
+	if insecure {
+		config.InsecureSkipVerify = true
+	}
+
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if insecure {
+			if err := verifyCertificateChain(state, roots, serverName); err != nil {
+				onInsecure(address, err)
+			}
+		}
+
+		if pins != nil && !matchesPinnedKeys(state, pins) {
+			return ErrTLSPinMismatch
+		}
+
+		if verifyConnection != nil {
+			return verifyConnection(state)
+		}
+
+		return nil
+	}
+
+	return config, nil
+}
+
+func verifyCertificateChain(state tls.ConnectionState, roots *x509.CertPool, serverName string) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server presented no certificates")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func matchesPinnedKeys(state tls.ConnectionState, pins []string) bool {
+	for _, cert := range state.PeerCertificates {
+		hash := SPKIHash(cert)
+
+		for _, pin := range pins {
+			if hash == pin {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package tinytcp
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDialTLSRootCAs(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	address := startTLSTestServer(t, cert)
+
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	if err := os.WriteFile(path, caPEM, 0600); err != nil {
+		t.Fatalf("failed to write CA bundle: %v", err)
+	}
+
+	// when
+	client, err := DialContext(context.Background(), address, &DialOptions{
+		TLSRootCAs: path,
+		TLSConfig:  &tls.Config{ServerName: "localhost"},
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	state, ok := client.TLSConnectionState()
+	assert.True(t, ok, "connection should be TLS secured")
+	assert.True(t, state.HandshakeComplete, "handshake should be complete")
+	_ = client.Close()
+}
+
+func TestDialTLSInsecureSkipVerify(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	address := startTLSTestServer(t, cert)
+
+	var warning error
+
+	// when
+	client, err := DialContext(context.Background(), address, &DialOptions{
+		TLSInsecureSkipVerify: true,
+		OnInsecureTLS: func(_ string, err error) {
+			warning = err
+		},
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.NotNil(t, warning, "warning should be reported")
+	_ = client.Close()
+}
+
+func TestDialTLSPinnedKeys(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	otherCert := generateTestCertificate(t)
+	address := startTLSTestServer(t, cert)
+
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	otherLeaf, _ := x509.ParseCertificate(otherCert.Certificate[0])
+	noWarning := func(_ string, _ error) {}
+
+	// when
+	client, err := DialContext(context.Background(), address, &DialOptions{
+		TLSPinnedKeys:         []string{SPKIHash(leaf)},
+		TLSInsecureSkipVerify: true,
+		OnInsecureTLS:         noWarning,
+	})
+	_, mismatchErr := DialContext(context.Background(), address, &DialOptions{
+		TLSPinnedKeys:         []string{SPKIHash(otherLeaf)},
+		TLSInsecureSkipVerify: true,
+		OnInsecureTLS:         noWarning,
+	})
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.ErrorIs(t, mismatchErr, ErrTLSPinMismatch, "pin mismatch should be reported")
+	_ = client.Close()
+}
+
+func startTLSTestServer(t *testing.T, cert tls.Certificate) string {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.(*tls.Conn).Handshake()
+				_, _ = conn.Read(make([]byte, 1))
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
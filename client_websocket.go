@@ -0,0 +1,363 @@
+package tinytcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxWebSocketHandshakeSize = 8 * 1024 // 8 KiB
+	webSocketAcceptGUID       = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	webSocketOpContinuation = 0x0
+	webSocketOpText         = 0x1
+	webSocketOpBinary       = 0x2
+	webSocketOpClose        = 0x8
+	webSocketOpPing         = 0x9
+	webSocketOpPong         = 0xa
+)
+
+// WebSocketOptions holds options for DialWebSocket.
+type WebSocketOptions struct {
+	// DialOptions are options used to establish the underlying connection. TLS is enabled automatically
+	// for wss:// URLs, if DialOptions don't specify TLSConfig.
+	DialOptions *DialOptions
+
+	// Header holds additional headers sent with the handshake request, eg. Origin, Authorization
+	// or Sec-WebSocket-Protocol.
+	Header http.Header
+
+	// TextFrames makes Write() send text frames instead of binary ones. The data is expected to be valid UTF-8.
+	TextFrames bool
+}
+
+func mergeWebSocketOptions(provided *WebSocketOptions) *WebSocketOptions {
+	options := &WebSocketOptions{
+		Header: http.Header{},
+	}
+
+	if provided == nil {
+		return options
+	}
+
+	if provided.DialOptions != nil {
+		options.DialOptions = provided.DialOptions
+	}
+	if provided.Header != nil {
+		options.Header = provided.Header.Clone()
+	}
+	if provided.TextFrames {
+		options.TextFrames = true
+	}
+
+	return options
+}
+
+// DialWebSocket connects to the WebSocket server under given ws:// or wss:// URL, performs the HTTP Upgrade
+// and creates new Client. Client exposes the WebSocket as a regular stream of bytes: each Write() is sent
+// as a single frame, and Read() returns the payloads of the received data frames. Ping and close frames
+// are handled transparently. Message boundaries are not preserved, so the protocol should be framed on its own
+// (see OnPacket).
+func DialWebSocket(ctx context.Context, rawURL string, options ...*WebSocketOptions) (*Client, error) {
+	var providedOptions *WebSocketOptions
+	if options != nil {
+		providedOptions = options[0]
+	}
+	o := mergeWebSocketOptions(providedOptions)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	dialOptions := mergeDialOptions(o.DialOptions)
+	address := u.Host
+
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "443")
+		}
+		if dialOptions.TLSConfig == nil {
+			dialOptions.TLSConfig = &tls.Config{}
+		}
+	default:
+		return nil, errors.New("unsupported WebSocket URL scheme: " + u.Scheme)
+	}
+
+	connection, err := dialContext(ctx, address, dialOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = connection.SetDeadline(deadline)
+	} else if dialOptions.Timeout > 0 {
+		_ = connection.SetDeadline(time.Now().Add(dialOptions.Timeout))
+	}
+
+	if err := webSocketHandshake(connection, u, o.Header); err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+
+	_ = connection.SetDeadline(time.Time{})
+
+	opcode := byte(webSocketOpBinary)
+	if o.TextFrames {
+		opcode = webSocketOpText
+	}
+
+	return &Client{
+		connection: &webSocketConn{
+			Conn:   connection,
+			opcode: opcode,
+		},
+	}, nil
+}
+
+func webSocketHandshake(conn net.Conn, u *url.URL, header http.Header) error {
+	var key [16]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	encodedKey := base64.StdEncoding.EncodeToString(key[:])
+
+	request := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Host:       u.Host,
+	}
+	request.Header.Set("Upgrade", "websocket")
+	request.Header.Set("Connection", "Upgrade")
+	request.Header.Set("Sec-WebSocket-Key", encodedKey)
+	request.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := request.Write(conn); err != nil {
+		return err
+	}
+
+	// response is read byte by byte, so no frames sent by the server right after the headers are consumed
+	var rawResponse bytes.Buffer
+	for !bytes.HasSuffix(rawResponse.Bytes(), []byte("\r\n\r\n")) {
+		if rawResponse.Len() >= maxWebSocketHandshakeSize {
+			return errors.New("WebSocket handshake response too long")
+		}
+
+		b, err := ReadByte(conn)
+		if err != nil {
+			return err
+		}
+
+		rawResponse.WriteByte(b)
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(&rawResponse), request)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		return errors.New("WebSocket handshake failed: " + response.Status)
+	}
+	if !strings.EqualFold(response.Header.Get("Upgrade"), "websocket") {
+		return errors.New("WebSocket handshake failed: invalid Upgrade header")
+	}
+
+	expectedAccept := sha1.Sum([]byte(encodedKey + webSocketAcceptGUID))
+	if response.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(expectedAccept[:]) {
+		return errors.New("WebSocket handshake failed: invalid Sec-WebSocket-Accept header")
+	}
+
+	return nil
+}
+
+// webSocketConn exposes WebSocket data frames as a stream of bytes.
+type webSocketConn struct {
+	net.Conn
+	opcode byte
+
+	remaining  uint64
+	readMask   [4]byte
+	readMasked bool
+	maskOffset int
+	control    [125]byte
+
+	writeBuffer []byte
+	writeMutex  sync.Mutex
+	closeOnce   sync.Once
+}
+
+func (c *webSocketConn) Read(b []byte) (int, error) {
+	for c.remaining == 0 {
+		if err := c.nextFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	if uint64(len(b)) > c.remaining {
+		b = b[:c.remaining]
+	}
+
+	n, err := c.Conn.Read(b)
+	if c.readMasked {
+		for i := 0; i < n; i++ {
+			b[i] ^= c.readMask[c.maskOffset%4]
+			c.maskOffset++
+		}
+	}
+
+	c.remaining -= uint64(n)
+	return n, err
+}
+
+func (c *webSocketConn) Write(b []byte) (int, error) {
+	if err := c.writeFrame(c.opcode, b); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (c *webSocketConn) Close() error {
+	c.closeOnce.Do(func() {
+		var status [2]byte
+		binary.BigEndian.PutUint16(status[:], 1000) // normal closure
+		_ = c.writeFrame(webSocketOpClose, status[:])
+	})
+
+	return c.Conn.Close()
+}
+
+func (c *webSocketConn) unwrapTLS() (*tls.Conn, bool) {
+	return unwrapTLSConn(c.Conn)
+}
+
+// nextFrame reads the header of the next frame. Control frames are handled right away.
+func (c *webSocketConn) nextFrame() error {
+	var header [2]byte
+	if _, err := io.ReadFull(c.Conn, header[:]); err != nil {
+		return err
+	}
+
+	opcode := header[0] & 0x0f
+	length := uint64(header[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.Conn, extended[:]); err != nil {
+			return err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.Conn, extended[:]); err != nil {
+			return err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+
+	c.readMasked = header[1]&0x80 != 0
+	c.maskOffset = 0
+	if c.readMasked {
+		if _, err := io.ReadFull(c.Conn, c.readMask[:]); err != nil {
+			return err
+		}
+	}
+
+	switch opcode {
+	case webSocketOpContinuation, webSocketOpText, webSocketOpBinary:
+		c.remaining = length
+		return nil
+	}
+
+	if length > uint64(len(c.control)) {
+		return errors.New("WebSocket control frame too long")
+	}
+
+	payload := c.control[:length]
+	if _, err := io.ReadFull(c.Conn, payload); err != nil {
+		return err
+	}
+	if c.readMasked {
+		for i := range payload {
+			payload[i] ^= c.readMask[i%4]
+		}
+	}
+
+	switch opcode {
+	case webSocketOpPing:
+		return c.writeFrame(webSocketOpPong, payload)
+	case webSocketOpPong:
+		return nil
+	case webSocketOpClose:
+		c.closeOnce.Do(func() {
+			// echo the status code, as required by the protocol
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			_ = c.writeFrame(webSocketOpClose, payload)
+		})
+
+		return io.EOF
+	default:
+		return errors.New("unsupported WebSocket opcode")
+	}
+}
+
+// writeFrame writes a single, masked frame, as required from the clients.
+func (c *webSocketConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+
+	buffer := append(c.writeBuffer[:0], 0x80|opcode)
+
+	switch {
+	case len(payload) < 126:
+		buffer = append(buffer, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		buffer = append(buffer, 0x80|126)
+		buffer = binary.BigEndian.AppendUint16(buffer, uint16(len(payload)))
+	default:
+		buffer = append(buffer, 0x80|127)
+		buffer = binary.BigEndian.AppendUint64(buffer, uint64(len(payload)))
+	}
+
+	buffer = append(buffer, mask[:]...)
+	start := len(buffer)
+	buffer = append(buffer, payload...)
+	for i := start; i < len(buffer); i++ {
+		buffer[i] ^= mask[(i-start)%4]
+	}
+
+	c.writeBuffer = buffer
+	return WriteBytes(c.Conn, buffer)
+}
@@ -0,0 +1,103 @@
+package tinytcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDialWebSocket(t *testing.T) {
+	// given
+	payload := []byte("Hello world!")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	pong := make(chan []byte, 1)
+	go serveTestWebSocket(t, listener, pong)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// when
+	client, err := DialWebSocket(ctx, "ws://"+listener.Addr().String()+"/echo")
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	_, writeErr := client.Write(payload)
+	buffer := make([]byte, len(payload))
+	_, readErr := io.ReadFull(client, buffer)
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, payload, buffer, "echoed payload should match")
+	assert.Equal(t, []byte("ping"), <-pong, "ping should be answered with pong")
+}
+
+// serveTestWebSocket accepts a single WebSocket connection, sends a ping, and echoes the first data frame.
+func serveTestWebSocket(t *testing.T, listener net.Listener, pong chan<- []byte) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		t.Errorf("failed to read handshake: %v", err)
+		return
+	}
+
+	accept := sha1.Sum([]byte(request.Header.Get("Sec-WebSocket-Key") + webSocketAcceptGUID))
+	_, _ = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(accept[:]) + "\r\n\r\n"))
+
+	// unmasked ping from the server
+	_, _ = conn.Write(append([]byte{0x80 | webSocketOpPing, 4}, "ping"...))
+
+	for {
+		opcode, payload := readTestWebSocketFrame(reader)
+		switch opcode {
+		case webSocketOpPong:
+			pong <- payload
+		case webSocketOpBinary:
+			_, _ = conn.Write(append([]byte{0x80 | webSocketOpBinary, byte(len(payload))}, payload...))
+		default:
+			return
+		}
+	}
+}
+
+func readTestWebSocketFrame(reader *bufio.Reader) (byte, []byte) {
+	var header [6]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil {
+		return 0, nil
+	}
+
+	// short frames only, always masked by the client
+	payload := make([]byte, header[1]&0x7f)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return 0, nil
+	}
+
+	for i := range payload {
+		payload[i] ^= header[2+i%4]
+	}
+
+	return header[0] & 0x0f, payload
+}
@@ -0,0 +1,143 @@
+package tinytcp
+
+import (
+	"io"
+	"time"
+)
+
+// WriteQueueConfig holds a configuration for Client.WriteAsync.
+type WriteQueueConfig struct {
+	// QueueSize is a maximal number of pending writes. WriteAsync blocks when the queue is full (default: 1024).
+	QueueSize int
+
+	// FlushSize is a number of buffered bytes that triggers an immediate flush (default: 64KiB).
+	FlushSize int
+
+	// FlushInterval is a maximal time the written data can wait in the buffer before being flushed (default: 1ms).
+	FlushInterval time.Duration
+
+	// OnWriteError is a handler called when the buffered data cannot be written to the connection.
+	OnWriteError func(error)
+}
+
+func mergeWriteQueueConfig(provided *WriteQueueConfig) *WriteQueueConfig {
+	config := &WriteQueueConfig{
+		QueueSize:     1024,
+		FlushSize:     64 * 1024,
+		FlushInterval: 1 * time.Millisecond,
+		OnWriteError:  func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.QueueSize > 0 {
+		config.QueueSize = provided.QueueSize
+	}
+	if provided.FlushSize > 0 {
+		config.FlushSize = provided.FlushSize
+	}
+	if provided.FlushInterval > 0 {
+		config.FlushInterval = provided.FlushInterval
+	}
+	if provided.OnWriteError != nil {
+		config.OnWriteError = provided.OnWriteError
+	}
+
+	return config
+}
+
+type writeQueue struct {
+	config  *WriteQueueConfig
+	writes  chan []byte
+	closed  chan struct{}
+	pending []byte
+}
+
+// WriteAsync queues payload to be written by a background goroutine. Consecutive writes are batched together and
+// flushed when the batch reaches FlushSize or FlushInterval passes, reducing the number of syscalls for clients
+// emitting many small frames. Payload is copied, so it can be reused right after the call.
+// Background goroutine is started on the first call, so config is only taken into account by the first call.
+// Writes still waiting in the queue when the connection is closed are discarded. Returns io.EOF after the close.
+func (c *Client) WriteAsync(payload []byte, config ...*WriteQueueConfig) error {
+	c.writeQueueOnce.Do(func() {
+		var providedConfig *WriteQueueConfig
+		if config != nil {
+			providedConfig = config[0]
+		}
+		wc := mergeWriteQueueConfig(providedConfig)
+
+		c.writeQueue = &writeQueue{
+			config:  wc,
+			writes:  make(chan []byte, wc.QueueSize),
+			closed:  make(chan struct{}),
+			pending: make([]byte, 0, wc.FlushSize),
+		}
+
+		c.OnClose(func(_ CloseReason) {
+			close(c.writeQueue.closed)
+		})
+
+		go c.writeQueue.run(c)
+	})
+
+	write := make([]byte, len(payload))
+	copy(write, payload)
+
+	select {
+	case <-c.writeQueue.closed:
+		return io.EOF
+	default:
+	}
+
+	select {
+	case c.writeQueue.writes <- write:
+		return nil
+	case <-c.writeQueue.closed:
+		return io.EOF
+	}
+}
+
+func (q *writeQueue) run(c *Client) {
+	timer := time.NewTimer(q.config.FlushInterval)
+	timer.Stop()
+	timerActive := false
+
+	for {
+		select {
+		case write := <-q.writes:
+			q.pending = append(q.pending, write...)
+
+			if len(q.pending) >= q.config.FlushSize {
+				if timerActive && !timer.Stop() {
+					<-timer.C
+				}
+				timerActive = false
+
+				q.flush(c)
+			} else if !timerActive {
+				timer.Reset(q.config.FlushInterval)
+				timerActive = true
+			}
+		case <-timer.C:
+			timerActive = false
+			q.flush(c)
+		case <-q.closed:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (q *writeQueue) flush(c *Client) {
+	if len(q.pending) == 0 {
+		return
+	}
+
+	if err := WriteBytes(c, q.pending); err != nil {
+		q.config.OnWriteError(err)
+	}
+
+	q.pending = q.pending[:0]
+}
@@ -0,0 +1,77 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type writeCountingConn struct {
+	net.Conn
+	writes uint32
+}
+
+func (c *writeCountingConn) Write(b []byte) (int, error) {
+	atomic.AddUint32(&c.writes, 1)
+	return c.Conn.Write(b)
+}
+
+func TestClientWriteAsync(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	countingConn := &writeCountingConn{Conn: conn}
+	client := &Client{connection: countingConn}
+
+	expected := bytes.Repeat([]byte("Hello"), 100)
+	received := make(chan []byte)
+
+	go func() {
+		buffer := make([]byte, len(expected))
+		_, _ = io.ReadFull(server, buffer)
+		received <- buffer
+	}()
+
+	config := &WriteQueueConfig{
+		FlushSize:     len(expected),
+		FlushInterval: time.Minute,
+	}
+
+	// when
+	for i := 0; i < 100; i++ {
+		err := client.WriteAsync([]byte("Hello"), config)
+		assert.Nil(t, err, "err should be nil")
+	}
+
+	data := <-received
+	_ = client.Close()
+
+	// then
+	assert.Equal(t, expected, data, "data should match")
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&countingConn.writes), "writes should be batched")
+	assert.Equal(t, io.EOF, client.WriteAsync([]byte("Hello")), "write after close should fail")
+}
+
+func TestClientWriteAsyncFlushInterval(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	client := &Client{connection: conn}
+	defer client.Close()
+
+	// when
+	err := client.WriteAsync([]byte("Hello"), &WriteQueueConfig{
+		FlushInterval: 10 * time.Millisecond,
+	})
+
+	buffer := make([]byte, 5)
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	_, readErr := io.ReadFull(server, buffer)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, []byte("Hello"), buffer, "data should be flushed after interval")
+}
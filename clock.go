@@ -0,0 +1,50 @@
+package tinytcp
+
+import "time"
+
+// Clock is a source of time used by the server. It exists mostly to allow replacing the system clock in tests,
+// so the time-dependent behavior (like housekeeping and metrics) can be verified without real sleeps
+// (see tinytcptest.FakeClock).
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a new Timer that sends the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer represents a single event, just like time.Timer.
+type Timer interface {
+	// C returns a channel on which the time is delivered when the timer fires.
+	C() <-chan time.Time
+
+	// Reset changes the timer to expire after duration d. It returns true if the timer had been active.
+	Reset(d time.Duration) bool
+
+	// Stop prevents the timer from firing. It returns true if the timer had been active.
+	Stop() bool
+}
+
+// SystemClock returns a Clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+type systemClock struct {
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
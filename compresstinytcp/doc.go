@@ -0,0 +1,8 @@
+/*
+Package compresstinytcp provides compression of the data sent through tinytcp sockets.
+Whole streams can be compressed with WrapDeflate, WrapGzip or WrapSnappy,
+while PacketCompressor compresses individual packets exceeding a size threshold.
+EnableZlib and EnableZstd switch the connection to compressed mode mid-stream, once negotiated by the protocol.
+Note that compressed streams are stateful, so the wrapped sockets must not be written to concurrently.
+*/
+package compresstinytcp
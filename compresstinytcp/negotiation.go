@@ -0,0 +1,138 @@
+package compresstinytcp
+
+import (
+	"compress/zlib"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mkorman9/tinytcp"
+)
+
+// EnableZlib switches the socket to zlib compression in both directions, mid-stream.
+// It's meant for protocols negotiating compression after the connection is established. The call should be made
+// from PacketHandler, right after handling the packet that enables compression: all the data written afterwards is
+// compressed, and all the data read past that packet is decompressed, including the data already buffered by
+// PacketFramingHandler (see tinytcp.Socket.UpgradeReader).
+// Level is one of the compress/zlib levels (default: zlib.DefaultCompression).
+func EnableZlib(socket *tinytcp.Socket, level ...int) error {
+	l := zlib.DefaultCompression
+	if level != nil {
+		l = level[0]
+	}
+
+	if l < zlib.HuffmanOnly || l > zlib.BestCompression {
+		return errors.New("invalid compression level")
+	}
+	pool := &zlibWriterPools[l-zlib.HuffmanOnly]
+
+	w := pool.Get().(*zlib.Writer)
+	r := &lazyReader{}
+	r.init = func(source io.Reader) error {
+		reader, err := zlib.NewReader(source)
+		if err != nil {
+			return err
+		}
+
+		r.reader = reader
+		return nil
+	}
+
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		w.Reset(writer)
+		return &flushingWriter{writer: w, flusher: w}
+	})
+	socket.UpgradeReader(func(reader io.Reader) io.Reader {
+		r.source = reader
+		return r
+	})
+
+	socket.OnRecycle(func() {
+		w.Reset(io.Discard)
+		pool.Put(w)
+	})
+
+	return nil
+}
+
+// ZstdMaxWindow is the largest window size accepted from the peer by EnableZstd, and the window size cap of its
+// encoders. Frames declaring a larger window are rejected with zstd.ErrWindowSizeExceeded, so that a peer cannot force
+// the server to allocate an arbitrary amount of memory for a single connection.
+const ZstdMaxWindow = 8 << 20
+
+// EnableZstd switches the socket to zstd compression in both directions, mid-stream (see EnableZlib).
+// Level defaults to zstd.SpeedDefault. The peer's window size must not exceed ZstdMaxWindow.
+func EnableZstd(socket *tinytcp.Socket, level ...zstd.EncoderLevel) error {
+	l := zstd.SpeedDefault
+	if level != nil {
+		l = level[0]
+	}
+
+	if l < zstd.SpeedFastest || l > zstd.SpeedBestCompression {
+		return errors.New("invalid compression level")
+	}
+	pool := &zstdEncoderPools[l-zstd.SpeedFastest]
+
+	w := pool.Get().(*zstd.Encoder)
+	r := zstdDecoderPool.Get().(*lazyReader)
+
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		w.Reset(writer)
+		return &flushingWriter{writer: w, flusher: w}
+	})
+	socket.UpgradeReader(func(reader io.Reader) io.Reader {
+		r.source = reader
+		return r
+	})
+
+	socket.OnRecycle(func() {
+		w.Reset(io.Discard)
+		pool.Put(w)
+		r.reset()
+		zstdDecoderPool.Put(r)
+	})
+
+	return nil
+}
+
+var (
+	zlibWriterPools  [zlib.BestCompression - zlib.HuffmanOnly + 1]sync.Pool
+	zstdEncoderPools [zstd.SpeedBestCompression - zstd.SpeedFastest + 1]sync.Pool
+
+	zstdDecoderPool = sync.Pool{
+		New: func() any {
+			// concurrency of 1 makes the decoder synchronous, without any background goroutines
+			decoder, _ := zstd.NewReader(
+				nil,
+				zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderMaxWindow(ZstdMaxWindow),
+				zstd.WithDecoderMaxMemory(ZstdMaxWindow),
+			)
+			return &lazyReader{reader: decoder, init: decoder.Reset}
+		},
+	}
+)
+
+func init() {
+	for i := range zlibWriterPools {
+		level := i + zlib.HuffmanOnly
+		zlibWriterPools[i].New = func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}
+	}
+
+	for i := range zstdEncoderPools {
+		level := zstd.EncoderLevel(i) + zstd.SpeedFastest
+		zstdEncoderPools[i].New = func() any {
+			w, _ := zstd.NewWriter(
+				io.Discard,
+				zstd.WithEncoderLevel(level),
+				zstd.WithEncoderConcurrency(1),
+				zstd.WithWindowSize(ZstdMaxWindow),
+			)
+			return w
+		}
+	}
+}
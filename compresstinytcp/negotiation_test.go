@@ -0,0 +1,148 @@
+package compresstinytcp
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableCompression(t *testing.T) {
+	peers := map[string]compressionPeer{
+		"zlib": {
+			wrap: func(socket *tinytcp.Socket) error {
+				return EnableZlib(socket)
+			},
+			newWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return zlib.NewWriter(w), nil
+			},
+			newReader: func(r io.Reader) (io.Reader, error) {
+				return zlib.NewReader(r)
+			},
+		},
+		"zstd": {
+			wrap: func(socket *tinytcp.Socket) error {
+				return EnableZstd(socket)
+			},
+			newWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return zstd.NewWriter(w)
+			},
+			newReader: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+		},
+	}
+
+	for name, peer := range peers {
+		t.Run(name, func(t *testing.T) {
+			// given
+			var input bytes.Buffer
+			input.WriteString("compress\n")
+			peerWriter, _ := peer.newWriter(&input)
+			_, _ = peerWriter.Write([]byte("first\nsecond\n"))
+			_ = peerWriter.Close()
+
+			var output bytes.Buffer
+			socket := tinytcptest.NewSocket(&input, &output)
+
+			// when
+			var (
+				received  []string
+				enableErr error
+			)
+
+			tinytcp.PacketFramingHandler(
+				tinytcp.SplitBySeparator([]byte{'\n'}),
+				func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+					return func(packet []byte) {
+						received = append(received, string(packet))
+
+						if string(packet) == "compress" {
+							_, _ = socket.Write([]byte("ok\n"))
+							enableErr = peer.wrap(socket)
+							return
+						}
+
+						_, _ = socket.Write(append(packet, '\n'))
+					}
+				},
+			)(socket)
+
+			plain, _ := output.ReadString('\n')
+			peerReader, _ := peer.newReader(&output)
+			echoed := make([]byte, len("first\nsecond\n"))
+			_, peerReadErr := io.ReadFull(peerReader, echoed)
+
+			// then
+			assert.Nil(t, enableErr, "enable err should be nil")
+			assert.Equal(t, []string{"compress", "first", "second"}, received, "packets should be decompressed")
+			assert.Equal(t, "ok\n", plain, "response to negotiation should not be compressed")
+			assert.Nil(t, peerReadErr, "peer read err should be nil")
+			assert.Equal(t, "first\nsecond\n", string(echoed), "responses should be compressed")
+		})
+	}
+}
+
+func TestEnableZstdOversizedWindow(t *testing.T) {
+	// given
+	var input bytes.Buffer
+	input.WriteString("compress\n")
+	// frame header declaring a 32 MB window, followed by a single raw block
+	input.Write([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, 15 << 3})
+	input.Write([]byte{6<<3 | 1, 0x00, 0x00})
+	input.WriteString("first\n")
+
+	socket := tinytcptest.NewSocket(&input, io.Discard)
+
+	// when
+	var (
+		received []string
+		readErr  error
+	)
+
+	tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(packet []byte) {
+				received = append(received, string(packet))
+
+				if string(packet) == "compress" {
+					_ = EnableZstd(socket)
+				}
+			}
+		},
+		&tinytcp.PacketFramingConfig{
+			OnSocketError: func(socket *tinytcp.Socket, err error) {
+				readErr = err
+				_ = socket.Close()
+			},
+		},
+	)(socket)
+
+	// then
+	assert.ErrorIs(t, readErr, zstd.ErrWindowSizeExceeded, "frame with oversized window should be rejected")
+	assert.Equal(t, []string{"compress"}, received, "data of the rejected frame should not be handled")
+}
+
+func TestEnableZstdBestCompressionWindow(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	socket := tinytcptest.NewSocket(&bytes.Buffer{}, &output)
+	_ = EnableZstd(socket, zstd.SpeedBestCompression)
+
+	// when
+	_, _ = socket.Write([]byte("response\n"))
+
+	peerReader, _ := zstd.NewReader(&output, zstd.WithDecoderMaxWindow(ZstdMaxWindow))
+	response := make([]byte, len("response\n"))
+	_, readErr := io.ReadFull(peerReader, response)
+
+	// then
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, "response\n", string(response), "response should fit in the window limit")
+}
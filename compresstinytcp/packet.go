@@ -0,0 +1,209 @@
+package compresstinytcp
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// PacketCompressionConfig holds a configuration for NewPacketCompressor.
+type PacketCompressionConfig struct {
+	// Threshold is a minimal size of packet to be compressed. Smaller packets are sent as is,
+	// as compressing them is not worth the CPU time (default: 256).
+	Threshold int
+
+	// Level is one of the compress/flate levels. The value of 0 means the default level (default: flate.DefaultCompression).
+	Level int
+
+	// MaxPacketSize is a maximal size of decompressed packet. Bigger packets are rejected with
+	// tinytcp.ErrLengthExceeded, which protects from decompression bombs (default: 16KiB).
+	MaxPacketSize int
+}
+
+func mergePacketCompressionConfig(provided *PacketCompressionConfig) *PacketCompressionConfig {
+	config := &PacketCompressionConfig{
+		Threshold:     256,
+		Level:         flate.DefaultCompression,
+		MaxPacketSize: 16 * 1024, // 16 KiB
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Threshold > 0 {
+		config.Threshold = provided.Threshold
+	}
+	if provided.Level != 0 {
+		config.Level = provided.Level
+	}
+	if provided.MaxPacketSize > 0 {
+		config.MaxPacketSize = provided.MaxPacketSize
+	}
+
+	return config
+}
+
+// PacketCompressor compresses individual packets exceeding a size threshold with deflate.
+// Compressed packet consists of a VarInt holding the size of uncompressed data (0 if the packet is not compressed),
+// followed by the data itself. Packets are not framed, so PacketCompressor should be used together with some
+// FramingProtocol (eg. tinytcp.LengthPrefixedFraming).
+// Compressors and decompressors are pooled, so PacketCompressor should be shared by all the connections.
+type PacketCompressor struct {
+	config      *PacketCompressionConfig
+	writerPool  sync.Pool
+	readerPool  sync.Pool
+	sourcesPool sync.Pool
+}
+
+// NewPacketCompressor creates new PacketCompressor.
+func NewPacketCompressor(config ...*PacketCompressionConfig) (*PacketCompressor, error) {
+	var providedConfig *PacketCompressionConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergePacketCompressionConfig(providedConfig)
+
+	if c.Level < flate.HuffmanOnly || c.Level > flate.BestCompression {
+		return nil, errors.New("invalid compression level")
+	}
+
+	return &PacketCompressor{
+		config: c,
+		writerPool: sync.Pool{
+			New: func() any {
+				w, _ := flate.NewWriter(io.Discard, c.Level)
+				return &packetWriter{writer: w}
+			},
+		},
+		readerPool: sync.Pool{
+			New: func() any {
+				return flate.NewReader(nil)
+			},
+		},
+		sourcesPool: sync.Pool{
+			New: func() any {
+				return &bytes.Reader{}
+			},
+		},
+	}, nil
+}
+
+// Compress appends compressed packet to dst and returns the extended buffer.
+// Packet is left uncompressed if it's smaller than the threshold, or if compression does not reduce its size.
+func (c *PacketCompressor) Compress(dst, packet []byte) []byte {
+	start := len(dst)
+
+	if len(packet) >= c.config.Threshold {
+		dst = binary.AppendUvarint(dst, uint64(len(packet)))
+		headerEnd := len(dst)
+
+		w := c.writerPool.Get().(*packetWriter)
+		w.buffer = dst
+		w.writer.Reset(w)
+		_, _ = w.writer.Write(packet)
+		_ = w.writer.Close()
+		dst = w.buffer
+		w.buffer = nil
+		c.writerPool.Put(w)
+
+		if len(dst)-headerEnd < len(packet) {
+			return dst
+		}
+
+		// incompressible data
+		dst = dst[:start]
+	}
+
+	dst = binary.AppendUvarint(dst, 0)
+	return append(dst, packet...)
+}
+
+// Decompress appends decompressed packet to dst and returns the extended buffer.
+func (c *PacketCompressor) Decompress(dst, packet []byte) ([]byte, error) {
+	size, n := binary.Uvarint(packet)
+	if n <= 0 {
+		return dst, errors.New("invalid header of compressed packet")
+	}
+
+	data := packet[n:]
+
+	if size == 0 {
+		if len(data) > c.config.MaxPacketSize {
+			return dst, tinytcp.ErrLengthExceeded
+		}
+
+		return append(dst, data...), nil
+	}
+
+	if size > uint64(c.config.MaxPacketSize) {
+		return dst, tinytcp.ErrLengthExceeded
+	}
+
+	start := len(dst)
+	dst = grow(dst, int(size))
+
+	source := c.sourcesPool.Get().(*bytes.Reader)
+	source.Reset(data)
+	r := c.readerPool.Get().(io.ReadCloser)
+	_ = r.(flate.Resetter).Reset(source, nil)
+
+	_, err := io.ReadFull(r, dst[start:])
+
+	source.Reset(nil)
+	c.sourcesPool.Put(source)
+	c.readerPool.Put(r)
+
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return dst[:start], err
+	}
+
+	return dst, nil
+}
+
+// Handler wraps given PacketHandler, so it receives decompressed packets. Packets that fail to decompress are dropped.
+// Decompressed packet is only valid until the handler returns.
+// Wrapped handler should be created separately for each connection, as it holds its own decompression buffer.
+func (c *PacketCompressor) Handler(handler tinytcp.PacketHandler) tinytcp.PacketHandler {
+	var buffer []byte
+
+	return func(packet []byte) {
+		decompressed, err := c.Decompress(buffer[:0], packet)
+		if err != nil {
+			return
+		}
+
+		buffer = decompressed
+		handler(decompressed)
+	}
+}
+
+// packetWriter is a flate.Writer writing straight into the output buffer.
+type packetWriter struct {
+	writer *flate.Writer
+	buffer []byte
+}
+
+func (p *packetWriter) Write(b []byte) (int, error) {
+	p.buffer = append(p.buffer, b...)
+	return len(b), nil
+}
+
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) >= n {
+		return b[:len(b)+n]
+	}
+
+	grown := make([]byte, len(b)+n)
+	copy(grown, b)
+	return grown
+}
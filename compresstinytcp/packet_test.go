@@ -0,0 +1,71 @@
+package compresstinytcp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacketCompressor(t *testing.T) {
+	// given
+	compressor, err := NewPacketCompressor(&PacketCompressionConfig{
+		Threshold: 64,
+	})
+	assert.Nil(t, err, "err should be nil")
+
+	small := []byte("Hello world")
+	big := bytes.Repeat([]byte("Hello world "), 100)
+
+	// when
+	compressedSmall := compressor.Compress(nil, small)
+	compressedBig := compressor.Compress(nil, big)
+
+	decompressedSmall, smallErr := compressor.Decompress(nil, compressedSmall)
+	decompressedBig, bigErr := compressor.Decompress(nil, compressedBig)
+
+	// then
+	assert.Equal(t, byte(0), compressedSmall[0], "small packet should not be compressed")
+	assert.Less(t, len(compressedBig), len(big), "big packet should be compressed")
+	assert.Nil(t, smallErr, "small err should be nil")
+	assert.Equal(t, small, decompressedSmall, "small packet should match")
+	assert.Nil(t, bigErr, "big err should be nil")
+	assert.Equal(t, big, decompressedBig, "big packet should match")
+}
+
+func TestPacketCompressorMaxPacketSize(t *testing.T) {
+	// given
+	compressor, _ := NewPacketCompressor()
+	limited, _ := NewPacketCompressor(&PacketCompressionConfig{
+		MaxPacketSize: 1024,
+	})
+
+	bomb := compressor.Compress(nil, make([]byte, 16*1024))
+
+	// when
+	_, err := limited.Decompress(nil, bomb)
+
+	// then
+	assert.Equal(t, tinytcp.ErrLengthExceeded, err, "err should be ErrLengthExceeded")
+}
+
+func TestPacketCompressorHandler(t *testing.T) {
+	// given
+	compressor, _ := NewPacketCompressor(&PacketCompressionConfig{
+		Threshold: 1,
+	})
+
+	var received []string
+	handler := compressor.Handler(func(packet []byte) {
+		received = append(received, string(packet))
+	})
+
+	// when
+	handler(compressor.Compress(nil, []byte("first packet")))
+	handler([]byte{0xFF})
+	handler(compressor.Compress(nil, []byte("second")))
+
+	// then
+	assert.Equal(t, []string{"first packet", "second"}, received, "valid packets should be decompressed")
+}
@@ -0,0 +1,215 @@
+package compresstinytcp
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/mkorman9/tinytcp"
+)
+
+// WrapDeflate enables deflate compression of all the data read from and written to the socket.
+// The other side is expected to use deflate as well. Every Write() is flushed, so the data is never held back.
+// Level is one of the compress/flate levels (default: flate.DefaultCompression).
+// Compressors are pooled and returned to the pool when the socket is recycled.
+func WrapDeflate(socket *tinytcp.Socket, level ...int) error {
+	l := flate.DefaultCompression
+	if level != nil {
+		l = level[0]
+	}
+
+	pool, err := deflateWriterPool(l)
+	if err != nil {
+		return err
+	}
+
+	w := pool.Get().(*flate.Writer)
+	r := deflateReaderPool.Get().(io.ReadCloser)
+
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		w.Reset(writer)
+		return &flushingWriter{writer: w, flusher: w}
+	})
+	socket.WrapReader(func(reader io.Reader) io.Reader {
+		_ = r.(flate.Resetter).Reset(reader, nil)
+		return r
+	})
+
+	socket.OnRecycle(func() {
+		w.Reset(io.Discard)
+		pool.Put(w)
+		deflateReaderPool.Put(r)
+	})
+
+	return nil
+}
+
+// WrapGzip enables gzip compression of all the data read from and written to the socket.
+// The other side is expected to use gzip as well. Every Write() is flushed, so the data is never held back.
+// Level is one of the compress/gzip levels (default: gzip.DefaultCompression).
+// Compressors are pooled and returned to the pool when the socket is recycled.
+func WrapGzip(socket *tinytcp.Socket, level ...int) error {
+	l := gzip.DefaultCompression
+	if level != nil {
+		l = level[0]
+	}
+
+	pool, err := gzipWriterPool(l)
+	if err != nil {
+		return err
+	}
+
+	w := pool.Get().(*gzip.Writer)
+	r := gzipReaderPool.Get().(*lazyReader)
+
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		w.Reset(writer)
+		return &flushingWriter{writer: w, flusher: w}
+	})
+	socket.WrapReader(func(reader io.Reader) io.Reader {
+		r.source = reader
+		return r
+	})
+
+	socket.OnRecycle(func() {
+		w.Reset(io.Discard)
+		pool.Put(w)
+		r.reset()
+		gzipReaderPool.Put(r)
+	})
+
+	return nil
+}
+
+// WrapSnappy enables compression of all the data read from and written to the socket, using Snappy framing format.
+// The other side is expected to use Snappy framing format as well. Every Write() is flushed.
+// Compressors are pooled and returned to the pool when the socket is recycled.
+func WrapSnappy(socket *tinytcp.Socket) {
+	w := snappyWriterPool.Get().(*snappy.Writer)
+	r := snappyReaderPool.Get().(*snappy.Reader)
+
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		w.Reset(writer)
+		return &flushingWriter{writer: w, flusher: w}
+	})
+	socket.WrapReader(func(reader io.Reader) io.Reader {
+		r.Reset(reader)
+		return r
+	})
+
+	socket.OnRecycle(func() {
+		w.Reset(io.Discard)
+		snappyWriterPool.Put(w)
+		r.Reset(nil)
+		snappyReaderPool.Put(r)
+	})
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// flushingWriter flushes the compressor after every Write(), so the data reaches the other side without delay.
+type flushingWriter struct {
+	writer  io.Writer
+	flusher flusher
+}
+
+func (f *flushingWriter) Write(b []byte) (int, error) {
+	n, err := f.writer.Write(b)
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.flusher.Flush()
+}
+
+// lazyReader postpones initialization of the decompressor until the first Read(), as some of them
+// (eg. gzip.NewReader) block until the header arrives.
+type lazyReader struct {
+	source      io.Reader
+	reader      io.Reader
+	init        func(io.Reader) error
+	initialized bool
+}
+
+func (l *lazyReader) Read(b []byte) (int, error) {
+	if !l.initialized {
+		if err := l.init(l.source); err != nil {
+			return 0, err
+		}
+
+		l.initialized = true
+	}
+
+	return l.reader.Read(b)
+}
+
+func (l *lazyReader) reset() {
+	l.source = nil
+	l.initialized = false
+}
+
+var (
+	deflateWriterPools [flate.BestCompression - flate.HuffmanOnly + 1]sync.Pool
+	gzipWriterPools    [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+	deflateReaderPool = sync.Pool{
+		New: func() any {
+			return flate.NewReader(nil)
+		},
+	}
+	gzipReaderPool = sync.Pool{
+		New: func() any {
+			reader := &gzip.Reader{}
+			return &lazyReader{reader: reader, init: reader.Reset}
+		},
+	}
+	snappyWriterPool = sync.Pool{
+		New: func() any {
+			return snappy.NewBufferedWriter(nil)
+		},
+	}
+	snappyReaderPool = sync.Pool{
+		New: func() any {
+			return snappy.NewReader(nil)
+		},
+	}
+)
+
+func init() {
+	for i := range deflateWriterPools {
+		level := i + flate.HuffmanOnly
+		deflateWriterPools[i].New = func() any {
+			w, _ := flate.NewWriter(io.Discard, level)
+			return w
+		}
+	}
+
+	for i := range gzipWriterPools {
+		level := i + gzip.HuffmanOnly
+		gzipWriterPools[i].New = func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}
+	}
+}
+
+func deflateWriterPool(level int) (*sync.Pool, error) {
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		return nil, errors.New("invalid compression level")
+	}
+
+	return &deflateWriterPools[level-flate.HuffmanOnly], nil
+}
+
+func gzipWriterPool(level int) (*sync.Pool, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, errors.New("invalid compression level")
+	}
+
+	return &gzipWriterPools[level-gzip.HuffmanOnly], nil
+}
@@ -0,0 +1,100 @@
+package compresstinytcp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"github.com/stretchr/testify/assert"
+)
+
+type compressionPeer struct {
+	wrap      func(socket *tinytcp.Socket) error
+	newWriter func(w io.Writer) (io.WriteCloser, error)
+	newReader func(r io.Reader) (io.Reader, error)
+}
+
+var compressionPeers = map[string]compressionPeer{
+	"deflate": {
+		wrap: func(socket *tinytcp.Socket) error {
+			return WrapDeflate(socket)
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(w, flate.DefaultCompression)
+		},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return flate.NewReader(r), nil
+		},
+	},
+	"gzip": {
+		wrap: func(socket *tinytcp.Socket) error {
+			return WrapGzip(socket)
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	"snappy": {
+		wrap: func(socket *tinytcp.Socket) error {
+			WrapSnappy(socket)
+			return nil
+		},
+		newWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return snappy.NewBufferedWriter(w), nil
+		},
+		newReader: func(r io.Reader) (io.Reader, error) {
+			return snappy.NewReader(r), nil
+		},
+	},
+}
+
+func TestWrap(t *testing.T) {
+	for name, peer := range compressionPeers {
+		t.Run(name, func(t *testing.T) {
+			// given
+			var input bytes.Buffer
+			peerWriter, _ := peer.newWriter(&input)
+			_, _ = peerWriter.Write([]byte("Hello from client"))
+			_ = peerWriter.Close()
+
+			var output bytes.Buffer
+			socket := tinytcptest.NewSocket(&input, &output)
+
+			// when
+			wrapErr := peer.wrap(socket)
+			_, writeErr := socket.Write([]byte("Hello from server"))
+			received, readErr := io.ReadAll(socket)
+
+			peerReader, _ := peer.newReader(&output)
+			sent := make([]byte, len("Hello from server"))
+			_, peerReadErr := io.ReadFull(peerReader, sent)
+
+			// then
+			assert.Nil(t, wrapErr, "wrap err should be nil")
+			assert.Nil(t, readErr, "read err should be nil")
+			assert.Equal(t, "Hello from client", string(received), "received data should be decompressed")
+			assert.Nil(t, writeErr, "write err should be nil")
+			assert.Nil(t, peerReadErr, "sent data should be flushed")
+			assert.Equal(t, "Hello from server", string(sent), "sent data should be compressed")
+		})
+	}
+}
+
+func TestWrapInvalidLevel(t *testing.T) {
+	// given
+	socket := tinytcptest.NewSocket(nil, nil)
+
+	// when
+	err := WrapDeflate(socket, 42)
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
@@ -2,30 +2,225 @@ package tinytcp
 
 import (
 	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"syscall"
 	"time"
 )
 
 // ServerConfig holds a configuration for NewServer.
 type ServerConfig struct {
 	// Network is a network parameter to pass to net.Listen (default: "tcp").
+	// "tcp" listening on a wildcard address (eg. ":7000" or "[::]:7000") accepts both IPv4 and IPv6 connections
+	// (dual-stack), "tcp4" and "tcp6" restrict the listener to a single IP version.
 	Network string
 
-	// Max clients denotes the maximum number of connection that can be accepted at once, -1 for no limit (default: -1).
+	// IPv6Only sets IPV6_V6ONLY on the listening socket, so the wildcard address "[::]" accepts only IPv6 connections.
+	// It allows running separate IPv4 and IPv6 listeners on the same port. It's equivalent to Network "tcp6"
+	// and has no effect for the other networks.
+	IPv6Only bool
+
+	// AdditionalAddresses is an optional list of addresses to listen on, besides the address passed to NewServer,
+	// eg. to bind to multiple specific interfaces. Connections accepted on all the addresses are handled
+	// by the same server. Server.Port() returns the port of the primary address.
+	AdditionalAddresses []string
+
+	// Max clients denotes the maximum number of connection that can be accepted at once, -1 or 0 for no limit
+	// (default: -1).
 	MaxClients int
 
+	// KeepAlive specifies the interval between keep-alive probes of the accepted connections. Negative value disables
+	// keep-alive probes. The value of 0 leaves the default set by Go runtime (default: 0).
+	KeepAlive time.Duration
+
+	// DisableNoDelay enables Nagle's algorithm on the accepted connections. By default, TCP_NODELAY is set
+	// by Go runtime, and small writes are sent immediately.
+	DisableNoDelay bool
+
+	// ReceiveBufferSize sets the size of the operating system's receive buffer (SO_RCVBUF) of the accepted connections.
+	// The value of 0 leaves the system default (default: 0).
+	ReceiveBufferSize int
+
+	// SendBufferSize sets the size of the operating system's send buffer (SO_SNDBUF) of the accepted connections.
+	// The value of 0 leaves the system default (default: 0).
+	SendBufferSize int
+
+	// WriteBufferSize enables buffered writes on the accepted sockets. Data passed to Socket.Write() is collected
+	// in a buffer of given size, and written to the connection when the buffer fills up, on Socket.Flush(), or by
+	// the housekeeping job (see WriteFlushDelay). Coalescing small writes reduces the number of syscalls, at the cost
+	// of latency. The value of 0 disables buffered writes (default: 0).
+	WriteBufferSize int
+
+	// WriteFlushDelay is a maximal time the data can wait in the write buffer (see WriteBufferSize), before it's
+	// flushed by the housekeeping job. The buffers are checked on every tick, so the data can actually wait
+	// up to WriteFlushDelay + TickInterval. The value of 0 flushes all the buffers on every tick (default: 0).
+	WriteFlushDelay time.Duration
+
 	// TLSCert is a path to TLS certificate to use. When specified with TLSKey - enables TLS mode.
 	TLSCert string
 
 	// TLSKey is a path to TLS key to use. When specified with TLSCert - enables TLS mode.
 	TLSKey string
 
+	// TLSCertPEM is a PEM encoded TLS certificate to use, an alternative to TLSCert for certificates kept in memory
+	// (eg. fetched from a secrets manager). When specified with TLSKeyPEM - enables TLS mode.
+	TLSCertPEM []byte
+
+	// TLSKeyPEM is a PEM encoded TLS key to use. When specified with TLSCertPEM - enables TLS mode.
+	TLSKeyPEM []byte
+
+	// TLSCertificate is a TLS certificate to use, an alternative to TLSCert and TLSCertPEM for certificates
+	// that have already been parsed. When specified - enables TLS mode.
+	TLSCertificate *tls.Certificate
+
+	// TLSGetConfigForClient is an optional callback called for each TLS connection after receiving the ClientHello,
+	// returning the TLS configuration to use for this connection (see tls.Config.GetConfigForClient). It allows
+	// applying per-connection parameters, eg. certificates or cipher suites selected by SNI. Returning nil config
+	// means the default configuration. When specified - enables TLS mode, even without a default certificate.
+	TLSGetConfigForClient func(hello *tls.ClientHelloInfo) (*tls.Config, error)
+
 	// TLSConfig is an optional TLS configuration to pass when using TLS mode.
 	TLSConfig *tls.Config
 
+	// TLSAutoDetect allows accepting both TLS and plaintext clients on the same port in TLS mode.
+	// Each connection is inspected, and only the ones starting with TLS ClientHello are wrapped in TLS.
+	// Useful during migrations. Server can't write to the socket before the client sends its first byte.
+	TLSAutoDetect bool
+
+	// TLSClientCAs is a path to PEM encoded CA bundle used to verify client certificates in TLS mode.
+	TLSClientCAs string
+
+	// TLSClientAuth specifies the policy of client certificates verification in TLS mode (default: tls.NoClientCert).
+	TLSClientAuth tls.ClientAuthType
+
+	// TLSVerifyPeer is an optional callback called for each TLS connection, right after the handshake completes.
+	// Returning non-nil error rejects the connection.
+	TLSVerifyPeer func(socket *Socket, state tls.ConnectionState) error
+
+	// ListenControl is an optional function passed to net.ListenConfig. It's called after creating the socket,
+	// but before binding it, and allows setting platform-specific options like SO_REUSEPORT or TCP_FASTOPEN.
+	ListenControl func(network, address string, c syscall.RawConn) error
+
+	// ListenRetry enables retrying transient bind failures on Start(), eg. EADDRINUSE when the previous instance
+	// of the process is still releasing the port during a fast restart (see ListenRetryConfig).
+	ListenRetry *ListenRetryConfig
+
+	// UnixSocketMode sets permissions of the socket file when Network is "unix" (default: left unchanged).
+	// Stale socket files are automatically removed on Listen() and the socket file is removed on Close().
+	// Addresses starting with "@" denote abstract sockets (Linux only), which have no file in the filesystem.
+	UnixSocketMode os.FileMode
+
+	// UnixSocketOwner is a name or numeric ID of the user that should own the socket file (default: left unchanged).
+	UnixSocketOwner string
+
+	// UnixSocketGroup is a name or numeric ID of the group that should own the socket file (default: left unchanged).
+	UnixSocketGroup string
+
+	// AdmissionController is an optional controller deciding whether the newly accepted connections should be served
+	// (see AdmissionController). Connections rejected by the controller are closed right away.
+	AdmissionController AdmissionController
+
+	// ClassifyPeer is an optional function evaluated for each accepted connection, before the AdmissionController.
+	// Returned labels are attached to the socket (see Socket.PeerLabels) and to the per-peer metrics
+	// (see PeerMetrics.Labels). Connections for which it returns allow=false are rejected. It allows plugging in
+	// eg. a GeoIP database, for country-based blocking and traffic attribution.
+	ClassifyPeer func(addr net.Addr) (labels map[string]string, allow bool)
+
+	// Tarpit enables tarpitting of the selected or rejected connections, instead of closing them (see TarpitConfig).
+	Tarpit *TarpitConfig
+
+	// AcceptQueueSize enables a bounded queue between the accept loop and the handling of new connections
+	// (admission, ForkingStrategy). Connections are taken off the queue by a dedicated goroutine, so a momentarily slow
+	// ForkingStrategy doesn't stall the accept loop. Connections that don't fit into the full queue are closed
+	// immediately. The value of 0 disables the queue (default: 0).
+	AcceptQueueSize int
+
+	// PeerMetricsLimit enables aggregating metrics per remote address (see Server.MetricsByPeer).
+	// Metrics are kept for at most PeerMetricsLimit peers, preferring the connected and the most active ones.
+	// The value of 0 disables per-peer metrics (default: 0).
+	PeerMetricsLimit int
+
 	// TickInterval is an interval that is used by the server to schedule housekeeping job runs.
 	// Housekeeping job updates server-wide metrics and recycles socket objects.
 	// (default: 1s).
 	TickInterval time.Duration
+
+	// Clock is a source of time used by the server, eg. by the housekeeping job, metrics and socket timestamps.
+	// It should only be replaced in tests (default: SystemClock()).
+	Clock Clock
+}
+
+// ConfigError is returned when a configuration contains an invalid value or a nonsensical combination of values.
+type ConfigError struct {
+	// Field is a name of the invalid field, eg. "ServerConfig.TickInterval".
+	Field string
+
+	// Reason describes why the value is invalid.
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid " + e.Field + ": " + e.Reason
+}
+
+// Validate checks the configuration for invalid values and nonsensical combinations of values, that would otherwise be
+// silently ignored or cause failures at runtime. It returns all the problems found, joined, as *ConfigError.
+// Validate is called by NewServer, the errors are returned by Server.Start().
+func (c *ServerConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if c.TLSCert != "" && c.TLSKey == "" {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.TLSKey", Reason: "must be specified together with TLSCert"})
+	}
+	if c.TLSKey != "" && c.TLSCert == "" {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.TLSCert", Reason: "must be specified together with TLSKey"})
+	}
+	if len(c.TLSCertPEM) > 0 && len(c.TLSKeyPEM) == 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.TLSKeyPEM", Reason: "must be specified together with TLSCertPEM"})
+	}
+	if len(c.TLSKeyPEM) > 0 && len(c.TLSCertPEM) == 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.TLSCertPEM", Reason: "must be specified together with TLSKeyPEM"})
+	}
+	if countTrue(c.TLSCert != "", len(c.TLSCertPEM) > 0, c.TLSCertificate != nil) > 1 {
+		errs = append(errs, &ConfigError{
+			Field:  "ServerConfig.TLSCertificate",
+			Reason: "only one of TLSCert, TLSCertPEM and TLSCertificate can be specified",
+		})
+	}
+	if c.MaxClients < -1 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.MaxClients", Reason: "must be -1 or greater"})
+	}
+	if c.ReceiveBufferSize < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.ReceiveBufferSize", Reason: "must not be negative"})
+	}
+	if c.SendBufferSize < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.SendBufferSize", Reason: "must not be negative"})
+	}
+	if c.WriteBufferSize < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.WriteBufferSize", Reason: "must not be negative"})
+	}
+	if c.WriteFlushDelay < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.WriteFlushDelay", Reason: "must not be negative"})
+	}
+	if c.WriteFlushDelay > 0 && c.WriteBufferSize == 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.WriteFlushDelay", Reason: "requires WriteBufferSize"})
+	}
+	if c.AcceptQueueSize < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.AcceptQueueSize", Reason: "must not be negative"})
+	}
+	if c.PeerMetricsLimit < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.PeerMetricsLimit", Reason: "must not be negative"})
+	}
+	if c.TickInterval < 0 {
+		errs = append(errs, &ConfigError{Field: "ServerConfig.TickInterval", Reason: "must not be negative"})
+	}
+
+	return errors.Join(errs...)
 }
 
 func mergeServerConfig(provided *ServerConfig) *ServerConfig {
@@ -34,6 +229,7 @@ func mergeServerConfig(provided *ServerConfig) *ServerConfig {
 		MaxClients:   -1,
 		TLSConfig:    &tls.Config{},
 		TickInterval: 1 * time.Second,
+		Clock:        SystemClock(),
 	}
 
 	if provided == nil {
@@ -43,21 +239,102 @@ func mergeServerConfig(provided *ServerConfig) *ServerConfig {
 	if provided.Network != "" {
 		config.Network = provided.Network
 	}
-	if provided.MaxClients > -1 {
+	if provided.IPv6Only {
+		config.IPv6Only = true
+	}
+	if provided.AdditionalAddresses != nil {
+		config.AdditionalAddresses = provided.AdditionalAddresses
+	}
+	if provided.MaxClients != 0 {
 		config.MaxClients = provided.MaxClients
 	}
+	if provided.KeepAlive != 0 {
+		config.KeepAlive = provided.KeepAlive
+	}
+	if provided.DisableNoDelay {
+		config.DisableNoDelay = true
+	}
+	if provided.ReceiveBufferSize > 0 {
+		config.ReceiveBufferSize = provided.ReceiveBufferSize
+	}
+	if provided.SendBufferSize > 0 {
+		config.SendBufferSize = provided.SendBufferSize
+	}
+	if provided.WriteBufferSize > 0 {
+		config.WriteBufferSize = provided.WriteBufferSize
+	}
+	if provided.WriteFlushDelay > 0 {
+		config.WriteFlushDelay = provided.WriteFlushDelay
+	}
 	if provided.TLSCert != "" {
 		config.TLSCert = provided.TLSCert
 	}
 	if provided.TLSKey != "" {
 		config.TLSKey = provided.TLSKey
 	}
+	if provided.TLSCertPEM != nil {
+		config.TLSCertPEM = provided.TLSCertPEM
+	}
+	if provided.TLSKeyPEM != nil {
+		config.TLSKeyPEM = provided.TLSKeyPEM
+	}
+	if provided.TLSCertificate != nil {
+		config.TLSCertificate = provided.TLSCertificate
+	}
+	if provided.TLSGetConfigForClient != nil {
+		config.TLSGetConfigForClient = provided.TLSGetConfigForClient
+	}
 	if provided.TLSConfig != nil {
 		config.TLSConfig = provided.TLSConfig
 	}
+	if provided.TLSAutoDetect {
+		config.TLSAutoDetect = true
+	}
+	if provided.TLSClientCAs != "" {
+		config.TLSClientCAs = provided.TLSClientCAs
+	}
+	if provided.TLSClientAuth != tls.NoClientCert {
+		config.TLSClientAuth = provided.TLSClientAuth
+	}
+	if provided.TLSVerifyPeer != nil {
+		config.TLSVerifyPeer = provided.TLSVerifyPeer
+	}
+	if provided.ListenControl != nil {
+		config.ListenControl = provided.ListenControl
+	}
+	if provided.ListenRetry != nil {
+		config.ListenRetry = mergeListenRetryConfig(provided.ListenRetry)
+	}
+	if provided.UnixSocketMode != 0 {
+		config.UnixSocketMode = provided.UnixSocketMode
+	}
+	if provided.UnixSocketOwner != "" {
+		config.UnixSocketOwner = provided.UnixSocketOwner
+	}
+	if provided.UnixSocketGroup != "" {
+		config.UnixSocketGroup = provided.UnixSocketGroup
+	}
+	if provided.AdmissionController != nil {
+		config.AdmissionController = provided.AdmissionController
+	}
+	if provided.ClassifyPeer != nil {
+		config.ClassifyPeer = provided.ClassifyPeer
+	}
+	if provided.Tarpit != nil {
+		config.Tarpit = mergeTarpitConfig(provided.Tarpit)
+	}
+	if provided.AcceptQueueSize > 0 {
+		config.AcceptQueueSize = provided.AcceptQueueSize
+	}
+	if provided.PeerMetricsLimit > 0 {
+		config.PeerMetricsLimit = provided.PeerMetricsLimit
+	}
 	if provided.TickInterval != 0 {
 		config.TickInterval = provided.TickInterval
 	}
+	if provided.Clock != nil {
+		config.Clock = provided.Clock
+	}
 
 	return config
 }
@@ -0,0 +1,86 @@
+package tinytcp
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestServerConfigValidate(t *testing.T) {
+	// given
+	config := &ServerConfig{
+		TLSCert:      "cert.pem",
+		MaxClients:   -2,
+		TickInterval: -1 * time.Second,
+	}
+
+	// when
+	err := config.Validate()
+
+	// then
+	var fields []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var configErr *ConfigError
+		if assert.True(t, errors.As(e, &configErr), "errors should be of type ConfigError") {
+			fields = append(fields, configErr.Field)
+		}
+	}
+
+	assert.Equal(
+		t,
+		[]string{"ServerConfig.TLSKey", "ServerConfig.MaxClients", "ServerConfig.TickInterval"},
+		fields,
+		"all the invalid fields should be reported",
+	)
+	assert.Contains(t, err.Error(), "invalid ServerConfig.TLSKey: must be specified together with TLSCert")
+}
+
+func TestServerConfigValidateTLSCertificates(t *testing.T) {
+	// given
+	configs := map[string]*ServerConfig{
+		"ServerConfig.TLSKeyPEM":      {TLSCertPEM: []byte("cert")},
+		"ServerConfig.TLSCertPEM":     {TLSKeyPEM: []byte("key")},
+		"ServerConfig.TLSCertificate": {TLSCert: "cert.pem", TLSKey: "key.pem", TLSCertificate: &tls.Certificate{}},
+	}
+
+	for field, config := range configs {
+		// when
+		err := config.Validate()
+
+		// then
+		var configErr *ConfigError
+		if assert.ErrorAs(t, err, &configErr, "config should be invalid") {
+			assert.Equal(t, field, configErr.Field, "invalid field should match")
+		}
+	}
+}
+
+func TestServerConfigValidateValid(t *testing.T) {
+	// given
+	configs := []*ServerConfig{
+		nil,
+		{},
+		{MaxClients: -1, TickInterval: time.Second},
+		{TLSCert: "cert.pem", TLSKey: "key.pem", MaxClients: 100},
+		{TLSCertPEM: []byte("cert"), TLSKeyPEM: []byte("key")},
+		{TLSCertificate: &tls.Certificate{}},
+	}
+
+	for _, config := range configs {
+		// when
+		err := config.Validate()
+
+		// then
+		assert.Nil(t, err, "config should be valid")
+	}
+}
+
+func TestServerConfigMaxClientsDefault(t *testing.T) {
+	// when
+	config := mergeServerConfig(&ServerConfig{TickInterval: time.Second})
+
+	// then
+	assert.Equal(t, -1, config.MaxClients, "zero value should mean no limit")
+}
@@ -0,0 +1,74 @@
+package tinytcp
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// ConnectionState denotes a state of the connection reported by Server.DumpConnections().
+type ConnectionState string
+
+const (
+	// ConnectionStateOpen means the connection is open.
+	ConnectionStateOpen ConnectionState = "open"
+
+	// ConnectionStateClosed means the connection has been closed, but its handler is still running.
+	ConnectionStateClosed ConnectionState = "closed"
+
+	// ConnectionStateRecyclable means the connection has been closed and its socket is waiting to be recycled.
+	ConnectionStateRecyclable ConnectionState = "recyclable"
+)
+
+// ConnectionInfo holds diagnostic information about a single connection.
+type ConnectionInfo struct {
+	// ID identifies the socket (see Socket.ID).
+	ID uint64 `json:"id"`
+
+	// RemoteAddress is a remote address of the socket.
+	RemoteAddress string `json:"remoteAddress"`
+
+	// ConnectedAt is a unix timestamp of the moment the socket has connected (UTC, in milliseconds).
+	ConnectedAt int64 `json:"connectedAt"`
+
+	// LastActivity is a unix timestamp of the last data transfer through the socket (UTC, in milliseconds).
+	// It's updated by the housekeeping job, so its precision is limited by TickInterval.
+	LastActivity int64 `json:"lastActivity"`
+
+	// TotalRead is a total number of bytes read through the socket.
+	TotalRead uint64 `json:"totalRead"`
+
+	// TotalWritten is a total number of bytes written through the socket.
+	TotalWritten uint64 `json:"totalWritten"`
+
+	// State is a state of the connection.
+	State ConnectionState `json:"state"`
+}
+
+// ConnectionsHandler returns http.Handler serving the result of Server.DumpConnections() as JSON.
+// It's meant to be exposed on an internal, administrative endpoint.
+func ConnectionsHandler(server *Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(server.DumpConnections())
+	})
+}
+
+func (s *Socket) info() ConnectionInfo {
+	state := ConnectionStateOpen
+	if s.isRecyclable() {
+		state = ConnectionStateRecyclable
+	} else if atomic.LoadInt64(&s.closedAt) != 0 {
+		state = ConnectionStateClosed
+	}
+
+	return ConnectionInfo{
+		ID:            s.id,
+		RemoteAddress: s.remoteAddr,
+		ConnectedAt:   s.timestamp,
+		LastActivity:  atomic.LoadInt64(&s.lastActivity),
+		TotalRead:     s.TotalRead(),
+		TotalWritten:  s.TotalWritten(),
+		State:         state,
+	}
+}
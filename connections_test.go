@@ -0,0 +1,47 @@
+package tinytcp
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDumpConnections(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0")
+
+	first := server.sockets.New(&ConnMock{})
+	second := server.sockets.New(&ConnMock{})
+	_ = second.Close()
+
+	// when
+	connections := server.DumpConnections()
+
+	// then
+	assert.Len(t, connections, 2, "all connections should be dumped")
+	assert.Equal(t, first.ID(), connections[0].ID, "ID should match")
+	assert.Equal(t, "127.0.0.1", connections[0].RemoteAddress, "remote address should match")
+	assert.Equal(t, ConnectionStateOpen, connections[0].State, "first connection should be open")
+	assert.Equal(t, ConnectionStateClosed, connections[1].State, "second connection should be closed")
+	assert.NotEqual(t, connections[0].ID, connections[1].ID, "IDs should be unique")
+}
+
+func TestConnectionsHandler(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0")
+	server.sockets.New(&ConnMock{})
+
+	recorder := httptest.NewRecorder()
+
+	// when
+	ConnectionsHandler(server).ServeHTTP(recorder, httptest.NewRequest("GET", "/connections", nil))
+
+	var connections []ConnectionInfo
+	err := json.Unmarshal(recorder.Body.Bytes(), &connections)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"), "content type should match")
+	assert.Len(t, connections, 1, "connection should be served")
+}
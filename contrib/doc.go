@@ -0,0 +1,6 @@
+/*
+Package contrib provides ready-made SocketHandlers implementing the classic diagnostic protocols
+(echo, discard, chargen, daytime) and a TCP health-check responder. They can be mounted on auxiliary ports
+for testing and diagnostics.
+*/
+package contrib
@@ -0,0 +1,99 @@
+package contrib
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+const (
+	chargenLineLength = 72
+	chargenCharacters = 95 // printable ASCII characters, from ' ' to '~'
+)
+
+var (
+	buffersPool = sync.Pool{
+		New: func() any {
+			b := make([]byte, 32*1024)
+			return &b
+		},
+	}
+
+	chargenPattern = newChargenPattern()
+)
+
+// Echo returns a SocketHandler sending back all the received data (RFC 862).
+func Echo() tinytcp.SocketHandler {
+	return func(socket *tinytcp.Socket) {
+		buffer := buffersPool.Get().(*[]byte)
+		defer buffersPool.Put(buffer)
+
+		_, _ = io.CopyBuffer(socket, socket, *buffer)
+	}
+}
+
+// Discard returns a SocketHandler throwing away all the received data (RFC 863).
+func Discard() tinytcp.SocketHandler {
+	return func(socket *tinytcp.Socket) {
+		buffer := buffersPool.Get().(*[]byte)
+		defer buffersPool.Put(buffer)
+
+		_, _ = io.CopyBuffer(io.Discard, socket, *buffer)
+	}
+}
+
+// Chargen returns a SocketHandler sending an endless stream of characters (RFC 864), until the client disconnects.
+// The data is sent in lines of 72 printable ASCII characters, each line starting one character further.
+// Received data is discarded.
+func Chargen() tinytcp.SocketHandler {
+	return func(socket *tinytcp.Socket) {
+		go func() {
+			_, _ = io.Copy(io.Discard, socket)
+			_ = socket.Close()
+		}()
+
+		for {
+			if err := tinytcp.WriteBytes(socket, chargenPattern); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Daytime returns a SocketHandler sending current date and time in a human-readable format and closing
+// the connection (RFC 867).
+func Daytime() tinytcp.SocketHandler {
+	return func(socket *tinytcp.Socket) {
+		defer socket.Close()
+
+		_, _ = socket.Write([]byte(time.Now().Format(time.RFC1123) + "\r\n"))
+	}
+}
+
+// HealthCheck returns a SocketHandler responding with "OK" and closing the connection. It's meant for the load
+// balancers probing only whether the process accepts the connections. See tinytcp.HealthSocketHandler for
+// the responder reporting the health of the services.
+func HealthCheck() tinytcp.SocketHandler {
+	return func(socket *tinytcp.Socket) {
+		defer socket.Close()
+
+		_, _ = socket.Write([]byte("OK\r\n"))
+	}
+}
+
+// newChargenPattern returns all the lines of chargen output, before the pattern repeats.
+func newChargenPattern() []byte {
+	pattern := make([]byte, 0, chargenCharacters*(chargenLineLength+2))
+
+	for line := 0; line < chargenCharacters; line++ {
+		for i := 0; i < chargenLineLength; i++ {
+			pattern = append(pattern, byte(' '+(line+i)%chargenCharacters))
+		}
+
+		pattern = append(pattern, '\r', '\n')
+	}
+
+	return pattern
+}
@@ -0,0 +1,73 @@
+package contrib
+
+import (
+	"bytes"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestEcho(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	socket := tinytcptest.NewScriptedSocket(&output, []byte("Hello "), []byte("world!"))
+
+	// when
+	Echo()(socket)
+
+	// then
+	assert.Equal(t, []byte("Hello world!"), output.Bytes(), "data should be echoed")
+}
+
+func TestDiscard(t *testing.T) {
+	// given
+	var output bytes.Buffer
+	socket := tinytcptest.NewScriptedSocket(&output, []byte("Hello world!"))
+
+	// when
+	Discard()(socket)
+
+	// then
+	assert.Empty(t, output.Bytes(), "nothing should be written")
+}
+
+func TestChargen(t *testing.T) {
+	// given
+	input, inputWriter := io.Pipe()
+	outputReader, outputWriter := io.Pipe()
+	socket := tinytcptest.NewSocket(input, outputWriter)
+
+	done := make(chan struct{})
+	go func() {
+		Chargen()(socket)
+		close(done)
+	}()
+
+	// when
+	output := make([]byte, 2*74)
+	_, err := io.ReadFull(outputReader, output)
+
+	_ = inputWriter.Close()
+	_ = outputReader.Close()
+	<-done
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefg\r\n", string(output[:74]))
+	assert.Equal(t, "!\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefgh\r\n", string(output[74:]))
+	assert.Len(t, chargenPattern, 95*74, "pattern should contain all the lines")
+}
+
+func TestDaytimeAndHealthCheck(t *testing.T) {
+	// given
+	var daytimeOutput, healthOutput bytes.Buffer
+
+	// when
+	Daytime()(tinytcptest.NewScriptedSocket(&daytimeOutput))
+	HealthCheck()(tinytcptest.NewScriptedSocket(&healthOutput))
+
+	// then
+	assert.True(t, bytes.HasSuffix(daytimeOutput.Bytes(), []byte("\r\n")), "daytime should end with CRLF")
+	assert.Equal(t, []byte("OK\r\n"), healthOutput.Bytes(), "health check should respond with OK")
+}
@@ -0,0 +1,33 @@
+package tinytcp
+
+import "sync/atomic"
+
+const counterShards = 16
+
+// shardedCounter is a counter shared by all the sockets of the server, split into shards occupying separate
+// cache lines. Each socket increments the shard picked by its id, so the sockets served concurrently on different
+// processors rarely contend for the same cache line. Shards are summed up by Swap, which is called periodically
+// by the housekeeping job.
+type shardedCounter struct {
+	shards [counterShards]counterShard
+}
+
+type counterShard struct {
+	value uint64
+	_     [cacheLineSize - 8]byte
+}
+
+// Shard returns the shard assigned to the socket with given id.
+func (c *shardedCounter) Shard(id uint64) *uint64 {
+	return &c.shards[id%counterShards].value
+}
+
+// Swap returns the sum of all the shards and zeroes them.
+func (c *shardedCounter) Swap() uint64 {
+	var sum uint64
+	for i := range c.shards {
+		sum += atomic.SwapUint64(&c.shards[i].value, 0)
+	}
+
+	return sum
+}
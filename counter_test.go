@@ -0,0 +1,36 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShardedCounter(t *testing.T) {
+	// given
+	var (
+		counter shardedCounter
+		wg      sync.WaitGroup
+	)
+
+	// when
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(id uint64) {
+			defer wg.Done()
+
+			shard := counter.Shard(id)
+			for j := 0; j < 1000; j++ {
+				atomic.AddUint64(shard, 2)
+			}
+		}(uint64(i))
+	}
+	wg.Wait()
+
+	swapped := counter.Swap()
+
+	// then
+	assert.Equal(t, uint64(32*1000*2), swapped, "sum should match")
+	assert.Equal(t, uint64(0), counter.Swap(), "shards should be zeroed after swap")
+}
@@ -0,0 +1,164 @@
+package cryptotinytcp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+var (
+	// ErrPacketTooShort is returned when the packet is too short to hold a nonce and an authentication tag.
+	ErrPacketTooShort = errors.New("encrypted packet too short")
+
+	// ErrReplayedPacket is returned when the packet counter is not greater than the counter of the last opened packet,
+	// which means the packet has been replayed or reordered.
+	ErrReplayedPacket = errors.New("replayed packet")
+)
+
+// counterSize is a size of the packet counter, sent in front of every encrypted packet.
+const counterSize = 8
+
+// PacketCipherConfig holds a configuration for NewServerCipher and NewClientCipher.
+type PacketCipherConfig struct {
+	// OnError is a handler called by Handler() when a packet fails to decrypt. Such packet is dropped.
+	// A failure means the packet has been tampered with, so it's usually a good idea to close the connection.
+	OnError func(error)
+}
+
+func mergePacketCipherConfig(provided *PacketCipherConfig) *PacketCipherConfig {
+	config := &PacketCipherConfig{
+		OnError: func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.OnError != nil {
+		config.OnError = provided.OnError
+	}
+
+	return config
+}
+
+// PacketCipher encrypts and decrypts individual packets with AES-GCM.
+// Each encrypted packet consists of an 8-byte counter, followed by ciphertext and an authentication tag.
+// Nonces are built from the counter and the direction of the packet, so the same key can be safely used by both sides,
+// and they never repeat for a single key. Opened packets must arrive in the order they were sealed, any replayed
+// or reordered packet is rejected.
+// PacketCipher holds the state of a single connection, so each connection needs its own instance.
+type PacketCipher struct {
+	config        *PacketCipherConfig
+	aead          cipher.AEAD
+	sealDirection uint32
+	openDirection uint32
+	sealCounter   uint64
+	openCounter   uint64
+	buffer        []byte
+}
+
+// NewServerCipher creates a PacketCipher for the server side of the connection.
+// Key must be 16, 24 or 32 bytes long, to select AES-128, AES-192 or AES-256. It's expected to be established by
+// the key exchange performed by the protocol, and to be unique for every connection.
+func NewServerCipher(key []byte, config ...*PacketCipherConfig) (*PacketCipher, error) {
+	return newPacketCipher(key, 0, 1, config)
+}
+
+// NewClientCipher creates a PacketCipher for the client side of the connection (see NewServerCipher).
+func NewClientCipher(key []byte, config ...*PacketCipherConfig) (*PacketCipher, error) {
+	return newPacketCipher(key, 1, 0, config)
+}
+
+func newPacketCipher(
+	key []byte,
+	sealDirection uint32,
+	openDirection uint32,
+	config []*PacketCipherConfig,
+) (*PacketCipher, error) {
+	var providedConfig *PacketCipherConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergePacketCipherConfig(providedConfig)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PacketCipher{
+		config:        c,
+		aead:          aead,
+		sealDirection: sealDirection,
+		openDirection: openDirection,
+	}, nil
+}
+
+// Overhead returns a number of bytes added to every sealed packet.
+func (p *PacketCipher) Overhead() int {
+	return counterSize + p.aead.Overhead()
+}
+
+// Seal appends encrypted packet to dst and returns the extended buffer.
+// It's safe to call Seal concurrently, but sealed packets must be sent in the order of sealing.
+func (p *PacketCipher) Seal(dst, packet []byte) []byte {
+	counter := atomic.AddUint64(&p.sealCounter, 1)
+
+	var nonce [12]byte
+	binary.BigEndian.PutUint32(nonce[:4], p.sealDirection)
+	binary.BigEndian.PutUint64(nonce[4:], counter)
+
+	dst = append(dst, nonce[4:]...)
+	return p.aead.Seal(dst, nonce[:], packet, nil)
+}
+
+// Open appends decrypted packet to dst and returns the extended buffer.
+// Returns error if the packet has been tampered with, replayed or reordered.
+// Open must not be called concurrently.
+func (p *PacketCipher) Open(dst, packet []byte) ([]byte, error) {
+	if len(packet) < p.Overhead() {
+		return dst, ErrPacketTooShort
+	}
+
+	counter := binary.BigEndian.Uint64(packet[:counterSize])
+	if counter <= p.openCounter {
+		return dst, ErrReplayedPacket
+	}
+
+	var nonce [12]byte
+	binary.BigEndian.PutUint32(nonce[:4], p.openDirection)
+	copy(nonce[4:], packet[:counterSize])
+
+	opened, err := p.aead.Open(dst, nonce[:], packet[counterSize:], nil)
+	if err != nil {
+		return dst, err
+	}
+
+	p.openCounter = counter
+	return opened, nil
+}
+
+// Handler wraps given PacketHandler, so it receives decrypted packets.
+// Packets that fail to decrypt are dropped and reported to OnError handler.
+// Decrypted packet is only valid until the handler returns.
+func (p *PacketCipher) Handler(handler tinytcp.PacketHandler) tinytcp.PacketHandler {
+	return func(packet []byte) {
+		decrypted, err := p.Open(p.buffer[:0], packet)
+		if err != nil {
+			p.config.OnError(err)
+			return
+		}
+
+		p.buffer = decrypted
+		handler(decrypted)
+	}
+}
@@ -0,0 +1,91 @@
+package cryptotinytcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestPacketCipher(t *testing.T) {
+	// given
+	server, _ := NewServerCipher(testKey)
+	client, _ := NewClientCipher(testKey)
+
+	// when
+	request := client.Seal(nil, []byte("request"))
+	response := server.Seal(nil, []byte("response"))
+
+	openedRequest, requestErr := server.Open(nil, request)
+	openedResponse, responseErr := client.Open(nil, response)
+
+	// then
+	assert.Equal(t, len("request")+client.Overhead(), len(request), "overhead should match")
+	assert.Nil(t, requestErr, "request err should be nil")
+	assert.Equal(t, "request", string(openedRequest), "request should match")
+	assert.Nil(t, responseErr, "response err should be nil")
+	assert.Equal(t, "response", string(openedResponse), "response should match")
+}
+
+func TestPacketCipherRejectsInvalidPackets(t *testing.T) {
+	// given
+	server, _ := NewServerCipher(testKey)
+	client, _ := NewClientCipher(testKey)
+
+	first := client.Seal(nil, []byte("first"))
+	second := client.Seal(nil, []byte("second"))
+	ownPacket := server.Seal(nil, []byte("own"))
+
+	tampered := append([]byte(nil), second...)
+	tampered[len(tampered)-1] ^= 0xFF
+
+	// when
+	_, tooShortErr := server.Open(nil, []byte{1, 2, 3})
+	_, reflectedErr := server.Open(nil, ownPacket)
+	_, tamperedErr := server.Open(nil, tampered)
+	_, secondErr := server.Open(nil, second)
+	_, reorderedErr := server.Open(nil, first)
+	_, replayedErr := server.Open(nil, second)
+
+	// then
+	assert.Equal(t, ErrPacketTooShort, tooShortErr, "short packet should be rejected")
+	assert.NotNil(t, reflectedErr, "packet sealed in the opposite direction should be rejected")
+	assert.NotNil(t, tamperedErr, "tampered packet should be rejected")
+	assert.Nil(t, secondErr, "valid packet should be opened")
+	assert.Equal(t, ErrReplayedPacket, reorderedErr, "reordered packet should be rejected")
+	assert.Equal(t, ErrReplayedPacket, replayedErr, "replayed packet should be rejected")
+}
+
+func TestPacketCipherHandler(t *testing.T) {
+	// given
+	var errs []error
+	server, _ := NewServerCipher(testKey, &PacketCipherConfig{
+		OnError: func(err error) {
+			errs = append(errs, err)
+		},
+	})
+	client, _ := NewClientCipher(testKey)
+
+	var received []string
+	handler := server.Handler(func(packet []byte) {
+		received = append(received, string(packet))
+	})
+
+	// when
+	handler(client.Seal(nil, []byte("first")))
+	handler([]byte("garbage"))
+	handler(client.Seal(nil, []byte("second")))
+
+	// then
+	assert.Equal(t, []string{"first", "second"}, received, "valid packets should be decrypted")
+	assert.Equal(t, []error{ErrPacketTooShort}, errs, "invalid packet should be reported")
+}
+
+func TestPacketCipherInvalidKey(t *testing.T) {
+	// when
+	_, err := NewServerCipher([]byte("short"))
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
@@ -0,0 +1,5 @@
+/*
+Package cryptotinytcp provides per-packet encryption for protocols that need confidentiality without full TLS,
+eg. game servers performing their own handshake and key exchange.
+*/
+package cryptotinytcp
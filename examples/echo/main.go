@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mkorman9/tinytcp"
 	"io"
@@ -26,7 +27,7 @@ func serve(socket *tinytcp.Socket) {
 	for {
 		n, err := socket.Read(buffer[:])
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				break
 			}
 
@@ -39,7 +40,7 @@ func serve(socket *tinytcp.Socket) {
 
 		_, err = socket.Write(message)
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				break
 			}
 
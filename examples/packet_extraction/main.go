@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mkorman9/tinytcp"
 	"io"
@@ -31,7 +32,7 @@ func serve(socket *tinytcp.Socket) tinytcp.PacketHandler {
 	return func(packet []byte) {
 		_, err := socket.Write(packet)
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				return
 			}
 
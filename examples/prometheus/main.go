@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/mkorman9/tinytcp"
 	"github.com/mkorman9/tinytcp/promtinytcp"
@@ -22,12 +23,16 @@ func main() {
 		),
 	))
 
-	go func() {
-		http.Handle("/", promhttp.Handler())
-		_ = http.ListenAndServe("0.0.0.0:8080", nil)
-	}()
+	mux := http.NewServeMux()
+	mux.Handle("/", promhttp.Handler())
+	mux.Handle("/readyz", tinytcp.ReadinessHandler(server))
 
-	if err := tinytcp.StartAndBlock(server); err != nil {
+	metricsServer := &http.Server{
+		Addr:    "0.0.0.0:8080",
+		Handler: mux,
+	}
+
+	if err := tinytcp.StartAndBlock(server, tinytcp.HTTPService(metricsServer)); err != nil {
 		fmt.Printf("Error while starting: %v\n", err)
 	}
 }
@@ -36,7 +41,7 @@ func serve(socket *tinytcp.Socket) tinytcp.PacketHandler {
 	return func(packet []byte) {
 		_, err := socket.Write(packet)
 		if err != nil {
-			if err == io.EOF {
+			if errors.Is(err, io.EOF) {
 				return
 			}
 
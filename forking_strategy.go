@@ -11,7 +11,7 @@ import (
 // This implementation might not fit the needs of some highly-concurrent servers,
 // so other implementations (like worker pool) may be implemented on top of this interface.
 type ForkingStrategy interface {
-	// OnStart is called once, after server start.
+	// OnStart is called after every server start.
 	OnStart()
 
 	// OnAccept is called for every connection accepted by the server.
@@ -20,9 +20,11 @@ type ForkingStrategy interface {
 	OnAccept(socket *Socket)
 
 	// OnMetricsUpdate is called every time the server updates its metrics.
+	// The implementation should report its own stats, like Goroutines, Workers, BusyWorkers, QueuedTasks
+	// and TaskLatency, by setting them in metrics.
 	OnMetricsUpdate(metrics *ServerMetrics)
 
-	// OnStop is called once, after server stops.
+	// OnStop is called after every server stop.
 	OnStop()
 }
 
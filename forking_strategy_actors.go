@@ -0,0 +1,231 @@
+package tinytcp
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Mailbox represents a connection handled by the Actors strategy.
+// Handlers interact with the connection only by receiving from Inbox and sending to Outbox.
+type Mailbox struct {
+	// Inbox receives packets extracted from the connection. It's closed after the connection is closed.
+	// Packets are copied, so they can be retained. Connection is not read while Inbox is full.
+	Inbox <-chan []byte
+
+	// Outbox accepts packets to be written to the connection. It's never closed, so it's safe to send to it
+	// at any time, but the sender should select on Done() to avoid blocking after the connection is closed.
+	Outbox chan<- []byte
+
+	socket *SocketRef
+	done   chan struct{}
+}
+
+// Done returns a channel that's closed when the connection is closed.
+func (m *Mailbox) Done() <-chan struct{} {
+	return m.done
+}
+
+// RemoteAddress returns a remote address of the connection.
+func (m *Mailbox) RemoteAddress() string {
+	return m.socket.RemoteAddress()
+}
+
+// Close closes the connection.
+func (m *Mailbox) Close() error {
+	return m.socket.Close()
+}
+
+// ActorsConfig holds an optional configuration for Actors strategy.
+type ActorsConfig struct {
+	// InboxSize is a capacity of the Inbox channel (default: 64).
+	InboxSize int
+
+	// OutboxSize is a capacity of the Outbox channel (default: 64).
+	OutboxSize int
+
+	// FramingConfig is a configuration of packet framing (see PacketFramingConfig).
+	FramingConfig *PacketFramingConfig
+
+	// OnPanic is a handler called when onConnect panics.
+	OnPanic func(error)
+}
+
+func mergeActorsConfig(provided *ActorsConfig) *ActorsConfig {
+	config := &ActorsConfig{
+		InboxSize:  64,
+		OutboxSize: 64,
+		OnPanic:    func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.InboxSize > 0 {
+		config.InboxSize = provided.InboxSize
+	}
+	if provided.OutboxSize > 0 {
+		config.OutboxSize = provided.OutboxSize
+	}
+	if provided.FramingConfig != nil {
+		config.FramingConfig = provided.FramingConfig
+	}
+	if provided.OnPanic != nil {
+		config.OnPanic = provided.OnPanic
+	}
+
+	return config
+}
+
+type actors struct {
+	config        *ActorsConfig
+	framingConfig *PacketFramingConfig
+	framer        *packetFramer
+	onConnect     func(*Mailbox)
+	goroutines    int32
+	mailboxes     map[*Mailbox]struct{}
+	m             sync.Mutex
+}
+
+// Actors is a ForkingStrategy based on message passing. Each connection is represented by a Mailbox,
+// with packets extracted according to given FramingProtocol delivered to its Inbox, and packets sent to its Outbox
+// written to the connection. onConnect is called for each new connection, and should pass the Mailbox
+// to the goroutine that owns it (eg. a game loop handling many connections at once with select).
+// Each connection is served by two goroutines - one reading and one writing.
+func Actors(framingProtocol FramingProtocol, onConnect func(*Mailbox), config ...*ActorsConfig) ForkingStrategy {
+	var providedConfig *ActorsConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeActorsConfig(providedConfig)
+	fc := mergePacketFramingConfig(c.FramingConfig)
+
+	return &actors{
+		config:        c,
+		framingConfig: fc,
+		framer:        newPacketFramer(framingProtocol, fc),
+		onConnect:     onConnect,
+		mailboxes:     make(map[*Mailbox]struct{}),
+	}
+}
+
+func (a *actors) OnStart() {
+}
+
+func (a *actors) OnStop() {
+}
+
+// OnMetricsUpdate reports the packets waiting in the Inboxes and Outboxes of all the connections as QueuedTasks.
+func (a *actors) OnMetricsUpdate(metrics *ServerMetrics) {
+	metrics.Goroutines = int(atomic.LoadInt32(&a.goroutines))
+
+	a.m.Lock()
+	defer a.m.Unlock()
+
+	metrics.QueuedTasks = 0
+	for mailbox := range a.mailboxes {
+		metrics.QueuedTasks += len(mailbox.Inbox) + len(mailbox.Outbox)
+	}
+}
+
+func (a *actors) OnAccept(socket *Socket) {
+	var (
+		inbox  = make(chan []byte, a.config.InboxSize)
+		outbox = make(chan []byte, a.config.OutboxSize)
+		done   = make(chan struct{})
+
+		// the last goroutine to finish recycles the socket
+		running int32 = 2
+	)
+
+	mailbox := &Mailbox{
+		Inbox:  inbox,
+		Outbox: outbox,
+		socket: NewSocketRef(socket),
+		done:   done,
+	}
+
+	var doneOnce sync.Once
+	closeDone := func() {
+		doneOnce.Do(func() {
+			close(done)
+		})
+	}
+
+	socket.OnClose(func(_ CloseReason) {
+		closeDone()
+	})
+	if socket.IsClosed() {
+		// closed before the close handler has been registered
+		closeDone()
+	}
+
+	finish := func() {
+		atomic.AddInt32(&a.goroutines, -1)
+
+		if atomic.AddInt32(&running, -1) == 0 {
+			a.m.Lock()
+			delete(a.mailboxes, mailbox)
+			a.m.Unlock()
+
+			_ = socket.Recycle()
+		}
+	}
+
+	a.m.Lock()
+	a.mailboxes[mailbox] = struct{}{}
+	a.m.Unlock()
+
+	atomic.AddInt32(&a.goroutines, 2)
+
+	// reader
+	go func() {
+		defer func() {
+			_ = socket.Close()
+			close(inbox)
+			finish()
+		}()
+
+		a.framer.run(socket, func(packet []byte) {
+			message := make([]byte, len(packet))
+			copy(message, packet)
+
+			select {
+			case inbox <- message:
+			case <-done:
+			}
+		}, socket, func(err error) {
+			a.framingConfig.OnSocketError(socket, err)
+		})
+	}()
+
+	// writer
+	go func() {
+		defer finish()
+
+		for {
+			select {
+			case packet := <-outbox:
+				if err := WriteBytes(socket, packet); err != nil {
+					_ = socket.Close()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	a.callOnConnect(mailbox)
+}
+
+func (a *actors) callOnConnect(mailbox *Mailbox) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = mailbox.Close()
+			a.config.OnPanic(fmt.Errorf("%v", r))
+		}
+	}()
+
+	a.onConnect(mailbox)
+}
@@ -0,0 +1,103 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestActors(t *testing.T) {
+	// given
+	serverConn, clientConn := net.Pipe()
+	socket := NewSocket(serverConn)
+
+	mailboxes := make(chan *Mailbox, 1)
+	strategy := Actors(SplitBySeparator([]byte{'\n'}), func(mailbox *Mailbox) {
+		mailboxes <- mailbox
+	})
+
+	// when
+	strategy.OnAccept(socket)
+	mailbox := <-mailboxes
+
+	go func() {
+		_, _ = clientConn.Write([]byte("ping\n"))
+	}()
+	received := <-mailbox.Inbox
+
+	mailbox.Outbox <- []byte("pong\n")
+	response := make([]byte, 5)
+	_, readErr := io.ReadFull(clientConn, response)
+
+	_ = clientConn.Close()
+
+	// then
+	assert.Equal(t, []byte("ping"), received, "packet should be delivered to inbox")
+	assert.Nil(t, readErr, "readErr should be nil")
+	assert.Equal(t, []byte("pong\n"), response, "packet from outbox should be written")
+
+	select {
+	case <-mailbox.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "mailbox should be done after disconnecting")
+	}
+
+	_, open := <-mailbox.Inbox
+	assert.False(t, open, "inbox should be closed")
+	assert.Eventually(t, socket.isRecyclable, time.Second, time.Millisecond, "socket should be recycled")
+}
+
+func TestActorsQueuedTasks(t *testing.T) {
+	// given
+	serverConn, clientConn := net.Pipe()
+	socket := NewSocket(serverConn)
+
+	strategy := Actors(SplitBySeparator([]byte{'\n'}), func(_ *Mailbox) {})
+	queuedTasks := func() int {
+		var metrics ServerMetrics
+		strategy.OnMetricsUpdate(&metrics)
+		return metrics.QueuedTasks
+	}
+
+	// when
+	strategy.OnAccept(socket)
+	_, _ = clientConn.Write([]byte("first\nsecond\nthird\n"))
+
+	// then
+	assert.Eventually(t, func() bool {
+		return queuedTasks() == 3
+	}, time.Second, time.Millisecond, "packets waiting in inbox should be reported")
+
+	_ = clientConn.Close()
+
+	assert.Eventually(t, func() bool {
+		return queuedTasks() == 0
+	}, time.Second, time.Millisecond, "closed connections should not be reported")
+}
+
+func TestActorsSocketClosedBeforeAccept(t *testing.T) {
+	// given
+	serverConn, _ := net.Pipe()
+	socket := NewSocket(serverConn)
+
+	mailboxes := make(chan *Mailbox, 1)
+	strategy := Actors(SplitBySeparator([]byte{'\n'}), func(mailbox *Mailbox) {
+		mailboxes <- mailbox
+	})
+
+	// when
+	_ = socket.Close()
+	strategy.OnAccept(socket)
+	mailbox := <-mailboxes
+
+	// then
+	select {
+	case <-mailbox.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "mailbox should be done when the socket is already closed")
+	}
+
+	assert.Eventually(t, socket.isRecyclable, time.Second, time.Millisecond, "socket should be recycled")
+}
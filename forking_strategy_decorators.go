@@ -0,0 +1,144 @@
+package tinytcp
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ForkingStrategyDecorator wraps ForkingStrategy to extend its behavior (see Decorate).
+// Custom decorators can embed the wrapped ForkingStrategy and override only the hooks they need.
+type ForkingStrategyDecorator func(ForkingStrategy) ForkingStrategy
+
+// Decorate wraps given ForkingStrategy with decorators. The first decorator is the outermost one.
+func Decorate(strategy ForkingStrategy, decorators ...ForkingStrategyDecorator) ForkingStrategy {
+	for i := len(decorators) - 1; i >= 0; i-- {
+		strategy = decorators[i](strategy)
+	}
+
+	return strategy
+}
+
+/*
+	Connections Gauge
+*/
+
+type connGaugeStrategy struct {
+	ForkingStrategy
+	active  int64
+	gauge   func(active int64)
+	onClose SocketCloseHandler
+}
+
+// WithConnGauge is a decorator that tracks a number of active connections handled by the strategy.
+// Gauge is called with the current number every time a connection is accepted or closed.
+func WithConnGauge(gauge func(active int64)) ForkingStrategyDecorator {
+	return func(strategy ForkingStrategy) ForkingStrategy {
+		s := &connGaugeStrategy{
+			ForkingStrategy: strategy,
+			gauge:           gauge,
+		}
+
+		// created once to avoid allocations for each connection
+		s.onClose = func(_ CloseReason) {
+			s.gauge(atomic.AddInt64(&s.active, -1))
+		}
+
+		return s
+	}
+}
+
+func (s *connGaugeStrategy) OnAccept(socket *Socket) {
+	s.gauge(atomic.AddInt64(&s.active, 1))
+	socket.OnClose(s.onClose)
+
+	s.ForkingStrategy.OnAccept(socket)
+}
+
+/*
+	Timeout
+*/
+
+type timeoutStrategy struct {
+	ForkingStrategy
+	timeout time.Duration
+}
+
+// WithTimeout is a decorator that limits the lifetime of each connection.
+// Connection is closed after the timeout, counting from the moment it has been accepted.
+func WithTimeout(timeout time.Duration) ForkingStrategyDecorator {
+	return func(strategy ForkingStrategy) ForkingStrategy {
+		return &timeoutStrategy{
+			ForkingStrategy: strategy,
+			timeout:         timeout,
+		}
+	}
+}
+
+func (s *timeoutStrategy) OnAccept(socket *Socket) {
+	// socket might be already recycled when the timer fires, so it's accessed through the reference
+	ref := NewSocketRef(socket)
+	timer := time.AfterFunc(s.timeout, func() {
+		_ = ref.Close()
+	})
+
+	socket.OnClose(func(_ CloseReason) {
+		timer.Stop()
+	})
+
+	s.ForkingStrategy.OnAccept(socket)
+}
+
+/*
+	Recovery
+*/
+
+type recoveryStrategy struct {
+	ForkingStrategy
+	panicHandler func(error)
+}
+
+// WithRecovery is a decorator that recovers from panics raised by the hooks of the strategy, and reports them to
+// panicHandler. Socket is closed if OnAccept panics. Without it, a panic in OnMetricsUpdate aborts the server.
+// Note that panics raised by handlers running on separate goroutines (like in GoroutinePerConnection) are handled
+// by the strategy itself.
+func WithRecovery(panicHandler func(error)) ForkingStrategyDecorator {
+	return func(strategy ForkingStrategy) ForkingStrategy {
+		return &recoveryStrategy{
+			ForkingStrategy: strategy,
+			panicHandler:    panicHandler,
+		}
+	}
+}
+
+func (s *recoveryStrategy) OnStart() {
+	defer s.recoverPanic()
+	s.ForkingStrategy.OnStart()
+}
+
+func (s *recoveryStrategy) OnAccept(socket *Socket) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = socket.Recycle()
+			s.panicHandler(fmt.Errorf("%v", r))
+		}
+	}()
+
+	s.ForkingStrategy.OnAccept(socket)
+}
+
+func (s *recoveryStrategy) OnMetricsUpdate(metrics *ServerMetrics) {
+	defer s.recoverPanic()
+	s.ForkingStrategy.OnMetricsUpdate(metrics)
+}
+
+func (s *recoveryStrategy) OnStop() {
+	defer s.recoverPanic()
+	s.ForkingStrategy.OnStop()
+}
+
+func (s *recoveryStrategy) recoverPanic() {
+	if r := recover(); r != nil {
+		s.panicHandler(fmt.Errorf("%v", r))
+	}
+}
@@ -0,0 +1,72 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+type noopStrategy struct {
+	accepted []*Socket
+}
+
+func (n *noopStrategy) OnStart()                         {}
+func (n *noopStrategy) OnAccept(socket *Socket)          { n.accepted = append(n.accepted, socket) }
+func (n *noopStrategy) OnMetricsUpdate(_ *ServerMetrics) { panic("metrics update") }
+func (n *noopStrategy) OnStop()                          {}
+
+func TestWithConnGauge(t *testing.T) {
+	// given
+	var values []int64
+	inner := &noopStrategy{}
+	strategy := Decorate(inner, WithConnGauge(func(active int64) {
+		values = append(values, active)
+	}))
+
+	// when
+	strategy.OnAccept(MockSocket(nil, io.Discard))
+	strategy.OnAccept(MockSocket(nil, io.Discard))
+	_ = inner.accepted[0].Close()
+
+	// then
+	assert.Equal(t, []int64{1, 2, 1}, values, "gauge values should match")
+	assert.Len(t, inner.accepted, 2, "sockets should be passed to the wrapped strategy")
+}
+
+func TestWithTimeout(t *testing.T) {
+	// given
+	closed := make(chan CloseReason, 1)
+	socket := MockSocket(nil, io.Discard)
+	socket.OnClose(func(reason CloseReason) {
+		closed <- reason
+	})
+
+	strategy := Decorate(&noopStrategy{}, WithTimeout(10*time.Millisecond))
+
+	// when
+	strategy.OnAccept(socket)
+
+	// then
+	select {
+	case reason := <-closed:
+		assert.Equal(t, CloseReasonServer, reason, "socket should be closed by the server")
+	case <-time.After(time.Second):
+		assert.Fail(t, "socket should be closed after timeout")
+	}
+}
+
+func TestWithRecovery(t *testing.T) {
+	// given
+	var receivedErr error
+	strategy := Decorate(&noopStrategy{}, WithRecovery(func(err error) {
+		receivedErr = err
+	}))
+
+	// when
+	strategy.OnMetricsUpdate(&ServerMetrics{})
+
+	// then
+	assert.NotNil(t, receivedErr, "receivedErr should not be nil")
+	assert.Equal(t, "metrics update", receivedErr.Error(), "panic errors should match")
+}
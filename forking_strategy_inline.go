@@ -0,0 +1,51 @@
+package tinytcp
+
+import (
+	"fmt"
+)
+
+// InlineHandler is a ForkingStrategy that runs the handler synchronously, on the goroutine accepting the connections
+// (or the accept queue goroutine, see ServerConfig.AcceptQueueSize), without starting any goroutine.
+// It's meant for trivial handlers performing ultra-short request/response exchanges, like health checks,
+// where the cost of scheduling a goroutine per connection dominates. No other connections are accepted while
+// the handler runs, so the handler must never block for long, eg. it should set a deadline before reading
+// from the client. Connections are automatically closed after their handler finishes.
+func InlineHandler(socketHandler SocketHandler, panicHandler ...func(error)) ForkingStrategy {
+	ph := func(_ error) {}
+	if panicHandler != nil {
+		ph = panicHandler[0]
+	}
+
+	return &inlineHandler{
+		handler:      socketHandler,
+		panicHandler: ph,
+	}
+}
+
+type inlineHandler struct {
+	handler      SocketHandler
+	panicHandler func(error)
+}
+
+func (i *inlineHandler) OnStart() {
+}
+
+func (i *inlineHandler) OnStop() {
+}
+
+func (i *inlineHandler) OnMetricsUpdate(_ *ServerMetrics) {
+}
+
+func (i *inlineHandler) OnAccept(socket *Socket) {
+	defer func() {
+		if r := recover(); r != nil {
+			i.panicHandler(fmt.Errorf("%v", r))
+		}
+	}()
+
+	defer func() {
+		_ = socket.Recycle()
+	}()
+
+	i.handler(socket)
+}
@@ -0,0 +1,95 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestInlineHandler(t *testing.T) {
+	// given
+	socket := MockSocket(nil, io.Discard)
+	parentGoroutineID := getGoroutineID()
+
+	var (
+		handlerGoroutineID uint64
+		receivedSocket     *Socket
+	)
+
+	handler := func(s *Socket) {
+		receivedSocket = s
+		handlerGoroutineID = getGoroutineID()
+	}
+
+	// when
+	InlineHandler(handler).OnAccept(socket)
+
+	// then
+	assert.Equal(t, socket, receivedSocket, "socket should be passed to handler")
+	assert.Equal(t, parentGoroutineID, handlerGoroutineID, "handler should be run on the calling goroutine")
+	assert.True(t, socket.IsClosed(), "socket should be closed after the handler returns")
+}
+
+func TestInlineHandlerPanic(t *testing.T) {
+	// given
+	socket := MockSocket(nil, io.Discard)
+	panicMsg := "panic inside handler"
+	var receivedPanicMsg string
+
+	handler := func(_ *Socket) {
+		panic(panicMsg)
+	}
+
+	panicHandler := func(err error) {
+		receivedPanicMsg = err.Error()
+	}
+
+	// when
+	InlineHandler(handler, panicHandler).OnAccept(socket)
+
+	// then
+	assert.Equal(t, panicMsg, receivedPanicMsg, "panic errors should match")
+}
+
+func TestInlineHandlerAcceptQueueStop(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1, AcceptQueueSize: 8})
+
+	handling := make(chan struct{})
+	server.ForkingStrategy(InlineHandler(func(socket *Socket) {
+		close(handling)
+
+		var b [1]byte
+		_, _ = socket.Read(b[:])
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	<-handling
+
+	// when
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Stop()
+	}()
+
+	// then
+	select {
+	case err := <-stopped:
+		assert.Nil(t, err, "err should be nil")
+	case <-time.After(time.Second):
+		t.Fatal("server should stop while the inline handler is blocked on the socket")
+	}
+}
@@ -0,0 +1,147 @@
+package tinytcp
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ReusedGoroutinesConfig holds an optional configuration for ReusedGoroutines strategy.
+type ReusedGoroutinesConfig struct {
+	// MaxIdleWorkers is a maximal number of idle goroutines kept for reuse (default: 1024).
+	MaxIdleWorkers int
+
+	// IdleTimeout specifies how long an idle goroutine waits for the next connection before exiting (default: 10s).
+	IdleTimeout time.Duration
+
+	// PanicHandler is a handler called when the socket handler panics.
+	PanicHandler func(error)
+}
+
+func mergeReusedGoroutinesConfig(provided *ReusedGoroutinesConfig) *ReusedGoroutinesConfig {
+	config := &ReusedGoroutinesConfig{
+		MaxIdleWorkers: 1024,
+		IdleTimeout:    10 * time.Second,
+		PanicHandler:   func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.MaxIdleWorkers > 0 {
+		config.MaxIdleWorkers = provided.MaxIdleWorkers
+	}
+	if provided.IdleTimeout > 0 {
+		config.IdleTimeout = provided.IdleTimeout
+	}
+	if provided.PanicHandler != nil {
+		config.PanicHandler = provided.PanicHandler
+	}
+
+	return config
+}
+
+type reusedGoroutines struct {
+	config      *ReusedGoroutinesConfig
+	handler     SocketHandler
+	sockets     chan *Socket
+	stopChannel chan struct{}
+	workers     int32
+	idleWorkers int32
+}
+
+// ReusedGoroutines is a ForkingStrategy that works like GoroutinePerConnection, but instead of exiting after the
+// connection is closed, goroutines park and wait to handle the next connection. New goroutine is started only when
+// there is no idle one. It reduces the overhead of starting goroutines and growing their stacks on servers
+// accepting connections at high rates.
+// Connections are automatically closed after their handler finishes.
+func ReusedGoroutines(socketHandler SocketHandler, config ...*ReusedGoroutinesConfig) ForkingStrategy {
+	var providedConfig *ReusedGoroutinesConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+
+	return &reusedGoroutines{
+		config:  mergeReusedGoroutinesConfig(providedConfig),
+		handler: socketHandler,
+		sockets: make(chan *Socket),
+	}
+}
+
+func (r *reusedGoroutines) OnStart() {
+	r.stopChannel = make(chan struct{})
+}
+
+func (r *reusedGoroutines) OnStop() {
+	close(r.stopChannel)
+}
+
+func (r *reusedGoroutines) OnMetricsUpdate(metrics *ServerMetrics) {
+	workers := int(atomic.LoadInt32(&r.workers))
+
+	metrics.Goroutines = workers
+	metrics.Workers = workers
+	metrics.BusyWorkers = workers - int(atomic.LoadInt32(&r.idleWorkers))
+}
+
+func (r *reusedGoroutines) OnAccept(socket *Socket) {
+	// hand over the socket to an idle goroutine, if there is any
+	select {
+	case r.sockets <- socket:
+		return
+	default:
+	}
+
+	atomic.AddInt32(&r.workers, 1)
+	go r.worker(socket, r.stopChannel)
+}
+
+func (r *reusedGoroutines) worker(socket *Socket, stopChannel chan struct{}) {
+	defer atomic.AddInt32(&r.workers, -1)
+
+	timer := time.NewTimer(r.config.IdleTimeout)
+	defer timer.Stop()
+
+	for {
+		r.handle(socket)
+
+		if int(atomic.AddInt32(&r.idleWorkers, 1)) > r.config.MaxIdleWorkers {
+			atomic.AddInt32(&r.idleWorkers, -1)
+			return
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(r.config.IdleTimeout)
+
+		select {
+		case socket = <-r.sockets:
+			atomic.AddInt32(&r.idleWorkers, -1)
+		case <-timer.C:
+			atomic.AddInt32(&r.idleWorkers, -1)
+			return
+		case <-stopChannel:
+			atomic.AddInt32(&r.idleWorkers, -1)
+			return
+		}
+	}
+}
+
+func (r *reusedGoroutines) handle(socket *Socket) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			r.config.PanicHandler(fmt.Errorf("%v", rec))
+		}
+	}()
+
+	defer func() {
+		_ = socket.Recycle()
+	}()
+
+	r.handler(socket)
+}
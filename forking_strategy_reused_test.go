@@ -0,0 +1,69 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReusedGoroutines(t *testing.T) {
+	// given
+	var (
+		goroutines = make(map[uint64]struct{})
+		m          sync.Mutex
+		handled    = make(chan struct{})
+	)
+
+	strategy := ReusedGoroutines(func(_ *Socket) {
+		m.Lock()
+		goroutines[getGoroutineID()] = struct{}{}
+		m.Unlock()
+
+		handled <- struct{}{}
+	})
+
+	strategy.OnStart()
+	defer strategy.OnStop()
+
+	// when
+	for i := 0; i < 3; i++ {
+		strategy.OnAccept(MockSocket(nil, io.Discard))
+		<-handled
+
+		// wait for the goroutine to park
+		assert.Eventually(t, func() bool {
+			var metrics ServerMetrics
+			strategy.OnMetricsUpdate(&metrics)
+			return metrics.BusyWorkers == 0
+		}, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// then
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Len(t, goroutines, 1, "goroutine should be reused")
+}
+
+func TestReusedGoroutinesIdleTimeout(t *testing.T) {
+	// given
+	strategy := ReusedGoroutines(func(_ *Socket) {}, &ReusedGoroutinesConfig{
+		IdleTimeout: 5 * time.Millisecond,
+	})
+
+	strategy.OnStart()
+	defer strategy.OnStop()
+
+	// when
+	strategy.OnAccept(MockSocket(nil, io.Discard))
+
+	// then
+	assert.Eventually(t, func() bool {
+		var metrics ServerMetrics
+		strategy.OnMetricsUpdate(&metrics)
+		return metrics.Goroutines == 0
+	}, time.Second, time.Millisecond, "idle goroutine should exit")
+}
@@ -0,0 +1,245 @@
+package tinytcp
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedConfig holds an optional configuration for Sharded strategy.
+type ShardedConfig struct {
+	// Shards is a number of worker goroutines processing packets (default: runtime.NumCPU()).
+	Shards int
+
+	// QueueSize is a capacity of the queue of packets waiting for each shard (default: 256).
+	QueueSize int
+
+	// KeyFunc returns a key used to select the shard for a connection (default: remote IP address).
+	// It's called on a separate goroutine before any packet is extracted, so it's allowed to read from the socket,
+	// eg. to receive a handshake containing client ID.
+	KeyFunc func(*Socket) string
+
+	// FramingConfig is a configuration of packet framing (see PacketFramingConfig).
+	FramingConfig *PacketFramingConfig
+
+	// OnPanic is a handler called when KeyFunc, the socket handler or a packet handler panics.
+	// Connection is closed after panic.
+	OnPanic func(error)
+}
+
+func mergeShardedConfig(provided *ShardedConfig) *ShardedConfig {
+	config := &ShardedConfig{
+		Shards:    runtime.NumCPU(),
+		QueueSize: 256,
+		KeyFunc:   (*Socket).RemoteAddress,
+		OnPanic:   func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Shards > 0 {
+		config.Shards = provided.Shards
+	}
+	if provided.QueueSize > 0 {
+		config.QueueSize = provided.QueueSize
+	}
+	if provided.KeyFunc != nil {
+		config.KeyFunc = provided.KeyFunc
+	}
+	if provided.FramingConfig != nil {
+		config.FramingConfig = provided.FramingConfig
+	}
+	if provided.OnPanic != nil {
+		config.OnPanic = provided.OnPanic
+	}
+
+	return config
+}
+
+type shardTask struct {
+	socket  *Socket
+	handler PacketHandler
+	packet  []byte
+	queued  time.Time
+
+	// last task of the connection - the socket is recycled after processing it
+	last bool
+}
+
+type sharded struct {
+	config        *ShardedConfig
+	framingConfig *PacketFramingConfig
+	framer        *packetFramer
+	socketHandler func(*Socket) PacketHandler
+
+	shards      []chan shardTask
+	stopChannel chan struct{}
+	workers     sync.WaitGroup
+	goroutines  int32
+	busyWorkers int32
+	taskLatency histogramRecorder[time.Duration]
+}
+
+// Sharded is a ForkingStrategy that processes packets on a fixed number of worker goroutines (shards).
+// Each connection is assigned to a shard by hashing its key (remote IP address by default, see KeyFunc),
+// which guarantees that all the packets from a single client are processed serially, on the same goroutine.
+// Packets are extracted according to given FramingProtocol on a separate goroutine for each connection.
+// It's useful for stateful protocols, where the state of many connections can be accessed without locking.
+func Sharded(
+	framingProtocol FramingProtocol,
+	socketHandler func(socket *Socket) PacketHandler,
+	config ...*ShardedConfig,
+) ForkingStrategy {
+	var providedConfig *ShardedConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeShardedConfig(providedConfig)
+	fc := mergePacketFramingConfig(c.FramingConfig)
+
+	return &sharded{
+		config:        c,
+		framingConfig: fc,
+		framer:        newPacketFramer(framingProtocol, fc),
+		socketHandler: socketHandler,
+		taskLatency: histogramRecorder[time.Duration]{
+			buckets: &PacketLatencyBuckets,
+		},
+	}
+}
+
+func (s *sharded) OnStart() {
+	s.stopChannel = make(chan struct{})
+	s.shards = make([]chan shardTask, s.config.Shards)
+
+	for i := range s.shards {
+		s.shards[i] = make(chan shardTask, s.config.QueueSize)
+
+		s.workers.Add(1)
+		atomic.AddInt32(&s.goroutines, 1)
+
+		go s.worker(s.shards[i])
+	}
+}
+
+func (s *sharded) OnStop() {
+	close(s.stopChannel)
+	s.workers.Wait()
+}
+
+func (s *sharded) OnMetricsUpdate(metrics *ServerMetrics) {
+	metrics.Goroutines = int(atomic.LoadInt32(&s.goroutines))
+	metrics.Workers = len(s.shards)
+	metrics.BusyWorkers = int(atomic.LoadInt32(&s.busyWorkers))
+
+	metrics.QueuedTasks = 0
+	for _, shard := range s.shards {
+		metrics.QueuedTasks += len(shard)
+	}
+
+	latency := s.taskLatency.Update()
+	metrics.TaskLatency.add(&latency)
+}
+
+func (s *sharded) OnAccept(socket *Socket) {
+	atomic.AddInt32(&s.goroutines, 1)
+
+	go func() {
+		defer atomic.AddInt32(&s.goroutines, -1)
+
+		shard, handler, ok := s.setup(socket)
+		if !ok {
+			return
+		}
+
+		s.framer.run(socket, func(packet []byte) {
+			task := shardTask{
+				socket:  socket,
+				handler: handler,
+				packet:  make([]byte, len(packet)),
+				queued:  time.Now(),
+			}
+			copy(task.packet, packet)
+
+			select {
+			case shard <- task:
+			case <-s.stopChannel:
+				_ = socket.Close()
+			}
+		}, socket, func(err error) {
+			s.framingConfig.OnSocketError(socket, err)
+		})
+
+		select {
+		case shard <- shardTask{socket: socket, last: true}:
+		case <-s.stopChannel:
+		}
+	}()
+}
+
+// setup selects the shard and creates the packet handler for the connection. If KeyFunc or the socket handler
+// panics, the socket is recycled right away, as no packets have been queued for it yet.
+func (s *sharded) setup(socket *Socket) (shard chan shardTask, handler PacketHandler, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = socket.Recycle()
+			s.config.OnPanic(fmt.Errorf("%v", r))
+		}
+	}()
+
+	shard = s.shards[shardIndex(s.config.KeyFunc(socket), len(s.shards))]
+	handler = s.socketHandler(socket)
+
+	return shard, handler, true
+}
+
+func (s *sharded) worker(tasks chan shardTask) {
+	defer func() {
+		atomic.AddInt32(&s.goroutines, -1)
+		s.workers.Done()
+	}()
+
+	for {
+		select {
+		case task := <-tasks:
+			if task.last {
+				_ = task.socket.Recycle()
+				continue
+			}
+
+			s.taskLatency.Observe(time.Since(task.queued))
+
+			atomic.AddInt32(&s.busyWorkers, 1)
+			s.handle(&task)
+			atomic.AddInt32(&s.busyWorkers, -1)
+		case <-s.stopChannel:
+			return
+		}
+	}
+}
+
+func (s *sharded) handle(task *shardTask) {
+	defer func() {
+		if r := recover(); r != nil {
+			_ = task.socket.Close()
+			s.config.OnPanic(fmt.Errorf("%v", r))
+		}
+	}()
+
+	task.handler(task.packet)
+}
+
+// shardIndex hashes key using FNV-1a.
+func shardIndex(key string, shards int) int {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+
+	return int(hash % uint32(shards))
+}
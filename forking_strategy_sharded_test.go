@@ -0,0 +1,127 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharded(t *testing.T) {
+	// given
+	var (
+		goroutines = make(map[uint64]struct{})
+		packets    []string
+		m          sync.Mutex
+		wg         sync.WaitGroup
+	)
+
+	strategy := Sharded(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(packet []byte) {
+				m.Lock()
+				defer m.Unlock()
+
+				goroutines[getGoroutineID()] = struct{}{}
+				packets = append(packets, string(packet))
+				wg.Done()
+			}
+		},
+		&ShardedConfig{
+			Shards: 4,
+			KeyFunc: func(_ *Socket) string {
+				return "client"
+			},
+		},
+	)
+
+	strategy.OnStart()
+	defer strategy.OnStop()
+
+	var sockets []*Socket
+	for i := 0; i < 2; i++ {
+		serverConn, clientConn := net.Pipe()
+		sockets = append(sockets, NewSocket(serverConn))
+
+		wg.Add(3)
+		go func(conn net.Conn) {
+			_, _ = conn.Write([]byte("a\nb\nc\n"))
+			_ = conn.Close()
+		}(clientConn)
+	}
+
+	// when
+	for _, socket := range sockets {
+		strategy.OnAccept(socket)
+	}
+	wg.Wait()
+
+	var metrics ServerMetrics
+	strategy.OnMetricsUpdate(&metrics)
+
+	// then
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Len(t, goroutines, 1, "packets with the same key should be processed on the same shard")
+	assert.Len(t, packets, 6, "all packets should be processed")
+	assert.Equal(t, 4, metrics.Workers, "workers should be reported")
+	assert.Equal(t, uint64(6), metrics.TaskLatency.Count, "task latency should be reported")
+
+	for _, socket := range sockets {
+		assert.Eventually(t, socket.isRecyclable, time.Second, time.Millisecond, "socket should be recycled")
+	}
+}
+
+func TestShardedKeyFuncPanic(t *testing.T) {
+	// given
+	panics := make(chan error, 1)
+
+	strategy := Sharded(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(_ []byte) {}
+		},
+		&ShardedConfig{
+			Shards: 1,
+			KeyFunc: func(_ *Socket) string {
+				panic("invalid handshake")
+			},
+			OnPanic: func(err error) {
+				panics <- err
+			},
+		},
+	)
+
+	strategy.OnStart()
+	defer strategy.OnStop()
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	socket := NewSocket(serverConn)
+
+	// when
+	strategy.OnAccept(socket)
+
+	// then
+	select {
+	case err := <-panics:
+		assert.EqualError(t, err, "invalid handshake", "panic should be reported")
+	case <-time.After(time.Second):
+		t.Fatal("panic should be reported")
+	}
+
+	assert.Eventually(t, socket.isRecyclable, time.Second, time.Millisecond, "socket should be recycled")
+}
+
+func TestShardIndex(t *testing.T) {
+	// when
+	first := shardIndex("10.0.0.1", 8)
+	second := shardIndex("10.0.0.1", 8)
+
+	// then
+	assert.Equal(t, first, second, "shard index should be stable")
+	assert.Less(t, first, 8, "shard index should be in range")
+}
@@ -2,15 +2,20 @@ package tinytcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"io"
-	"sync"
 	"time"
 )
 
 // PacketHandler is a function to be called after receiving packet data.
 type PacketHandler func(packet []byte)
 
+// PacketHandlerCtx is a function to be called after receiving packet data, with the context of the connection
+// (see PacketFramingHandlerCtx).
+type PacketHandlerCtx func(ctx context.Context, packet []byte)
+
 // FramingProtocol defines a strategy of extracting meaningful chunks of data out of read buffer.
 type FramingProtocol interface {
 	// ExtractPacket splits the source buffer into packet and "the rest".
@@ -35,7 +40,8 @@ type PacketFramingConfig struct {
 	MaxPacketSize int
 
 	// MinReadSpace sets a minimal space in read buffer that's needed to fit another Read() into it,
-	// without allocating auxiliary buffer (default: 1KiB or 1/4 of ReadBufferSize).
+	// without allocating auxiliary buffer. Must not exceed ReadBufferSize
+	// (default: 1KiB, or 1/4 of ReadBufferSize if ReadBufferSize is smaller than 1KiB).
 	MinReadSpace int
 
 	// OnSocketError is a handler called when a socket operation encounters an error other than EOF or a timeout.
@@ -48,15 +54,57 @@ type PacketFramingConfig struct {
 	// NowFunc is a function used to determine current time when handling socket timeout.
 	// (default: time.Now)
 	NowFunc func() time.Time
+
+	// Allocator provides the read buffers and the buffers holding fragmented packets (default: PoolAllocator()).
+	Allocator Allocator
+}
+
+// Validate checks the configuration for invalid values and nonsensical combinations of values. It returns all
+// the problems found, joined, as *ConfigError. PacketFramingHandler, Client.OnPacket, ProtocolFSM.Handler, Actors
+// and Sharded can't report errors, so they panic with the error returned by Validate when given an invalid config.
+func (c *PacketFramingConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	var errs []error
+
+	if c.ReadBufferSize < 0 {
+		errs = append(errs, &ConfigError{Field: "PacketFramingConfig.ReadBufferSize", Reason: "must not be negative"})
+	}
+	if c.MaxPacketSize < 0 {
+		errs = append(errs, &ConfigError{Field: "PacketFramingConfig.MaxPacketSize", Reason: "must not be negative"})
+	}
+	if c.MinReadSpace < 0 {
+		errs = append(errs, &ConfigError{Field: "PacketFramingConfig.MinReadSpace", Reason: "must not be negative"})
+	}
+
+	readBufferSize := c.ReadBufferSize
+	if readBufferSize <= 0 {
+		readBufferSize = mergePacketFramingConfig(nil).ReadBufferSize
+	}
+	if c.MinReadSpace > readBufferSize {
+		errs = append(errs, &ConfigError{
+			Field:  "PacketFramingConfig.MinReadSpace",
+			Reason: "must not exceed ReadBufferSize",
+		})
+	}
+
+	return errors.Join(errs...)
 }
 
 func mergePacketFramingConfig(provided *PacketFramingConfig) *PacketFramingConfig {
+	if err := provided.Validate(); err != nil {
+		panic(err)
+	}
+
 	config := &PacketFramingConfig{
 		ReadBufferSize: 4 * 1024,  // 4 KiB
 		MaxPacketSize:  16 * 1024, // 16 KiB
 		MinReadSpace:   1024,      // 1 KiB
 		OnSocketError:  func(_ *Socket, _ error) {},
 		NowFunc:        time.Now,
+		Allocator:      PoolAllocator(),
 	}
 
 	if provided == nil {
@@ -71,6 +119,8 @@ func mergePacketFramingConfig(provided *PacketFramingConfig) *PacketFramingConfi
 	}
 	if provided.MinReadSpace > 0 {
 		config.MinReadSpace = provided.MinReadSpace
+	} else if config.MinReadSpace > config.ReadBufferSize {
+		config.MinReadSpace = config.ReadBufferSize / 4
 	}
 	if provided.OnSocketError != nil {
 		config.OnSocketError = provided.OnSocketError
@@ -81,9 +131,8 @@ func mergePacketFramingConfig(provided *PacketFramingConfig) *PacketFramingConfi
 	if provided.NowFunc != nil {
 		config.NowFunc = provided.NowFunc
 	}
-
-	if config.MinReadSpace > config.ReadBufferSize {
-		config.MinReadSpace = config.ReadBufferSize / 4
+	if provided.Allocator != nil {
+		config.Allocator = provided.Allocator
 	}
 
 	return config
@@ -101,136 +150,259 @@ func PacketFramingHandler(
 	}
 	c := mergePacketFramingConfig(providedConfig)
 
-	// common buffers are pooled to avoid memory allocation in hot path
-	var (
-		readBufferPool = sync.Pool{
-			New: func() any {
-				return make([]byte, c.ReadBufferSize)
-			},
-		}
-		receiveBufferPool = sync.Pool{
-			New: func() any {
-				return &bytes.Buffer{}
-			},
-		}
-	)
+	framer := newPacketFramer(framingProtocol, c)
 
 	return func(socket *Socket) {
-		packetHandler := socketHandler(socket)
+		socket.framing = true
 
-		var (
-			// readBuffer is a fixed-size page, which is never reallocated. Socket pumps data straight into it.
-			readBuffer = readBufferPool.Get().([]byte)
+		framer.run(socket, socketHandler(socket), socket, func(err error) {
+			c.OnSocketError(socket, err)
+		})
 
-			// receiveBuffer is used to hold data between consecutive Read() calls in case a packet is fragmented.
-			receiveBuffer *bytes.Buffer
-
-			// leftOffset indicates a place in read buffer after the last, already handled packet.
-			leftOffset int
+		socket.framing = false
+		socket.runHandoff()
+	}
+}
 
-			// rightOffset indicates a place in read buffer in which the next Read() will occur.
-			rightOffset int
-		)
+// PacketFramingHandlerCtx works like PacketFramingHandler, but passes a context to the handlers. The context
+// is cancelled with ErrSocketClosed as the cause when the socket is closed, either by the client, the handler,
+// or by the server stopping, and with ErrSocketDraining when the server starts a graceful shutdown (see
+// Server.Shutdown). It allows the packets to be processed with context-aware calls (eg. database queries),
+// which are abandoned as soon as there is no one to respond to.
+func PacketFramingHandlerCtx(
+	framingProtocol FramingProtocol,
+	socketHandler func(ctx context.Context, socket *Socket) PacketHandlerCtx,
+	config ...*PacketFramingConfig,
+) SocketHandler {
+	return PacketFramingHandler(
+		framingProtocol,
+		func(socket *Socket) PacketHandler {
+			ctx, cancel := context.WithCancelCause(context.Background())
+
+			socket.OnClose(func(_ CloseReason) {
+				cancel(ErrSocketClosed)
+			})
+			if socket.IsClosed() {
+				// closed before the close handler has been registered
+				cancel(ErrSocketClosed)
+			}
+			socket.OnDrain(func() {
+				cancel(ErrSocketDraining)
+			})
 
-		defer func() {
-			readBufferPool.Put(readBuffer)
+			handler := socketHandler(ctx, socket)
 
-			if receiveBuffer != nil {
-				receiveBuffer.Reset()
-				receiveBufferPool.Put(receiveBuffer)
+			return func(packet []byte) {
+				handler(ctx, packet)
 			}
-		}()
+		},
+		config...,
+	)
+}
 
-		for {
-			// set read timeout
-			if c.ReadTimeout > 0 {
+type readDeadliner interface {
+	SetReadDeadline(deadline time.Time) error
+}
+
+// packetFramer holds the state of packet framing shared by all the connections.
+type packetFramer struct {
+	framingProtocol FramingProtocol
+	config          *PacketFramingConfig
+}
+
+func newPacketFramer(framingProtocol FramingProtocol, config *PacketFramingConfig) *packetFramer {
+	return &packetFramer{
+		framingProtocol: framingProtocol,
+		config:          config,
+	}
+}
+
+// run reads data from reader and passes extracted packets to packetHandler, until reader is closed or times out.
+// If socket is not nil, sizes of the packets and time spent by packetHandler are recorded in it.
+func (f *packetFramer) run(
+	reader io.Reader,
+	packetHandler PacketHandler,
+	socket *Socket,
+	onError func(error),
+) {
+	f.runWith(f.framingProtocol, reader, packetHandler, socket, onError)
+}
+
+// runWith works like run, but extracts packets with given FramingProtocol instead of the shared one.
+// It allows the protocol to hold per-connection state (see ProtocolFSM).
+func (f *packetFramer) runWith(
+	framingProtocol FramingProtocol,
+	reader io.Reader,
+	packetHandler PacketHandler,
+	socket *Socket,
+	onError func(error),
+) {
+	c := f.config
+
+	var (
+		// readBuffer is a fixed-size page, which is never reallocated. Reader pumps data straight into it.
+		// Common buffers are obtained from the allocator to avoid memory allocation in hot path.
+		readBuffer = c.Allocator.Allocate(c.ReadBufferSize)
+
+		// receiveBuffer is used to hold data between consecutive Read() calls in case a packet is fragmented.
+		receiveBuffer = allocatedBuffer{allocator: c.Allocator}
+
+		// leftOffset indicates a place in read buffer after the last, already handled packet.
+		leftOffset int
+
+		// rightOffset indicates a place in read buffer in which the next Read() will occur.
+		rightOffset int
+
+		// upgraded indicates that the reader has been upgraded by packetHandler (see Socket.UpgradeReader).
+		upgraded bool
+	)
+
+	defer func() {
+		c.Allocator.Free(readBuffer)
+		receiveBuffer.Free()
+	}()
+
+	for {
+		// wait if reads are paused
+		if socket != nil {
+			socket.waitForReads()
+		}
+
+		// set read timeout
+		if c.ReadTimeout > 0 {
+			if d, ok := reader.(readDeadliner); ok {
 				deadline := c.NowFunc().Add(c.ReadTimeout)
-				err := socket.SetReadDeadline(deadline)
+				err := d.SetReadDeadline(deadline)
 				if err != nil {
-					if err == io.EOF {
+					if errors.Is(err, io.EOF) {
 						break
 					}
 
-					c.OnSocketError(socket, err)
+					onError(err)
 					continue
 				}
 			}
+		}
 
-			// read
-			bytesRead, err := socket.Read(readBuffer[rightOffset:])
-			if err != nil {
-				if err == io.EOF || isTimeout(err) {
-					break
-				}
+		// read
+		bytesRead, err := reader.Read(readBuffer[rightOffset:])
+		if err != nil && bytesRead == 0 {
+			if errors.Is(err, io.EOF) || isTimeout(err) {
+				break
+			}
+
+			onError(err)
+			continue
+		}
+		// data returned together with an error is handled first, the error is returned again by the next Read()
+
+		// end indicates a place in read buffer right after the last byte read
+		end := rightOffset + bytesRead
+
+		// validate packet size
+		if c.MaxPacketSize > 0 {
+			memoryUsed := end - leftOffset + receiveBuffer.Len()
 
-				c.OnSocketError(socket, err)
+			if memoryUsed > c.MaxPacketSize {
+				// packet too big
+				receiveBuffer.Reset()
+
+				leftOffset = 0
+				rightOffset = 0
 				continue
 			}
+		}
 
-			// validate packet size
-			if c.MaxPacketSize > 0 {
-				memoryUsed := rightOffset + bytesRead - leftOffset
-				if receiveBuffer != nil {
-					memoryUsed += receiveBuffer.Len()
-				}
+		// include data from past iteration if receive buffer is not empty
+		source := readBuffer[leftOffset:end]
+		buffered := receiveBuffer.Len() > 0
+		if buffered {
+			receiveBuffer.Write(source)
+			source = receiveBuffer.Bytes()
+		}
 
-				if memoryUsed > c.MaxPacketSize {
-					// packet too big
-					if receiveBuffer != nil {
-						receiveBuffer.Reset()
-					}
+		for {
+			if socket != nil {
+				// handler might have paused reads, hold the packets already buffered as well
+				socket.waitForReads()
+			}
 
-					leftOffset = 0
-					rightOffset = 0
-					continue
-				}
+			packet, rest, extracted := framingProtocol.ExtractPacket(source)
+			if !extracted {
+				break
 			}
 
-			// include data from past iteration if receive buffer is not empty
-			source := readBuffer[leftOffset : rightOffset+bytesRead]
-			if receiveBuffer != nil && receiveBuffer.Len() > 0 {
-				receiveBuffer.Write(source)
-				source = receiveBuffer.Bytes()
-				receiveBuffer.Reset()
+			if !buffered {
+				// fast path - packet is extracted straight from the readBuffer, without memory allocations
+				leftOffset += len(source) - len(rest)
 			}
 
-			for {
-				packet, rest, extracted := framingProtocol.ExtractPacket(source)
-				if extracted {
-					// fast path - packet is extracted straight from the readBuffer, without memory allocations
-					excessBytes := len(source) - len(packet) - len(rest)
-					leftOffset += len(packet) + excessBytes
-					rightOffset += len(packet) + excessBytes
-					source = rest
-
-					packetHandler(packet)
-				} else {
-					if len(source) == 0 {
-						leftOffset = 0
-						rightOffset = 0
-						break
-					}
+			source = rest
 
-					// packet is fragmented
+			if socket != nil {
+				socket.packetSize.Observe(uint64(len(packet)))
 
-					if rightOffset+len(source) > len(readBuffer)-c.MinReadSpace {
-						// slow path - memory allocation needed
-						if receiveBuffer == nil {
-							receiveBuffer = receiveBufferPool.Get().(*bytes.Buffer)
-						}
+				start := socket.clock.Now()
+				packetHandler(packet)
+				socket.packetLatency.Observe(socket.clock.Now().Sub(start))
 
-						receiveBuffer.Write(source)
-						leftOffset = 0
-						rightOffset = 0
+				if socket.handoff != nil {
+					// the rest of the data belongs to the next handler
+					if socket.readerUpgrade != nil {
+						socket.applyReaderUpgrade(source)
 					} else {
-						// we'll still fit another Read() into read buffer
-						rightOffset += len(source)
+						socket.unread(source)
 					}
 
+					return
+				}
+
+				if socket.readerUpgrade != nil {
+					// the rest of the data needs to go through the upgraded reader
+					socket.applyReaderUpgrade(source)
+					source = nil
+					upgraded = true
 					break
 				}
+			} else {
+				packetHandler(packet)
 			}
 		}
+
+		if upgraded {
+			upgraded = false
+			receiveBuffer.Reset()
+
+			leftOffset = 0
+			rightOffset = 0
+			continue
+		}
+
+		if buffered {
+			// drop the extracted packets, but keep the fragmented one in receive buffer
+			receiveBuffer.Next(receiveBuffer.Len() - len(source))
+			leftOffset = 0
+			rightOffset = 0
+			continue
+		}
+
+		if len(source) == 0 {
+			leftOffset = 0
+			rightOffset = 0
+			continue
+		}
+
+		// packet is fragmented
+
+		if end > len(readBuffer)-c.MinReadSpace {
+			// slow path - memory allocation needed
+			receiveBuffer.Write(source)
+			leftOffset = 0
+			rightOffset = 0
+		} else {
+			// we'll still fit another Read() into read buffer
+			rightOffset = end
+		}
 	}
 }
 
@@ -292,6 +464,11 @@ func (l *lengthPrefixedFramingProtocol) ExtractPacket(buffer []byte) ([]byte, []
 		return nil, buffer, false
 	}
 
+	if packetSize < 0 {
+		// length overflowing int64, it could never be satisfied
+		return nil, buffer, false
+	}
+
 	if int64(len(buffer[prefixLength:])) >= packetSize {
 		buffer = buffer[prefixLength:]
 		return buffer[:packetSize], buffer[packetSize:], true
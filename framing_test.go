@@ -2,9 +2,13 @@ package tinytcp
 
 import (
 	"bytes"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"net"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestFramingHandlerSimple(t *testing.T) {
@@ -31,6 +35,123 @@ func TestFramingHandlerSimple(t *testing.T) {
 	assert.Equal(t, 1, receivedPackets, "received packets count must match")
 }
 
+func TestFramingHandlerCtx(t *testing.T) {
+	// given
+	in := bytes.NewBuffer(generateTestPayloadWithSeparator(128))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var (
+		handlerCtx context.Context
+		packetErrs []error
+	)
+
+	PacketFramingHandlerCtx(
+		SplitBySeparator([]byte{'\n'}),
+		func(ctx context.Context, _ *Socket) PacketHandlerCtx {
+			handlerCtx = ctx
+
+			return func(ctx context.Context, packet []byte) {
+				packetErrs = append(packetErrs, ctx.Err())
+				assert.True(t, validateTestPayload(128, packet), "packet should be valid")
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, []error{nil}, packetErrs, "context should be active while handling packets")
+	assert.NotNil(t, handlerCtx.Err(), "context should be cancelled after the socket is closed")
+	assert.Equal(t, ErrSocketClosed, context.Cause(handlerCtx), "cause should match")
+}
+
+func TestFramingHandlerCtxServerShutdown(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+
+	contexts := make(chan context.Context, 1)
+	server.ForkingStrategy(GoroutinePerConnection(PacketFramingHandlerCtx(
+		SplitBySeparator([]byte{'\n'}),
+		func(ctx context.Context, _ *Socket) PacketHandlerCtx {
+			return func(_ context.Context, _ []byte) {
+				contexts <- ctx
+			}
+		},
+	)))
+
+	go func() {
+		_ = server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("Hello\n"))
+	ctx := <-contexts
+
+	// when
+	_ = server.Stop()
+
+	// then
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("context should be cancelled on server shutdown")
+	}
+}
+
+func TestFramingHandlerCtxServerDrain(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+
+	contexts := make(chan context.Context, 1)
+	server.ForkingStrategy(GoroutinePerConnection(PacketFramingHandlerCtx(
+		SplitBySeparator([]byte{'\n'}),
+		func(ctx context.Context, _ *Socket) PacketHandlerCtx {
+			return func(_ context.Context, _ []byte) {
+				contexts <- ctx
+			}
+		},
+	)))
+
+	go func() {
+		_ = server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("Hello\n"))
+	ctx := <-contexts
+
+	// when
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	go func() {
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	// then
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, ErrSocketDraining, context.Cause(ctx), "cause should match")
+	case <-time.After(time.Second):
+		t.Fatal("context should be cancelled when the server starts draining")
+	}
+
+	_ = conn.Close()
+}
+
 func TestFramingHandlerTwoPackets(t *testing.T) {
 	// given
 	in := bytes.NewBuffer(bytes.Join(
@@ -117,6 +238,55 @@ func TestFramingHandlerTwoFragmentedPackets(t *testing.T) {
 	assert.Equal(t, 2, receivedPackets, "received packets count must match")
 }
 
+func TestPacketFramingConfigValidate(t *testing.T) {
+	// given
+	valid := []*PacketFramingConfig{
+		nil,
+		{ReadBufferSize: 512},
+		{ReadBufferSize: 768, MinReadSpace: 100},
+	}
+	invalid := []*PacketFramingConfig{
+		{ReadBufferSize: 512, MinReadSpace: 1024},
+		{MinReadSpace: 8 * 1024},
+		{MaxPacketSize: -1},
+	}
+
+	// then
+	for _, config := range valid {
+		assert.Nil(t, config.Validate(), "config should be valid")
+	}
+	for _, config := range invalid {
+		var configErr *ConfigError
+		assert.ErrorAs(t, config.Validate(), &configErr, "config should be invalid")
+	}
+}
+
+func TestPacketFramingConfigInvalidPanics(t *testing.T) {
+	// given
+	config := &PacketFramingConfig{ReadBufferSize: 512, MinReadSpace: 1024}
+	handler := func(socket *Socket) PacketHandler {
+		return func(packet []byte) {}
+	}
+
+	// then
+	assert.Panics(t, func() {
+		PacketFramingHandler(SplitBySeparator([]byte{'\n'}), handler, config)
+	}, "PacketFramingHandler should panic")
+	assert.Panics(t, func() {
+		Actors(SplitBySeparator([]byte{'\n'}), func(*Mailbox) {}, &ActorsConfig{FramingConfig: config})
+	}, "Actors should panic")
+}
+
+func TestPacketFramingConfigDefaultMinReadSpace(t *testing.T) {
+	// when
+	small := mergePacketFramingConfig(&PacketFramingConfig{ReadBufferSize: 512})
+	large := mergePacketFramingConfig(&PacketFramingConfig{ReadBufferSize: 8 * 1024})
+
+	// then
+	assert.Equal(t, 128, small.MinReadSpace, "default should be lowered for small buffers")
+	assert.Equal(t, 1024, large.MinReadSpace, "default should be used for large buffers")
+}
+
 func TestFramingHandlerDelayedWriter(t *testing.T) {
 	// given
 	in := newDelayedReader(
@@ -147,6 +317,66 @@ func TestFramingHandlerDelayedWriter(t *testing.T) {
 	assert.Equal(t, 2, receivedPackets, "received packets count must match")
 }
 
+func TestFramingHandlerPendingFragment(t *testing.T) {
+	// given
+	in := newDelayedReader(
+		bytes.NewBuffer(bytes.Join(
+			[][]byte{generateTestPayloadWithSeparator(128), generateTestPayloadWithSeparator(128)},
+			nil,
+		)),
+		100, 100, 58,
+	)
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var receivedPackets int
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(providedSocket *Socket) PacketHandler {
+			return func(packet []byte) {
+				receivedPackets++
+				assert.True(t, validateTestPayload(128, packet), "packet should be valid")
+			}
+		},
+	)(socket)
+
+	assert.Equal(t, 2, receivedPackets, "received packets count must match")
+}
+
+func TestFramingHandlerReceiveBufferFragment(t *testing.T) {
+	// given
+	packets := [][]byte{
+		bytes.Repeat([]byte{'a'}, 180),
+		bytes.Repeat([]byte{'b'}, 30),
+		bytes.Repeat([]byte{'c'}, 30),
+	}
+	in := newDelayedReader(
+		bytes.NewBuffer(append(bytes.Join(packets, []byte{'\n'}), '\n')),
+		200, 20, 23,
+	)
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var receivedPackets [][]byte
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(packet []byte) {
+				receivedPackets = append(receivedPackets, bytes.Clone(packet))
+			}
+		},
+		&PacketFramingConfig{
+			ReadBufferSize: 256,
+			MinReadSpace:   64,
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, packets, receivedPackets, "packets should match")
+}
+
 func TestFramingHandlerPacketTooBig(t *testing.T) {
 	// given
 	in := bytes.NewBuffer(generateTestPayloadWithSeparator(1024))
@@ -173,6 +403,113 @@ func TestFramingHandlerPacketTooBig(t *testing.T) {
 	assert.Equal(t, 0, receivedPackets, "received packets count must match")
 }
 
+func TestFramingHandlerUpgradeReader(t *testing.T) {
+	// given
+	upgraded := []byte("second\nthird\n")
+	for i := range upgraded {
+		upgraded[i] ^= 0x55
+	}
+
+	in := bytes.NewBuffer(append([]byte("upgrade\n"), upgraded...))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var receivedPackets []string
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(socket *Socket) PacketHandler {
+			return func(packet []byte) {
+				receivedPackets = append(receivedPackets, string(packet))
+
+				if string(packet) == "upgrade" {
+					socket.UpgradeReader(func(reader io.Reader) io.Reader {
+						return &xorReader{reader: reader, key: 0x55}
+					})
+				}
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, []string{"upgrade", "second", "third"}, receivedPackets, "buffered data should be upgraded")
+}
+
+func TestFramingHandlerHandoff(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("first\nupgrade\nraw bytes"))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var receivedPackets []string
+	var handedOff []byte
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(socket *Socket) PacketHandler {
+			return func(packet []byte) {
+				receivedPackets = append(receivedPackets, string(packet))
+
+				if string(packet) == "upgrade" {
+					socket.Handoff(func(socket *Socket) {
+						handedOff, _ = io.ReadAll(socket)
+					})
+				}
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, []string{"first", "upgrade"}, receivedPackets, "received packets must match")
+	assert.Equal(t, "raw bytes", string(handedOff), "buffered data should be handed off")
+}
+
+func TestFramingHandlerPauseReads(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("first\nsecond\n"))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var receivedPackets atomic.Int32
+	var receivedWhilePaused int32
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(socket *Socket) PacketHandler {
+			return func(packet []byte) {
+				if receivedPackets.Add(1) == 1 {
+					socket.PauseReads()
+
+					go func() {
+						time.Sleep(50 * time.Millisecond)
+						receivedWhilePaused = receivedPackets.Load()
+						socket.ResumeReads()
+					}()
+				}
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, int32(1), receivedWhilePaused, "no packets should be received while paused")
+	assert.Equal(t, int32(2), receivedPackets.Load(), "packets should be received after resume")
+	assert.False(t, socket.ReadsPaused(), "reads should be resumed")
+}
+
+type xorReader struct {
+	reader io.Reader
+	key    byte
+}
+
+func (x *xorReader) Read(b []byte) (int, error) {
+	n, err := x.reader.Read(b)
+	for i := 0; i < n; i++ {
+		b[i] ^= x.key
+	}
+
+	return n, err
+}
+
 func TestSeparatorFraming(t *testing.T) {
 	// given
 	protocol := SplitBySeparator([]byte{'\n'})
@@ -345,3 +682,29 @@ func (dr *delayedReader) Read(b []byte) (int, error) {
 	copy(b, readBuffer[:n])
 	return n, err
 }
+
+func TestFramingHandlerHandoffChained(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("upgrade\nraw bytes"))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	var handedOff []byte
+
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(socket *Socket) PacketHandler {
+			return func(_ []byte) {
+				socket.Handoff(func(socket *Socket) {
+					// outside PacketFramingHandler, the next handoff runs immediately
+					socket.Handoff(func(socket *Socket) {
+						handedOff, _ = io.ReadAll(socket)
+					})
+				})
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, "raw bytes", string(handedOff), "handed off handler should be able to hand off again")
+}
@@ -0,0 +1,99 @@
+package tinytcp
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HealthChecker is an optional interface of the Service, reporting whether the service works correctly.
+// Unhealthy service is expected to be restarted by the orchestrator (liveness probe).
+type HealthChecker interface {
+	// Healthy returns a non-nil error if the service is unhealthy.
+	Healthy() error
+}
+
+// ReadinessChecker is an optional interface of the Service, reporting whether the service is able to handle traffic.
+// Service that is not ready is expected to be temporarily excluded from load balancing (readiness probe).
+type ReadinessChecker interface {
+	// Ready returns a non-nil error if the service is not ready.
+	Ready() error
+}
+
+// CheckHealth calls Healthy() on all the services implementing HealthChecker and joins the returned errors.
+// Services not implementing HealthChecker are considered healthy.
+func CheckHealth(services ...Service) error {
+	var errs []error
+
+	for _, service := range services {
+		if checker, ok := service.(HealthChecker); ok {
+			if err := checker.Healthy(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckReadiness calls Ready() on all the services implementing ReadinessChecker and joins the returned errors.
+// Services not implementing ReadinessChecker are considered ready.
+func CheckReadiness(services ...Service) error {
+	var errs []error
+
+	for _, service := range services {
+		if checker, ok := service.(ReadinessChecker); ok {
+			if err := checker.Ready(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// HealthHandler returns http.Handler serving an aggregated health of given services (see CheckHealth).
+// Handler responds with 200 OK if all the services are healthy, or 503 Service Unavailable with the errors otherwise.
+func HealthHandler(services ...Service) http.Handler {
+	return probeHandler(func() error {
+		return CheckHealth(services...)
+	})
+}
+
+// ReadinessHandler returns http.Handler serving an aggregated readiness of given services (see CheckReadiness).
+// Handler responds with 200 OK if all the services are ready, or 503 Service Unavailable with the errors otherwise.
+func ReadinessHandler(services ...Service) http.Handler {
+	return probeHandler(func() error {
+		return CheckReadiness(services...)
+	})
+}
+
+// HealthSocketHandler returns a SocketHandler serving an aggregated health of given services over plain TCP,
+// for the environments without HTTP probes. Handler writes "ok" or "error: <errors>" line and closes the connection.
+func HealthSocketHandler(services ...Service) SocketHandler {
+	return func(socket *Socket) {
+		defer socket.Close()
+
+		if err := CheckHealth(services...); err != nil {
+			_, _ = fmt.Fprintf(socket, "error: %v\n", err)
+			return
+		}
+
+		_, _ = socket.Write([]byte("ok\n"))
+	}
+}
+
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = fmt.Fprintln(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+}
@@ -0,0 +1,84 @@
+package tinytcp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type probedTestService struct {
+	testService
+	healthErr error
+	readyErr  error
+}
+
+func (s *probedTestService) Healthy() error {
+	return s.healthErr
+}
+
+func (s *probedTestService) Ready() error {
+	return s.readyErr
+}
+
+func TestCheckHealth(t *testing.T) {
+	// given
+	healthErr := errors.New("database connection lost")
+	healthy := &probedTestService{}
+	unhealthy := &probedTestService{healthErr: healthErr}
+	plain := newTestService()
+
+	// when
+	okErr := CheckHealth(healthy, plain)
+	err := CheckHealth(healthy, unhealthy, plain)
+
+	// then
+	assert.Nil(t, okErr, "services should be healthy")
+	assert.ErrorIs(t, err, healthErr, "health error should be returned")
+}
+
+func TestReadinessHandler(t *testing.T) {
+	// given
+	service := &probedTestService{readyErr: errors.New("warming up")}
+	handler := ReadinessHandler(service)
+
+	// when
+	notReadyResponse := httptest.NewRecorder()
+	handler.ServeHTTP(notReadyResponse, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	service.readyErr = nil
+
+	readyResponse := httptest.NewRecorder()
+	handler.ServeHTTP(readyResponse, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	// then
+	assert.Equal(t, http.StatusServiceUnavailable, notReadyResponse.Code, "service should not be ready")
+	assert.Contains(t, notReadyResponse.Body.String(), "warming up", "error should be returned")
+	assert.Equal(t, http.StatusOK, readyResponse.Code, "service should be ready")
+}
+
+func TestHealthSocketHandler(t *testing.T) {
+	// given
+	service := &probedTestService{healthErr: errors.New("disk full")}
+	var outputBuffer bytes.Buffer
+	socket := MockSocket(&bytes.Buffer{}, &outputBuffer)
+
+	// when
+	HealthSocketHandler(service)(socket)
+
+	// then
+	assert.Equal(t, "error: disk full\n", outputBuffer.String(), "error should be written")
+}
+
+func TestServerReady(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0")
+
+	// when
+	err := server.Ready()
+
+	// then
+	assert.NotNil(t, err, "server should not be ready before start")
+}
@@ -0,0 +1,155 @@
+package tinytcp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const histogramSize = 12
+
+// PacketLatencyBuckets are upper bounds of the buckets used by packet latency histograms.
+var PacketLatencyBuckets = [histogramSize]time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	1<<63 - 1,
+}
+
+// ConnectionAgeBuckets are upper bounds of the buckets used by connection age and duration histograms.
+var ConnectionAgeBuckets = [histogramSize]time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	1 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	1 * time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	1<<63 - 1,
+}
+
+// PacketSizeBuckets are upper bounds of the buckets used by packet size histograms (in bytes).
+var PacketSizeBuckets = [histogramSize]uint64{
+	16,
+	64,
+	128,
+	256,
+	512,
+	1024,
+	2048,
+	4096,
+	8192,
+	16384,
+	65536,
+	1<<64 - 1,
+}
+
+// HistogramValue is a type of values that can be observed by Histogram.
+type HistogramValue interface {
+	time.Duration | uint64
+}
+
+// Histogram represents a distribution of observed values. Bounds of the buckets are defined by the producer of
+// the histogram (see PacketLatencyBuckets, ConnectionAgeBuckets and PacketSizeBuckets).
+type Histogram[T HistogramValue] struct {
+	// Counts holds a number of observations falling into each bucket (non-cumulative).
+	Counts [histogramSize]uint64
+
+	// Count is a total number of observations.
+	Count uint64
+
+	// Sum is a sum of all the observed values.
+	Sum T
+}
+
+// Mean returns an average observed value.
+func (h *Histogram[T]) Mean() T {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / T(h.Count)
+}
+
+func (h *Histogram[T]) observe(buckets *[histogramSize]T, value T) {
+	h.Counts[bucketIndex(buckets, value)]++
+	h.Count++
+	h.Sum += value
+}
+
+func (h *Histogram[T]) add(other *Histogram[T]) {
+	for i := range h.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+}
+
+func bucketIndex[T HistogramValue](buckets *[histogramSize]T, value T) int {
+	for i, bound := range buckets {
+		if value <= bound {
+			return i
+		}
+	}
+
+	return histogramSize - 1
+}
+
+// histogramRecorder collects observations of a single socket without locking.
+type histogramRecorder[T HistogramValue] struct {
+	buckets *[histogramSize]T
+	current [histogramSize]uint64
+	sum     uint64
+	total   Histogram[T]
+	m       sync.Mutex
+}
+
+func (r *histogramRecorder[T]) Observe(value T) {
+	atomic.AddUint64(&r.current[bucketIndex(r.buckets, value)], 1)
+	atomic.AddUint64(&r.sum, uint64(value))
+}
+
+// Update moves observations collected since the last call into the total histogram, and returns them.
+// It's expected to be called only from the housekeeping job.
+func (r *histogramRecorder[T]) Update() Histogram[T] {
+	var delta Histogram[T]
+
+	for i := range r.current {
+		n := atomic.SwapUint64(&r.current[i], 0)
+		delta.Counts[i] = n
+		delta.Count += n
+	}
+	delta.Sum = T(atomic.SwapUint64(&r.sum, 0))
+
+	r.m.Lock()
+	r.total.add(&delta)
+	r.m.Unlock()
+
+	return delta
+}
+
+func (r *histogramRecorder[T]) Total() Histogram[T] {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.total
+}
+
+func (r *histogramRecorder[T]) reset() {
+	r.current = [histogramSize]uint64{}
+	r.sum = 0
+	r.total = Histogram[T]{}
+	r.m = sync.Mutex{}
+}
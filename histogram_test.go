@@ -0,0 +1,73 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestHistogramRecorder(t *testing.T) {
+	// given
+	recorder := histogramRecorder[time.Duration]{buckets: &PacketLatencyBuckets}
+
+	// when
+	recorder.Observe(5 * time.Microsecond)
+	recorder.Observe(2 * time.Millisecond)
+	recorder.Observe(time.Hour)
+	delta := recorder.Update()
+
+	// then
+	assert.Equal(t, uint64(3), delta.Count, "count should match")
+	assert.Equal(t, uint64(1), delta.Counts[0], "first bucket should match")
+	assert.Equal(t, uint64(1), delta.Counts[5], "5ms bucket should match")
+	assert.Equal(t, uint64(1), delta.Counts[histogramSize-1], "last bucket should match")
+	assert.Equal(t, delta, recorder.Total(), "total should match")
+	assert.Equal(t, uint64(0), recorder.Update().Count, "observations should be consumed")
+}
+
+func TestFramingHandlerPacketLatency(t *testing.T) {
+	// given
+	in := bytes.NewBuffer(bytes.Join(
+		[][]byte{generateTestPayloadWithSeparator(128), generateTestPayloadWithSeparator(128)},
+		nil,
+	))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(_ []byte) {}
+		},
+	)(socket)
+	latency := socket.packetLatency.Update()
+
+	// then
+	assert.Equal(t, uint64(2), latency.Count, "packets should be observed")
+	assert.Equal(t, uint64(2), socket.PacketLatency().Count, "socket histogram should match")
+}
+
+func TestFramingHandlerPacketSize(t *testing.T) {
+	// given
+	in := bytes.NewBuffer(bytes.Join(
+		[][]byte{generateTestPayloadWithSeparator(100), generateTestPayloadWithSeparator(1000)},
+		nil,
+	))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	PacketFramingHandler(
+		SplitBySeparator([]byte{'\n'}),
+		func(_ *Socket) PacketHandler {
+			return func(_ []byte) {}
+		},
+	)(socket)
+	size := socket.packetSize.Update()
+
+	// then
+	assert.Equal(t, uint64(2), size.Count, "packets should be observed")
+	assert.Equal(t, uint64(1), size.Counts[2], "128B bucket should match")
+	assert.Equal(t, uint64(1), size.Counts[5], "1KiB bucket should match")
+}
@@ -2,26 +2,44 @@ package tinytcp
 
 import (
 	"fmt"
-	"io"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// housekeepingJob runs fn periodically. Runs are scheduled relative to the start time, rather than to the end
+// of the previous run, so the schedule doesn't drift under load. Runs missed because of an overrun are skipped.
+// fn receives the actual time elapsed since its previous run, which should be used instead of the nominal interval
+// when calculating rates.
 type housekeepingJob struct {
-	fn           func()
+	clock        Clock
+	fn           func(elapsed time.Duration)
 	panicHandler func(error)
 	interval     time.Duration
+	jitter       time.Duration
 
-	ticker  *time.Ticker
-	m       sync.Mutex
-	running bool
+	stopChannel chan struct{}
+	m           sync.Mutex
+	running     bool
+
+	lastDuration int64
+	overruns     uint64
 }
 
-func newHousekeepingJob(interval time.Duration, fn func(), panicHandler func(error)) *housekeepingJob {
+func newHousekeepingJob(
+	clock Clock,
+	interval time.Duration,
+	jitter time.Duration,
+	fn func(elapsed time.Duration),
+	panicHandler func(error),
+) *housekeepingJob {
 	return &housekeepingJob{
+		clock:        clock,
 		fn:           fn,
 		panicHandler: panicHandler,
 		interval:     interval,
+		jitter:       jitter,
 	}
 }
 
@@ -33,44 +51,106 @@ func (h *housekeepingJob) Start() {
 		return
 	}
 	h.running = true
+	h.stopChannel = make(chan struct{})
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				h.panicHandler(fmt.Errorf("%v", r))
-			}
-		}()
+	go h.run(h.stopChannel)
+}
 
-		h.ticker = time.NewTicker(h.interval)
+func (h *housekeepingJob) Stop() {
+	h.m.Lock()
+	defer h.m.Unlock()
 
-		for range h.ticker.C {
-			err := func() error {
-				h.m.Lock()
-				defer h.m.Unlock()
+	if !h.running {
+		return
+	}
+	h.running = false
 
-				if !h.running {
-					return io.EOF
-				}
+	close(h.stopChannel)
+}
 
-				h.fn()
-				return nil
-			}()
+// LastDuration returns the duration of the last completed run.
+func (h *housekeepingJob) LastDuration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&h.lastDuration))
+}
+
+// Overruns returns a number of runs that took longer than the interval.
+func (h *housekeepingJob) Overruns() uint64 {
+	return atomic.LoadUint64(&h.overruns)
+}
 
-			if err != nil {
-				break
-			}
+func (h *housekeepingJob) run(stopChannel chan struct{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			h.panicHandler(fmt.Errorf("%v", r))
 		}
 	}()
+
+	var (
+		start   = h.clock.Now()
+		lastRun = start
+		n       int64
+		timer   = h.clock.NewTimer(h.interval)
+	)
+	defer timer.Stop()
+
+	for {
+		n++
+
+		if n > 1 {
+			timer.Reset(h.delay(start.Add(time.Duration(n) * h.interval)))
+		}
+
+		select {
+		case <-stopChannel:
+			return
+		case <-timer.C():
+		}
+
+		now := h.clock.Now()
+		elapsed := now.Sub(lastRun)
+		lastRun = now
+
+		if !h.tick(stopChannel, elapsed) {
+			return
+		}
+
+		took := h.clock.Now().Sub(now)
+		atomic.StoreInt64(&h.lastDuration, int64(took))
+
+		if took > h.interval {
+			atomic.AddUint64(&h.overruns, 1)
+		}
+
+		// skip the runs that should have already happened
+		if due := int64(h.clock.Now().Sub(start) / h.interval); due > n {
+			n = due
+		}
+	}
 }
 
-func (h *housekeepingJob) Stop() {
+// tick calls fn, unless the job has been stopped. The lock is released with defer, as the panic handler
+// is likely to call Stop() (eg. through Server.Abort).
+func (h *housekeepingJob) tick(stopChannel chan struct{}, elapsed time.Duration) bool {
 	h.m.Lock()
 	defer h.m.Unlock()
 
-	if !h.running {
-		return
+	if !h.running || h.stopChannel != stopChannel {
+		return false
+	}
+
+	h.fn(elapsed)
+	return true
+}
+
+func (h *housekeepingJob) delay(scheduled time.Time) time.Duration {
+	delay := scheduled.Sub(h.clock.Now())
+	if h.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(h.jitter)))
+	}
+
+	if delay < 0 {
+		return 0
 	}
-	h.running = false
 
-	h.ticker.Stop()
+	return delay
 }
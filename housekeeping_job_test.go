@@ -0,0 +1,80 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHousekeepingJobOverrun(t *testing.T) {
+	// given
+	var (
+		elapsed []time.Duration
+		m       sync.Mutex
+		done    = make(chan struct{})
+	)
+
+	job := newHousekeepingJob(SystemClock(), 10*time.Millisecond, 0, func(e time.Duration) {
+		m.Lock()
+		defer m.Unlock()
+
+		elapsed = append(elapsed, e)
+
+		switch len(elapsed) {
+		case 1:
+			time.Sleep(25 * time.Millisecond)
+		case 3:
+			close(done)
+		}
+	}, func(_ error) {})
+
+	// when
+	job.Start()
+	<-done
+	job.Stop()
+
+	// then
+	m.Lock()
+	defer m.Unlock()
+
+	assert.Equal(t, uint64(1), job.Overruns(), "overrun should be reported")
+	assert.GreaterOrEqual(t, elapsed[1], 20*time.Millisecond, "actual elapsed time should be passed after overrun")
+}
+
+func TestHousekeepingJobJitter(t *testing.T) {
+	// given
+	job := newHousekeepingJob(SystemClock(), time.Second, 100*time.Millisecond, func(_ time.Duration) {}, func(_ error) {})
+	scheduled := time.Now().Add(time.Second)
+
+	// when
+	delay := job.delay(scheduled)
+
+	// then
+	assert.Greater(t, delay, 800*time.Millisecond, "delay should not be shorter than scheduled")
+	assert.Less(t, delay, 1100*time.Millisecond, "delay should not exceed the jitter")
+}
+
+func TestHousekeepingJobPanic(t *testing.T) {
+	// given
+	var job *housekeepingJob
+	panics := make(chan error, 1)
+
+	job = newHousekeepingJob(SystemClock(), 10*time.Millisecond, 0, func(_ time.Duration) {
+		panic("tick failed")
+	}, func(err error) {
+		job.Stop()
+		panics <- err
+	})
+
+	// when
+	job.Start()
+
+	// then
+	select {
+	case err := <-panics:
+		assert.EqualError(t, err, "tick failed", "panic should be passed to the handler")
+	case <-time.After(time.Second):
+		t.Fatal("panic handler should be able to stop the job")
+	}
+}
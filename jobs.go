@@ -0,0 +1,83 @@
+package tinytcp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// JobConfig holds an optional configuration for Server.RegisterJob.
+type JobConfig struct {
+	// Jitter is a maximal random delay added to each run of the job. It helps to spread the load when many
+	// servers run the same job with the same interval (default: 0).
+	Jitter time.Duration
+
+	// OnPanic is a handler called when the job panics. Job keeps running after the panic (default: no-op).
+	OnPanic func(error)
+}
+
+func mergeJobConfig(provided *JobConfig) *JobConfig {
+	config := &JobConfig{
+		OnPanic: func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Jitter > 0 {
+		config.Jitter = provided.Jitter
+	}
+	if provided.OnPanic != nil {
+		config.OnPanic = provided.OnPanic
+	}
+
+	return config
+}
+
+// RegisterJob registers a function to be called periodically while the server is running.
+// Jobs are started together with the server (or immediately if the server is already running),
+// and stopped when the server stops. Runs of the job never overlap, and a run that panics doesn't stop the job.
+// Name must be unique within the server.
+func (s *Server) RegisterJob(name string, interval time.Duration, fn func(), config ...*JobConfig) error {
+	var providedConfig *JobConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeJobConfig(providedConfig)
+
+	if interval <= 0 {
+		return errors.New("job interval must be positive")
+	}
+
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	if _, ok := s.jobs[name]; ok {
+		return errors.New("job already registered: " + name)
+	}
+
+	job := newHousekeepingJob(
+		s.config.Clock,
+		interval,
+		c.Jitter,
+		func(_ time.Duration) {
+			defer func() {
+				if r := recover(); r != nil {
+					c.OnPanic(fmt.Errorf("job %s: %v", name, r))
+				}
+			}()
+
+			fn()
+		},
+		c.OnPanic,
+	)
+
+	s.jobs[name] = job
+
+	if s.State() == ServerRunning {
+		job.Start()
+	}
+
+	return nil
+}
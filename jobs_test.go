@@ -0,0 +1,50 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegisterJob(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0")
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	var (
+		runs   int32
+		panics int32
+	)
+
+	err := server.RegisterJob("test", 5*time.Millisecond, func() {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			panic("first run")
+		}
+	}, &JobConfig{
+		OnPanic: func(_ error) {
+			atomic.AddInt32(&panics, 1)
+		},
+	})
+	assert.Nil(t, err, "err should be nil")
+
+	duplicateErr := server.RegisterJob("test", time.Second, func() {})
+
+	// when
+	go func() {
+		_ = server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) >= 3
+	}, time.Second, time.Millisecond, "job should keep running after panic")
+
+	_ = server.Stop()
+	runsAfterStop := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+
+	// then
+	assert.NotNil(t, duplicateErr, "duplicateErr should not be nil")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&panics), "panic should be reported")
+	assert.Equal(t, runsAfterStop, atomic.LoadInt32(&runs), "job should be stopped with the server")
+}
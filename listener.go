@@ -1,10 +1,16 @@
 package tinytcp
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"io"
 	"net"
+	"os"
 	"sync"
+	"syscall"
+	"time"
 )
 
 // Listener represents a low-level interface used by server to manage its interface.
@@ -16,39 +22,21 @@ type Listener interface {
 }
 
 type netListener struct {
-	address  string
-	config   *ServerConfig
-	listener net.Listener
-	m        sync.RWMutex
+	listenFunc func() (net.Listener, error)
+	listener   net.Listener
+	m          sync.RWMutex
 }
 
 func (l *netListener) Listen() error {
 	l.m.Lock()
 	defer l.m.Unlock()
 
-	if l.config.TLSCert != "" && l.config.TLSKey != "" {
-		cert, err := tls.LoadX509KeyPair(l.config.TLSCert, l.config.TLSKey)
-		if err != nil {
-			return err
-		}
-
-		l.config.TLSConfig.Certificates = []tls.Certificate{cert}
-
-		socket, err := tls.Listen(l.config.Network, l.address, l.config.TLSConfig)
-		if err != nil {
-			return err
-		}
-
-		l.listener = socket
-	} else {
-		socket, err := net.Listen(l.config.Network, l.address)
-		if err != nil {
-			return err
-		}
-
-		l.listener = socket
+	socket, err := l.listenFunc()
+	if err != nil {
+		return err
 	}
 
+	l.listener = socket
 	return nil
 }
 
@@ -101,9 +89,320 @@ func (l *netListener) Close() error {
 	return nil
 }
 
+// ListenRetryConfig holds a configuration of retrying failed Listen() calls (see ServerConfig.ListenRetry).
+// Only the transient failures are retried (EADDRINUSE and EADDRNOTAVAIL), other errors are returned immediately.
+type ListenRetryConfig struct {
+	// Attempts is a maximal number of Listen() attempts, including the first one (default: 5).
+	Attempts int
+
+	// Backoff is a delay before the first retry. Delay is doubled after each failed attempt (default: 100ms).
+	Backoff time.Duration
+}
+
+func mergeListenRetryConfig(provided *ListenRetryConfig) *ListenRetryConfig {
+	config := &ListenRetryConfig{
+		Attempts: 5,
+		Backoff:  100 * time.Millisecond,
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Attempts > 0 {
+		config.Attempts = provided.Attempts
+	}
+	if provided.Backoff > 0 {
+		config.Backoff = provided.Backoff
+	}
+
+	return config
+}
+
+// listenWithRetry calls Listen() on the listener, retrying the transient failures according to config.
+func listenWithRetry(listener Listener, config *ListenRetryConfig) error {
+	err := listener.Listen()
+	if config == nil {
+		return err
+	}
+
+	delay := config.Backoff
+
+	for attempt := 1; attempt < config.Attempts && isTransientListenError(err); attempt++ {
+		time.Sleep(delay)
+		delay *= 2
+
+		err = listener.Listen()
+	}
+
+	return err
+}
+
+func isTransientListenError(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE) || errors.Is(err, syscall.EADDRNOTAVAIL)
+}
+
+// ListenerFromNetListener wraps an already created net.Listener, so it can be used by Server.
+// It allows accepting connections from sources like in-memory listeners or userspace network stacks.
+// The listener is closed together with the server and cannot be reused afterwards.
+func ListenerFromNetListener(listener net.Listener) Listener {
+	return &netListener{
+		listenFunc: func() (net.Listener, error) {
+			return listener, nil
+		},
+	}
+}
+
 func newListener(address string, config *ServerConfig) Listener {
 	return &netListener{
-		address: address,
-		config:  config,
+		listenFunc: func() (net.Listener, error) {
+			if len(config.AdditionalAddresses) > 0 {
+				return listenMultiple(append([]string{address}, config.AdditionalAddresses...), config)
+			}
+
+			return listen(address, config)
+		},
+	}
+}
+
+func listenMultiple(addresses []string, config *ServerConfig) (net.Listener, error) {
+	listeners := make([]net.Listener, 0, len(addresses))
+
+	for _, address := range addresses {
+		listener, err := listen(address, config)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return nil, err
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return newMultiListener(listeners), nil
+}
+
+// multiListener merges connections accepted by multiple listeners into a single stream.
+// Accept errors of the underlying listeners are passed to Accept(), except for the listeners being closed.
+type multiListener struct {
+	listeners []net.Listener
+	accepted  chan acceptResult
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+const (
+	multiListenerMinBackoff = 5 * time.Millisecond
+	multiListenerMaxBackoff = time.Second
+)
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	l := &multiListener{
+		listeners: listeners,
+		accepted:  make(chan acceptResult),
+		closed:    make(chan struct{}),
 	}
+
+	for _, listener := range listeners {
+		go l.acceptLoop(listener)
+	}
+
+	return l
+}
+
+func (l *multiListener) acceptLoop(listener net.Listener) {
+	var backoff time.Duration
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil && isBrokenPipe(err) {
+			return
+		}
+
+		select {
+		case l.accepted <- acceptResult{conn: conn, err: err}:
+		case <-l.closed:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+
+		if err == nil {
+			backoff = 0
+			continue
+		}
+
+		// errors such as EMFILE are likely to be returned again right away, so the loop backs off instead of spinning
+		if backoff == 0 {
+			backoff = multiListenerMinBackoff
+		} else if backoff *= 2; backoff > multiListenerMaxBackoff {
+			backoff = multiListenerMaxBackoff
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-l.closed:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (l *multiListener) Accept() (net.Conn, error) {
+	select {
+	case result := <-l.accepted:
+		return result.conn, result.err
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *multiListener) Addr() net.Addr {
+	return l.listeners[0].Addr()
+}
+
+func (l *multiListener) Close() error {
+	var errs []error
+
+	l.closeOnce.Do(func() {
+		close(l.closed)
+
+		for _, listener := range l.listeners {
+			if err := listener.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+func listen(address string, config *ServerConfig) (net.Listener, error) {
+	var tlsEnabled bool
+
+	cert, err := loadTLSCertificate(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert != nil || config.TLSGetConfigForClient != nil {
+		if cert != nil {
+			config.TLSConfig.Certificates = []tls.Certificate{*cert}
+		}
+		if config.TLSGetConfigForClient != nil {
+			config.TLSConfig.GetConfigForClient = config.TLSGetConfigForClient
+		}
+
+		if config.TLSClientCAs != "" {
+			pool, err := loadCertPool(config.TLSClientCAs)
+			if err != nil {
+				return nil, err
+			}
+
+			config.TLSConfig.ClientCAs = pool
+		}
+		if config.TLSClientAuth != tls.NoClientCert {
+			config.TLSConfig.ClientAuth = config.TLSClientAuth
+		}
+
+		tlsEnabled = true
+	}
+
+	socket, err := listenNetwork(address, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsEnabled {
+		if config.TLSAutoDetect {
+			return &autoDetectListener{
+				Listener: socket,
+				config:   config.TLSConfig,
+			}, nil
+		}
+
+		return tls.NewListener(socket, config.TLSConfig), nil
+	}
+
+	return socket, nil
+}
+
+func listenNetwork(address string, config *ServerConfig) (net.Listener, error) {
+	if isUnixNetwork(config.Network) {
+		if err := removeStaleUnixSocket(config.Network, address); err != nil {
+			return nil, err
+		}
+	}
+
+	listenConfig := &net.ListenConfig{
+		Control: config.ListenControl,
+	}
+
+	network := config.Network
+	if config.IPv6Only && network == "tcp" {
+		// Go sets IPV6_V6ONLY for the wildcard addresses of tcp6 network
+		network = "tcp6"
+	}
+
+	socket, err := listenConfig.Listen(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	if isUnixNetwork(config.Network) {
+		if err := setupUnixSocket(address, config); err != nil {
+			_ = socket.Close()
+			return nil, err
+		}
+	}
+
+	return socket, nil
+}
+
+// loadTLSCertificate returns the certificate configured by either TLSCert and TLSKey, TLSCertPEM and TLSKeyPEM
+// or TLSCertificate, or nil if none is configured.
+func loadTLSCertificate(config *ServerConfig) (*tls.Certificate, error) {
+	switch {
+	case config.TLSCertificate != nil:
+		return config.TLSCertificate, nil
+	case len(config.TLSCertPEM) > 0 && len(config.TLSKeyPEM) > 0:
+		cert, err := tls.X509KeyPair(config.TLSCertPEM, config.TLSKeyPEM)
+		if err != nil {
+			return nil, err
+		}
+
+		return &cert, nil
+	case config.TLSCert != "" && config.TLSKey != "":
+		cert, err := tls.LoadX509KeyPair(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &cert, nil
+	}
+
+	return nil, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no valid certificates found in " + path)
+	}
+
+	return pool, nil
 }
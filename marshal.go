@@ -0,0 +1,447 @@
+package tinytcp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MarshalPacket encodes given struct (or pointer to struct) into binary form, according to its layout.
+// Exported fields are encoded one after another, in order of declaration. Encoding of each field can be adjusted using
+// `tinytcp` struct tag, which holds a comma-separated list of options:
+//
+//   - "-" skips the field.
+//   - "be" or "le" sets the byte order of fixed-size numbers (default: "be").
+//   - "varint", "varlong" or "zigzag" encode integers as VarInt, VarLong or zigzag-encoded VarLong.
+//   - "prefix=<type>" sets a prefix of strings, byte slices and slices, one of: "varint", "varlong", "int16_be",
+//     "int16_le", "int32_be", "int32_le", "int64_be", "int64_le" (default: "varint").
+//   - "cstring" encodes string as NUL-terminated.
+//   - "unit=<unit>" sets the unit of time.Time fields, one of: "s", "ms", "us", "ns" (default: "ms").
+//
+// Supported types are bool, all sized integers, float32, float64, string, time.Time, net.IP (16 bytes),
+// slices and arrays of supported types ([N]byte is encoded raw) and nested structs. int and uint are encoded as 64-bit.
+// Options of slice or array field apply to its elements as well.
+func MarshalPacket(v any) ([]byte, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, errors.New("value must be a struct or a pointer to struct")
+	}
+
+	writer := NewPacketWriter()
+	if err := encodeStruct(writer, value); err != nil {
+		return nil, err
+	}
+
+	return writer.Bytes(), writer.Err()
+}
+
+// UnmarshalPacket decodes packet into the struct pointed by v (see MarshalPacket for the description of layout).
+// Returns io.ErrUnexpectedEOF if the packet is too short.
+func UnmarshalPacket(data []byte, v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("value must be a non-nil pointer to struct")
+	}
+
+	reader := NewPacketReader(data)
+	if err := decodeStruct(reader, value.Elem()); err != nil {
+		return err
+	}
+
+	return reader.Err()
+}
+
+type fieldLayout struct {
+	index   int
+	options fieldOptions
+}
+
+type fieldOptions struct {
+	order    binary.ByteOrder
+	encoding string
+	prefix   PrefixType
+	cstring  bool
+	unit     time.Duration
+}
+
+var (
+	structLayouts sync.Map
+
+	timeType = reflect.TypeOf(time.Time{})
+	ipType   = reflect.TypeOf(net.IP{})
+)
+
+func resolveStructLayout(t reflect.Type) ([]fieldLayout, error) {
+	if layout, ok := structLayouts.Load(t); ok {
+		return layout.([]fieldLayout), nil
+	}
+
+	var layout []fieldLayout
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("tinytcp")
+		if tag == "-" {
+			continue
+		}
+
+		options, err := parseFieldOptions(tag)
+		if err != nil {
+			return nil, errors.New("field " + field.Name + ": " + err.Error())
+		}
+
+		layout = append(layout, fieldLayout{index: i, options: options})
+	}
+
+	structLayouts.Store(t, layout)
+	return layout, nil
+}
+
+func parseFieldOptions(tag string) (fieldOptions, error) {
+	options := fieldOptions{
+		order:  binary.BigEndian,
+		prefix: PrefixVarInt,
+		unit:   time.Millisecond,
+	}
+
+	if tag == "" {
+		return options, nil
+	}
+
+	for _, option := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+
+		switch key {
+		case "be":
+			options.order = binary.BigEndian
+		case "le":
+			options.order = binary.LittleEndian
+		case "varint", "varlong", "zigzag":
+			options.encoding = key
+		case "cstring":
+			options.cstring = true
+		case "prefix":
+			prefix, ok := prefixTypesByName[value]
+			if !ok {
+				return options, errors.New("invalid prefix: " + value)
+			}
+			options.prefix = prefix
+		case "unit":
+			unit, ok := timeUnitsByName[value]
+			if !ok {
+				return options, errors.New("invalid unit: " + value)
+			}
+			options.unit = unit
+		default:
+			return options, errors.New("invalid option: " + option)
+		}
+	}
+
+	return options, nil
+}
+
+var prefixTypesByName = map[string]PrefixType{
+	"varint":   PrefixVarInt,
+	"varlong":  PrefixVarLong,
+	"int16_be": PrefixInt16_BE,
+	"int16_le": PrefixInt16_LE,
+	"int32_be": PrefixInt32_BE,
+	"int32_le": PrefixInt32_LE,
+	"int64_be": PrefixInt64_BE,
+	"int64_le": PrefixInt64_LE,
+}
+
+var timeUnitsByName = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+func encodeStruct(writer *PacketWriter, value reflect.Value) error {
+	layout, err := resolveStructLayout(value.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range layout {
+		if err := encodeValue(writer, value.Field(field.index), &field.options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeValue(writer *PacketWriter, value reflect.Value, options *fieldOptions) error {
+	switch value.Type() {
+	case timeType:
+		writer.Time(value.Interface().(time.Time), options.unit, options.order)
+		return nil
+	case ipType:
+		writer.IP(value.Interface().(net.IP))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		writer.Bool(value.Bool())
+	case reflect.Int8:
+		writer.Byte(byte(value.Int()))
+	case reflect.Uint8:
+		writer.Byte(byte(value.Uint()))
+	case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return encodeInt(writer, value, options)
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return encodeUint(writer, value, options)
+	case reflect.Float32:
+		writer.Float32(float32(value.Float()), options.order)
+	case reflect.Float64:
+		writer.Float64(value.Float(), options.order)
+	case reflect.String:
+		if options.cstring {
+			writer.CString(value.String())
+		} else {
+			writer.PrefixedString(value.String(), options.prefix)
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			writer.PrefixedByteArray(value.Bytes(), options.prefix)
+			return nil
+		}
+
+		if err := writeLength(writer, value.Len(), options.prefix); err != nil {
+			return err
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			if err := encodeValue(writer, value.Index(i), options); err != nil {
+				return err
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := encodeValue(writer, value.Index(i), options); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return encodeStruct(writer, value)
+	default:
+		return errors.New("unsupported type: " + value.Type().String())
+	}
+
+	return nil
+}
+
+func encodeInt(writer *PacketWriter, value reflect.Value, options *fieldOptions) error {
+	v := value.Int()
+
+	switch options.encoding {
+	case "varint":
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return errors.New("value does not fit into VarInt")
+		}
+		// negative values are encoded on 5 bytes, as their 32-bit two's complement
+		writer.VarInt(int(uint32(int32(v))))
+	case "varlong":
+		writer.check(writeUvarint(writer, uint64(v)))
+	case "zigzag":
+		writer.SVarLong(v)
+	default:
+		switch value.Kind() {
+		case reflect.Int16:
+			writer.Int16(int16(v), options.order)
+		case reflect.Int32:
+			writer.Int32(int32(v), options.order)
+		default:
+			writer.Int64(v, options.order)
+		}
+	}
+
+	return nil
+}
+
+func encodeUint(writer *PacketWriter, value reflect.Value, options *fieldOptions) error {
+	v := value.Uint()
+
+	switch options.encoding {
+	case "varint":
+		if v > math.MaxUint32 {
+			return errors.New("value does not fit into VarInt")
+		}
+		writer.VarInt(int(v))
+	case "varlong":
+		writer.check(writeUvarint(writer, v))
+	case "zigzag":
+		return errors.New("zigzag encoding is not supported for unsigned integers")
+	default:
+		switch value.Kind() {
+		case reflect.Uint16:
+			writer.Uint16(uint16(v), options.order)
+		case reflect.Uint32:
+			writer.Uint32(uint32(v), options.order)
+		default:
+			writer.Uint64(v, options.order)
+		}
+	}
+
+	return nil
+}
+
+func decodeStruct(reader *PacketReader, value reflect.Value) error {
+	layout, err := resolveStructLayout(value.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range layout {
+		if err := decodeValue(reader, value.Field(field.index), &field.options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeValue(reader *PacketReader, value reflect.Value, options *fieldOptions) error {
+	switch value.Type() {
+	case timeType:
+		value.Set(reflect.ValueOf(reader.Time(options.unit, options.order)))
+		return nil
+	case ipType:
+		value.Set(reflect.ValueOf(reader.IP()))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		value.SetBool(reader.Bool())
+	case reflect.Int8:
+		value.SetInt(int64(int8(reader.Byte())))
+	case reflect.Uint8:
+		value.SetUint(uint64(reader.Byte()))
+	case reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		return decodeInt(reader, value, options)
+	case reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		return decodeUint(reader, value, options)
+	case reflect.Float32:
+		value.SetFloat(float64(reader.Float32(options.order)))
+	case reflect.Float64:
+		value.SetFloat(reader.Float64(options.order))
+	case reflect.String:
+		if options.cstring {
+			value.SetString(reader.CString())
+		} else {
+			value.SetString(reader.PrefixedString(options.prefix))
+		}
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			b := reader.PrefixedByteArray(options.prefix)
+			value.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+
+		length := readPacketValue(reader, func(r io.Reader) (int64, error) {
+			return readLength(r, options.prefix)
+		})
+		if reader.Err() != nil {
+			return nil
+		}
+		// every element takes at least one byte, so the length can't exceed the remaining space
+		if length < 0 || length > int64(reader.Remaining()) {
+			return ErrLengthExceeded
+		}
+
+		slice := reflect.MakeSlice(value.Type(), int(length), int(length))
+		for i := 0; i < int(length); i++ {
+			if err := decodeValue(reader, slice.Index(i), options); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	case reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := decodeValue(reader, value.Index(i), options); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		return decodeStruct(reader, value)
+	default:
+		return errors.New("unsupported type: " + value.Type().String())
+	}
+
+	return nil
+}
+
+func decodeInt(reader *PacketReader, value reflect.Value, options *fieldOptions) error {
+	var v int64
+
+	switch options.encoding {
+	case "varint":
+		v = int64(int32(reader.VarInt()))
+	case "varlong":
+		v = reader.VarLong()
+	case "zigzag":
+		v = reader.SVarLong()
+	default:
+		switch value.Kind() {
+		case reflect.Int16:
+			v = int64(reader.Int16(options.order))
+		case reflect.Int32:
+			v = int64(reader.Int32(options.order))
+		default:
+			v = reader.Int64(options.order)
+		}
+	}
+
+	if value.OverflowInt(v) {
+		return errors.New("value overflows " + value.Type().String())
+	}
+
+	value.SetInt(v)
+	return nil
+}
+
+func decodeUint(reader *PacketReader, value reflect.Value, options *fieldOptions) error {
+	var v uint64
+
+	switch options.encoding {
+	case "varint":
+		v = uint64(uint32(reader.VarInt()))
+	case "varlong":
+		v = uint64(reader.VarLong())
+	case "zigzag":
+		return errors.New("zigzag encoding is not supported for unsigned integers")
+	default:
+		switch value.Kind() {
+		case reflect.Uint16:
+			v = uint64(reader.Uint16(options.order))
+		case reflect.Uint32:
+			v = uint64(reader.Uint32(options.order))
+		default:
+			v = reader.Uint64(options.order)
+		}
+	}
+
+	if value.OverflowUint(v) {
+		return errors.New("value overflows " + value.Type().String())
+	}
+
+	value.SetUint(v)
+	return nil
+}
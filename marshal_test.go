@@ -0,0 +1,87 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type testPacketHeader struct {
+	Version uint8
+	Flags   uint16 `tinytcp:"le"`
+}
+
+type testPacket struct {
+	Header    testPacketHeader
+	ID        int32  `tinytcp:"varint"`
+	Offset    int64  `tinytcp:"zigzag"`
+	Name      string `tinytcp:"prefix=int16_be"`
+	Comment   string `tinytcp:"cstring"`
+	Payload   []byte
+	Scores    []uint32 `tinytcp:"prefix=int16_be,le"`
+	Checksum  [4]byte
+	CreatedAt time.Time `tinytcp:"unit=s"`
+	Address   net.IP
+	Ignored   string `tinytcp:"-"`
+	internal  int
+}
+
+func TestMarshalPacket(t *testing.T) {
+	// given
+	packet := testPacket{
+		Header:    testPacketHeader{Version: 2, Flags: 0x0102},
+		ID:        -1,
+		Offset:    -300,
+		Name:      "Hello",
+		Comment:   "world",
+		Payload:   []byte{1, 2, 3},
+		Scores:    []uint32{10, 20},
+		Checksum:  [4]byte{0xDE, 0xAD, 0xBE, 0xEF},
+		CreatedAt: time.Date(2023, 7, 14, 12, 30, 15, 0, time.UTC),
+		Address:   net.ParseIP("10.0.0.1"),
+		Ignored:   "ignored",
+		internal:  1,
+	}
+
+	// when
+	data, marshalErr := MarshalPacket(&packet)
+
+	var decoded testPacket
+	unmarshalErr := UnmarshalPacket(data, &decoded)
+
+	// then
+	assert.Nil(t, marshalErr, "marshal err should be nil")
+	assert.Nil(t, unmarshalErr, "unmarshal err should be nil")
+	assert.Equal(t, []byte{0x02, 0x02, 0x01}, data[:3], "header should be encoded first")
+
+	packet.Ignored = ""
+	packet.internal = 0
+	assert.Equal(t, packet, decoded, "packets should match")
+}
+
+func TestUnmarshalPacketTooShort(t *testing.T) {
+	// given
+	data, _ := MarshalPacket(testPacketHeader{Version: 1, Flags: 1})
+
+	// when
+	var decoded testPacket
+	err := UnmarshalPacket(data, &decoded)
+
+	// then
+	assert.Equal(t, io.ErrUnexpectedEOF, err, "err should be io.ErrUnexpectedEOF")
+}
+
+func TestMarshalPacketInvalidTag(t *testing.T) {
+	// given
+	packet := struct {
+		Value int32 `tinytcp:"prefix=int128"`
+	}{}
+
+	// when
+	_, err := MarshalPacket(packet)
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
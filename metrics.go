@@ -2,6 +2,7 @@ package tinytcp
 
 import (
 	"io"
+	"math"
 	"sync/atomic"
 	"time"
 )
@@ -20,28 +21,181 @@ type ServerMetrics struct {
 	// WrittenLastSecond is total number of bytes written by the server last second.
 	WrittenLastSecond uint64
 
+	// ReadRate is a rate of bytes read by the server, averaged over different time windows.
+	ReadRate Rate
+
+	// WriteRate is a rate of bytes written by the server, averaged over different time windows.
+	WriteRate Rate
+
 	// Connections is a total number of active connections during the last second.
 	Connections int
 
+	// PeakConnections is the highest number of connections active at once since the server creation.
+	// Unlike Connections, it's maintained on the accept path, so it includes short-lived bursts between the updates.
+	PeakConnections int
+
 	// Goroutines is a total number of active goroutines during the last second.
 	Goroutines int
+
+	// Workers is a number of worker goroutines maintained by the ForkingStrategy (if it uses any).
+	Workers int
+
+	// BusyWorkers is a number of worker goroutines that were processing a task during the metrics update.
+	BusyWorkers int
+
+	// QueuedTasks is a number of tasks waiting in the queues of the ForkingStrategy (if it uses any).
+	// For Actors, it's a number of packets waiting in the Inboxes and Outboxes.
+	QueuedTasks int
+
+	// TaskLatency is a distribution of time the tasks spent waiting in the queues of the ForkingStrategy,
+	// since the server start. Buckets are defined by PacketLatencyBuckets.
+	TaskLatency Histogram[time.Duration]
+
+	// HousekeepingDuration is a duration of the last completed housekeeping job run.
+	HousekeepingDuration time.Duration
+
+	// HousekeepingOverruns is a total number of housekeeping job runs that took longer than TickInterval.
+	// Frequent overruns mean the server is overloaded, or TickInterval is too short for the number of connections.
+	HousekeepingOverruns uint64
+
+	// TotalAccepted is a total number of connections accepted by the server.
+	TotalAccepted uint64
+
+	// TotalRejected is a total number of connections rejected by the server, due to MaxClients limit
+	// or by the AdmissionController.
+	TotalRejected uint64
+
+	// TotalTarpitted is a total number of connections put into the tarpit (see TarpitConfig).
+	TotalTarpitted uint64
+
+	// Tarpitted is a number of connections currently held in the tarpit.
+	Tarpitted int
+
+	// AcceptQueueLength is a number of connections waiting in the accept queue (see ServerConfig.AcceptQueueSize).
+	AcceptQueueLength int
+
+	// TotalAcceptQueueDropped is a total number of connections closed because the accept queue was full.
+	TotalAcceptQueueDropped uint64
+
+	// TotalClosedByServer is a total number of connections closed with CloseReasonServer or CloseReasonAbort.
+	TotalClosedByServer uint64
+
+	// TotalClosedByClient is a total number of connections closed with CloseReasonClient.
+	TotalClosedByClient uint64
+
+	// PacketLatency is a distribution of time spent by packet handlers on processing packets since the server start.
+	// Buckets are defined by PacketLatencyBuckets.
+	PacketLatency Histogram[time.Duration]
+
+	// PacketSize is a distribution of sizes of packets received since the server start.
+	// Buckets are defined by PacketSizeBuckets.
+	PacketSize Histogram[uint64]
+
+	// ConnectionAge is a distribution of ages of the currently active connections.
+	// Buckets are defined by ConnectionAgeBuckets.
+	ConnectionAge Histogram[time.Duration]
+
+	// AverageConnectionDuration is an average lifetime of the already closed connections.
+	AverageConnectionDuration time.Duration
+
+	// ConnectionDuration is a distribution of lifetimes of the already closed connections.
+	// Buckets are defined by ConnectionAgeBuckets.
+	ConnectionDuration Histogram[time.Duration]
+
+	// ReadyLatency is a distribution of time elapsed between accepting the connections and the moment they became
+	// ready to transfer application data (see Socket.ReadyLatency), since the server start.
+	// Buckets are defined by PacketLatencyBuckets.
+	ReadyLatency Histogram[time.Duration]
+
+	// HandshakeDuration is a distribution of durations of TLS handshakes since the server start.
+	// Comparing it with ReadyLatency allows to tell the handshake slowness apart from the application latency.
+	// Buckets are defined by PacketLatencyBuckets.
+	HandshakeDuration Histogram[time.Duration]
+}
+
+// Rate holds a transfer rate (in bytes per second) averaged over different time windows.
+// Averages over 10 seconds and 1 minute are exponentially weighted moving averages,
+// so they're smoothed, but still reflect the recent changes quicker than a plain sliding window.
+type Rate struct {
+	// LastSecond is a rate measured during the last second.
+	LastSecond uint64
+
+	// TenSeconds is a rate averaged over the last 10 seconds.
+	TenSeconds uint64
+
+	// Minute is a rate averaged over the last minute.
+	Minute uint64
+}
+
+// rateWindows calculates exponentially weighted moving averages of a transfer rate.
+type rateWindows struct {
+	tenSeconds uint64 // float64 bits
+	minute     uint64 // float64 bits
+}
+
+func (w *rateWindows) Update(rate float64, interval time.Duration) {
+	updateEWMA(&w.tenSeconds, rate, interval, 10*time.Second)
+	updateEWMA(&w.minute, rate, interval, 1*time.Minute)
+}
+
+func (w *rateWindows) Rate(lastSecond uint64) Rate {
+	return Rate{
+		LastSecond: lastSecond,
+		TenSeconds: uint64(math.Float64frombits(atomic.LoadUint64(&w.tenSeconds))),
+		Minute:     uint64(math.Float64frombits(atomic.LoadUint64(&w.minute))),
+	}
+}
+
+func (w *rateWindows) reset() {
+	w.tenSeconds = 0
+	w.minute = 0
+}
+
+func updateEWMA(value *uint64, rate float64, interval time.Duration, window time.Duration) {
+	alpha := 1 - math.Exp(-interval.Seconds()/window.Seconds())
+	previous := math.Float64frombits(atomic.LoadUint64(value))
+	atomic.StoreUint64(value, math.Float64bits(previous+alpha*(rate-previous)))
+}
+
+// socketMetricsDelta holds metrics collected from a single socket during the last housekeeping interval.
+type socketMetricsDelta struct {
+	reads         uint64
+	writes        uint64
+	packetLatency Histogram[time.Duration]
+	packetSize    Histogram[uint64]
 }
 
 type meteredReader struct {
 	reader  io.Reader
+	hooks   []func(n int)
 	total   uint64
 	current uint64
 	rate    uint64
+	windows rateWindows
+
+	// shared is the shard of the server-wide counter assigned to the socket (see shardedCounter)
+	shared *uint64
 }
 
 func (r *meteredReader) Read(b []byte) (int, error) {
 	n, err := r.reader.Read(b)
+	r.add(n)
+
+	return n, err
+}
 
+// add accounts the bytes read bypassing Read(), eg. spliced by Pipe.
+func (r *meteredReader) add(n int) {
 	if n > 0 {
 		atomic.AddUint64(&r.current, uint64(n))
-	}
+		if r.shared != nil {
+			atomic.AddUint64(r.shared, uint64(n))
+		}
 
-	return n, err
+		for _, hook := range r.hooks {
+			hook(n)
+		}
+	}
 }
 
 func (r *meteredReader) Total() uint64 {
@@ -52,10 +206,16 @@ func (r *meteredReader) PerSecond() uint64 {
 	return atomic.LoadUint64(&r.rate)
 }
 
+func (r *meteredReader) Rate() Rate {
+	return r.windows.Rate(r.PerSecond())
+}
+
 func (r *meteredReader) Update(interval time.Duration) uint64 {
 	current := atomic.SwapUint64(&r.current, 0)
+	rate := float64(current) / interval.Seconds()
 
-	atomic.StoreUint64(&r.rate, uint64(float64(current)/interval.Seconds()))
+	atomic.StoreUint64(&r.rate, uint64(rate))
+	r.windows.Update(rate, interval)
 	atomic.AddUint64(&r.total, current)
 
 	return current
@@ -63,28 +223,60 @@ func (r *meteredReader) Update(interval time.Duration) uint64 {
 
 func (r *meteredReader) reset() {
 	r.reader = nil
+	r.hooks = nil
 	r.total = 0
 	r.current = 0
+	r.shared = nil
 	r.rate = 0
+	r.windows.reset()
 }
 
 type meteredWriter struct {
 	writer  io.Writer
+	hooks   []func(n int)
 	total   uint64
 	current uint64
 	rate    uint64
+	windows rateWindows
+
+	// shared is the shard of the server-wide counter assigned to the socket (see shardedCounter)
+	shared *uint64
 }
 
 func (w *meteredWriter) Write(b []byte) (int, error) {
 	n, err := w.writer.Write(b)
+	w.add(n)
 
-	if n > 0 {
-		atomic.AddUint64(&w.current, uint64(n))
+	return n, err
+}
+
+// ReadFrom lets the underlying writer transfer the data on its own if it supports io.ReaderFrom (eg. *net.TCPConn
+// using sendfile or splice). The bytes are accounted at once.
+func (w *meteredWriter) ReadFrom(r io.Reader) (int64, error) {
+	readerFrom, ok := w.writer.(io.ReaderFrom)
+	if !ok {
+		return io.Copy(writerOnly{w}, r)
 	}
 
+	n, err := readerFrom.ReadFrom(r)
+	w.add(int(n))
+
 	return n, err
 }
 
+func (w *meteredWriter) add(n int) {
+	if n > 0 {
+		atomic.AddUint64(&w.current, uint64(n))
+		if w.shared != nil {
+			atomic.AddUint64(w.shared, uint64(n))
+		}
+
+		for _, hook := range w.hooks {
+			hook(n)
+		}
+	}
+}
+
 func (w *meteredWriter) Total() uint64 {
 	return atomic.LoadUint64(&w.total)
 }
@@ -93,10 +285,16 @@ func (w *meteredWriter) PerSecond() uint64 {
 	return atomic.LoadUint64(&w.rate)
 }
 
+func (w *meteredWriter) Rate() Rate {
+	return w.windows.Rate(w.PerSecond())
+}
+
 func (w *meteredWriter) Update(interval time.Duration) uint64 {
 	current := atomic.SwapUint64(&w.current, 0)
+	rate := float64(current) / interval.Seconds()
 
-	atomic.StoreUint64(&w.rate, uint64(float64(current)/interval.Seconds()))
+	atomic.StoreUint64(&w.rate, uint64(rate))
+	w.windows.Update(rate, interval)
 	atomic.AddUint64(&w.total, current)
 
 	return current
@@ -104,7 +302,10 @@ func (w *meteredWriter) Update(interval time.Duration) uint64 {
 
 func (w *meteredWriter) reset() {
 	w.writer = nil
+	w.hooks = nil
 	w.total = 0
 	w.current = 0
+	w.shared = nil
 	w.rate = 0
+	w.windows.reset()
 }
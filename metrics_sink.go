@@ -0,0 +1,154 @@
+package tinytcp
+
+import (
+	"expvar"
+	"time"
+)
+
+// Names of the metrics reported to MetricsSink.
+const (
+	// MetricConnectionsAccepted is a counter incremented for each accepted connection.
+	MetricConnectionsAccepted = "connections_accepted"
+
+	// MetricConnectionsRejected is a counter incremented for each connection rejected due to MaxClients limit
+	// or by the AdmissionController.
+	MetricConnectionsRejected = "connections_rejected"
+
+	// MetricConnectionsTarpitted is a counter incremented for each connection put into the tarpit.
+	MetricConnectionsTarpitted = "connections_tarpitted"
+
+	// MetricAcceptQueueDropped is a counter incremented for each connection closed because the accept queue was full.
+	MetricAcceptQueueDropped = "accept_queue_dropped"
+
+	// MetricConnectionsClosedByServer is a counter incremented for each connection closed with CloseReasonServer
+	// or CloseReasonAbort.
+	MetricConnectionsClosedByServer = "connections_closed_server"
+
+	// MetricConnectionsClosedByClient is a counter incremented for each connection closed with CloseReasonClient.
+	MetricConnectionsClosedByClient = "connections_closed_client"
+
+	// MetricConnectionDuration is a histogram of lifetimes of the closed connections, in seconds.
+	MetricConnectionDuration = "connection_duration_seconds"
+
+	// MetricReadyLatency is a histogram of time elapsed between accepting the connections and the moment they became
+	// ready to transfer application data, in seconds.
+	MetricReadyLatency = "connection_ready_latency_seconds"
+
+	// MetricHandshakeDuration is a histogram of durations of TLS handshakes, in seconds.
+	MetricHandshakeDuration = "tls_handshake_duration_seconds"
+
+	// MetricAcceptErrors is a counter incremented for each error returned by the listener, other than the closed one.
+	MetricAcceptErrors = "accept_errors"
+
+	// MetricBytesRead is a counter of bytes read by the server, incremented on each metrics update.
+	MetricBytesRead = "read_bytes"
+
+	// MetricBytesWritten is a counter of bytes written by the server, incremented on each metrics update.
+	MetricBytesWritten = "written_bytes"
+
+	// MetricConnections is a gauge of active connections, set on each metrics update.
+	MetricConnections = "connections"
+
+	// MetricGoroutines is a gauge of active goroutines, set on each metrics update.
+	MetricGoroutines = "goroutines"
+
+	// MetricAcceptQueueLength is a gauge of connections waiting in the accept queue, set on each metrics update.
+	MetricAcceptQueueLength = "accept_queue_length"
+
+	// MetricWorkers is a gauge of worker goroutines maintained by the ForkingStrategy, set on each metrics update.
+	MetricWorkers = "strategy_workers"
+
+	// MetricQueuedTasks is a gauge of tasks waiting in the queues of the ForkingStrategy, set on each metrics update.
+	MetricQueuedTasks = "strategy_queued_tasks"
+)
+
+// MetricsSink receives metrics of the server as they're produced. Unlike OnMetricsUpdate, which is called with
+// an aggregated snapshot once per TickInterval, events like accepted connections or accept errors are reported
+// to the sink right away. Metrics are identified by the names defined as Metric* constants.
+// Sink is called concurrently from multiple goroutines, including the accept loop, so it needs to be fast.
+type MetricsSink interface {
+	// Counter increments the counter with given name by delta.
+	Counter(name string, delta uint64)
+
+	// Gauge sets the current value of the gauge with given name.
+	Gauge(name string, value float64)
+
+	// Histogram records a single observation in the histogram with given name.
+	Histogram(name string, value float64)
+}
+
+type noopMetricsSink struct {
+}
+
+func (noopMetricsSink) Counter(_ string, _ uint64) {
+}
+
+func (noopMetricsSink) Gauge(_ string, _ float64) {
+}
+
+func (noopMetricsSink) Histogram(_ string, _ float64) {
+}
+
+// NewExpvarMetricsSink creates a MetricsSink publishing metrics as an expvar.Map with given name.
+// Histograms are represented by the count and the sum of observations, published as <name>_count and <name>_sum.
+// Name must be unique within the process, as expvar panics when a variable is published twice.
+func NewExpvarMetricsSink(name string) MetricsSink {
+	return &expvarMetricsSink{
+		vars: expvar.NewMap(name),
+	}
+}
+
+type expvarMetricsSink struct {
+	vars *expvar.Map
+}
+
+func (e *expvarMetricsSink) Counter(name string, delta uint64) {
+	e.vars.Add(name, int64(delta))
+}
+
+func (e *expvarMetricsSink) Gauge(name string, value float64) {
+	gauge, ok := e.vars.Get(name).(*expvar.Float)
+	if !ok {
+		gauge = new(expvar.Float)
+		e.vars.Set(name, gauge)
+	}
+
+	gauge.Set(value)
+}
+
+func (e *expvarMetricsSink) Histogram(name string, value float64) {
+	e.vars.Add(name+"_count", 1)
+	e.vars.AddFloat(name+"_sum", value)
+}
+
+// reportMetrics reports the aggregated metrics to the sink. It's called by the housekeeping job.
+func reportMetrics(sink MetricsSink, metrics *ServerMetrics, readsPerInterval, writesPerInterval uint64) {
+	sink.Counter(MetricBytesRead, readsPerInterval)
+	sink.Counter(MetricBytesWritten, writesPerInterval)
+	sink.Gauge(MetricConnections, float64(metrics.Connections))
+	sink.Gauge(MetricGoroutines, float64(metrics.Goroutines))
+	sink.Gauge(MetricAcceptQueueLength, float64(metrics.AcceptQueueLength))
+	sink.Gauge(MetricWorkers, float64(metrics.Workers))
+	sink.Gauge(MetricQueuedTasks, float64(metrics.QueuedTasks))
+}
+
+// reportClosedSocket reports the closed connection to the sink. It's called by the housekeeping job.
+func reportClosedSocket(sink MetricsSink, reason CloseReason, duration time.Duration) {
+	switch reason {
+	case CloseReasonServer, CloseReasonAbort:
+		sink.Counter(MetricConnectionsClosedByServer, 1)
+	case CloseReasonClient:
+		sink.Counter(MetricConnectionsClosedByClient, 1)
+	}
+
+	sink.Histogram(MetricConnectionDuration, duration.Seconds())
+}
+
+// reportReadySocket reports the latencies of the socket that became ready to the sink.
+// It's called by the housekeeping job.
+func reportReadySocket(sink MetricsSink, readyLatency, handshakeDuration time.Duration) {
+	sink.Histogram(MetricReadyLatency, readyLatency.Seconds())
+	if handshakeDuration > 0 {
+		sink.Histogram(MetricHandshakeDuration, handshakeDuration.Seconds())
+	}
+}
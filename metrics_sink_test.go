@@ -0,0 +1,84 @@
+package tinytcp
+
+import (
+	"expvar"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerMetricsSink(t *testing.T) {
+	// given
+	sink := &recordingMetricsSink{counters: map[string]uint64{}}
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1, TickInterval: 10 * time.Millisecond})
+	server.MetricsSink(sink)
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_ = socket.Close()
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	// then
+	assert.Eventually(t, func() bool {
+		return sink.counter(MetricConnectionsAccepted) == 1 && sink.counter(MetricConnectionsClosedByServer) == 1
+	}, time.Second, time.Millisecond, "accepted and closed connections should be reported")
+}
+
+func TestExpvarMetricsSink(t *testing.T) {
+	// given
+	name := "tinytcp_test_" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	sink := NewExpvarMetricsSink(name)
+
+	// when
+	sink.Counter(MetricConnectionsAccepted, 2)
+	sink.Gauge(MetricConnections, 3)
+	sink.Histogram(MetricConnectionDuration, 1.5)
+
+	// then
+	vars := expvar.Get(name).(*expvar.Map)
+	assert.Equal(t, "2", vars.Get(MetricConnectionsAccepted).String(), "counter should match")
+	assert.Equal(t, "3", vars.Get(MetricConnections).String(), "gauge should match")
+	assert.Equal(t, "1", vars.Get(MetricConnectionDuration+"_count").String(), "histogram count should match")
+	assert.Equal(t, "1.5", vars.Get(MetricConnectionDuration+"_sum").String(), "histogram sum should match")
+}
+
+type recordingMetricsSink struct {
+	counters map[string]uint64
+	m        sync.Mutex
+}
+
+func (r *recordingMetricsSink) Counter(name string, delta uint64) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.counters[name] += delta
+}
+
+func (r *recordingMetricsSink) Gauge(_ string, _ float64) {
+}
+
+func (r *recordingMetricsSink) Histogram(_ string, _ float64) {
+}
+
+func (r *recordingMetricsSink) counter(name string) uint64 {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return r.counters[name]
+}
@@ -0,0 +1,56 @@
+package tinytcp
+
+import (
+	"sync"
+)
+
+// metricsStreams holds the subscribers of Server.MetricsStream().
+type metricsStreams struct {
+	subscribers []chan ServerMetrics
+	m           sync.Mutex
+}
+
+func (s *metricsStreams) subscribe() (<-chan ServerMetrics, func()) {
+	ch := make(chan ServerMetrics, 1)
+
+	s.m.Lock()
+	s.subscribers = append(s.subscribers, ch)
+	s.m.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			s.m.Lock()
+			defer s.m.Unlock()
+
+			for i, subscriber := range s.subscribers {
+				if subscriber == ch {
+					s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+					break
+				}
+			}
+
+			close(ch)
+		})
+	}
+}
+
+// publish sends metrics to all the subscribers without blocking. Stale values not yet received are replaced.
+// It's expected to be called only from the housekeeping job.
+func (s *metricsStreams) publish(metrics ServerMetrics) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for _, ch := range s.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+
+		select {
+		case ch <- metrics:
+		default:
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMetricsStream(t *testing.T) {
+	// given
+	var streams metricsStreams
+	ch, cancel := streams.subscribe()
+
+	// when
+	streams.publish(ServerMetrics{Connections: 1})
+	streams.publish(ServerMetrics{Connections: 2})
+	received := <-ch
+
+	cancel()
+	_, open := <-ch
+
+	// then
+	assert.Equal(t, 2, received.Connections, "only the latest value should be received")
+	assert.False(t, open, "channel should be closed after cancelling")
+	assert.Empty(t, streams.subscribers, "subscriber should be removed")
+}
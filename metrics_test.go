@@ -0,0 +1,30 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMeteredReaderRate(t *testing.T) {
+	// given
+	reader := &meteredReader{}
+
+	// when
+	for i := 0; i < 60; i++ {
+		reader.current = 1000
+		reader.Update(time.Second)
+	}
+	steady := reader.Rate()
+
+	reader.Update(time.Second)
+	idle := reader.Rate()
+
+	// then
+	assert.Equal(t, uint64(1000), steady.LastSecond, "last second rate should match")
+	assert.InDelta(t, 1000, steady.TenSeconds, 10, "10s average should converge")
+	assert.InDelta(t, 632, steady.Minute, 10, "1m average should follow the EWMA curve")
+
+	assert.Equal(t, uint64(0), idle.LastSecond, "last second rate should drop immediately")
+	assert.Greater(t, idle.TenSeconds, uint64(800), "10s average should decay smoothly")
+}
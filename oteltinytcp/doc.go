@@ -0,0 +1,5 @@
+/*
+Package oteltinytcp provides OpenTelemetry integration for tinytcp - server metrics exported as OTel instruments,
+and spans created per connection and per packet.
+*/
+package oteltinytcp
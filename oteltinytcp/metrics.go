@@ -0,0 +1,173 @@
+package oteltinytcp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mkorman9/tinytcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const instrumentationName = "github.com/mkorman9/tinytcp/oteltinytcp"
+
+// MetricsConfig specifies an optional config for NewMetricsHandler.
+type MetricsConfig struct {
+	// MeterProvider is a provider used to create instruments (default: otel.GetMeterProvider()).
+	MeterProvider metric.MeterProvider
+
+	// Prefix is prepended to the names of all the instruments (default: "tinytcp.").
+	Prefix string
+
+	// Attributes are attached to all the observations.
+	Attributes []attribute.KeyValue
+}
+
+func mergeMetricsConfig(provided *MetricsConfig) *MetricsConfig {
+	config := &MetricsConfig{
+		MeterProvider: otel.GetMeterProvider(),
+		Prefix:        "tinytcp.",
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.MeterProvider != nil {
+		config.MeterProvider = provided.MeterProvider
+	}
+	if provided.Prefix != "" {
+		config.Prefix = provided.Prefix
+	}
+	if provided.Attributes != nil {
+		config.Attributes = provided.Attributes
+	}
+
+	return config
+}
+
+// NewMetricsHandler creates a metrics handler for tinytcp.Server. It can be registered using OnMetricsUpdate method.
+// Created handler exposes server metrics as asynchronous OTel instruments, observed in the state from the last update.
+// Histograms are not exported, as OTel does not support asynchronous histograms.
+func NewMetricsHandler(config ...*MetricsConfig) (func(metrics tinytcp.ServerMetrics), error) {
+	var providedConfig *MetricsConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeMetricsConfig(providedConfig)
+
+	meter := c.MeterProvider.Meter(instrumentationName)
+
+	var (
+		latest tinytcp.ServerMetrics
+		m      sync.RWMutex
+	)
+
+	readBytes, err := meter.Int64ObservableCounter(
+		c.Prefix+"read",
+		metric.WithDescription("Total number of bytes read by the server."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	writtenBytes, err := meter.Int64ObservableCounter(
+		c.Prefix+"written",
+		metric.WithDescription("Total number of bytes written by the server."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	connections, err := meter.Int64ObservableUpDownCounter(
+		c.Prefix+"connections",
+		metric.WithDescription("Number of active connections."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	goroutines, err := meter.Int64ObservableUpDownCounter(
+		c.Prefix+"goroutines",
+		metric.WithDescription("Number of active goroutines."),
+		metric.WithUnit("{goroutine}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted, err := meter.Int64ObservableCounter(
+		c.Prefix+"connections.accepted",
+		metric.WithDescription("Total number of connections accepted by the server."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, err := meter.Int64ObservableCounter(
+		c.Prefix+"connections.rejected",
+		metric.WithDescription("Total number of connections rejected by the server due to the connections limit."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := meter.Int64ObservableCounter(
+		c.Prefix+"connections.closed",
+		metric.WithDescription("Total number of closed connections, by the side that closed them."),
+		metric.WithUnit("{connection}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		common       = metric.WithAttributes(c.Attributes...)
+		closedServer = withReason("server", c.Attributes)
+		closedClient = withReason("client", c.Attributes)
+	)
+
+	_, err = meter.RegisterCallback(
+		func(_ context.Context, o metric.Observer) error {
+			m.RLock()
+			metrics := latest
+			m.RUnlock()
+
+			o.ObserveInt64(readBytes, int64(metrics.TotalRead), common)
+			o.ObserveInt64(writtenBytes, int64(metrics.TotalWritten), common)
+			o.ObserveInt64(connections, int64(metrics.Connections), common)
+			o.ObserveInt64(goroutines, int64(metrics.Goroutines), common)
+			o.ObserveInt64(accepted, int64(metrics.TotalAccepted), common)
+			o.ObserveInt64(rejected, int64(metrics.TotalRejected), common)
+			o.ObserveInt64(closed, int64(metrics.TotalClosedByServer), closedServer)
+			o.ObserveInt64(closed, int64(metrics.TotalClosedByClient), closedClient)
+			return nil
+		},
+		readBytes,
+		writtenBytes,
+		connections,
+		goroutines,
+		accepted,
+		rejected,
+		closed,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(metrics tinytcp.ServerMetrics) {
+		m.Lock()
+		latest = metrics
+		m.Unlock()
+	}, nil
+}
+
+func withReason(reason string, attributes []attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(append([]attribute.KeyValue{attribute.String("reason", reason)}, attributes...)...)
+}
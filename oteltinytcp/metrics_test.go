@@ -0,0 +1,42 @@
+package oteltinytcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	// given
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	handler, err := NewMetricsHandler(&MetricsConfig{MeterProvider: provider})
+	assert.Nil(t, err, "err should be nil")
+
+	// when
+	handler(tinytcp.ServerMetrics{
+		TotalRead:   1024,
+		Connections: 2,
+	})
+
+	var data metricdata.ResourceMetrics
+	err = reader.Collect(context.Background(), &data)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	values := make(map[string]int64)
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		if sum, ok := m.Data.(metricdata.Sum[int64]); ok && len(sum.DataPoints) == 1 {
+			values[m.Name] = sum.DataPoints[0].Value
+		}
+	}
+
+	assert.Equal(t, int64(1024), values["tinytcp.read"], "bytes read should match")
+	assert.Equal(t, int64(2), values["tinytcp.connections"], "connections should match")
+}
@@ -0,0 +1,115 @@
+package oteltinytcp
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mkorman9/tinytcp"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type sink struct {
+	meter      metric.Meter
+	prefix     string
+	attributes metric.MeasurementOption
+
+	counters   map[string]metric.Int64Counter
+	gauges     map[string]*uint64 // float64 bits
+	histograms map[string]metric.Float64Histogram
+	m          sync.RWMutex
+}
+
+// NewMetricsSink creates a tinytcp.MetricsSink recording the reported metrics as OTel instruments.
+// It can be registered using Server.MetricsSink method. Instruments are created lazily, the first time a metric
+// is reported. Counters and histograms are synchronous instruments, gauges are observed asynchronously
+// in the state from the last report. Metrics that fail to create an instrument are dropped.
+func NewMetricsSink(config ...*MetricsConfig) tinytcp.MetricsSink {
+	var providedConfig *MetricsConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeMetricsConfig(providedConfig)
+
+	return &sink{
+		meter:      c.MeterProvider.Meter(instrumentationName),
+		prefix:     c.Prefix,
+		attributes: metric.WithAttributes(c.Attributes...),
+		counters:   make(map[string]metric.Int64Counter),
+		gauges:     make(map[string]*uint64),
+		histograms: make(map[string]metric.Float64Histogram),
+	}
+}
+
+func (s *sink) Counter(name string, delta uint64) {
+	counter := getOrCreate(s, s.counters, name, func() (metric.Int64Counter, error) {
+		return s.meter.Int64Counter(s.prefix + name)
+	})
+	if counter == nil {
+		return
+	}
+
+	counter.Add(context.Background(), int64(delta), s.attributes)
+}
+
+func (s *sink) Gauge(name string, value float64) {
+	gauge := getOrCreate(s, s.gauges, name, func() (*uint64, error) {
+		var bits uint64
+
+		_, err := s.meter.Float64ObservableGauge(
+			s.prefix+name,
+			metric.WithFloat64Callback(func(_ context.Context, o metric.Float64Observer) error {
+				o.Observe(math.Float64frombits(atomic.LoadUint64(&bits)), s.attributes)
+				return nil
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		return &bits, nil
+	})
+	if gauge == nil {
+		return
+	}
+
+	atomic.StoreUint64(gauge, math.Float64bits(value))
+}
+
+func (s *sink) Histogram(name string, value float64) {
+	histogram := getOrCreate(s, s.histograms, name, func() (metric.Float64Histogram, error) {
+		return s.meter.Float64Histogram(s.prefix + name)
+	})
+	if histogram == nil {
+		return
+	}
+
+	histogram.Record(context.Background(), value, s.attributes)
+}
+
+func getOrCreate[T comparable](s *sink, instruments map[string]T, name string, create func() (T, error)) T {
+	s.m.RLock()
+	instrument, ok := instruments[name]
+	s.m.RUnlock()
+
+	if ok {
+		return instrument
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if instrument, ok = instruments[name]; ok {
+		return instrument
+	}
+
+	instrument, err := create()
+	if err != nil {
+		var zero T
+		return zero
+	}
+
+	instruments[name] = instrument
+	return instrument
+}
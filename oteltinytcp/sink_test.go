@@ -0,0 +1,47 @@
+package oteltinytcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestMetricsSink(t *testing.T) {
+	// given
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	sink := NewMetricsSink(&MetricsConfig{MeterProvider: provider})
+
+	// when
+	sink.Counter(tinytcp.MetricConnectionsAccepted, 2)
+	sink.Gauge(tinytcp.MetricConnections, 3)
+	sink.Histogram(tinytcp.MetricConnectionDuration, 0.5)
+
+	var data metricdata.ResourceMetrics
+	err := reader.Collect(context.Background(), &data)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	found := make(map[string]bool)
+	for _, m := range data.ScopeMetrics[0].Metrics {
+		switch d := m.Data.(type) {
+		case metricdata.Sum[int64]:
+			assert.Equal(t, int64(2), d.DataPoints[0].Value, "counter should match")
+		case metricdata.Gauge[float64]:
+			assert.Equal(t, 3.0, d.DataPoints[0].Value, "gauge should match")
+		case metricdata.Histogram[float64]:
+			assert.Equal(t, uint64(1), d.DataPoints[0].Count, "histogram should match")
+		}
+
+		found[m.Name] = true
+	}
+
+	assert.True(t, found["tinytcp.connections_accepted"], "counter should be exported")
+	assert.True(t, found["tinytcp.connections"], "gauge should be exported")
+	assert.True(t, found["tinytcp.connection_duration_seconds"], "histogram should be exported")
+}
@@ -0,0 +1,183 @@
+package oteltinytcp
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+
+	"github.com/mkorman9/tinytcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingConfig specifies an optional config for TraceConnections and TracePacketFraming.
+type TracingConfig struct {
+	// TracerProvider is a provider used to create spans (default: otel.GetTracerProvider()).
+	TracerProvider trace.TracerProvider
+
+	// TracePackets enables creating a child span for every packet handled by TracePacketFraming (default: false).
+	TracePackets bool
+
+	// Attributes are attached to all the spans.
+	Attributes []attribute.KeyValue
+}
+
+func mergeTracingConfig(provided *TracingConfig) *TracingConfig {
+	config := &TracingConfig{
+		TracerProvider: otel.GetTracerProvider(),
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.TracerProvider != nil {
+		config.TracerProvider = provided.TracerProvider
+	}
+	if provided.TracePackets {
+		config.TracePackets = provided.TracePackets
+	}
+	if provided.Attributes != nil {
+		config.Attributes = provided.Attributes
+	}
+
+	return config
+}
+
+// TraceConnections wraps given SocketHandler, so each connection is represented by a span.
+// Span starts when the handler is called and ends when it returns.
+// Remote address and numbers of bytes read and written are recorded as span attributes.
+func TraceConnections(handler tinytcp.SocketHandler, config ...*TracingConfig) tinytcp.SocketHandler {
+	var providedConfig *TracingConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeTracingConfig(providedConfig)
+
+	tracer := c.TracerProvider.Tracer(instrumentationName)
+
+	return func(socket *tinytcp.Socket) {
+		_, end := startConnectionSpan(tracer, socket, c, false)
+		defer end()
+
+		handler(socket)
+	}
+}
+
+// TracePacketFraming wraps a handler passed to tinytcp.PacketFramingHandler, so each connection is represented by
+// a span, ending when the socket is closed. If TracePackets is enabled, each packet is represented by a child span.
+func TracePacketFraming(
+	socketHandler func(socket *tinytcp.Socket) tinytcp.PacketHandler,
+	config ...*TracingConfig,
+) func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+	var providedConfig *TracingConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeTracingConfig(providedConfig)
+
+	tracer := c.TracerProvider.Tracer(instrumentationName)
+
+	return func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+		ctx, _ := startConnectionSpan(tracer, socket, c, true)
+
+		packetHandler := socketHandler(socket)
+		if !c.TracePackets {
+			return packetHandler
+		}
+
+		return func(packet []byte) {
+			_, span := tracer.Start(
+				ctx,
+				"tinytcp.packet",
+				trace.WithAttributes(attribute.Int("tinytcp.packet.size", len(packet))),
+			)
+			defer span.End()
+
+			packetHandler(packet)
+		}
+	}
+}
+
+func startConnectionSpan(
+	tracer trace.Tracer,
+	socket *tinytcp.Socket,
+	config *TracingConfig,
+	endOnClose bool,
+) (context.Context, func()) {
+	ctx, span := tracer.Start(
+		context.Background(),
+		"tinytcp.connection",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("net.sock.peer.addr", socket.RemoteAddress())),
+		trace.WithAttributes(config.Attributes...),
+	)
+
+	var (
+		reader = &countingReader{}
+		writer = &countingWriter{}
+		ended  uint32
+
+		// 0 - not closed yet, otherwise tinytcp.CloseReason + 1
+		closeReason uint32
+	)
+
+	socket.WrapReader(func(r io.Reader) io.Reader {
+		reader.reader = r
+		return reader
+	})
+	socket.WrapWriter(func(w io.Writer) io.Writer {
+		writer.writer = w
+		return writer
+	})
+
+	end := func() {
+		if !atomic.CompareAndSwapUint32(&ended, 0, 1) {
+			return
+		}
+
+		span.SetAttributes(
+			attribute.Int64("tinytcp.bytes_read", int64(atomic.LoadUint64(&reader.n))),
+			attribute.Int64("tinytcp.bytes_written", int64(atomic.LoadUint64(&writer.n))),
+		)
+
+		if reason := atomic.LoadUint32(&closeReason); reason > 0 {
+			span.SetAttributes(attribute.String("tinytcp.close_reason", tinytcp.CloseReason(reason-1).String()))
+		}
+
+		span.End()
+	}
+
+	socket.OnClose(func(reason tinytcp.CloseReason) {
+		atomic.StoreUint32(&closeReason, uint32(reason)+1)
+
+		if endOnClose {
+			end()
+		}
+	})
+
+	return ctx, end
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      uint64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	atomic.AddUint64(&r.n, uint64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	writer io.Writer
+	n      uint64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	atomic.AddUint64(&w.n, uint64(n))
+	return n, err
+}
@@ -0,0 +1,53 @@
+package oteltinytcp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/mkorman9/tinytcp/tinytcptest"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracePacketFraming(t *testing.T) {
+	// given
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	socket := tinytcptest.NewSocket(bytes.NewBufferString("first\nsecond\n"), io.Discard)
+
+	handler := tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		TracePacketFraming(
+			func(_ *tinytcp.Socket) tinytcp.PacketHandler {
+				return func(_ []byte) {}
+			},
+			&TracingConfig{TracerProvider: provider, TracePackets: true},
+		),
+	)
+
+	// when
+	handler(socket)
+	_ = socket.Close(tinytcp.CloseReasonClient)
+
+	// then
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3, "connection and packet spans should be recorded")
+
+	connection := spans[len(spans)-1]
+	assert.Equal(t, "tinytcp.connection", connection.Name(), "last span should represent the connection")
+
+	attributes := make(map[string]any)
+	for _, attribute := range connection.Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.AsInterface()
+	}
+	assert.Equal(t, int64(13), attributes["tinytcp.bytes_read"], "bytes read should match")
+	assert.Equal(t, "client", attributes["tinytcp.close_reason"], "close reason should match")
+
+	for _, span := range spans[:2] {
+		assert.Equal(t, connection.SpanContext().SpanID(), span.Parent().SpanID(), "packet span should be a child")
+	}
+}
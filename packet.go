@@ -0,0 +1,514 @@
+package tinytcp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"time"
+)
+
+// PacketReader is a cursor over a packet, providing typed getters for all the values supported by Read* functions.
+// Instead of returning errors, getters return zero values once the first error occurs. The error is accessible through
+// Err(), so it's enough to check it once after reading all the fields.
+// Slices returned by Bytes() and ByteArray() point into the packet, they should be copied if retained.
+type PacketReader struct {
+	packet []byte
+	offset int
+	err    error
+}
+
+// NewPacketReader creates a PacketReader over given packet.
+func NewPacketReader(packet []byte) *PacketReader {
+	return &PacketReader{
+		packet: packet,
+	}
+}
+
+// Reset resets the reader to read from the beginning of given packet, allowing to reuse PacketReader instances.
+func (r *PacketReader) Reset(packet []byte) {
+	r.packet = packet
+	r.offset = 0
+	r.err = nil
+}
+
+// Err returns the first error that occurred while reading the packet.
+func (r *PacketReader) Err() error {
+	return r.err
+}
+
+// Remaining returns a number of bytes left to read.
+func (r *PacketReader) Remaining() int {
+	return len(r.packet) - r.offset
+}
+
+// Skip skips next n bytes of the packet.
+func (r *PacketReader) Skip(n int) {
+	r.next(n)
+}
+
+// Read conforms to the io.Reader interface.
+func (r *PacketReader) Read(b []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.offset >= len(r.packet) {
+		return 0, io.EOF
+	}
+
+	n := copy(b, r.packet[r.offset:])
+	r.offset += n
+	return n, nil
+}
+
+// Bytes returns next n bytes of the packet.
+func (r *PacketReader) Bytes(n int) []byte {
+	return r.next(n)
+}
+
+// Byte reads byte from the packet.
+func (r *PacketReader) Byte() byte {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+
+	return b[0]
+}
+
+// Bool reads bool from the packet.
+func (r *PacketReader) Bool() bool {
+	return r.Byte() > 0
+}
+
+// Int16 reads int16 from the packet.
+func (r *PacketReader) Int16(byteOrder ...binary.ByteOrder) int16 {
+	return int16(r.Uint16(byteOrder...))
+}
+
+// Int32 reads int32 from the packet.
+func (r *PacketReader) Int32(byteOrder ...binary.ByteOrder) int32 {
+	return int32(r.Uint32(byteOrder...))
+}
+
+// Int64 reads int64 from the packet.
+func (r *PacketReader) Int64(byteOrder ...binary.ByteOrder) int64 {
+	return int64(r.Uint64(byteOrder...))
+}
+
+// Uint16 reads uint16 from the packet.
+func (r *PacketReader) Uint16(byteOrder ...binary.ByteOrder) uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+
+	return resolveByteOrder(byteOrder).Uint16(b)
+}
+
+// Uint32 reads uint32 from the packet.
+func (r *PacketReader) Uint32(byteOrder ...binary.ByteOrder) uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+
+	return resolveByteOrder(byteOrder).Uint32(b)
+}
+
+// Uint64 reads uint64 from the packet.
+func (r *PacketReader) Uint64(byteOrder ...binary.ByteOrder) uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+
+	return resolveByteOrder(byteOrder).Uint64(b)
+}
+
+// Float32 reads float32 from the packet.
+func (r *PacketReader) Float32(byteOrder ...binary.ByteOrder) float32 {
+	return math.Float32frombits(r.Uint32(byteOrder...))
+}
+
+// Float64 reads float64 from the packet.
+func (r *PacketReader) Float64(byteOrder ...binary.ByteOrder) float64 {
+	return math.Float64frombits(r.Uint64(byteOrder...))
+}
+
+// VarInt reads var int from the packet.
+func (r *PacketReader) VarInt() int {
+	return readPacketValue(r, ReadVarInt)
+}
+
+// VarLong reads var long from the packet.
+func (r *PacketReader) VarLong() int64 {
+	return readPacketValue(r, ReadVarLong)
+}
+
+// SVarInt reads zigzag-encoded var int from the packet.
+func (r *PacketReader) SVarInt() int32 {
+	return readPacketValue(r, ReadSVarInt)
+}
+
+// SVarLong reads zigzag-encoded var long from the packet.
+func (r *PacketReader) SVarLong() int64 {
+	return readPacketValue(r, ReadSVarLong)
+}
+
+// ByteArray reads VarInt-prefixed byte array from the packet.
+func (r *PacketReader) ByteArray() []byte {
+	return r.PrefixedByteArray(PrefixVarInt)
+}
+
+// PrefixedByteArray reads byte array prefixed with its length encoded as specified by prefix.
+func (r *PacketReader) PrefixedByteArray(prefix PrefixType) []byte {
+	length := readPacketValue(r, func(reader io.Reader) (int64, error) {
+		return readLength(reader, prefix)
+	})
+	if r.err != nil {
+		return nil
+	}
+
+	if length < 0 || length > int64(r.Remaining()) {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+
+	return r.next(int(length))
+}
+
+// Text reads VarInt-prefixed string from the packet.
+// It is not named String, so that PacketReader does not accidentally implement fmt.Stringer.
+func (r *PacketReader) Text() string {
+	return string(r.ByteArray())
+}
+
+// PrefixedString reads string prefixed with its length encoded as specified by prefix.
+func (r *PacketReader) PrefixedString(prefix PrefixType) string {
+	return string(r.PrefixedByteArray(prefix))
+}
+
+// CString reads NUL-terminated string from the packet.
+func (r *PacketReader) CString() string {
+	return readPacketValue(r, ReadCString)
+}
+
+// Time reads time encoded as int64 number of units elapsed since Unix epoch (see ReadTime).
+func (r *PacketReader) Time(unit time.Duration, byteOrder ...binary.ByteOrder) time.Time {
+	return readPacketValue(r, func(reader io.Reader) (time.Time, error) {
+		return ReadTime(reader, unit, byteOrder...)
+	})
+}
+
+// UUID reads 16-byte UUID from the packet.
+func (r *PacketReader) UUID() [16]byte {
+	var value [16]byte
+	copy(value[:], r.next(16))
+	return value
+}
+
+// IP reads IP address encoded on 16 bytes from the packet.
+func (r *PacketReader) IP() net.IP {
+	return readPacketValue(r, ReadIP)
+}
+
+// IPv4 reads IPv4 address encoded on 4 bytes from the packet.
+func (r *PacketReader) IPv4() net.IP {
+	return readPacketValue(r, ReadIPv4)
+}
+
+func (r *PacketReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+
+	if n < 0 || n > r.Remaining() {
+		r.err = io.ErrUnexpectedEOF
+		r.offset = len(r.packet)
+		return nil
+	}
+
+	b := r.packet[r.offset : r.offset+n]
+	r.offset += n
+	return b
+}
+
+func readPacketValue[T any](r *PacketReader, readFunc func(io.Reader) (T, error)) T {
+	var zero T
+	if r.err != nil {
+		return zero
+	}
+
+	value, err := readFunc(r)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		r.err = err
+		return zero
+	}
+
+	return value
+}
+
+// PacketWriter is a builder of packets, providing typed setters for all the values supported by Write* functions.
+// Setters can be chained. Writing into memory never fails, but some values might be rejected (eg. CString containing
+// NUL), in which case the first error is accessible through Err().
+// PacketWriter can be reused after calling Reset(), which makes it well suited for pooling.
+type PacketWriter struct {
+	buffer    []byte
+	err       error
+	allocator Allocator
+}
+
+// NewPacketWriter creates a PacketWriter with given initial capacity.
+func NewPacketWriter(capacity ...int) *PacketWriter {
+	c := 64
+	if capacity != nil {
+		c = capacity[0]
+	}
+
+	return &PacketWriter{
+		buffer: make([]byte, 0, c),
+	}
+}
+
+// NewPacketWriterWithAllocator creates a PacketWriter with given initial capacity, which obtains its buffer
+// from given Allocator. The buffer should be returned to the allocator by calling Release().
+func NewPacketWriterWithAllocator(allocator Allocator, capacity ...int) *PacketWriter {
+	c := 64
+	if capacity != nil {
+		c = capacity[0]
+	}
+
+	return &PacketWriter{
+		buffer:    allocator.Allocate(c)[:0],
+		allocator: allocator,
+	}
+}
+
+// Release returns the underlying buffer to the Allocator passed to NewPacketWriterWithAllocator.
+// Writer must not be used afterwards, and slices returned by Bytes() become invalid.
+// For the writers created by NewPacketWriter it does nothing.
+func (w *PacketWriter) Release() {
+	if w.allocator == nil {
+		return
+	}
+
+	if w.buffer != nil {
+		w.allocator.Free(w.buffer)
+	}
+
+	w.buffer = nil
+	w.err = nil
+}
+
+// Reset empties the writer, retaining its underlying buffer.
+func (w *PacketWriter) Reset() {
+	w.buffer = w.buffer[:0]
+	w.err = nil
+}
+
+// Err returns the first error that occurred while building the packet.
+func (w *PacketWriter) Err() error {
+	return w.err
+}
+
+// Len returns the number of bytes written so far.
+func (w *PacketWriter) Len() int {
+	return len(w.buffer)
+}
+
+// Bytes returns the packet. Returned slice is only valid until the next modification of the writer.
+func (w *PacketWriter) Bytes() []byte {
+	return w.buffer
+}
+
+// WriteTo writes the packet into given writer. It conforms to the io.WriterTo interface.
+func (w *PacketWriter) WriteTo(writer io.Writer) (int64, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	err := WriteBytes(writer, w.buffer)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(w.buffer)), nil
+}
+
+// Write conforms to the io.Writer interface.
+func (w *PacketWriter) Write(b []byte) (int, error) {
+	w.append(b...)
+	return len(b), nil
+}
+
+func (w *PacketWriter) append(b ...byte) {
+	if w.allocator != nil && len(w.buffer)+len(b) > cap(w.buffer) {
+		grown := w.allocator.Allocate(2*cap(w.buffer) + len(b))
+		grown = grown[:copy(grown, w.buffer)]
+
+		if w.buffer != nil {
+			w.allocator.Free(w.buffer)
+		}
+
+		w.buffer = grown
+	}
+
+	w.buffer = append(w.buffer, b...)
+}
+
+// RawBytes appends raw bytes to the packet.
+func (w *PacketWriter) RawBytes(value []byte) *PacketWriter {
+	w.append(value...)
+	return w
+}
+
+// Byte appends byte to the packet.
+func (w *PacketWriter) Byte(value byte) *PacketWriter {
+	w.append(value)
+	return w
+}
+
+// Bool appends bool to the packet.
+func (w *PacketWriter) Bool(value bool) *PacketWriter {
+	if value {
+		return w.Byte(1)
+	}
+
+	return w.Byte(0)
+}
+
+// Int16 appends int16 to the packet.
+func (w *PacketWriter) Int16(value int16, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.Uint16(uint16(value), byteOrder...)
+}
+
+// Int32 appends int32 to the packet.
+func (w *PacketWriter) Int32(value int32, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.Uint32(uint32(value), byteOrder...)
+}
+
+// Int64 appends int64 to the packet.
+func (w *PacketWriter) Int64(value int64, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.Uint64(uint64(value), byteOrder...)
+}
+
+// Uint16 appends uint16 to the packet.
+func (w *PacketWriter) Uint16(value uint16, byteOrder ...binary.ByteOrder) *PacketWriter {
+	var b [2]byte
+	resolveByteOrder(byteOrder).PutUint16(b[:], value)
+	w.append(b[:]...)
+	return w
+}
+
+// Uint32 appends uint32 to the packet.
+func (w *PacketWriter) Uint32(value uint32, byteOrder ...binary.ByteOrder) *PacketWriter {
+	var b [4]byte
+	resolveByteOrder(byteOrder).PutUint32(b[:], value)
+	w.append(b[:]...)
+	return w
+}
+
+// Uint64 appends uint64 to the packet.
+func (w *PacketWriter) Uint64(value uint64, byteOrder ...binary.ByteOrder) *PacketWriter {
+	var b [8]byte
+	resolveByteOrder(byteOrder).PutUint64(b[:], value)
+	w.append(b[:]...)
+	return w
+}
+
+// Float32 appends float32 to the packet.
+func (w *PacketWriter) Float32(value float32, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.Uint32(math.Float32bits(value), byteOrder...)
+}
+
+// Float64 appends float64 to the packet.
+func (w *PacketWriter) Float64(value float64, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.Uint64(math.Float64bits(value), byteOrder...)
+}
+
+// VarInt appends var int to the packet.
+func (w *PacketWriter) VarInt(value int) *PacketWriter {
+	return w.check(WriteVarInt(w, value))
+}
+
+// VarLong appends var long to the packet.
+func (w *PacketWriter) VarLong(value int64) *PacketWriter {
+	return w.check(WriteVarLong(w, value))
+}
+
+// SVarInt appends zigzag-encoded var int to the packet.
+func (w *PacketWriter) SVarInt(value int32) *PacketWriter {
+	return w.check(WriteSVarInt(w, value))
+}
+
+// SVarLong appends zigzag-encoded var long to the packet.
+func (w *PacketWriter) SVarLong(value int64) *PacketWriter {
+	return w.check(WriteSVarLong(w, value))
+}
+
+// ByteArray appends VarInt-prefixed byte array to the packet.
+func (w *PacketWriter) ByteArray(value []byte) *PacketWriter {
+	return w.check(WriteByteArray(w, value))
+}
+
+// PrefixedByteArray appends byte array prefixed with its length encoded as specified by prefix.
+func (w *PacketWriter) PrefixedByteArray(value []byte, prefix PrefixType) *PacketWriter {
+	return w.check(WritePrefixedByteArray(w, value, prefix))
+}
+
+// Text appends VarInt-prefixed string to the packet.
+func (w *PacketWriter) Text(value string) *PacketWriter {
+	return w.check(WriteString(w, value))
+}
+
+// PrefixedString appends string prefixed with its length encoded as specified by prefix.
+func (w *PacketWriter) PrefixedString(value string, prefix PrefixType) *PacketWriter {
+	return w.check(WritePrefixedString(w, value, prefix))
+}
+
+// CString appends NUL-terminated string to the packet.
+func (w *PacketWriter) CString(value string) *PacketWriter {
+	return w.check(WriteCString(w, value))
+}
+
+// Time appends time encoded as int64 number of units elapsed since Unix epoch (see WriteTime).
+func (w *PacketWriter) Time(value time.Time, unit time.Duration, byteOrder ...binary.ByteOrder) *PacketWriter {
+	return w.check(WriteTime(w, value, unit, byteOrder...))
+}
+
+// UUID appends 16-byte UUID to the packet.
+func (w *PacketWriter) UUID(value [16]byte) *PacketWriter {
+	w.append(value[:]...)
+	return w
+}
+
+// IP appends IP address encoded on 16 bytes to the packet.
+func (w *PacketWriter) IP(value net.IP) *PacketWriter {
+	return w.check(WriteIP(w, value))
+}
+
+// IPv4 appends IPv4 address encoded on 4 bytes to the packet.
+func (w *PacketWriter) IPv4(value net.IP) *PacketWriter {
+	return w.check(WriteIPv4(w, value))
+}
+
+func (w *PacketWriter) check(err error) *PacketWriter {
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	return w
+}
+
+func resolveByteOrder(byteOrder []binary.ByteOrder) binary.ByteOrder {
+	if len(byteOrder) > 0 {
+		return byteOrder[0]
+	}
+
+	return binary.BigEndian
+}
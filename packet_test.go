@@ -0,0 +1,88 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPacketReaderWriter(t *testing.T) {
+	// given
+	now := time.Date(2023, 7, 14, 12, 30, 15, 0, time.UTC)
+	ip := net.ParseIP("10.0.0.1")
+	uuid := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	writer := NewPacketWriter()
+	writer.
+		Byte(0x01).
+		Bool(true).
+		Int16(-2).
+		Uint32(0xFFFFFFFE).
+		Float64(3.14).
+		VarInt(300).
+		SVarLong(-5).
+		Text("Hello").
+		PrefixedString("world", PrefixInt16_LE).
+		CString("!").
+		Time(now, time.Millisecond).
+		UUID(uuid).
+		IPv4(ip)
+
+	// when
+	reader := NewPacketReader(writer.Bytes())
+
+	// then
+	assert.Nil(t, writer.Err(), "writer err should be nil")
+	assert.Equal(t, byte(0x01), reader.Byte(), "byte should match")
+	assert.Equal(t, true, reader.Bool(), "bool should match")
+	assert.Equal(t, int16(-2), reader.Int16(), "int16 should match")
+	assert.Equal(t, uint32(0xFFFFFFFE), reader.Uint32(), "uint32 should match")
+	assert.Equal(t, 3.14, reader.Float64(), "float64 should match")
+	assert.Equal(t, 300, reader.VarInt(), "var int should match")
+	assert.Equal(t, int64(-5), reader.SVarLong(), "zigzag var long should match")
+	assert.Equal(t, "Hello", reader.Text(), "text should match")
+	assert.Equal(t, "world", reader.PrefixedString(PrefixInt16_LE), "prefixed string should match")
+	assert.Equal(t, "!", reader.CString(), "c string should match")
+	assert.Equal(t, now, reader.Time(time.Millisecond), "time should match")
+	assert.Equal(t, uuid, reader.UUID(), "uuid should match")
+	assert.True(t, ip.Equal(reader.IPv4()), "ip should match")
+	assert.Equal(t, 0, reader.Remaining(), "whole packet should be read")
+	assert.Nil(t, reader.Err(), "reader err should be nil")
+}
+
+func TestPacketReaderErrorAccumulation(t *testing.T) {
+	// given
+	reader := NewPacketReader([]byte{0x00, 0x01, 0x02, 0x05, 'a'})
+
+	// when
+	first := reader.Int16()
+	reader.Skip(1)
+	text := reader.Text()
+	last := reader.Byte()
+
+	// then
+	assert.Equal(t, int16(1), first, "first value should be read")
+	assert.Equal(t, "", text, "truncated text should be empty")
+	assert.Equal(t, byte(0), last, "values after the error should be zero")
+	assert.Equal(t, io.ErrUnexpectedEOF, reader.Err(), "err should be io.ErrUnexpectedEOF")
+}
+
+func TestPacketWriterWriteTo(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	writer := NewPacketWriter()
+	writer.Int32(42)
+
+	// when
+	n, err := writer.WriteTo(&buffer)
+	writer.Reset()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, int64(4), n, "number of bytes should match")
+	assert.Equal(t, []byte{0, 0, 0, 42}, buffer.Bytes(), "bytes should match")
+	assert.Equal(t, 0, writer.Len(), "writer should be empty after reset")
+}
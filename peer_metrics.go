@@ -0,0 +1,99 @@
+package tinytcp
+
+import (
+	"sort"
+	"sync"
+)
+
+// PeerMetrics contains metrics aggregated for a single remote address (see ServerConfig.PeerMetricsLimit).
+type PeerMetrics struct {
+	// Address is a remote address of the peer (without port).
+	Address string
+
+	// Labels are labels assigned to the peer by ServerConfig.ClassifyPeer, if any.
+	Labels map[string]string
+
+	// Connections is a number of active connections from the peer.
+	Connections int
+
+	// TotalRead is a total number of bytes read from the peer.
+	TotalRead uint64
+
+	// TotalWritten is a total number of bytes written to the peer.
+	TotalWritten uint64
+}
+
+// peerMetricsAggregator keeps metrics of at most limit peers, preferring the connected and the most active ones.
+type peerMetricsAggregator struct {
+	limit    int
+	peers    map[string]*PeerMetrics
+	snapshot []PeerMetrics
+	m        sync.RWMutex
+}
+
+func newPeerMetricsAggregator(limit int) *peerMetricsAggregator {
+	return &peerMetricsAggregator{
+		limit: limit,
+		peers: make(map[string]*PeerMetrics),
+	}
+}
+
+// begin starts a new aggregation round. It's expected to be called only from the housekeeping job.
+func (a *peerMetricsAggregator) begin() {
+	for _, peer := range a.peers {
+		peer.Connections = 0
+	}
+}
+
+func (a *peerMetricsAggregator) observe(address string, labels map[string]string, reads, writes uint64) {
+	peer, ok := a.peers[address]
+	if !ok {
+		peer = &PeerMetrics{Address: address}
+		a.peers[address] = peer
+	}
+
+	if labels != nil {
+		peer.Labels = labels
+	}
+
+	peer.Connections++
+	peer.TotalRead += reads
+	peer.TotalWritten += writes
+}
+
+// commit evicts peers exceeding the limit and publishes the results.
+func (a *peerMetricsAggregator) commit() {
+	snapshot := make([]PeerMetrics, 0, len(a.peers))
+	for _, peer := range a.peers {
+		snapshot = append(snapshot, *peer)
+	}
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if (snapshot[i].Connections > 0) != (snapshot[j].Connections > 0) {
+			return snapshot[i].Connections > 0
+		}
+
+		return snapshot[i].TotalRead+snapshot[i].TotalWritten > snapshot[j].TotalRead+snapshot[j].TotalWritten
+	})
+
+	if len(snapshot) > a.limit {
+		for _, peer := range snapshot[a.limit:] {
+			delete(a.peers, peer.Address)
+		}
+
+		snapshot = snapshot[:a.limit]
+	}
+
+	a.m.Lock()
+	a.snapshot = snapshot
+	a.m.Unlock()
+}
+
+func (a *peerMetricsAggregator) Top() []PeerMetrics {
+	a.m.RLock()
+	defer a.m.RUnlock()
+
+	top := make([]PeerMetrics, len(a.snapshot))
+	copy(top, a.snapshot)
+	return top
+}
@@ -0,0 +1,36 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPeerMetricsAggregator(t *testing.T) {
+	// given
+	aggregator := newPeerMetricsAggregator(2)
+
+	// when
+	aggregator.begin()
+	aggregator.observe("10.0.0.1", nil, 100, 0)
+	aggregator.observe("10.0.0.1", nil, 50, 50)
+	aggregator.observe("10.0.0.2", nil, 10, 0)
+	aggregator.observe("10.0.0.3", nil, 500, 0)
+	aggregator.commit()
+	first := aggregator.Top()
+
+	aggregator.begin()
+	aggregator.observe("10.0.0.2", nil, 1, 0)
+	aggregator.commit()
+	second := aggregator.Top()
+
+	// then
+	assert.Equal(t, []PeerMetrics{
+		{Address: "10.0.0.3", Connections: 1, TotalRead: 500},
+		{Address: "10.0.0.1", Connections: 2, TotalRead: 150, TotalWritten: 50},
+	}, first, "the most active peers should be kept")
+
+	assert.Equal(t, []PeerMetrics{
+		{Address: "10.0.0.2", Connections: 1, TotalRead: 1},
+		{Address: "10.0.0.3", Connections: 0, TotalRead: 500},
+	}, second, "connected peers should be preferred")
+}
@@ -0,0 +1,134 @@
+package tinytcp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+)
+
+const pipeBufferSize = 32 * 1024 // 32 KiB
+
+// copy buffers are pooled to avoid memory allocation in hot path
+var pipeBuffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, pipeBufferSize)
+		return &b
+	},
+}
+
+// Pipe copies data between two connections in both directions, until both directions are finished.
+// When one side finishes sending, the write side of the other connection is closed (if it supports half-close,
+// eg. *net.TCPConn), so the protocols relying on half-closed connections keep working. Otherwise, or on error,
+// both connections are closed. Both connections are always closed before Pipe returns.
+// On Linux, when the connections are *net.TCPConn or *net.UnixConn, data is moved within the kernel by splice(2),
+// without copying it into user space. Other connections (eg. TLS) are copied through pooled buffers.
+// Pipe returns the number of bytes copied from a to b, from b to a, and the errors other than EOF or closed
+// connection encountered in either direction.
+func Pipe(a, b net.Conn) (aToB int64, bToA int64, err error) {
+	return pipe(
+		pipeEnd{rw: a, conn: a, close: func() { _ = a.Close() }},
+		pipeEnd{rw: b, conn: b, close: func() { _ = b.Close() }},
+	)
+}
+
+// PipeSocket works like Pipe, but one end of the pipe is a Socket. The data is transferred through the Socket,
+// so it's accounted in its metrics, and the data already buffered by the socket (eg. by Peek or Handoff)
+// is not lost. Splicing is used as long as the reader and the writer of the socket have not been wrapped.
+// It returns the number of bytes copied from the socket to conn, and from conn to the socket.
+func PipeSocket(socket *Socket, conn net.Conn) (toConn int64, toSocket int64, err error) {
+	return pipe(
+		pipeEnd{rw: socket, conn: socket.Unwrap(), close: func() { _ = socket.Close() }},
+		pipeEnd{rw: conn, conn: conn, close: func() { _ = conn.Close() }},
+	)
+}
+
+type pipeEnd struct {
+	rw    io.ReadWriter
+	conn  net.Conn
+	close func()
+}
+
+type writeCloser interface {
+	CloseWrite() error
+}
+
+func pipe(a, b pipeEnd) (int64, int64, error) {
+	var (
+		aToB, bToA int64
+		errs       [2]error
+		done       = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		bToA, errs[1] = pipeDirection(a, b)
+	}()
+
+	aToB, errs[0] = pipeDirection(b, a)
+	<-done
+
+	a.close()
+	b.close()
+
+	return aToB, bToA, errors.Join(filterPipeError(errs[0]), filterPipeError(errs[1]))
+}
+
+// pipeDirection copies the data from src to dst, until src is finished.
+func pipeDirection(dst, src pipeEnd) (int64, error) {
+	n, err := pipeCopy(dst.rw, src.rw)
+
+	if err == nil {
+		if c, ok := dst.conn.(writeCloser); ok && c.CloseWrite() == nil {
+			return n, nil
+		}
+	}
+
+	// unblock the other direction
+	dst.close()
+	src.close()
+
+	return n, err
+}
+
+func pipeCopy(dst io.Writer, src io.Reader) (int64, error) {
+	if isSpliceable(src) {
+		// *net.TCPConn splices from the connections of the same kind, Socket passes the data to its connection
+		if readerFrom, ok := dst.(io.ReaderFrom); ok {
+			return readerFrom.ReadFrom(src)
+		}
+	}
+
+	if socket, ok := src.(*Socket); ok {
+		if conn, ok := socket.spliceableConn(); ok {
+			if readerFrom, ok := dst.(io.ReaderFrom); ok {
+				n, err := readerFrom.ReadFrom(conn)
+				socket.meteredReader.add(int(n))
+				return n, err
+			}
+		}
+	}
+
+	buffer := pipeBuffers.Get().(*[]byte)
+	defer pipeBuffers.Put(buffer)
+
+	return io.CopyBuffer(writerOnly{dst}, readerOnly{src}, *buffer)
+}
+
+func isSpliceable(r io.Reader) bool {
+	switch r.(type) {
+	case *net.TCPConn, *net.UnixConn:
+		return true
+	}
+
+	return false
+}
+
+// filterPipeError drops errors that are expected when either side of the pipe is closed.
+func filterPipeError(err error) error {
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, ErrSocketClosed) {
+		return nil
+	}
+
+	return err
+}
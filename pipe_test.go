@@ -0,0 +1,107 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestPipe(t *testing.T) {
+	// given
+	clientA, serverA := tcpConnPair(t)
+	clientB, serverB := tcpConnPair(t)
+	defer clientA.Close()
+	defer clientB.Close()
+
+	type result struct {
+		aToB, bToA int64
+		err        error
+	}
+	results := make(chan result, 1)
+
+	go func() {
+		aToB, bToA, err := Pipe(serverA, serverB)
+		results <- result{aToB, bToA, err}
+	}()
+
+	// when
+	_, _ = clientA.Write([]byte("Hello world!"))
+	_ = clientA.(*net.TCPConn).CloseWrite()
+
+	receivedByB, _ := io.ReadAll(clientB)
+	_, _ = clientB.Write([]byte("Hi!"))
+	_ = clientB.(*net.TCPConn).CloseWrite()
+
+	receivedByA, _ := io.ReadAll(clientA)
+	r := <-results
+
+	// then
+	assert.Nil(t, r.err, "err should be nil")
+	assert.Equal(t, "Hello world!", string(receivedByB), "payloads should match")
+	assert.Equal(t, "Hi!", string(receivedByA), "payloads should match after half-close")
+	assert.Equal(t, int64(12), r.aToB, "bytes copied from a to b should match")
+	assert.Equal(t, int64(3), r.bToA, "bytes copied from b to a should match")
+}
+
+func TestPipeSocket(t *testing.T) {
+	// given
+	client, server := tcpConnPair(t)
+	upstreamClient, upstream := tcpConnPair(t)
+	defer client.Close()
+	defer upstreamClient.Close()
+
+	socket := NewSocket(server)
+	_, _ = client.Write([]byte("Hello world!"))
+	peeked, _ := socket.Peek(5)
+
+	done := make(chan error, 1)
+	var toConn, toSocket int64
+
+	go func() {
+		var err error
+		toConn, toSocket, err = PipeSocket(socket, upstream)
+		done <- err
+	}()
+
+	// when
+	received := make([]byte, 12)
+	_, readErr := io.ReadFull(upstreamClient, received)
+
+	_, _ = upstreamClient.Write([]byte("Hi!"))
+	_ = upstreamClient.Close()
+
+	response, _ := io.ReadAll(client)
+	_ = client.Close()
+	err := <-done
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, "Hello", string(peeked), "peeked data should match")
+	assert.Equal(t, "Hello world!", string(received), "peeked data should not be lost")
+	assert.Equal(t, "Hi!", string(response), "payloads should match")
+	assert.Equal(t, int64(12), toConn, "bytes copied to conn should match")
+	assert.Equal(t, int64(3), toSocket, "bytes copied to socket should match")
+	assert.Equal(t, uint64(3), socket.meteredWriter.current, "written bytes should be metered")
+}
+
+func tcpConnPair(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+
+	server, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("failed to accept: %v", err)
+	}
+
+	return client, server
+}
@@ -0,0 +1,58 @@
+package promtinytcp
+
+import (
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// NewDisconnectHandler creates a handler exposing a histogram of connection lifetimes labelled by the close reason
+// ("server" or "client"), so client churn can be distinguished from connections killed by the server.
+// It can be registered using OnDisconnect method. Buckets are defined by tinytcp.ConnectionAgeBuckets.
+func NewDisconnectHandler(
+	registerer prometheus.Registerer,
+	config ...*Config,
+) func(reason tinytcp.CloseReason, duration time.Duration) {
+	c := &Config{}
+	if config != nil {
+		c = config[0]
+	}
+
+	// the last bucket is unbounded, it's represented by the implicit +Inf bucket
+	buckets := make([]float64, len(tinytcp.ConnectionAgeBuckets)-1)
+	for i := range buckets {
+		buckets[i] = tinytcp.ConnectionAgeBuckets[i].Seconds()
+	}
+
+	lifetime := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace:   c.Namespace,
+			Subsystem:   c.Subsystem,
+			Name:        "connection_lifetime_seconds",
+			Help:        "Distribution of lifetimes of the closed connections, labelled by the side that closed them.",
+			ConstLabels: c.ConstLabels,
+			Buckets:     buckets,
+		},
+		[]string{"reason"},
+	)
+
+	registerer.MustRegister(lifetime)
+
+	var (
+		server = lifetime.WithLabelValues(tinytcp.CloseReasonServer.String())
+		client = lifetime.WithLabelValues(tinytcp.CloseReasonClient.String())
+		abort  = lifetime.WithLabelValues(tinytcp.CloseReasonAbort.String())
+	)
+
+	return func(reason tinytcp.CloseReason, duration time.Duration) {
+		switch reason {
+		case tinytcp.CloseReasonServer:
+			server.Observe(duration.Seconds())
+		case tinytcp.CloseReasonClient:
+			client.Observe(duration.Seconds())
+		case tinytcp.CloseReasonAbort:
+			abort.Observe(duration.Seconds())
+		}
+	}
+}
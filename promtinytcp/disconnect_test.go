@@ -0,0 +1,34 @@
+package promtinytcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisconnectHandler(t *testing.T) {
+	// given
+	registry := prometheus.NewRegistry()
+	handler := NewDisconnectHandler(registry)
+
+	// when
+	handler(tinytcp.CloseReasonClient, time.Second)
+	handler(tinytcp.CloseReasonClient, 2*time.Second)
+	handler(tinytcp.CloseReasonServer, time.Minute)
+	handler(tinytcp.CloseReasonAbort, time.Hour)
+	families, err := registry.Gather()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Len(t, families, 1, "histogram should be registered")
+
+	counts := make(map[string]uint64)
+	for _, metric := range families[0].GetMetric() {
+		counts[metric.GetLabel()[0].GetValue()] = metric.GetHistogram().GetSampleCount()
+	}
+
+	assert.Equal(t, map[string]uint64{"client": 2, "server": 1, "abort": 1}, counts, "lifetimes should be labelled by reason")
+}
@@ -1,6 +1,9 @@
 package promtinytcp
 
 import (
+	"sync"
+	"time"
+
 	"github.com/mkorman9/tinytcp"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -12,71 +15,300 @@ type Config struct {
 
 	// Subsystem is a parameter attached to all Prometheus metrics registered in NewHandler.
 	Subsystem string
+
+	// ConstLabels are labels attached to all Prometheus metrics registered in NewHandler.
+	// Useful for distinguishing multiple servers exposed through the same registry.
+	ConstLabels prometheus.Labels
+}
+
+// Collector is a prometheus.Collector exposing metrics of one or more tinytcp.Server instances.
+// Metrics of each server are distinguished by the values of variable labels (eg. listener address or server name),
+// so multiple servers running in a single process can share the same registry and be monitored separately.
+type Collector struct {
+	labelNames []string
+	servers    []*serverMetrics
+	m          sync.RWMutex
+
+	readBytes          *prometheus.Desc
+	writtenBytes       *prometheus.Desc
+	readLastSecond     *prometheus.Desc
+	writtenLastSecond  *prometheus.Desc
+	connections        *prometheus.Desc
+	peakConnections    *prometheus.Desc
+	goroutines         *prometheus.Desc
+	accepted           *prometheus.Desc
+	rejected           *prometheus.Desc
+	acceptQueueLength  *prometheus.Desc
+	acceptQueueDropped *prometheus.Desc
+	closed             *prometheus.Desc
+	connectionDuration *prometheus.Desc
+	readyLatency       *prometheus.Desc
+	handshakeDuration  *prometheus.Desc
+	packetLatency      *prometheus.Desc
+	packetSize         *prometheus.Desc
+	workers            *prometheus.Desc
+	busyWorkers        *prometheus.Desc
+	queuedTasks        *prometheus.Desc
+	taskLatency        *prometheus.Desc
+}
+
+type serverMetrics struct {
+	labelValues []string
+	metrics     tinytcp.ServerMetrics
 }
 
 // NewHandler creates a metrics handler for tinytcp.Server. It can be registered using OnMetricsUpdate method.
 // Created handler exposes all server metrics to the given prometheus.Registerer.
+// Metrics are exported in the state from the last update, so scraping never blocks the server.
+// To expose multiple servers through the same registry, use NewCollector.
 func NewHandler(
 	registerer prometheus.Registerer,
 	config ...*Config,
 ) func(metrics tinytcp.ServerMetrics) {
+	return NewCollector(registerer, nil, config...).Handler()
+}
+
+// NewCollector creates a Collector and registers it in the given prometheus.Registerer.
+// labelNames are names of the variable labels attached to all the metrics, their values are specified
+// for each server separately (see Collector.Handler).
+func NewCollector(
+	registerer prometheus.Registerer,
+	labelNames []string,
+	config ...*Config,
+) *Collector {
 	c := &Config{}
 	if config != nil {
 		c = config[0]
 	}
 
-	totalRead := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "total_read",
-		Help:      "Total number of bytes read by the server.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-	totalWritten := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "total_written",
-		Help:      "Total number of bytes written by the server.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-	readLastSecond := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "read_last_second",
-		Help:      "Total number of bytes read by the server last second.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-	writtenLastSecond := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "written_last_second",
-		Help:      "Total number of bytes written by the server last second.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-	connections := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "connections",
-		Help:      "Total number of active connections during the last second.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-	goroutines := prometheus.NewGauge(prometheus.GaugeOpts{
-		Name:      "goroutines",
-		Help:      "Total number of active goroutines during the last second.",
-		Namespace: c.Namespace,
-		Subsystem: c.Subsystem,
-	})
-
-	registerer.MustRegister(
-		totalRead,
-		totalWritten,
-		readLastSecond,
-		writtenLastSecond,
-		connections,
-		goroutines,
-	)
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(c.Namespace, c.Subsystem, name),
+			help,
+			append(append([]string(nil), labelNames...), labels...),
+			c.ConstLabels,
+		)
+	}
+
+	col := &Collector{
+		labelNames:        labelNames,
+		readBytes:         desc("read_bytes_total", "Total number of bytes read by the server."),
+		writtenBytes:      desc("written_bytes_total", "Total number of bytes written by the server."),
+		readLastSecond:    desc("read_last_second", "Total number of bytes read by the server last second."),
+		writtenLastSecond: desc("written_last_second", "Total number of bytes written by the server last second."),
+		connections:       desc("connections", "Total number of active connections during the last second."),
+		peakConnections: desc(
+			"connections_peak",
+			"Highest number of connections active at once since the server creation.",
+		),
+		goroutines: desc("goroutines", "Total number of active goroutines during the last second."),
+		accepted:   desc("connections_accepted_total", "Total number of connections accepted by the server."),
+		rejected: desc(
+			"connections_rejected_total",
+			"Total number of connections rejected by the server due to the connections limit.",
+		),
+		acceptQueueLength: desc("accept_queue_length", "Number of connections waiting in the accept queue."),
+		acceptQueueDropped: desc(
+			"accept_queue_dropped_total",
+			"Total number of connections closed because the accept queue was full.",
+		),
+		closed: desc(
+			"connections_closed_total",
+			"Total number of closed connections, labelled by the side that closed them.",
+			"reason",
+		),
+		connectionDuration: desc(
+			"connection_duration_seconds",
+			"Distribution of lifetimes of the closed connections.",
+		),
+		readyLatency: desc(
+			"connection_ready_latency_seconds",
+			"Distribution of time elapsed between accepting the connections and their readiness for application data.",
+		),
+		handshakeDuration: desc(
+			"tls_handshake_duration_seconds",
+			"Distribution of durations of TLS handshakes.",
+		),
+		packetLatency: desc(
+			"packet_latency_seconds",
+			"Distribution of time spent by packet handlers on processing packets.",
+		),
+		packetSize:  desc("packet_size_bytes", "Distribution of sizes of the received packets."),
+		workers:     desc("strategy_workers", "Number of worker goroutines maintained by the forking strategy."),
+		busyWorkers: desc("strategy_busy_workers", "Number of worker goroutines processing a task."),
+		queuedTasks: desc("strategy_queued_tasks", "Number of tasks waiting in the queues of the forking strategy."),
+		taskLatency: desc(
+			"strategy_task_latency_seconds",
+			"Distribution of time the tasks spent waiting in the queues of the forking strategy.",
+		),
+	}
+
+	registerer.MustRegister(col)
+
+	return col
+}
+
+// Handler returns a metrics handler for a single tinytcp.Server, identified by the given values of the variable
+// labels. It can be registered using OnMetricsUpdate method. The number of values must match the number of label
+// names passed to NewCollector, otherwise Handler panics. Handlers created with the same values share the metrics.
+func (c *Collector) Handler(labelValues ...string) func(metrics tinytcp.ServerMetrics) {
+	if len(labelValues) != len(c.labelNames) {
+		panic("promtinytcp: number of label values doesn't match the number of label names")
+	}
+
+	server := c.server(labelValues)
 
 	return func(metrics tinytcp.ServerMetrics) {
-		totalRead.Set(float64(metrics.TotalRead))
-		totalWritten.Set(float64(metrics.TotalWritten))
-		readLastSecond.Set(float64(metrics.ReadLastSecond))
-		writtenLastSecond.Set(float64(metrics.WrittenLastSecond))
-		connections.Set(float64(metrics.Connections))
-		goroutines.Set(float64(metrics.Goroutines))
+		c.m.Lock()
+		defer c.m.Unlock()
+
+		server.metrics = metrics
+	}
+}
+
+func (c *Collector) server(labelValues []string) *serverMetrics {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	for _, server := range c.servers {
+		if equalLabelValues(server.labelValues, labelValues) {
+			return server
+		}
+	}
+
+	server := &serverMetrics{
+		labelValues: append([]string(nil), labelValues...),
+	}
+	c.servers = append(c.servers, server)
+
+	return server
+}
+
+// Describe conforms to the prometheus.Collector interface.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.readBytes
+	ch <- c.writtenBytes
+	ch <- c.readLastSecond
+	ch <- c.writtenLastSecond
+	ch <- c.connections
+	ch <- c.peakConnections
+	ch <- c.goroutines
+	ch <- c.accepted
+	ch <- c.rejected
+	ch <- c.acceptQueueLength
+	ch <- c.acceptQueueDropped
+	ch <- c.closed
+	ch <- c.connectionDuration
+	ch <- c.readyLatency
+	ch <- c.handshakeDuration
+	ch <- c.packetLatency
+	ch <- c.packetSize
+	ch <- c.workers
+	ch <- c.busyWorkers
+	ch <- c.queuedTasks
+	ch <- c.taskLatency
+}
+
+// Collect conforms to the prometheus.Collector interface.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.m.RLock()
+	servers := make([]serverMetrics, len(c.servers))
+	for i, server := range c.servers {
+		servers[i] = *server
+	}
+	c.m.RUnlock()
+
+	for i := range servers {
+		c.collectServer(ch, &servers[i].metrics, servers[i].labelValues)
+	}
+}
+
+func (c *Collector) collectServer(ch chan<- prometheus.Metric, metrics *tinytcp.ServerMetrics, labels []string) {
+	counter := func(desc *prometheus.Desc, value float64, extraLabels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value, withLabels(labels, extraLabels)...)
+	}
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...)
+	}
+
+	counter(c.readBytes, float64(metrics.TotalRead))
+	counter(c.writtenBytes, float64(metrics.TotalWritten))
+	gauge(c.readLastSecond, float64(metrics.ReadLastSecond))
+	gauge(c.writtenLastSecond, float64(metrics.WrittenLastSecond))
+	gauge(c.connections, float64(metrics.Connections))
+	gauge(c.peakConnections, float64(metrics.PeakConnections))
+	gauge(c.goroutines, float64(metrics.Goroutines))
+	counter(c.accepted, float64(metrics.TotalAccepted))
+	counter(c.rejected, float64(metrics.TotalRejected))
+	gauge(c.acceptQueueLength, float64(metrics.AcceptQueueLength))
+	counter(c.acceptQueueDropped, float64(metrics.TotalAcceptQueueDropped))
+	counter(c.closed, float64(metrics.TotalClosedByServer), "server")
+	counter(c.closed, float64(metrics.TotalClosedByClient), "client")
+	ch <- durationHistogram(c.connectionDuration, &metrics.ConnectionDuration, &tinytcp.ConnectionAgeBuckets, labels)
+	ch <- durationHistogram(c.readyLatency, &metrics.ReadyLatency, &tinytcp.PacketLatencyBuckets, labels)
+	ch <- durationHistogram(c.handshakeDuration, &metrics.HandshakeDuration, &tinytcp.PacketLatencyBuckets, labels)
+	ch <- durationHistogram(c.packetLatency, &metrics.PacketLatency, &tinytcp.PacketLatencyBuckets, labels)
+	ch <- sizeHistogram(c.packetSize, &metrics.PacketSize, &tinytcp.PacketSizeBuckets, labels)
+	gauge(c.workers, float64(metrics.Workers))
+	gauge(c.busyWorkers, float64(metrics.BusyWorkers))
+	gauge(c.queuedTasks, float64(metrics.QueuedTasks))
+	ch <- durationHistogram(c.taskLatency, &metrics.TaskLatency, &tinytcp.PacketLatencyBuckets, labels)
+}
+
+func withLabels(labels []string, extraLabels []string) []string {
+	if len(extraLabels) == 0 {
+		return labels
+	}
+
+	return append(append([]string(nil), labels...), extraLabels...)
+}
+
+func equalLabelValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
 	}
+
+	return true
+}
+
+func durationHistogram(
+	desc *prometheus.Desc,
+	histogram *tinytcp.Histogram[time.Duration],
+	bounds *[len(tinytcp.PacketLatencyBuckets)]time.Duration,
+	labels []string,
+) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(bounds)-1)
+	var cumulative uint64
+
+	// the last bucket is unbounded, it's represented by the implicit +Inf bucket
+	for i := 0; i < len(bounds)-1; i++ {
+		cumulative += histogram.Counts[i]
+		buckets[bounds[i].Seconds()] = cumulative
+	}
+
+	return prometheus.MustNewConstHistogram(desc, histogram.Count, histogram.Sum.Seconds(), buckets, labels...)
+}
+
+func sizeHistogram(
+	desc *prometheus.Desc,
+	histogram *tinytcp.Histogram[uint64],
+	bounds *[len(tinytcp.PacketSizeBuckets)]uint64,
+	labels []string,
+) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(bounds)-1)
+	var cumulative uint64
+
+	// the last bucket is unbounded, it's represented by the implicit +Inf bucket
+	for i := 0; i < len(bounds)-1; i++ {
+		cumulative += histogram.Counts[i]
+		buckets[float64(bounds[i])] = cumulative
+	}
+
+	return prometheus.MustNewConstHistogram(desc, histogram.Count, float64(histogram.Sum), buckets, labels...)
 }
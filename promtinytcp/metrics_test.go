@@ -0,0 +1,88 @@
+package promtinytcp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	// given
+	registry := prometheus.NewRegistry()
+	handler := NewHandler(registry, &Config{
+		Namespace:   "tcp",
+		ConstLabels: prometheus.Labels{"server": "test"},
+	})
+
+	var latency tinytcp.Histogram[time.Duration]
+	latency.Counts[0] = 2
+	latency.Count = 2
+	latency.Sum = 10 * time.Microsecond
+
+	// when
+	handler(tinytcp.ServerMetrics{
+		TotalRead:           1024,
+		TotalClosedByClient: 3,
+		PacketLatency:       latency,
+	})
+	families, err := registry.Gather()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	byName := make(map[string]int)
+	for i, family := range families {
+		byName[family.GetName()] = i
+	}
+
+	read := families[byName["tcp_read_bytes_total"]]
+	assert.Equal(t, 1024.0, read.GetMetric()[0].GetCounter().GetValue(), "read bytes should match")
+	assert.Equal(t, "test", read.GetMetric()[0].GetLabel()[0].GetValue(), "const label should be attached")
+
+	closed := families[byName["tcp_connections_closed_total"]]
+	assert.Len(t, closed.GetMetric(), 2, "both close reasons should be exported")
+
+	histogram := families[byName["tcp_packet_latency_seconds"]].GetMetric()[0].GetHistogram()
+	assert.Equal(t, uint64(2), histogram.GetSampleCount(), "sample count should match")
+	assert.Equal(t, uint64(2), histogram.GetBucket()[0].GetCumulativeCount(), "first bucket should match")
+}
+
+func TestCollectorMultipleServers(t *testing.T) {
+	// given
+	registry := prometheus.NewRegistry()
+	collector := NewCollector(registry, []string{"listener"})
+
+	first := collector.Handler("0.0.0.0:7000")
+	second := collector.Handler("0.0.0.0:7001")
+
+	// when
+	first(tinytcp.ServerMetrics{Connections: 1})
+	second(tinytcp.ServerMetrics{Connections: 2})
+	families, err := registry.Gather()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	var connections map[string]float64
+	for _, family := range families {
+		if family.GetName() != "connections" {
+			continue
+		}
+
+		connections = make(map[string]float64)
+		for _, metric := range family.GetMetric() {
+			connections[metric.GetLabel()[0].GetValue()] = metric.GetGauge().GetValue()
+		}
+	}
+
+	assert.Equal(
+		t,
+		map[string]float64{"0.0.0.0:7000": 1, "0.0.0.0:7001": 2},
+		connections,
+		"servers should be exported separately",
+	)
+	assert.Panics(t, func() { collector.Handler() }, "missing label values should panic")
+}
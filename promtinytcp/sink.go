@@ -0,0 +1,111 @@
+package promtinytcp
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type sink struct {
+	config     *Config
+	registerer prometheus.Registerer
+
+	counters   map[string]prometheus.Counter
+	gauges     map[string]prometheus.Gauge
+	histograms map[string]prometheus.Histogram
+	m          sync.RWMutex
+}
+
+// NewSink creates a tinytcp.MetricsSink exposing the reported metrics to the given prometheus.Registerer.
+// It can be registered using Server.MetricsSink method. Metrics are created and registered lazily, the first time
+// they're reported. Counters are suffixed with "_total". Histograms use the default Prometheus buckets.
+// Names of the metrics overlap with the ones exported by NewHandler, so both shouldn't use the same registry
+// without a different Namespace or Subsystem.
+func NewSink(registerer prometheus.Registerer, config ...*Config) tinytcp.MetricsSink {
+	c := &Config{}
+	if config != nil {
+		c = config[0]
+	}
+
+	return &sink{
+		config:     c,
+		registerer: registerer,
+		counters:   make(map[string]prometheus.Counter),
+		gauges:     make(map[string]prometheus.Gauge),
+		histograms: make(map[string]prometheus.Histogram),
+	}
+}
+
+func (s *sink) Counter(name string, delta uint64) {
+	counter := getOrCreate(s, s.counters, name, func() prometheus.Counter {
+		return prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   s.config.Namespace,
+			Subsystem:   s.config.Subsystem,
+			Name:        name + "_total",
+			Help:        "tinytcp counter " + name + ".",
+			ConstLabels: s.config.ConstLabels,
+		})
+	})
+
+	counter.Add(float64(delta))
+}
+
+func (s *sink) Gauge(name string, value float64) {
+	gauge := getOrCreate(s, s.gauges, name, func() prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   s.config.Namespace,
+			Subsystem:   s.config.Subsystem,
+			Name:        name,
+			Help:        "tinytcp gauge " + name + ".",
+			ConstLabels: s.config.ConstLabels,
+		})
+	})
+
+	gauge.Set(value)
+}
+
+func (s *sink) Histogram(name string, value float64) {
+	histogram := getOrCreate(s, s.histograms, name, func() prometheus.Histogram {
+		return prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   s.config.Namespace,
+			Subsystem:   s.config.Subsystem,
+			Name:        name,
+			Help:        "tinytcp histogram " + name + ".",
+			ConstLabels: s.config.ConstLabels,
+		})
+	})
+
+	histogram.Observe(value)
+}
+
+func getOrCreate[T prometheus.Collector](s *sink, metrics map[string]T, name string, create func() T) T {
+	s.m.RLock()
+	metric, ok := metrics[name]
+	s.m.RUnlock()
+
+	if ok {
+		return metric
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if metric, ok = metrics[name]; ok {
+		return metric
+	}
+
+	metric = create()
+	if err := s.registerer.Register(metric); err != nil {
+		var alreadyRegistered prometheus.AlreadyRegisteredError
+		if errors.As(err, &alreadyRegistered) {
+			if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+				metric = existing
+			}
+		}
+	}
+
+	metrics[name] = metric
+	return metric
+}
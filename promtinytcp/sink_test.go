@@ -0,0 +1,39 @@
+package promtinytcp
+
+import (
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSink(t *testing.T) {
+	// given
+	registry := prometheus.NewRegistry()
+	sink := NewSink(registry, &Config{Namespace: "tcp"})
+
+	// when
+	sink.Counter(tinytcp.MetricConnectionsAccepted, 1)
+	sink.Counter(tinytcp.MetricConnectionsAccepted, 2)
+	sink.Gauge(tinytcp.MetricConnections, 5)
+	sink.Histogram(tinytcp.MetricConnectionDuration, 0.5)
+	families, err := registry.Gather()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+
+	byName := make(map[string]int)
+	for i, family := range families {
+		byName[family.GetName()] = i
+	}
+
+	accepted := families[byName["tcp_connections_accepted_total"]]
+	assert.Equal(t, 3.0, accepted.GetMetric()[0].GetCounter().GetValue(), "counter should match")
+
+	connections := families[byName["tcp_connections"]]
+	assert.Equal(t, 5.0, connections.GetMetric()[0].GetGauge().GetValue(), "gauge should match")
+
+	duration := families[byName["tcp_connection_duration_seconds"]]
+	assert.Equal(t, uint64(1), duration.GetMetric()[0].GetHistogram().GetSampleCount(), "histogram should match")
+}
@@ -0,0 +1,143 @@
+package tinytcp
+
+import (
+	"errors"
+)
+
+// ProtocolState is a single state of the ProtocolFSM, eg. handshake, authentication or streaming.
+type ProtocolState struct {
+	// Name identifies the state in transitions.
+	Name string
+
+	// Framing is a FramingProtocol used to extract packets while in this state.
+	Framing FramingProtocol
+
+	// Handler is called for each packet received while in this state.
+	// It can switch the connection to another state with ProtocolContext.Transition.
+	Handler func(ctx *ProtocolContext, packet []byte)
+
+	// OnEnter is an optional function called when the connection enters this state.
+	OnEnter func(ctx *ProtocolContext)
+}
+
+// ProtocolFSM describes a protocol consisting of multiple phases, each using its own framing and packet handler.
+// Every connection starts in the first state and moves between the states by explicit transitions.
+// Transition takes effect right after the current packet is handled, so the data already received
+// is extracted with the framing of the new state.
+type ProtocolFSM struct {
+	states  map[string]*ProtocolState
+	initial *ProtocolState
+}
+
+// NewProtocolFSM creates new ProtocolFSM. The first state is the initial one. State names must be unique.
+func NewProtocolFSM(states ...ProtocolState) (*ProtocolFSM, error) {
+	if len(states) == 0 {
+		return nil, errors.New("no states defined")
+	}
+
+	fsm := &ProtocolFSM{
+		states: make(map[string]*ProtocolState, len(states)),
+	}
+
+	for i := range states {
+		state := states[i]
+
+		if state.Framing == nil || state.Handler == nil {
+			return nil, errors.New("state " + state.Name + " has no framing or handler")
+		}
+		if _, exists := fsm.states[state.Name]; exists {
+			return nil, errors.New("duplicate state " + state.Name)
+		}
+
+		fsm.states[state.Name] = &state
+		if fsm.initial == nil {
+			fsm.initial = &state
+		}
+	}
+
+	return fsm, nil
+}
+
+// Handler returns a SocketHandler running the state machine for each connection, on top of PacketFramingHandler.
+func (f *ProtocolFSM) Handler(config ...*PacketFramingConfig) SocketHandler {
+	var providedConfig *PacketFramingConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergePacketFramingConfig(providedConfig)
+
+	framer := newPacketFramer(nil, c)
+
+	return func(socket *Socket) {
+		ctx := &ProtocolContext{
+			fsm:    f,
+			socket: socket,
+		}
+		ctx.enter(f.initial)
+
+		socket.framing = true
+
+		framer.runWith(ctx, socket, ctx.handle, socket, func(err error) {
+			c.OnSocketError(socket, err)
+		})
+
+		socket.framing = false
+		socket.runHandoff()
+	}
+}
+
+// ProtocolContext holds the state of a single connection handled by ProtocolFSM.
+type ProtocolContext struct {
+	fsm    *ProtocolFSM
+	socket *Socket
+	state  *ProtocolState
+	value  any
+}
+
+// Socket returns the socket of the connection.
+func (c *ProtocolContext) Socket() *Socket {
+	return c.socket
+}
+
+// State returns the name of the current state.
+func (c *ProtocolContext) State() string {
+	return c.state.Name
+}
+
+// Value returns a value associated with the connection, eg. authenticated user.
+func (c *ProtocolContext) Value() any {
+	return c.value
+}
+
+// SetValue associates a value with the connection.
+func (c *ProtocolContext) SetValue(value any) {
+	c.value = value
+}
+
+// Transition switches the connection to the state with given name and calls its OnEnter function.
+func (c *ProtocolContext) Transition(name string) error {
+	state, ok := c.fsm.states[name]
+	if !ok {
+		return errors.New("unknown state " + name)
+	}
+
+	c.enter(state)
+	return nil
+}
+
+// ExtractPacket conforms to the FramingProtocol interface, delegating to the framing of the current state.
+func (c *ProtocolContext) ExtractPacket(source []byte) ([]byte, []byte, bool) {
+	return c.state.Framing.ExtractPacket(source)
+}
+
+func (c *ProtocolContext) handle(packet []byte) {
+	c.state.Handler(c, packet)
+}
+
+func (c *ProtocolContext) enter(state *ProtocolState) {
+	c.state = state
+
+	if state.OnEnter != nil {
+		state.OnEnter(c)
+	}
+}
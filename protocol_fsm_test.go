@@ -0,0 +1,94 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProtocolFSMTransition(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("HELLO\n\x05world\x03foo"))
+	out := &bytes.Buffer{}
+	socket := MockSocket(in, out)
+
+	var streamed []string
+	var entered []string
+
+	fsm, err := NewProtocolFSM(
+		ProtocolState{
+			Name:    "handshake",
+			Framing: SplitBySeparator([]byte{'\n'}),
+			Handler: func(ctx *ProtocolContext, packet []byte) {
+				assert.Equal(t, "HELLO", string(packet), "handshake must match")
+
+				ctx.SetValue("user")
+				_, _ = ctx.Socket().Write([]byte("OK\n"))
+				_ = ctx.Transition("streaming")
+			},
+		},
+		ProtocolState{
+			Name:    "streaming",
+			Framing: LengthPrefixedFraming(PrefixVarInt),
+			Handler: func(ctx *ProtocolContext, packet []byte) {
+				assert.Equal(t, "user", ctx.Value(), "value must be preserved")
+				streamed = append(streamed, string(packet))
+			},
+			OnEnter: func(ctx *ProtocolContext) {
+				entered = append(entered, ctx.State())
+			},
+		},
+	)
+	assert.Nil(t, err, "fsm should be created")
+
+	// when
+	fsm.Handler()(socket)
+
+	// then
+	assert.Equal(t, []string{"streaming"}, entered, "entered states must match")
+	assert.Equal(t, []string{"world", "foo"}, streamed, "streamed packets must match")
+	assert.Equal(t, "OK\n", out.String(), "response must match")
+}
+
+func TestProtocolFSMUnknownState(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("x\n"))
+	socket := MockSocket(in, &bytes.Buffer{})
+
+	var transitionErr error
+
+	fsm, _ := NewProtocolFSM(
+		ProtocolState{
+			Name:    "initial",
+			Framing: SplitBySeparator([]byte{'\n'}),
+			Handler: func(ctx *ProtocolContext, _ []byte) {
+				transitionErr = ctx.Transition("missing")
+			},
+		},
+	)
+
+	// when
+	fsm.Handler()(socket)
+
+	// then
+	assert.NotNil(t, transitionErr, "transition should fail")
+}
+
+func TestProtocolFSMInvalidStates(t *testing.T) {
+	// given
+	state := ProtocolState{
+		Name:    "state",
+		Framing: SplitBySeparator([]byte{'\n'}),
+		Handler: func(_ *ProtocolContext, _ []byte) {},
+	}
+
+	// when
+	_, errEmpty := NewProtocolFSM()
+	_, errDuplicate := NewProtocolFSM(state, state)
+	_, errNoHandler := NewProtocolFSM(ProtocolState{Name: "state", Framing: state.Framing})
+
+	// then
+	assert.NotNil(t, errEmpty, "no states should fail")
+	assert.NotNil(t, errDuplicate, "duplicate states should fail")
+	assert.NotNil(t, errNoHandler, "state without handler should fail")
+}
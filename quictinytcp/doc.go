@@ -0,0 +1,7 @@
+/*
+Package quictinytcp allows running tinytcp servers over QUIC, using quic-go.
+Listener conforms to the tinytcp.Listener interface and turns every QUIC stream into a separate Socket,
+so the existing handlers (including framing) work without any changes. Dial opens a single stream on a new
+QUIC connection, to be used on the client side.
+*/
+package quictinytcp
@@ -0,0 +1,203 @@
+package quictinytcp
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+)
+
+// NextProto is the ALPN protocol negotiated by Listener and Dial when TLS configuration doesn't specify any.
+const NextProto = "tinytcp"
+
+// Listener is an implementation of tinytcp.Listener accepting QUIC connections. Each stream opened by the client
+// is accepted as a separate connection. Note that QUIC streams are only announced when the client sends some data,
+// so the server can't write to the stream before the client does.
+// Listener can be passed to the server with Server.Listener().
+type Listener struct {
+	address   string
+	tlsConfig *tls.Config
+	config    *quic.Config
+
+	listener *quic.Listener
+	streams  chan net.Conn
+	closed   chan struct{}
+	m        sync.Mutex
+}
+
+// NewListener creates new Listener bound to given UDP address. TLS configuration is mandatory in QUIC
+// and must contain the server certificate. Config is an optional configuration of quic-go.
+func NewListener(address string, tlsConfig *tls.Config, config ...*quic.Config) *Listener {
+	var c *quic.Config
+	if config != nil {
+		c = config[0]
+	}
+
+	closed := make(chan struct{})
+	close(closed)
+
+	return &Listener{
+		address:   address,
+		tlsConfig: withNextProto(tlsConfig),
+		config:    c,
+		streams:   make(chan net.Conn),
+		closed:    closed,
+	}
+}
+
+// Listen conforms to the tinytcp.Listener interface.
+func (l *Listener) Listen() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	listener, err := quic.ListenAddr(l.address, l.tlsConfig, l.config)
+	if err != nil {
+		return err
+	}
+
+	l.listener = listener
+	l.closed = make(chan struct{})
+
+	go l.acceptConnections(listener, l.closed)
+
+	return nil
+}
+
+// Accept conforms to the net.Listener interface.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.streams:
+		return conn, nil
+	case <-l.closedChannel():
+		return nil, net.ErrClosed
+	}
+}
+
+// Addr conforms to the net.Listener interface.
+func (l *Listener) Addr() net.Addr {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if l.listener == nil {
+		return &net.UDPAddr{}
+	}
+
+	return l.listener.Addr()
+}
+
+// Close conforms to the net.Listener interface. Closing the listener closes all the QUIC connections.
+func (l *Listener) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	select {
+	case <-l.closed:
+		return nil
+	default:
+		close(l.closed)
+	}
+
+	return l.listener.Close()
+}
+
+func (l *Listener) acceptConnections(listener *quic.Listener, closed chan struct{}) {
+	for {
+		connection, err := listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+
+		go l.acceptStreams(connection, closed)
+	}
+}
+
+func (l *Listener) acceptStreams(connection quic.Connection, closed chan struct{}) {
+	defer func() {
+		_ = connection.CloseWithError(0, "")
+	}()
+
+	for {
+		stream, err := connection.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+
+		select {
+		case l.streams <- &streamConn{Stream: stream, connection: connection}:
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (l *Listener) closedChannel() <-chan struct{} {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.closed
+}
+
+// Dial connects to the QUIC server and opens a single stream, returned as net.Conn.
+// Closing the returned connection immediately closes the whole QUIC connection, so any data not yet
+// acknowledged by the server might be lost.
+func Dial(ctx context.Context, address string, tlsConfig *tls.Config, config ...*quic.Config) (net.Conn, error) {
+	var c *quic.Config
+	if config != nil {
+		c = config[0]
+	}
+
+	connection, err := quic.DialAddr(ctx, address, withNextProto(tlsConfig), c)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := connection.OpenStreamSync(ctx)
+	if err != nil {
+		_ = connection.CloseWithError(0, "")
+		return nil, err
+	}
+
+	return &streamConn{Stream: stream, connection: connection, ownsConnection: true}, nil
+}
+
+// streamConn adapts QUIC stream to the net.Conn interface.
+type streamConn struct {
+	quic.Stream
+	connection     quic.Connection
+	ownsConnection bool
+}
+
+func (s *streamConn) Close() error {
+	s.Stream.CancelRead(0)
+	err := s.Stream.Close()
+
+	if s.ownsConnection {
+		return s.connection.CloseWithError(0, "")
+	}
+
+	return err
+}
+
+func (s *streamConn) LocalAddr() net.Addr {
+	return s.connection.LocalAddr()
+}
+
+func (s *streamConn) RemoteAddr() net.Addr {
+	return s.connection.RemoteAddr()
+}
+
+func withNextProto(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{}
+	}
+
+	if len(config.NextProtos) > 0 {
+		return config
+	}
+
+	config = config.Clone()
+	config.NextProtos = []string{NextProto}
+	return config
+}
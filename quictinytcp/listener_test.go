@@ -0,0 +1,94 @@
+package quictinytcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestListener(t *testing.T) {
+	// given
+	payload := []byte("Hello world!\n")
+
+	listener := NewListener("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{generateTestCertificate(t)},
+	})
+
+	server := tinytcp.NewServer("quic")
+	server.Listener(listener)
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(
+		tinytcp.PacketFramingHandler(
+			tinytcp.SplitBySeparator([]byte{'\n'}),
+			func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+				return func(packet []byte) {
+					_, _ = socket.Write(append(packet, '\n'))
+				}
+			},
+		),
+	))
+
+	started := make(chan struct{})
+	server.OnStart(func() {
+		close(started)
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// when
+	conn, err := Dial(ctx, listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	_, writeErr := conn.Write(payload)
+	buffer := make([]byte, len(payload))
+	_, readErr := io.ReadFull(conn, buffer)
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, payload, buffer, "payloads should match")
+}
+
+func generateTestCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}
+}
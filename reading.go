@@ -5,12 +5,17 @@ import (
 	"errors"
 	"io"
 	"math"
+	"net"
+	"time"
 )
 
+// All the functions below read exactly as many bytes as needed, even if the data arrives in chunks.
+// io.EOF is returned only if no bytes were read, io.ErrUnexpectedEOF is returned if the value was read partially.
+
 // ReadByte reads byte from given reader.
 func ReadByte(reader io.Reader) (byte, error) {
 	var buff [1]byte
-	_, err := reader.Read(buff[:])
+	_, err := io.ReadFull(reader, buff[:])
 	if err != nil {
 		return 0, err
 	}
@@ -31,7 +36,7 @@ func ReadBool(reader io.Reader) (bool, error) {
 // ReadInt16 reads int16 from given reader.
 func ReadInt16(reader io.Reader, byteOrder ...binary.ByteOrder) (int16, error) {
 	var buff [2]byte
-	_, err := reader.Read(buff[:])
+	_, err := io.ReadFull(reader, buff[:])
 	if err != nil {
 		return 0, err
 	}
@@ -47,7 +52,7 @@ func ReadInt16(reader io.Reader, byteOrder ...binary.ByteOrder) (int16, error) {
 // ReadInt32 reads int32 from given reader.
 func ReadInt32(reader io.Reader, byteOrder ...binary.ByteOrder) (int32, error) {
 	var buff [4]byte
-	_, err := reader.Read(buff[:])
+	_, err := io.ReadFull(reader, buff[:])
 	if err != nil {
 		return 0, err
 	}
@@ -63,7 +68,7 @@ func ReadInt32(reader io.Reader, byteOrder ...binary.ByteOrder) (int32, error) {
 // ReadInt64 reads int64 from given reader.
 func ReadInt64(reader io.Reader, byteOrder ...binary.ByteOrder) (int64, error) {
 	var buff [8]byte
-	_, err := reader.Read(buff[:])
+	_, err := io.ReadFull(reader, buff[:])
 	if err != nil {
 		return 0, err
 	}
@@ -76,6 +81,36 @@ func ReadInt64(reader io.Reader, byteOrder ...binary.ByteOrder) (int64, error) {
 	return int64(order.Uint64(buff[:])), nil
 }
 
+// ReadUint16 reads uint16 from given reader.
+func ReadUint16(reader io.Reader, byteOrder ...binary.ByteOrder) (uint16, error) {
+	value, err := ReadInt16(reader, byteOrder...)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint16(value), nil
+}
+
+// ReadUint32 reads uint32 from given reader.
+func ReadUint32(reader io.Reader, byteOrder ...binary.ByteOrder) (uint32, error) {
+	value, err := ReadInt32(reader, byteOrder...)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(value), nil
+}
+
+// ReadUint64 reads uint64 from given reader.
+func ReadUint64(reader io.Reader, byteOrder ...binary.ByteOrder) (uint64, error) {
+	value, err := ReadInt64(reader, byteOrder...)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint64(value), nil
+}
+
 // ReadVarInt reads var int from given reader.
 func ReadVarInt(reader io.Reader) (int, error) {
 	var value int
@@ -84,6 +119,11 @@ func ReadVarInt(reader io.Reader) (int, error) {
 	for {
 		currentByte, err := ReadByte(reader)
 		if err != nil {
+			if err == io.EOF && position > 0 {
+				// value was truncated in the middle
+				err = io.ErrUnexpectedEOF
+			}
+
 			return 0, err
 		}
 
@@ -111,6 +151,11 @@ func ReadVarLong(reader io.Reader) (int64, error) {
 	for {
 		currentByte, err := ReadByte(reader)
 		if err != nil {
+			if err == io.EOF && position > 0 {
+				// value was truncated in the middle
+				err = io.ErrUnexpectedEOF
+			}
+
 			return 0, err
 		}
 
@@ -130,6 +175,79 @@ func ReadVarLong(reader io.Reader) (int64, error) {
 	return value, nil
 }
 
+// ReadSVarInt reads zigzag-encoded var int from given reader (compatible with protobuf sint32).
+// Unlike ReadVarInt, small negative values are encoded using a few bytes only.
+func ReadSVarInt(reader io.Reader) (int32, error) {
+	value, err := ReadVarInt(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	u := uint32(value)
+	return int32(u>>1) ^ -int32(u&1), nil
+}
+
+// ReadSVarLong reads zigzag-encoded var long from given reader (compatible with protobuf sint64).
+// Unlike ReadVarLong, small negative values are encoded using a few bytes only.
+func ReadSVarLong(reader io.Reader) (int64, error) {
+	value, err := ReadVarLong(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	u := uint64(value)
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+// ReadTime reads time encoded as int64 number of units elapsed since Unix epoch (eg. time.Millisecond).
+// Unit must be a divisor of time.Second. Returned time is in UTC.
+func ReadTime(reader io.Reader, unit time.Duration, byteOrder ...binary.ByteOrder) (time.Time, error) {
+	if unit <= 0 || time.Second%unit != 0 {
+		return time.Time{}, errors.New("invalid time unit")
+	}
+
+	value, err := ReadInt64(reader, byteOrder...)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	perSecond := int64(time.Second / unit)
+	return time.Unix(value/perSecond, (value%perSecond)*int64(unit)).UTC(), nil
+}
+
+// ReadUUID reads 16-byte UUID from given reader.
+func ReadUUID(reader io.Reader) ([16]byte, error) {
+	var value [16]byte
+	_, err := io.ReadFull(reader, value[:])
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	return value, nil
+}
+
+// ReadIP reads IP address encoded on 16 bytes from given reader. IPv4 addresses are expected to be IPv4-mapped.
+func ReadIP(reader io.Reader) (net.IP, error) {
+	value := make(net.IP, net.IPv6len)
+	_, err := io.ReadFull(reader, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// ReadIPv4 reads IPv4 address encoded on 4 bytes from given reader.
+func ReadIPv4(reader io.Reader) (net.IP, error) {
+	var buff [net.IPv4len]byte
+	_, err := io.ReadFull(reader, buff[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return net.IPv4(buff[0], buff[1], buff[2], buff[3]), nil
+}
+
 // ReadFloat32 reads float32 from given reader.
 func ReadFloat32(reader io.Reader, byteOrder ...binary.ByteOrder) (float32, error) {
 	value, err := ReadInt32(reader, byteOrder...)
@@ -149,3 +267,288 @@ func ReadFloat64(reader io.Reader, byteOrder ...binary.ByteOrder) (float64, erro
 
 	return math.Float64frombits(uint64(value)), nil
 }
+
+// MaxByteArrayLength is a default limit of the length of byte arrays and strings read by ReadByteArray and ReadString.
+// It protects against peers claiming huge lengths in order to exhaust server memory.
+// Use ReadByteArrayMax or ReadStringMax to specify the limit explicitly.
+var MaxByteArrayLength = 1024 * 1024 // 1 MiB
+
+// ErrLengthExceeded is returned when the length of a byte array or a string exceeds the specified limit.
+var ErrLengthExceeded = errors.New("length exceeds the limit")
+
+// ReadByteArray reads VarInt-prefixed byte array from given reader.
+// Length of the array is limited by MaxByteArrayLength.
+func ReadByteArray(reader io.Reader) ([]byte, error) {
+	return ReadByteArrayMax(reader, MaxByteArrayLength)
+}
+
+// ReadByteArrayMax reads VarInt-prefixed byte array from given reader.
+// ErrLengthExceeded is returned if the length of the array is greater than max, before allocating any memory.
+func ReadByteArrayMax(reader io.Reader, max int) ([]byte, error) {
+	return ReadPrefixedByteArrayMax(reader, PrefixVarInt, max)
+}
+
+// ReadString reads VarInt-prefixed string from given reader.
+// Length of the string is limited by MaxByteArrayLength.
+func ReadString(reader io.Reader) (string, error) {
+	return ReadStringMax(reader, MaxByteArrayLength)
+}
+
+// ReadStringMax reads VarInt-prefixed string from given reader.
+// ErrLengthExceeded is returned if the length of the string in bytes is greater than max.
+func ReadStringMax(reader io.Reader, max int) (string, error) {
+	value, err := ReadByteArrayMax(reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+// ReadPrefixedByteArray reads byte array prefixed with its length encoded as specified by prefix.
+// Length of the array is limited by MaxByteArrayLength.
+func ReadPrefixedByteArray(reader io.Reader, prefix PrefixType) ([]byte, error) {
+	return ReadPrefixedByteArrayMax(reader, prefix, MaxByteArrayLength)
+}
+
+// ReadPrefixedByteArrayMax reads byte array prefixed with its length encoded as specified by prefix.
+// ErrLengthExceeded is returned if the length of the array is greater than max, before allocating any memory.
+func ReadPrefixedByteArrayMax(reader io.Reader, prefix PrefixType, max int) ([]byte, error) {
+	length, err := readLength(reader, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if length < 0 {
+		return nil, errors.New("invalid length of byte array")
+	}
+	if length > int64(max) {
+		return nil, ErrLengthExceeded
+	}
+
+	value := make([]byte, length)
+	_, err = io.ReadFull(reader, value)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// ReadPrefixedString reads string prefixed with its length encoded as specified by prefix.
+// Length of the string is limited by MaxByteArrayLength.
+func ReadPrefixedString(reader io.Reader, prefix PrefixType) (string, error) {
+	value, err := ReadPrefixedByteArray(reader, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	return string(value), nil
+}
+
+// ReadCString reads NUL-terminated string from given reader. Terminating NUL is not included in the result.
+// Length of the string is limited by MaxByteArrayLength.
+// Reader is consumed byte by byte, so it should be buffered.
+func ReadCString(reader io.Reader) (string, error) {
+	var value []byte
+
+	for {
+		b, err := ReadByte(reader)
+		if err != nil {
+			if err == io.EOF && value != nil {
+				return "", io.ErrUnexpectedEOF
+			}
+
+			return "", err
+		}
+
+		if b == 0 {
+			break
+		}
+
+		if len(value) >= MaxByteArrayLength {
+			return "", ErrLengthExceeded
+		}
+
+		value = append(value, b)
+	}
+
+	return string(value), nil
+}
+
+func readLength(reader io.Reader, prefix PrefixType) (int64, error) {
+	switch prefix {
+	case PrefixVarInt:
+		value, err := ReadVarInt(reader)
+		return int64(value), err
+	case PrefixVarLong:
+		return ReadVarLong(reader)
+	case PrefixInt16_BE:
+		value, err := ReadUint16(reader, binary.BigEndian)
+		return int64(value), err
+	case PrefixInt16_LE:
+		value, err := ReadUint16(reader, binary.LittleEndian)
+		return int64(value), err
+	case PrefixInt32_BE:
+		value, err := ReadUint32(reader, binary.BigEndian)
+		return int64(value), err
+	case PrefixInt32_LE:
+		value, err := ReadUint32(reader, binary.LittleEndian)
+		return int64(value), err
+	case PrefixInt64_BE:
+		return ReadInt64(reader, binary.BigEndian)
+	case PrefixInt64_LE:
+		return ReadInt64(reader, binary.LittleEndian)
+	}
+
+	return 0, errors.New("invalid prefix type")
+}
+
+// ReadBitSet reads a set of size bits packed into ceil(size/8) bytes, starting from the least significant bit
+// of the first byte.
+func ReadBitSet(reader io.Reader, size int) ([]bool, error) {
+	if size < 0 {
+		return nil, errors.New("invalid size of bit set")
+	}
+
+	buff := make([]byte, (size+7)/8)
+	_, err := io.ReadFull(reader, buff)
+	if err != nil {
+		return nil, err
+	}
+
+	bits := make([]bool, size)
+	for i := range bits {
+		bits[i] = buff[i/8]&(1<<(i%8)) != 0
+	}
+
+	return bits, nil
+}
+
+// ReadFlags8 reads 8-bit flags from given reader.
+func ReadFlags8(reader io.Reader) (Flags, error) {
+	value, err := ReadByte(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	return Flags(value), nil
+}
+
+// ReadFlags16 reads 16-bit flags from given reader.
+func ReadFlags16(reader io.Reader, byteOrder ...binary.ByteOrder) (Flags, error) {
+	value, err := ReadUint16(reader, byteOrder...)
+	if err != nil {
+		return 0, err
+	}
+
+	return Flags(value), nil
+}
+
+// ReadFlags32 reads 32-bit flags from given reader.
+func ReadFlags32(reader io.Reader, byteOrder ...binary.ByteOrder) (Flags, error) {
+	value, err := ReadUint32(reader, byteOrder...)
+	if err != nil {
+		return 0, err
+	}
+
+	return Flags(value), nil
+}
+
+// ReadSlice reads a slice of values, each decoded by decode function, prefixed with the number of elements.
+// Prefix is VarInt by default. Number of elements is limited by MaxByteArrayLength.
+func ReadSlice[T any](
+	reader io.Reader,
+	decode func(io.Reader) (T, error),
+	prefix ...PrefixType,
+) ([]T, error) {
+	length, err := readCount(reader, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]T, 0, preallocatedCount(length))
+	for i := 0; i < length; i++ {
+		value, err := decode(reader)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// ReadMap reads a map of key-value pairs, decoded by decodeKey and decodeValue functions,
+// prefixed with the number of pairs. Prefix is VarInt by default. Number of pairs is limited by MaxByteArrayLength.
+func ReadMap[K comparable, V any](
+	reader io.Reader,
+	decodeKey func(io.Reader) (K, error),
+	decodeValue func(io.Reader) (V, error),
+	prefix ...PrefixType,
+) (map[K]V, error) {
+	length, err := readCount(reader, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[K]V, preallocatedCount(length))
+	for i := 0; i < length; i++ {
+		key, err := decodeKey(reader)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		value, err := decodeValue(reader)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+func readCount(reader io.Reader, prefix []PrefixType) (int, error) {
+	p := PrefixVarInt
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
+
+	length, err := readLength(reader, p)
+	if err != nil {
+		return 0, err
+	}
+
+	if length < 0 {
+		return 0, errors.New("invalid number of elements")
+	}
+	if length > int64(MaxByteArrayLength) {
+		return 0, ErrLengthExceeded
+	}
+
+	return int(length), nil
+}
+
+// preallocatedCount limits the memory allocated up front, before the elements are actually read.
+func preallocatedCount(length int) int {
+	if length > 1024 {
+		return 1024
+	}
+
+	return length
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
@@ -2,8 +2,14 @@ package tinytcp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"math"
+	"net"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 func TestReadByte(t *testing.T) {
@@ -185,3 +191,355 @@ func TestReadFloat64(t *testing.T) {
 
 	assert.Equal(t, value, readValue, "values should match")
 }
+
+func TestReadChunked(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteInt16(&buffer, 0x0102)
+	_ = WriteInt32(&buffer, 0x01020304)
+	_ = WriteInt64(&buffer, 0x0102030405060708)
+	_ = WriteFloat64(&buffer, 3.14)
+
+	reader := iotest.OneByteReader(&buffer)
+
+	// when
+	int16Value, int16Err := ReadInt16(reader)
+	int32Value, int32Err := ReadInt32(reader)
+	int64Value, int64Err := ReadInt64(reader)
+	float64Value, float64Err := ReadFloat64(reader)
+
+	// then
+	assert.Nil(t, int16Err, "int16 err should be nil")
+	assert.Equal(t, int16(0x0102), int16Value, "int16 values should match")
+	assert.Nil(t, int32Err, "int32 err should be nil")
+	assert.Equal(t, int32(0x01020304), int32Value, "int32 values should match")
+	assert.Nil(t, int64Err, "int64 err should be nil")
+	assert.Equal(t, int64(0x0102030405060708), int64Value, "int64 values should match")
+	assert.Nil(t, float64Err, "float64 err should be nil")
+	assert.Equal(t, 3.14, float64Value, "float64 values should match")
+}
+
+func TestReadPartial(t *testing.T) {
+	// given
+	reader := bytes.NewReader([]byte{0x01, 0x02})
+
+	// when
+	_, partialErr := ReadInt32(reader)
+	_, eofErr := ReadInt32(reader)
+
+	// then
+	assert.Equal(t, io.ErrUnexpectedEOF, partialErr, "partial read should return io.ErrUnexpectedEOF")
+	assert.Equal(t, io.EOF, eofErr, "empty read should return io.EOF")
+}
+
+func TestReadVarIntPartial(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteVarInt(&buffer, 12345)
+	_ = WriteVarLong(&buffer, 12345)
+	encoded := buffer.Bytes()
+
+	varIntReader := iotest.OneByteReader(bytes.NewReader(encoded[:1]))
+	varLongReader := iotest.OneByteReader(bytes.NewReader(encoded[2:3]))
+
+	// when
+	_, varIntErr := ReadVarInt(varIntReader)
+	_, varLongErr := ReadVarLong(varLongReader)
+	_, eofErr := ReadVarInt(varIntReader)
+
+	// then
+	assert.Equal(t, io.ErrUnexpectedEOF, varIntErr, "truncated VarInt should return io.ErrUnexpectedEOF")
+	assert.Equal(t, io.ErrUnexpectedEOF, varLongErr, "truncated VarLong should return io.ErrUnexpectedEOF")
+	assert.Equal(t, io.EOF, eofErr, "empty read should return io.EOF")
+}
+
+func TestReadString(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+
+	value := "Hello world"
+
+	// when then
+	err := WriteString(&buffer, value)
+	if err != nil {
+		assert.Nil(t, err, "write err should be nil")
+	}
+
+	readValue, err := ReadString(&buffer)
+	if err != nil {
+		assert.Nil(t, err, "read err should be nil")
+	}
+
+	assert.Equal(t, value, readValue, "values should match")
+}
+
+func TestReadByteArrayMax(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteVarInt(&buffer, 1<<30) // claimed length, no actual payload
+
+	// when
+	value, err := ReadByteArrayMax(&buffer, 1024)
+
+	// then
+	assert.ErrorIs(t, err, ErrLengthExceeded, "err should be ErrLengthExceeded")
+	assert.Nil(t, value, "value should be nil")
+}
+
+func TestReadUint(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteUint16(&buffer, 0xFFFE)
+	_ = WriteUint32(&buffer, 0xFFFFFFFE)
+	_ = WriteUint64(&buffer, 0xFFFFFFFFFFFFFFFE)
+
+	// when
+	uint16Value, uint16Err := ReadUint16(&buffer)
+	uint32Value, uint32Err := ReadUint32(&buffer)
+	uint64Value, uint64Err := ReadUint64(&buffer)
+
+	// then
+	assert.Nil(t, uint16Err, "uint16 err should be nil")
+	assert.Equal(t, uint16(0xFFFE), uint16Value, "uint16 values should match")
+	assert.Nil(t, uint32Err, "uint32 err should be nil")
+	assert.Equal(t, uint32(0xFFFFFFFE), uint32Value, "uint32 values should match")
+	assert.Nil(t, uint64Err, "uint64 err should be nil")
+	assert.Equal(t, uint64(0xFFFFFFFFFFFFFFFE), uint64Value, "uint64 values should match")
+}
+
+func TestReadSVarInt(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	values := []int32{0, -1, 1, -64, math.MaxInt32, math.MinInt32}
+
+	// when then
+	for _, value := range values {
+		err := WriteSVarInt(&buffer, value)
+		assert.Nil(t, err, "write err should be nil")
+	}
+
+	assert.Equal(t, byte(0x01), buffer.Bytes()[1], "-1 should be encoded as 1")
+
+	for _, value := range values {
+		readValue, err := ReadSVarInt(&buffer)
+		assert.Nil(t, err, "read err should be nil")
+		assert.Equal(t, value, readValue, "values should match")
+	}
+}
+
+func TestReadSVarLong(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	values := []int64{0, -1, 1, -64, math.MaxInt64, math.MinInt64}
+
+	// when then
+	for _, value := range values {
+		err := WriteSVarLong(&buffer, value)
+		assert.Nil(t, err, "write err should be nil")
+	}
+
+	for _, value := range values {
+		readValue, err := ReadSVarLong(&buffer)
+		assert.Nil(t, err, "read err should be nil")
+		assert.Equal(t, value, readValue, "values should match")
+	}
+}
+
+func TestReadPrefixedString(t *testing.T) {
+	// given
+	prefixes := []PrefixType{
+		PrefixVarInt,
+		PrefixVarLong,
+		PrefixInt16_BE,
+		PrefixInt16_LE,
+		PrefixInt32_BE,
+		PrefixInt32_LE,
+		PrefixInt64_BE,
+		PrefixInt64_LE,
+	}
+	value := "Hello world"
+
+	for _, prefix := range prefixes {
+		var buffer bytes.Buffer
+
+		// when
+		writeErr := WritePrefixedString(&buffer, value, prefix)
+		encodedLength := buffer.Len()
+		readValue, readErr := ReadPrefixedString(&buffer, prefix)
+
+		// then
+		assert.Nil(t, writeErr, "write err should be nil")
+		assert.Nil(t, readErr, "read err should be nil")
+		if prefix.Size() > 0 {
+			assert.Equal(t, len(value)+prefix.Size(), encodedLength, "encoded length should match")
+		}
+		assert.Equal(t, value, readValue, "values should match")
+	}
+}
+
+func TestReadCString(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteCString(&buffer, "Hello")
+	_ = WriteCString(&buffer, "")
+	buffer.WriteString("world")
+
+	// when
+	first, firstErr := ReadCString(&buffer)
+	second, secondErr := ReadCString(&buffer)
+	_, unterminatedErr := ReadCString(&buffer)
+	invalidErr := WriteCString(&buffer, "Hel\x00lo")
+
+	// then
+	assert.Nil(t, firstErr, "first err should be nil")
+	assert.Equal(t, "Hello", first, "first value should match")
+	assert.Nil(t, secondErr, "second err should be nil")
+	assert.Equal(t, "", second, "second value should be empty")
+	assert.Equal(t, io.ErrUnexpectedEOF, unterminatedErr, "unterminated string should return io.ErrUnexpectedEOF")
+	assert.NotNil(t, invalidErr, "string containing NUL should not be written")
+}
+
+func TestReadTime(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	value := time.Date(2023, 7, 14, 12, 30, 15, 123456789, time.UTC)
+	before := time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)
+
+	// when
+	_ = WriteTime(&buffer, value, time.Millisecond)
+	_ = WriteTime(&buffer, value, time.Nanosecond, binary.LittleEndian)
+	_ = WriteTime(&buffer, before, time.Millisecond)
+	millis, millisErr := ReadTime(&buffer, time.Millisecond)
+	nanos, nanosErr := ReadTime(&buffer, time.Nanosecond, binary.LittleEndian)
+	beforeValue, beforeErr := ReadTime(&buffer, time.Millisecond)
+
+	// then
+	assert.Nil(t, millisErr, "millis err should be nil")
+	assert.Equal(t, value.Truncate(time.Millisecond), millis, "millis values should match")
+	assert.Nil(t, nanosErr, "nanos err should be nil")
+	assert.Equal(t, value, nanos, "nanos values should match")
+	assert.Nil(t, beforeErr, "before epoch err should be nil")
+	assert.Equal(t, before, beforeValue, "before epoch values should match")
+}
+
+func TestReadUUID(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	value := [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+
+	// when
+	writeErr := WriteUUID(&buffer, value)
+	readValue, readErr := ReadUUID(&buffer)
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, value, readValue, "values should match")
+}
+
+func TestReadIP(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	ipv4 := net.ParseIP("192.168.1.10")
+	ipv6 := net.ParseIP("2001:db8::1")
+
+	// when
+	_ = WriteIP(&buffer, ipv4)
+	_ = WriteIP(&buffer, ipv6)
+	_ = WriteIPv4(&buffer, ipv4)
+	ipv6Err := WriteIPv4(&buffer, ipv6)
+	readIPv4, readIPv4Err := ReadIP(&buffer)
+	readIPv6, readIPv6Err := ReadIP(&buffer)
+	readShortIPv4, readShortIPv4Err := ReadIPv4(&buffer)
+
+	// then
+	assert.Nil(t, readIPv4Err, "ipv4 err should be nil")
+	assert.True(t, ipv4.Equal(readIPv4), "ipv4 values should match")
+	assert.Nil(t, readIPv6Err, "ipv6 err should be nil")
+	assert.True(t, ipv6.Equal(readIPv6), "ipv6 values should match")
+	assert.Nil(t, readShortIPv4Err, "short ipv4 err should be nil")
+	assert.True(t, ipv4.Equal(readShortIPv4), "short ipv4 values should match")
+	assert.NotNil(t, ipv6Err, "ipv6 should not be written as ipv4")
+}
+
+func TestReadSlice(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	values := []string{"a", "bb", "ccc"}
+
+	// when
+	writeErr := WriteSlice(&buffer, values, WriteString, PrefixInt16_BE)
+	readValues, readErr := ReadSlice(&buffer, ReadString, PrefixInt16_BE)
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, values, readValues, "values should match")
+}
+
+func TestReadSliceTruncated(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	_ = WriteVarInt(&buffer, 3)
+	_ = WriteVarInt(&buffer, 1)
+
+	// when
+	_, err := ReadSlice(&buffer, ReadVarInt)
+
+	// then
+	assert.Equal(t, io.ErrUnexpectedEOF, err, "err should be io.ErrUnexpectedEOF")
+}
+
+func TestReadMap(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	values := map[string]int{"a": 1, "b": 2, "c": 300}
+
+	// when
+	writeErr := WriteMap(&buffer, values, WriteString, WriteVarInt)
+	readValues, readErr := ReadMap(&buffer, ReadString, ReadVarInt)
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, values, readValues, "values should match")
+}
+
+func TestReadBitSet(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	bits := []bool{true, false, true, true, false, false, false, false, true, false}
+
+	// when
+	writeErr := WriteBitSet(&buffer, bits)
+	encoded := append([]byte(nil), buffer.Bytes()...)
+	readBits, readErr := ReadBitSet(&buffer, len(bits))
+
+	// then
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Equal(t, []byte{0x0D, 0x01}, encoded, "bits should be packed")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, bits, readBits, "values should match")
+}
+
+func TestReadFlags(t *testing.T) {
+	// given
+	var buffer bytes.Buffer
+	flags := NewFlags(true, false, false, true).Set(15, true)
+
+	// when
+	overflowErr := WriteFlags8(&buffer, flags)
+	writeErr := WriteFlags16(&buffer, flags, binary.LittleEndian)
+	readFlags, readErr := ReadFlags16(&buffer, binary.LittleEndian)
+
+	// then
+	assert.NotNil(t, overflowErr, "flags should not fit into 8 bits")
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, flags, readFlags, "values should match")
+	assert.True(t, readFlags.Has(0), "bit 0 should be set")
+	assert.False(t, readFlags.Has(1), "bit 1 should not be set")
+	assert.True(t, readFlags.Has(3), "bit 3 should be set")
+	assert.True(t, readFlags.Has(15), "bit 15 should be set")
+	assert.False(t, readFlags.Set(15, false).Has(15), "bit 15 should be cleared")
+}
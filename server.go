@@ -1,9 +1,12 @@
 package tinytcp
 
 import (
+	"context"
 	"errors"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Server represents a TCP server. Server is responsible for accepting new connections using Listener,
@@ -11,24 +14,76 @@ import (
 // This struct conforms to the Service interface.
 type Server struct {
 	config          *ServerConfig
+	configErr       error
 	address         string
 	listener        Listener
 	forkingStrategy ForkingStrategy
 	sockets         *socketsList
 	metrics         ServerMetrics
+	metricsSnapshot atomic.Pointer[ServerMetrics]
+	metricsStreams  metricsStreams
+	readWindows     rateWindows
+	writeWindows    rateWindows
 	housekeepingJob *housekeepingJob
+	jobs            map[string]*housekeepingJob
 
-	errorChannel chan error
-	isRunning    bool
+	peerMetrics *peerMetricsAggregator
+	tarpit      *tarpit
+	acceptQueue *acceptQueue
+	metricsSink MetricsSink
+
+	acceptedConnections  uint64
+	rejectedConnections  uint64
+	tarpittedConnections uint64
+
+	state        atomic.Int32
+	err          error
 	runningMutex sync.Mutex
-	abortOnce    sync.Once
+	stopped      chan struct{}
+
+	metricsUpdateHandler    func(ServerMetrics)
+	disconnectHandler       func(CloseReason, time.Duration)
+	socketSetupErrorHandler func(*SocketSetupError)
+	startHandler            func()
+	stopHandler             func()
+	abortHandler            func(error)
+}
+
+// ServerState represents a stage of the server lifecycle.
+type ServerState int32
+
+const (
+	// ServerStopped means the server has not been started yet, or it has been stopped.
+	ServerStopped ServerState = iota
+
+	// ServerStarting means the server is setting up its listener and jobs.
+	ServerStarting
 
-	metricsUpdateHandler func(ServerMetrics)
-	startHandler         func()
-	stopHandler          func()
+	// ServerRunning means the server is accepting connections.
+	ServerRunning
+
+	// ServerDraining means the server is being stopped. It no longer accepts connections and closes the existing ones.
+	ServerDraining
+)
+
+// String returns a name of the state.
+func (s ServerState) String() string {
+	switch s {
+	case ServerStopped:
+		return "stopped"
+	case ServerStarting:
+		return "starting"
+	case ServerRunning:
+		return "running"
+	case ServerDraining:
+		return "draining"
+	default:
+		return "unknown"
+	}
 }
 
-// NewServer returns new Server instance.
+// NewServer returns new Server instance. Provided config is validated (see ServerConfig.Validate), and Start()
+// fails with the validation errors, if there are any.
 func NewServer(address string, config ...*ServerConfig) *Server {
 	var providedConfig *ServerConfig
 	if config != nil {
@@ -37,17 +92,35 @@ func NewServer(address string, config ...*ServerConfig) *Server {
 	c := mergeServerConfig(providedConfig)
 
 	s := &Server{
-		config:               c,
-		address:              address,
-		listener:             newListener(address, c),
-		sockets:              newSocketsList(c.MaxClients),
-		errorChannel:         make(chan error, 1),
-		metricsUpdateHandler: func(_ ServerMetrics) {},
-		startHandler:         func() {},
-		stopHandler:          func() {},
+		config:                  c,
+		configErr:               providedConfig.Validate(),
+		address:                 address,
+		listener:                newListener(address, c),
+		sockets:                 newSocketsList(c.MaxClients, c.Clock),
+		jobs:                    make(map[string]*housekeepingJob),
+		metricsSink:             noopMetricsSink{},
+		metricsUpdateHandler:    func(_ ServerMetrics) {},
+		disconnectHandler:       func(_ CloseReason, _ time.Duration) {},
+		socketSetupErrorHandler: func(_ *SocketSetupError) {},
+		startHandler:            func() {},
+		stopHandler:             func() {},
+		abortHandler:            func(_ error) {},
 	}
 
-	s.housekeepingJob = newHousekeepingJob(c.TickInterval, s.housekeepingJobTick, s.housekeepingJobPanic)
+	s.sockets.writeBufferSize = c.WriteBufferSize
+	if c.Tarpit != nil {
+		s.tarpit = newTarpit(c.Tarpit, c.Clock)
+	}
+	if c.PeerMetricsLimit > 0 {
+		s.peerMetrics = newPeerMetricsAggregator(c.PeerMetricsLimit)
+	}
+	if c.AcceptQueueSize > 0 {
+		s.acceptQueue = newAcceptQueue(c.AcceptQueueSize, s.handleNewConnection, func() {
+			s.metricsSink.Counter(MetricAcceptQueueDropped, 1)
+		})
+	}
+
+	s.housekeepingJob = newHousekeepingJob(c.Clock, c.TickInterval, 0, s.housekeepingJobTick, s.housekeepingJobPanic)
 
 	return s
 }
@@ -57,7 +130,7 @@ func (s *Server) ForkingStrategy(forkingStrategy ForkingStrategy) {
 	s.runningMutex.Lock()
 	defer s.runningMutex.Unlock()
 
-	if s.isRunning {
+	if s.State() != ServerStopped {
 		return
 	}
 
@@ -69,21 +142,78 @@ func (s *Server) Listener(listener Listener) {
 	s.runningMutex.Lock()
 	defer s.runningMutex.Unlock()
 
-	if s.isRunning {
+	if s.State() != ServerStopped {
 		return
 	}
 
 	s.listener = listener
 }
 
+// State returns the current stage of the server lifecycle.
+func (s *Server) State() ServerState {
+	return ServerState(s.state.Load())
+}
+
+// Err returns the error passed to Abort() that has stopped the server, or nil if the server has been stopped
+// with Stop(). The value is cleared when the server is started again.
+func (s *Server) Err() error {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	return s.err
+}
+
 // Port returns a port number used by underlying Listener. Only returns a valid value after Start().
 func (s *Server) Port() int {
 	return resolveNetworkPort(s.listener.Addr())
 }
 
-// Metrics returns aggregated server metrics.
+// Metrics returns aggregated server metrics. Returned value is a consistent snapshot from the last metrics update.
 func (s *Server) Metrics() ServerMetrics {
-	return s.metrics
+	if snapshot := s.metricsSnapshot.Load(); snapshot != nil {
+		return *snapshot
+	}
+
+	return ServerMetrics{}
+}
+
+// MetricsStream subscribes to the server metrics, which are sent to the returned channel on every update.
+// Channel holds only the latest value, so slow consumers skip the stale updates instead of blocking the server.
+// Returned function cancels the subscription and closes the channel.
+func (s *Server) MetricsStream() (<-chan ServerMetrics, func()) {
+	return s.metricsStreams.subscribe()
+}
+
+// DumpConnections returns diagnostic information about all the connections currently held by the server.
+func (s *Server) DumpConnections() []ConnectionInfo {
+	connections := make([]ConnectionInfo, 0, s.sockets.Len())
+
+	s.sockets.Iterate(func(socket *Socket) {
+		connections = append(connections, socket.info())
+	})
+
+	return connections
+}
+
+// IterateRefs calls fn for each connection currently held by the server, eg. to read per-socket counters
+// from monitoring or admin code. Unlike the raw sockets, references passed to fn can be safely retained after fn
+// returns (see SocketRef). Each socket has a single reference, shared by all the calls. fn is called while holding
+// a lock on the list of connections, so it should be fast and must not call IterateRefs or DumpConnections.
+func (s *Server) IterateRefs(fn func(ref *SocketRef)) {
+	s.sockets.Iterate(func(socket *Socket) {
+		fn(socket.sharedRef())
+	})
+}
+
+// MetricsByPeer returns metrics aggregated per remote address, sorted from the most active peer.
+// Only the connected peers and the most active of the disconnected ones are kept, up to PeerMetricsLimit.
+// Returns nil if per-peer metrics are disabled (see ServerConfig.PeerMetricsLimit).
+func (s *Server) MetricsByPeer() []PeerMetrics {
+	if s.peerMetrics == nil {
+		return nil
+	}
+
+	return s.peerMetrics.Top()
 }
 
 // OnMetricsUpdate sets a handler that is called everytime the server metrics are updated.
@@ -91,22 +221,63 @@ func (s *Server) OnMetricsUpdate(handler func(ServerMetrics)) {
 	s.metricsUpdateHandler = handler
 }
 
+// OnDisconnect sets a handler that is called for each closed connection, with the reason of the close
+// and the lifetime of the connection. Handler is called by the housekeeping job, once the socket is recycled.
+func (s *Server) OnDisconnect(handler func(reason CloseReason, duration time.Duration)) {
+	s.disconnectHandler = handler
+}
+
+// OnSocketSetupError sets a handler that is called when applying the per-connection options configured
+// in ServerConfig (KeepAlive, DisableNoDelay, ReceiveBufferSize, SendBufferSize) to the accepted connection fails,
+// eg. due to platform-specific limits. Handler is called by the accept loop, once for each failed option.
+// The connection is served regardless of the error.
+func (s *Server) OnSocketSetupError(handler func(err *SocketSetupError)) {
+	s.socketSetupErrorHandler = handler
+}
+
+// MetricsSink sets a MetricsSink receiving metrics of the server as they're produced (see MetricsSink).
+// It can be set only while the server is stopped.
+func (s *Server) MetricsSink(sink MetricsSink) {
+	s.runningMutex.Lock()
+	defer s.runningMutex.Unlock()
+
+	if s.State() != ServerStopped {
+		return
+	}
+
+	s.metricsSink = sink
+}
+
 // OnStart sets a handler that is called when server starts.
 func (s *Server) OnStart(handler func()) {
 	s.startHandler = handler
 }
 
+// OnAbort sets a handler that is called with the error passed to Abort(), eg. the panic of the housekeeping job,
+// so the root cause can be logged. It's called right before the handler set with OnStop.
+// Sockets closed due to the abort receive CloseReasonAbort (see Socket.CloseError).
+func (s *Server) OnAbort(handler func(err error)) {
+	s.abortHandler = handler
+}
+
 // OnStop sets a handler that is called when server stops.
 func (s *Server) OnStop(handler func()) {
 	s.stopHandler = handler
 }
 
 // Start starts TCP server and blocks until Stop() or Abort() are called.
+// Server can be started again after it's stopped, but not while it's still running.
 func (s *Server) Start() error {
 	err := func() error {
 		s.runningMutex.Lock()
 		defer s.runningMutex.Unlock()
 
+		if s.configErr != nil {
+			return s.configErr
+		}
+		if s.State() != ServerStopped {
+			return errors.New("server is already running")
+		}
 		if s.listener == nil {
 			return errors.New("empty listener")
 		}
@@ -114,16 +285,29 @@ func (s *Server) Start() error {
 			return errors.New("empty forking strategy")
 		}
 
-		err := s.listener.Listen()
+		s.setState(ServerStarting)
+		s.err = nil
+		s.stopped = make(chan struct{})
+
+		err := listenWithRetry(s.listener, s.config.ListenRetry)
 		if err != nil {
+			s.setState(ServerStopped)
 			return err
 		}
 
+		s.sockets.Open()
 		s.housekeepingJob.Start()
+		for _, job := range s.jobs {
+			job.Start()
+		}
+
 		s.forkingStrategy.OnStart()
+		if s.acceptQueue != nil {
+			s.acceptQueue.Start()
+		}
 		s.startHandler()
 
-		s.isRunning = true
+		s.setState(ServerRunning)
 		return nil
 	}()
 
@@ -131,18 +315,105 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	return s.acceptLoop()
+	stopped := s.stopped
+	s.acceptLoop()
+
+	if s.State() == ServerRunning {
+		// listener has failed on its own
+		_ = s.stop(nil)
+	}
+
+	// listener is closed before the connections are drained (see Shutdown)
+	<-stopped
+	return s.Err()
 }
 
 // Stop immediately stops the server and unblocks the Start() method.
-func (s *Server) Stop() (err error) {
+func (s *Server) Stop() error {
+	return s.stop(nil)
+}
+
+// Abort immediately stops the server with error and unblocks the Start() method.
+// The error is returned by Start() and can be later retrieved with Err().
+func (s *Server) Abort(e error) error {
+	return s.stop(e)
+}
+
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown gracefully stops the server. It stops accepting new connections, notifies all the live sockets
+// (see Socket.Draining and Socket.OnDrain), so handlers can inform the clients (eg. with a GOAWAY-style message),
+// and waits for the connections to close. When ctx is done, remaining connections are closed forcibly,
+// like with Stop(). Start() is unblocked once the server is stopped.
+// Connections are polled every 10ms of ServerConfig.Clock, so with a fake clock Shutdown returns only after
+// the clock is advanced (see tinytcptest.FakeClock), even if ctx has no deadline and all the connections are closed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.runningMutex.Lock()
+
+	if s.State() != ServerRunning {
+		s.runningMutex.Unlock()
+		return nil
+	}
+	s.setState(ServerDraining)
+
+	var err error
+	if e := s.listener.Close(); e != nil {
+		if !isBrokenPipe(e) {
+			err = e
+		}
+	}
+
+	s.runningMutex.Unlock()
+
+	var sockets []*Socket
+	s.sockets.Iterate(func(socket *Socket) {
+		sockets = append(sockets, socket)
+	})
+
+	for _, socket := range sockets {
+		socket.drain()
+	}
+
+	timer := s.config.Clock.NewTimer(shutdownPollInterval)
+	defer timer.Stop()
+
+	for s.hasOpenSockets() {
+		select {
+		case <-timer.C():
+			timer.Reset(shutdownPollInterval)
+		case <-ctx.Done():
+			return errors.Join(err, s.stop(nil))
+		}
+	}
+
+	return errors.Join(err, s.stop(nil))
+}
+
+func (s *Server) hasOpenSockets() bool {
+	open := false
+
+	s.sockets.Iterate(func(socket *Socket) {
+		if !socket.IsClosed() {
+			open = true
+		}
+	})
+
+	return open
+}
+
+func (s *Server) stop(abortErr error) (err error) {
 	s.runningMutex.Lock()
 	defer s.runningMutex.Unlock()
 
-	if !s.isRunning {
+	state := s.State()
+	if state != ServerRunning && state != ServerDraining {
 		return
 	}
-	s.isRunning = false
+	s.setState(ServerDraining)
+	defer s.setState(ServerStopped)
+	defer close(s.stopped)
+
+	s.err = abortErr
 
 	if e := s.listener.Close(); e != nil {
 		if !isBrokenPipe(e) {
@@ -151,28 +422,37 @@ func (s *Server) Stop() (err error) {
 	}
 
 	s.housekeepingJob.Stop()
-	s.sockets.Reset()
+	for _, job := range s.jobs {
+		job.Stop()
+	}
+
+	// sockets are closed before waiting for the accept queue, as its goroutine might be blocked
+	// on one of them (eg. by InlineHandler)
+	if s.acceptQueue != nil {
+		s.acceptQueue.Close()
+	}
+	s.sockets.Reset(abortErr)
+	if s.acceptQueue != nil {
+		s.acceptQueue.Wait()
+	}
+	if s.tarpit != nil {
+		s.tarpit.Reset()
+	}
+
 	s.forkingStrategy.OnStop()
+	if abortErr != nil {
+		s.abortHandler(abortErr)
+	}
 	s.stopHandler()
 
 	return
 }
 
-// Abort immediately stops the server with error and unblocks the Start() method.
-func (s *Server) Abort(e error) (err error) {
-	s.abortOnce.Do(func() {
-		select {
-		case s.errorChannel <- e:
-		default:
-		}
-
-		err = s.Stop()
-	})
-
-	return
+func (s *Server) setState(state ServerState) {
+	s.state.Store(int32(state))
 }
 
-func (s *Server) acceptLoop() error {
+func (s *Server) acceptLoop() {
 	for {
 		connection, err := s.listener.Accept()
 		if err != nil {
@@ -180,56 +460,203 @@ func (s *Server) acceptLoop() error {
 				break
 			}
 
+			s.metricsSink.Counter(MetricAcceptErrors, 1)
 			continue
 		}
 
-		s.handleNewConnection(connection)
-	}
+		if s.acceptQueue != nil {
+			if !s.acceptQueue.Push(connection) {
+				_ = connection.Close()
+			}
 
-	select {
-	case err := <-s.errorChannel:
-		return err
-	default:
-		return nil
+			continue
+		}
+
+		s.handleNewConnection(connection)
 	}
 }
 
 func (s *Server) handleNewConnection(connection net.Conn) {
+	if s.tarpit != nil && s.tarpit.matches(connection) {
+		s.tarpitConnection(connection)
+		return
+	}
+
+	var labels map[string]string
+	if s.config.ClassifyPeer != nil {
+		var allow bool
+		if labels, allow = s.config.ClassifyPeer(connection.RemoteAddr()); !allow {
+			s.rejectConnection(connection)
+			return
+		}
+	}
+
+	if s.config.AdmissionController != nil && !s.config.AdmissionController.Admit(connection) {
+		s.rejectConnection(connection)
+		return
+	}
+
 	socket := s.sockets.New(connection)
 	if socket == nil {
+		s.rejectConnection(connection)
 		return
 	}
 
+	atomic.AddUint64(&s.acceptedConnections, 1)
+	s.metricsSink.Counter(MetricConnectionsAccepted, 1)
+
+	applySocketSetup(connection, s.config, s.socketSetupErrorHandler)
+
+	socket.verifyPeer = s.config.TLSVerifyPeer
+	socket.peerLabels = labels
+
 	s.forkingStrategy.OnAccept(socket)
 }
 
-func (s *Server) housekeepingJobTick() {
-	s.updateMetrics()
-	s.sockets.Cleanup()
+func (s *Server) rejectConnection(connection net.Conn) {
+	atomic.AddUint64(&s.rejectedConnections, 1)
+	s.metricsSink.Counter(MetricConnectionsRejected, 1)
+
+	if s.tarpit != nil && s.config.Tarpit.Rejected {
+		s.tarpitConnection(connection)
+		return
+	}
+
+	_ = connection.Close()
+}
+
+func (s *Server) tarpitConnection(connection net.Conn) {
+	if !s.tarpit.hold(connection) {
+		_ = connection.Close()
+		return
+	}
+
+	atomic.AddUint64(&s.tarpittedConnections, 1)
+	s.metricsSink.Counter(MetricConnectionsTarpitted, 1)
+}
+
+func (s *Server) housekeepingJobTick(elapsed time.Duration) {
+	s.updateMetrics(elapsed)
+	s.sockets.Cleanup(s.recordClosedSocket)
+
+	if s.tarpit != nil {
+		s.tarpit.ReleaseExpired(s.config.Clock.Now().UnixMilli())
+	}
 }
 
 func (s *Server) housekeepingJobPanic(err error) {
 	_ = s.Abort(err)
 }
 
-func (s *Server) updateMetrics() {
+func (s *Server) updateMetrics(elapsed time.Duration) {
 	var (
-		readsPerInterval  uint64
-		writesPerInterval uint64
+		readsPerInterval  = s.sockets.reads.Swap()
+		writesPerInterval = s.sockets.writes.Swap()
+		connectionAge     Histogram[time.Duration]
+		now               = s.config.Clock.Now().UTC().UnixMilli()
+		flushBefore       = now - s.config.WriteFlushDelay.Milliseconds()
 	)
 
+	if s.peerMetrics != nil {
+		s.peerMetrics.begin()
+	}
+
 	s.sockets.Iterate(func(socket *Socket) {
-		reads, writes := socket.updateMetrics(s.config.TickInterval)
-		readsPerInterval += reads
-		writesPerInterval += writes
+		socket.closeIfScheduled(now)
+		socket.flushIfDirty(flushBefore)
+
+		delta := socket.updateMetrics(elapsed, now)
+		if s.peerMetrics != nil {
+			s.peerMetrics.observe(socket.RemoteAddress(), socket.PeerLabels(), delta.reads, delta.writes)
+		}
+
+		s.metrics.PacketLatency.add(&delta.packetLatency)
+		s.metrics.PacketSize.add(&delta.packetSize)
+		s.recordReadySocket(socket)
+
+		age := time.Duration(now-socket.ConnectedAt()) * time.Millisecond
+		connectionAge.observe(&ConnectionAgeBuckets, age)
 	})
 
 	s.metrics.Connections = s.sockets.Len()
+	s.metrics.PeakConnections = s.sockets.Peak()
 	s.metrics.TotalRead += readsPerInterval
 	s.metrics.TotalWritten += writesPerInterval
-	s.metrics.ReadLastSecond = uint64(float64(readsPerInterval) / s.config.TickInterval.Seconds())
-	s.metrics.WrittenLastSecond = uint64(float64(writesPerInterval) / s.config.TickInterval.Seconds())
+	s.metrics.ReadLastSecond = uint64(float64(readsPerInterval) / elapsed.Seconds())
+	s.metrics.WrittenLastSecond = uint64(float64(writesPerInterval) / elapsed.Seconds())
+	s.readWindows.Update(float64(readsPerInterval)/elapsed.Seconds(), elapsed)
+	s.writeWindows.Update(float64(writesPerInterval)/elapsed.Seconds(), elapsed)
+	s.metrics.HousekeepingDuration = s.housekeepingJob.LastDuration()
+	s.metrics.HousekeepingOverruns = s.housekeepingJob.Overruns()
+	s.metrics.ReadRate = s.readWindows.Rate(s.metrics.ReadLastSecond)
+	s.metrics.WriteRate = s.writeWindows.Rate(s.metrics.WrittenLastSecond)
+	s.metrics.ConnectionAge = connectionAge
+	s.metrics.AverageConnectionDuration = s.metrics.ConnectionDuration.Mean()
+	s.metrics.TotalAccepted = atomic.LoadUint64(&s.acceptedConnections)
+	s.metrics.TotalRejected = atomic.LoadUint64(&s.rejectedConnections)
+	s.metrics.TotalTarpitted = atomic.LoadUint64(&s.tarpittedConnections)
+	if s.tarpit != nil {
+		s.metrics.Tarpitted = s.tarpit.Len()
+	}
+	if s.acceptQueue != nil {
+		s.metrics.AcceptQueueLength = s.acceptQueue.Len()
+		s.metrics.TotalAcceptQueueDropped = s.acceptQueue.Dropped()
+	}
+
+	if s.peerMetrics != nil {
+		s.peerMetrics.commit()
+	}
 
 	s.forkingStrategy.OnMetricsUpdate(&s.metrics)
-	s.metricsUpdateHandler(s.metrics)
+
+	reportMetrics(s.metricsSink, &s.metrics, readsPerInterval, writesPerInterval)
+
+	snapshot := s.metrics
+	s.metricsSnapshot.Store(&snapshot)
+	s.metricsStreams.publish(snapshot)
+
+	s.metricsUpdateHandler(snapshot)
+}
+
+// recordReadySocket is called by the housekeeping job for each socket, to record its accept-to-ready latency
+// and TLS handshake duration once it becomes ready. Metrics of each socket are recorded only once.
+func (s *Server) recordReadySocket(socket *Socket) {
+	readyLatency, handshakeDuration, ok := socket.takeReadyMetrics()
+	if !ok {
+		return
+	}
+
+	s.metrics.ReadyLatency.observe(&PacketLatencyBuckets, readyLatency)
+	if handshakeDuration > 0 {
+		s.metrics.HandshakeDuration.observe(&PacketLatencyBuckets, handshakeDuration)
+	}
+
+	reportReadySocket(s.metricsSink, readyLatency, handshakeDuration)
+}
+
+// recordClosedSocket is called by the housekeeping job for each closed socket, right before it's recycled.
+func (s *Server) recordClosedSocket(socket *Socket) {
+	s.recordReadySocket(socket)
+
+	switch socket.closeReason {
+	case CloseReasonServer, CloseReasonAbort:
+		s.metrics.TotalClosedByServer++
+	case CloseReasonClient:
+		s.metrics.TotalClosedByClient++
+	}
+
+	duration := time.Duration(atomic.LoadInt64(&socket.closedAt)-socket.ConnectedAt()) * time.Millisecond
+	s.metrics.ConnectionDuration.observe(&ConnectionAgeBuckets, duration)
+
+	reportClosedSocket(s.metricsSink, socket.closeReason, duration)
+	s.disconnectHandler(socket.closeReason, duration)
+}
+
+// Ready returns an error if the server is not accepting connections. It conforms to the ReadinessChecker interface.
+func (s *Server) Ready() error {
+	if s.State() != ServerRunning {
+		return errors.New("server is not running")
+	}
+
+	return nil
 }
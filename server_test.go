@@ -0,0 +1,759 @@
+package tinytcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServerInvalidConfig(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{TLSKey: "key.pem"})
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	// when
+	err := server.Start()
+
+	// then
+	var configErr *ConfigError
+	assert.ErrorAs(t, err, &configErr, "start should fail with config error")
+	assert.Equal(t, "ServerConfig.TLSCert", configErr.Field, "invalid field should match")
+	assert.Equal(t, ServerStopped, server.State(), "server should not be started")
+}
+
+func TestServerRestart(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0")
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	start := func() <-chan error {
+		result := make(chan error, 1)
+		go func() {
+			result <- server.Start()
+		}()
+
+		assert.Eventually(t, func() bool {
+			return server.State() == ServerRunning
+		}, time.Second, time.Millisecond, "server should be running")
+
+		return result
+	}
+
+	// when
+	firstRun := start()
+	secondStartErr := server.Start()
+	_ = server.Stop()
+	firstRunErr := <-firstRun
+
+	abortErr := errors.New("fatal error")
+	secondRun := start()
+	_ = server.Abort(abortErr)
+	secondRunErr := <-secondRun
+
+	// then
+	assert.NotNil(t, secondStartErr, "server should not be started twice")
+	assert.Nil(t, firstRunErr, "first run should return nil")
+	assert.Equal(t, abortErr, secondRunErr, "second run should return the abort error")
+	assert.Equal(t, abortErr, server.Err(), "abort error should be retained")
+	assert.Equal(t, ServerStopped, server.State(), "server should be stopped")
+}
+
+func TestServerOnDisconnect(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1, TickInterval: 10 * time.Millisecond})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_ = socket.Close()
+	}))
+
+	reasons := make(chan CloseReason, 1)
+	server.OnDisconnect(func(reason CloseReason, _ time.Duration) {
+		reasons <- reason
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	// then
+	select {
+	case reason := <-reasons:
+		assert.Equal(t, CloseReasonServer, reason, "close reason should match")
+	case <-time.After(time.Second):
+		assert.Fail(t, "disconnect handler should be called")
+	}
+}
+
+func TestServerReadyLatency(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1, TickInterval: 10 * time.Millisecond})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = socket.Write([]byte("ready\n"))
+		<-socket.Done()
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	// then
+	assert.Eventually(t, func() bool {
+		return server.Metrics().ReadyLatency.Count == 1
+	}, time.Second, time.Millisecond, "ready latency should be observed")
+	assert.Equal(t, uint64(0), server.Metrics().HandshakeDuration.Count, "plain connection should have no handshake")
+}
+
+func TestServerAcceptQueue(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1, AcceptQueueSize: 8})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = socket.Write([]byte("Hello world!"))
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	response, readErr := io.ReadAll(conn)
+
+	// then
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, "Hello world!", string(response), "connection should be handled through the queue")
+}
+
+func TestServerIterateRefs(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("Hello"))
+
+	// when
+	var refs []*SocketRef
+	assert.Eventually(t, func() bool {
+		refs = nil
+		server.IterateRefs(func(ref *SocketRef) {
+			refs = append(refs, ref)
+		})
+
+		return len(refs) == 1
+	}, time.Second, time.Millisecond, "connection should be visible")
+
+	var secondRef *SocketRef
+	server.IterateRefs(func(ref *SocketRef) {
+		secondRef = ref
+	})
+
+	// then
+	assert.Equal(t, "127.0.0.1", refs[0].RemoteAddress(), "remote address should match")
+	assert.Same(t, refs[0], secondRef, "reference should be shared")
+}
+
+func TestServerTLSCertificatePEM(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	assert.Nil(t, err, "err should be nil")
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients: -1,
+		TLSCertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}),
+		TLSKeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}),
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = io.Copy(socket, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := tls.Dial("tcp", server.listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("Hello"))
+
+	response := make([]byte, 5)
+	_, err = io.ReadFull(conn, response)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, "Hello", string(response), "response should match")
+	assert.Equal(t, cert.Certificate[0], conn.ConnectionState().PeerCertificates[0].Raw, "certificate should match")
+}
+
+func TestServerTLSGetConfigForClient(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	serverNames := make(chan string, 1)
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:     -1,
+		TLSCertificate: &cert,
+		TLSGetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			serverNames <- hello.ServerName
+
+			return &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				MaxVersion:   tls.VersionTLS12,
+			}, nil
+		},
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = io.Copy(socket, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := tls.Dial("tcp", server.listener.Addr().String(), &tls.Config{
+		ServerName:         "example.com",
+		InsecureSkipVerify: true,
+	})
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	// then
+	assert.Equal(t, "example.com", <-serverNames, "server name should be passed to the callback")
+	assert.Equal(t, uint16(tls.VersionTLS12), conn.ConnectionState().Version, "config for client should be applied")
+}
+
+func TestServerTLSClientAuth(t *testing.T) {
+	// given
+	serverCert := generateTestCertificate(t)
+	trustedCert := generateTestCertificate(t)
+	untrustedCert := generateTestCertificate(t)
+
+	clientCAs := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: trustedCert.Certificate[0]})
+	err := os.WriteFile(clientCAs, caPEM, 0600)
+	assert.Nil(t, err, "err should be nil")
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:     -1,
+		TLSCertificate: &serverCert,
+		TLSClientCAs:   clientCAs,
+		TLSClientAuth:  tls.RequireAndVerifyClientCert,
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = io.Copy(socket, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	exchange := func(cert tls.Certificate) ([]byte, error) {
+		conn, err := tls.Dial("tcp", server.listener.Addr().String(), &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		_ = conn.SetDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Write([]byte("Hello")); err != nil {
+			return nil, err
+		}
+
+		response := make([]byte, 5)
+		_, err = io.ReadFull(conn, response)
+		return response, err
+	}
+
+	// when
+	trustedResponse, trustedErr := exchange(trustedCert)
+	_, untrustedErr := exchange(untrustedCert)
+
+	// then
+	assert.Nil(t, trustedErr, "client with trusted certificate should be accepted")
+	assert.Equal(t, "Hello", string(trustedResponse), "response should match")
+	assert.NotNil(t, untrustedErr, "client with untrusted certificate should be rejected")
+}
+
+func TestServerTLSVerifyPeerRejected(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	handled := make(chan []byte, 1)
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:     -1,
+		TLSCertificate: &cert,
+		TLSVerifyPeer: func(_ *Socket, _ tls.ConnectionState) error {
+			return errors.New("peer not allowed")
+		},
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		buffer := make([]byte, 5)
+		n, _ := socket.Read(buffer)
+		handled <- buffer[:n]
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := tls.Dial("tcp", server.listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte("Hello"))
+	_, readErr := conn.Read(make([]byte, 5))
+
+	// then
+	assert.NotNil(t, readErr, "connection should be closed by the server")
+	assert.False(t, errors.Is(readErr, os.ErrDeadlineExceeded), "connection should be closed before the deadline")
+	assert.Empty(t, <-handled, "handler should not receive data from rejected peer")
+}
+
+func TestServerClassifyPeer(t *testing.T) {
+	// given
+	var allow uint32 = 1
+
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients: -1,
+		ClassifyPeer: func(_ net.Addr) (map[string]string, bool) {
+			return map[string]string{"country": "PL"}, atomic.LoadUint32(&allow) == 1
+		},
+	})
+
+	labelsChannel := make(chan map[string]string, 1)
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		labelsChannel <- socket.PeerLabels()
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	allowedConn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer allowedConn.Close()
+
+	labels := <-labelsChannel
+
+	atomic.StoreUint32(&allow, 0)
+
+	blockedConn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer blockedConn.Close()
+
+	_ = blockedConn.SetReadDeadline(time.Now().Add(time.Second))
+	_, readErr := blockedConn.Read(make([]byte, 1))
+
+	// then
+	assert.Equal(t, map[string]string{"country": "PL"}, labels, "labels should be attached to the socket")
+	assert.Equal(t, io.EOF, readErr, "blocked connection should be closed")
+}
+
+func TestServerShutdown(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = socket.Write([]byte("HELLO\n"))
+
+		<-socket.Draining()
+		_, _ = socket.Write([]byte("GOAWAY\n"))
+		_ = socket.Close()
+	}))
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	hello := make([]byte, 6)
+	_, _ = io.ReadFull(conn, hello)
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	shutdownErr := server.Shutdown(ctx)
+	remaining, readErr := io.ReadAll(conn)
+
+	// then
+	assert.Nil(t, shutdownErr, "err should be nil")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, []byte("GOAWAY\n"), remaining, "client should be notified")
+	assert.Nil(t, <-stopped, "server should stop without error")
+	assert.Equal(t, ServerStopped, server.State(), "server should be stopped")
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool {
+		return len(server.DumpConnections()) == 1
+	}, time.Second, time.Millisecond, "connection should be visible")
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	shutdownErr := server.Shutdown(ctx)
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, readErr := conn.Read(make([]byte, 1))
+
+	// then
+	assert.Nil(t, shutdownErr, "err should be nil")
+	assert.Equal(t, io.EOF, readErr, "connection should be closed forcibly")
+	assert.Nil(t, <-stopped, "server should stop without error")
+}
+
+func TestServerHousekeepingPanic(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:   -1,
+		TickInterval: 20 * time.Millisecond,
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+	server.OnMetricsUpdate(func(_ ServerMetrics) {
+		panic("metrics update failed")
+	})
+
+	// when
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	// then
+	select {
+	case err := <-stopped:
+		assert.ErrorContains(t, err, "metrics update failed", "server should be aborted with the panic")
+	case <-time.After(time.Second):
+		t.Fatal("server should be aborted after the housekeeping job panics")
+	}
+}
+
+func TestServerAbort(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{MaxClients: -1})
+
+	closeErrors := make(chan error, 1)
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		socket.OnClose(func(reason CloseReason) {
+			if reason == CloseReasonAbort {
+				closeErrors <- socket.CloseError()
+			}
+		})
+
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+
+	var abortErr error
+	server.OnAbort(func(err error) {
+		abortErr = err
+	})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool {
+		return len(server.DumpConnections()) == 1
+	}, time.Second, time.Millisecond, "connection should be visible")
+
+	cause := errors.New("housekeeping failed")
+
+	// when
+	_ = server.Abort(cause)
+
+	// then
+	assert.Equal(t, cause, <-stopped, "Start should return the abort error")
+	assert.Equal(t, cause, abortErr, "OnAbort should receive the abort error")
+	assert.Equal(t, cause, <-closeErrors, "socket should be closed with the abort error")
+}
+
+func TestServerListenRetry(t *testing.T) {
+	// given
+	occupant, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := NewServer(occupant.Addr().String(), &ServerConfig{
+		MaxClients: -1,
+		ListenRetry: &ListenRetryConfig{
+			Attempts: 10,
+			Backoff:  10 * time.Millisecond,
+		},
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	// when
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = occupant.Close()
+	}()
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	// then
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, 5*time.Second, time.Millisecond, "server should be running once the port is released")
+}
+
+func TestServerListenRetryExhausted(t *testing.T) {
+	// given
+	occupant, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer occupant.Close()
+
+	server := NewServer(occupant.Addr().String(), &ServerConfig{
+		MaxClients: -1,
+		ListenRetry: &ListenRetryConfig{
+			Attempts: 2,
+			Backoff:  time.Millisecond,
+		},
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	// when
+	err = server.Start()
+
+	// then
+	assert.ErrorIs(t, err, syscall.EADDRINUSE, "bind error should be returned")
+	assert.Equal(t, ServerStopped, server.State(), "server should be stopped")
+}
+
+func TestServerAdditionalAddresses(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:          -1,
+		AdditionalAddresses: []string{"127.0.0.1:0"},
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = socket.Write([]byte("Hello"))
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	listeners := server.listener.(*netListener).listener.(*multiListener).listeners
+
+	// when
+	var responses [][]byte
+	for _, listener := range listeners {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		assert.Nil(t, err, "err should be nil")
+
+		response, _ := io.ReadAll(conn)
+		responses = append(responses, response)
+		_ = conn.Close()
+	}
+
+	// then
+	assert.Len(t, listeners, 2, "server should listen on both addresses")
+	assert.NotEqual(t, listeners[0].Addr().String(), listeners[1].Addr().String(), "addresses should differ")
+	assert.Equal(t, [][]byte{[]byte("Hello"), []byte("Hello")}, responses, "both addresses should be served")
+}
+
+type failingListener struct {
+	net.Listener
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	return nil, errors.New("too many open files")
+}
+
+func TestMultiListenerAcceptError(t *testing.T) {
+	// given
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	failing := &failingListener{Listener: tcpListener}
+	listener := newMultiListener([]net.Listener{failing})
+	defer listener.Close()
+
+	// when
+	var errs []error
+	deadline := time.Now().Add(100 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		_, err := listener.Accept()
+		errs = append(errs, err)
+	}
+
+	// then
+	for _, err := range errs {
+		assert.EqualError(t, err, "too many open files", "accept error should be passed to Accept")
+	}
+	assert.Less(t, len(errs), 10, "failing listener should be retried with a backoff")
+}
+
+func TestServerIPv6Only(t *testing.T) {
+	// given
+	probe, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 is not available")
+	}
+	_ = probe.Close()
+
+	server := NewServer("[::]:0", &ServerConfig{
+		MaxClients: -1,
+		IPv6Only:   true,
+	})
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	ipv6Conn, ipv6Err := net.Dial("tcp", fmt.Sprintf("[::1]:%d", server.Port()))
+	_, ipv4Err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", server.Port()))
+
+	// then
+	assert.Nil(t, ipv6Err, "IPv6 connection should be accepted")
+	assert.NotNil(t, ipv4Err, "IPv4 connection should be refused")
+
+	if ipv6Conn != nil {
+		_ = ipv6Conn.Close()
+	}
+}
@@ -1,14 +1,19 @@
 package tinytcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
-var shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+// ErrShutdownTimeout is returned by StartAndBlockContext when some services fail to stop within ShutdownTimeout.
+var ErrShutdownTimeout = errors.New("services failed to stop before shutdown timeout")
 
 // Service represents concurrent job, that is expected to run in background for the whole lifetime of the process.
 type Service interface {
@@ -20,11 +25,128 @@ type Service interface {
 	Stop() error
 }
 
+// ServiceFunc returns a Service calling given functions on Start() and Stop().
+// Start is expected to block until Stop is called, exactly as Service.Start().
+func ServiceFunc(start func() error, stop func() error) Service {
+	return &serviceFunc{
+		start: start,
+		stop:  stop,
+	}
+}
+
+type serviceFunc struct {
+	start func() error
+	stop  func() error
+}
+
+func (s *serviceFunc) Start() error {
+	return s.start()
+}
+
+func (s *serviceFunc) Stop() error {
+	return s.stop()
+}
+
+// HTTPService returns a Service running given *http.Server, so it can be supervised by StartAndBlock.
+// Server is started with ListenAndServe(), or ListenAndServeTLS() if its TLSConfig is set (certificates are expected
+// to be provided by TLSConfig). Stop() gracefully shuts the server down, waiting for the active requests to complete.
+func HTTPService(server *http.Server) Service {
+	return ServiceFunc(
+		func() error {
+			var err error
+			if server.TLSConfig != nil {
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+
+			return err
+		},
+		func() error {
+			return server.Shutdown(context.Background())
+		},
+	)
+}
+
+// ShutdownOrder denotes the order in which services are stopped by StartAndBlockContext.
+type ShutdownOrder int
+
+const (
+	// ShutdownConcurrent stops all the services at once.
+	ShutdownConcurrent ShutdownOrder = iota
+
+	// ShutdownReverse stops services one by one, in the reverse order of start. It makes sense when a service relies
+	// on the ones passed before it, eg. a TCP server should be stopped before the database pool it uses.
+	ShutdownReverse
+)
+
+// StartOptions holds options for StartAndBlockContext.
+type StartOptions struct {
+	// Signals is a list of signals that trigger the shutdown (default: SIGINT, SIGTERM).
+	Signals []os.Signal
+
+	// ShutdownTimeout is a maximal duration of the graceful shutdown. Services that fail to stop in time are abandoned
+	// and ErrShutdownTimeout is returned. The value of 0 means no timeout (default: 0).
+	ShutdownTimeout time.Duration
+
+	// ShutdownOrder specifies the order in which services are stopped (default: ShutdownConcurrent).
+	ShutdownOrder ShutdownOrder
+
+	// Dependencies maps a service to the list of services it depends on. Service is stopped only after all the
+	// services that depend on it have been stopped, services without dependencies between them are stopped
+	// concurrently. Dependencies take precedence over ShutdownOrder. All the services must be passed to
+	// StartAndBlockContext as well, and the dependency graph must not contain cycles.
+	Dependencies map[Service][]Service
+}
+
+func mergeStartOptions(provided *StartOptions) *StartOptions {
+	options := &StartOptions{
+		Signals: []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+
+	if provided == nil {
+		return options
+	}
+
+	if provided.Signals != nil {
+		options.Signals = provided.Signals
+	}
+	if provided.ShutdownTimeout > 0 {
+		options.ShutdownTimeout = provided.ShutdownTimeout
+	}
+	if provided.ShutdownOrder != ShutdownConcurrent {
+		options.ShutdownOrder = provided.ShutdownOrder
+	}
+	if provided.Dependencies != nil {
+		options.Dependencies = provided.Dependencies
+	}
+
+	return options
+}
+
 // StartAndBlock starts all passed services in their designated goroutines and then blocks the current thread.
 // Thread is unblocked when the process receives SIGINT or SIGTERM signals or one of the Start() functions returns an error.
 // When exiting, StartAndBlock gracefully stops all the services by calling their Stop() functions and waiting for them to exit.
-func StartAndBlock(services ...Service) (err error) {
-	errorChannel := make(chan error)
+// Returned error joins the error that caused the exit with all the errors returned by Stop() functions.
+func StartAndBlock(services ...Service) error {
+	return StartAndBlockContext(context.Background(), nil, services...)
+}
+
+// StartAndBlockContext works like StartAndBlock, but the thread is also unblocked when the context is cancelled.
+// Options allow to customize shutdown signals and limit the duration of the graceful shutdown (see StartOptions).
+func StartAndBlockContext(ctx context.Context, options *StartOptions, services ...Service) error {
+	o := mergeStartOptions(options)
+
+	dependents, err := resolveShutdownDependents(services, o)
+	if err != nil {
+		return err
+	}
+
+	errorChannel := make(chan error, 1)
 
 	for _, service := range services {
 		s := service
@@ -48,43 +170,170 @@ func StartAndBlock(services ...Service) (err error) {
 		}()
 	}
 
-	defer func() {
-		wg := &sync.WaitGroup{}
-		wg.Add(len(services))
+	err = blockThread(ctx, errorChannel, o.Signals)
+	return errors.Join(err, stopServices(services, dependents, o.ShutdownTimeout))
+}
 
-		for _, service := range services {
-			s := service
+func blockThread(ctx context.Context, errorChannel <-chan error, signals []os.Signal) error {
+	// signal.Notify doesn't block on sending, so with an unbuffered channel a signal arriving
+	// while the loop isn't receiving (eg. before it starts) would be dropped
+	shutdownSignalsChannel := make(chan os.Signal, 1)
+	signal.Notify(shutdownSignalsChannel, signals...)
+	defer signal.Stop(shutdownSignalsChannel)
 
-			go func() {
-				defer func() {
-					if r := recover(); r != nil {
-						err = fmt.Errorf("%v", r)
-					}
+	select {
+	case err := <-errorChannel:
+		return err
+	case <-shutdownSignalsChannel:
+		return nil
+	case <-ctx.Done():
+		return nil
+	}
+}
 
-					wg.Done()
-				}()
+// resolveShutdownDependents returns, for each service, indices of the services that need to be stopped before it.
+func resolveShutdownDependents(services []Service, options *StartOptions) ([][]int, error) {
+	dependents := make([][]int, len(services))
 
-				err = s.Stop()
-			}()
+	if options.Dependencies != nil {
+		indices := make(map[Service]int, len(services))
+		for i, service := range services {
+			indices[service] = i
 		}
 
-		wg.Wait()
-	}()
+		for service, dependencies := range options.Dependencies {
+			i, ok := indices[service]
+			if !ok {
+				return nil, errors.New("dependencies refer to a service that has not been passed")
+			}
 
-	err = blockThread(errorChannel)
-	return
+			for _, dependency := range dependencies {
+				j, ok := indices[dependency]
+				if !ok {
+					return nil, errors.New("dependencies refer to a service that has not been passed")
+				}
+
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+
+		if hasCycle(dependents) {
+			return nil, errors.New("dependencies between services contain a cycle")
+		}
+	} else if options.ShutdownOrder == ShutdownReverse {
+		for i := 0; i < len(services)-1; i++ {
+			dependents[i] = []int{i + 1}
+		}
+	}
+
+	return dependents, nil
 }
 
-func blockThread(errorChannel <-chan error) error {
-	shutdownSignalsChannel := make(chan os.Signal)
-	signal.Notify(shutdownSignalsChannel, shutdownSignals...)
+func hasCycle(graph [][]int) bool {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
 
-	for {
-		select {
-		case err := <-errorChannel:
-			return err
-		case <-shutdownSignalsChannel:
-			return nil
+	state := make([]int, len(graph))
+
+	var visit func(node int) bool
+	visit = func(node int) bool {
+		switch state[node] {
+		case visiting:
+			return true
+		case visited:
+			return false
 		}
+
+		state[node] = visiting
+		for _, next := range graph[node] {
+			if visit(next) {
+				return true
+			}
+		}
+		state[node] = visited
+
+		return false
+	}
+
+	for node := range graph {
+		if visit(node) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stopServices(services []Service, dependents [][]int, timeout time.Duration) error {
+	var (
+		errs      []error
+		errsMutex sync.Mutex
+		wg        sync.WaitGroup
+		done      = make(chan struct{})
+		stopped   = make([]chan struct{}, len(services))
+	)
+
+	for i := range stopped {
+		stopped[i] = make(chan struct{})
 	}
+
+	wg.Add(len(services))
+
+	for i, service := range services {
+		s := service
+		i := i
+
+		go func() {
+			defer wg.Done()
+			defer close(stopped[i])
+
+			for _, dependent := range dependents[i] {
+				<-stopped[dependent]
+			}
+
+			if err := stopService(s); err != nil {
+				errsMutex.Lock()
+				errs = append(errs, err)
+				errsMutex.Unlock()
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var timeoutChannel <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutChannel = timer.C
+	}
+
+	select {
+	case <-done:
+	case <-timeoutChannel:
+		errsMutex.Lock()
+		errs = append(errs, ErrShutdownTimeout)
+		errsMutex.Unlock()
+	}
+
+	errsMutex.Lock()
+	defer errsMutex.Unlock()
+
+	return errors.Join(errs...)
+}
+
+func stopService(service Service) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return service.Stop()
 }
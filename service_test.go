@@ -0,0 +1,198 @@
+package tinytcp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type testService struct {
+	startErr  error
+	stopErr   error
+	stopDelay time.Duration
+	stop      chan struct{}
+}
+
+func newTestService() *testService {
+	return &testService{
+		stop: make(chan struct{}),
+	}
+}
+
+func (s *testService) Start() error {
+	if s.startErr != nil {
+		return s.startErr
+	}
+
+	<-s.stop
+	return nil
+}
+
+func (s *testService) Stop() error {
+	time.Sleep(s.stopDelay)
+	close(s.stop)
+	return s.stopErr
+}
+
+func TestStartAndBlockContextCancelled(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	first := newTestService()
+	second := newTestService()
+
+	// when
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := StartAndBlockContext(ctx, nil, first, second)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	_, firstOpen := <-first.stop
+	assert.False(t, firstOpen, "first service should be stopped")
+	_, secondOpen := <-second.stop
+	assert.False(t, secondOpen, "second service should be stopped")
+}
+
+func TestStartAndBlockContextErrors(t *testing.T) {
+	// given
+	startErr := errors.New("start failed")
+	stopErr := errors.New("stop failed")
+
+	failing := newTestService()
+	failing.startErr = startErr
+	other := newTestService()
+	other.stopErr = stopErr
+
+	// when
+	err := StartAndBlockContext(context.Background(), nil, failing, other)
+
+	// then
+	assert.ErrorIs(t, err, startErr, "start err should be returned")
+	assert.ErrorIs(t, err, stopErr, "stop err should be returned")
+}
+
+func TestStartAndBlockContextShutdownTimeout(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	slow := newTestService()
+	slow.stopDelay = time.Second
+
+	// when
+	start := time.Now()
+	err := StartAndBlockContext(ctx, &StartOptions{ShutdownTimeout: 10 * time.Millisecond}, slow)
+
+	// then
+	assert.ErrorIs(t, err, ErrShutdownTimeout, "err should be ErrShutdownTimeout")
+	assert.Less(t, time.Since(start), time.Second, "slow service should be abandoned")
+}
+
+func TestStartAndBlockContextShutdownReverse(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stopOrder []int
+	services := make([]Service, 3)
+	for i := range services {
+		i := i
+		services[i] = &orderedTestService{onStop: func() { stopOrder = append(stopOrder, i) }}
+	}
+
+	// when
+	err := StartAndBlockContext(ctx, &StartOptions{ShutdownOrder: ShutdownReverse}, services...)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, []int{2, 1, 0}, stopOrder, "services should be stopped in reverse order")
+}
+
+func TestStartAndBlockContextDependencies(t *testing.T) {
+	// given
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stopOrder []string
+	database := &orderedTestService{onStop: func() { stopOrder = append(stopOrder, "database") }}
+	server := &orderedTestService{onStop: func() { stopOrder = append(stopOrder, "server") }}
+
+	// when
+	err := StartAndBlockContext(ctx, &StartOptions{
+		Dependencies: map[Service][]Service{
+			server: {database},
+		},
+	}, database, server)
+
+	cycleErr := StartAndBlockContext(ctx, &StartOptions{
+		Dependencies: map[Service][]Service{
+			server:   {database},
+			database: {server},
+		},
+	}, database, server)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, []string{"server", "database"}, stopOrder, "server should be stopped before database")
+	assert.NotNil(t, cycleErr, "cycle should be detected")
+}
+
+type orderedTestService struct {
+	onStop func()
+}
+
+func (s *orderedTestService) Start() error {
+	return nil
+}
+
+func (s *orderedTestService) Stop() error {
+	s.onStop()
+	return nil
+}
+
+func TestServiceFunc(t *testing.T) {
+	// given
+	stop := make(chan struct{})
+	service := ServiceFunc(
+		func() error {
+			<-stop
+			return nil
+		},
+		func() error {
+			close(stop)
+			return nil
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	err := StartAndBlockContext(ctx, nil, service)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.NotPanics(t, func() {
+		_ = map[Service]struct{}{service: {}}
+	}, "service should be usable as a map key")
+}
+
+func TestHTTPService(t *testing.T) {
+	// given
+	service := HTTPService(&http.Server{Addr: "127.0.0.1:0"})
+	startResult := make(chan error, 1)
+
+	go func() {
+		startResult <- service.Start()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// when
+	stopErr := service.Stop()
+
+	// then
+	assert.Nil(t, stopErr, "stop error should be nil")
+	assert.Nil(t, <-startResult, "start should return nil after graceful shutdown")
+}
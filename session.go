@@ -0,0 +1,315 @@
+package tinytcp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrSessionQueueFull is returned by Session.Write when the session is detached and its outbound queue is full.
+var ErrSessionQueueFull = errors.New("session queue is full")
+
+// SessionManagerConfig holds a configuration for NewSessionManager.
+type SessionManagerConfig struct {
+	// TTL specifies how long the session is kept after its socket disconnects (default: 1m).
+	TTL time.Duration
+
+	// MaxQueuedPackets specifies how many outbound packets can be queued while the session is detached.
+	// Queued packets are replayed after the session is resumed. The value of 0 disables queueing (default: 0).
+	MaxQueuedPackets int
+
+	// NowFunc is a function used to determine current time when handling session expiration.
+	// (default: time.Now)
+	NowFunc func() time.Time
+}
+
+// SessionManager keeps server-side session state across client reconnects.
+// Each session is identified by an opaque token, that should be passed to the client.
+// After reconnecting, client presents the token and the new socket is re-attached to its previous session.
+type SessionManager struct {
+	config   *SessionManagerConfig
+	sessions map[string]*Session
+	m        sync.Mutex
+}
+
+// Session represents a server-side state of a client, that outlives a single connection.
+// The attached socket is held through SocketRef, as the session is used outside the handler of the socket.
+type Session struct {
+	token      string
+	manager    *SessionManager
+	ref        *SocketRef
+	replaying  bool
+	detachedAt time.Time
+	queue      [][]byte
+	value      any
+	m          sync.Mutex
+}
+
+func mergeSessionManagerConfig(provided *SessionManagerConfig) *SessionManagerConfig {
+	config := &SessionManagerConfig{
+		TTL:     1 * time.Minute,
+		NowFunc: time.Now,
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.TTL > 0 {
+		config.TTL = provided.TTL
+	}
+	if provided.MaxQueuedPackets > 0 {
+		config.MaxQueuedPackets = provided.MaxQueuedPackets
+	}
+	if provided.NowFunc != nil {
+		config.NowFunc = provided.NowFunc
+	}
+
+	return config
+}
+
+// NewSessionManager returns new SessionManager instance.
+func NewSessionManager(config ...*SessionManagerConfig) *SessionManager {
+	var providedConfig *SessionManagerConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+
+	return &SessionManager{
+		config:   mergeSessionManagerConfig(providedConfig),
+		sessions: make(map[string]*Session),
+	}
+}
+
+// Create creates a new session, attached to given socket.
+func (m *SessionManager) Create(socket *Socket) (*Session, error) {
+	token, err := generateSessionToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{
+		token:   token,
+		manager: m,
+	}
+
+	m.m.Lock()
+	m.sessions[token] = session
+	m.m.Unlock()
+
+	session.attach(socket)
+	return session, nil
+}
+
+// Resume re-attaches given socket to the session identified by token.
+// If the session is still attached to another socket, that socket is closed.
+// Returns false if the session does not exist or has expired.
+func (m *SessionManager) Resume(token string, socket *Socket) (*Session, bool) {
+	m.m.Lock()
+	session, ok := m.sessions[token]
+	if ok && session.isExpired() {
+		delete(m.sessions, token)
+		ok = false
+	}
+	m.m.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	session.attach(socket)
+	return session, true
+}
+
+// Remove immediately removes the session identified by token.
+func (m *SessionManager) Remove(token string) {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	delete(m.sessions, token)
+}
+
+// Len returns a number of sessions held by the manager.
+func (m *SessionManager) Len() int {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	return len(m.sessions)
+}
+
+// Cleanup removes all the expired sessions. It should be called periodically.
+func (m *SessionManager) Cleanup() {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	for token, session := range m.sessions {
+		if session.isExpired() {
+			delete(m.sessions, token)
+		}
+	}
+}
+
+// Token returns an opaque token identifying the session.
+func (s *Session) Token() string {
+	return s.token
+}
+
+// Value returns a user-defined value associated with the session.
+func (s *Session) Value() any {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.value
+}
+
+// SetValue associates user-defined value with the session.
+func (s *Session) SetValue(value any) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.value = value
+}
+
+// Attached returns true if the session is currently attached to a connected socket.
+func (s *Session) Attached() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.ref != nil
+}
+
+// Write writes data to the socket attached to the session.
+// If the session is detached, data is queued and replayed after the session is resumed. Data written while
+// the queue is being replayed is queued as well, so the order of the writes is preserved.
+func (s *Session) Write(b []byte) (int, error) {
+	s.m.Lock()
+	ref := s.ref
+	if ref != nil && !s.replaying {
+		s.m.Unlock()
+
+		// socket is written to without holding the lock, as the failed write closes the socket, which detaches it
+		return ref.Write(b)
+	}
+	defer s.m.Unlock()
+
+	if s.manager.config.MaxQueuedPackets == 0 {
+		return 0, io.EOF
+	}
+	if len(s.queue) >= s.manager.config.MaxQueuedPackets {
+		return 0, ErrSessionQueueFull
+	}
+
+	packet := make([]byte, len(b))
+	copy(packet, b)
+	s.queue = append(s.queue, packet)
+
+	return len(b), nil
+}
+
+// Close removes the session from its manager and closes the attached socket.
+func (s *Session) Close() error {
+	s.manager.Remove(s.token)
+
+	s.m.Lock()
+	ref := s.ref
+	s.ref = nil
+	s.replaying = false
+	s.queue = nil
+	s.m.Unlock()
+
+	if ref != nil {
+		return ref.Close()
+	}
+
+	return nil
+}
+
+func (s *Session) attach(socket *Socket) {
+	ref := socket.sharedRef()
+
+	s.m.Lock()
+	previous := s.ref
+	s.ref = ref
+	s.replaying = len(s.queue) > 0
+	s.m.Unlock()
+
+	if previous != nil && previous != ref {
+		_ = previous.Close()
+	}
+
+	socket.OnClose(func(_ CloseReason) {
+		s.detach(ref)
+	})
+	if socket.IsClosed() {
+		// closed before the close handler has been registered
+		s.detach(ref)
+		return
+	}
+
+	s.replay(ref)
+}
+
+// replay writes the queued packets to the attached socket. The lock is not held while writing, so a slow client
+// doesn't block the other operations on the session. Packets written in the meantime are queued and replayed too.
+func (s *Session) replay(ref *SocketRef) {
+	for {
+		s.m.Lock()
+		if s.ref != ref || !s.replaying {
+			s.m.Unlock()
+			return
+		}
+
+		queue := s.queue
+		s.queue = nil
+		if len(queue) == 0 {
+			s.replaying = false
+			s.m.Unlock()
+			return
+		}
+		s.m.Unlock()
+
+		for _, packet := range queue {
+			if err := WriteBytes(ref, packet); err != nil {
+				s.m.Lock()
+				if s.ref == ref {
+					s.replaying = false
+				}
+				s.m.Unlock()
+
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) detach(ref *SocketRef) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.ref != ref {
+		return
+	}
+
+	s.ref = nil
+	s.replaying = false
+	s.detachedAt = s.manager.config.NowFunc()
+}
+
+func (s *Session) isExpired() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.ref == nil && s.manager.config.NowFunc().Sub(s.detachedAt) > s.manager.config.TTL
+}
+
+func generateSessionToken() (string, error) {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
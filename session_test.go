@@ -0,0 +1,171 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionResume(t *testing.T) {
+	// given
+	manager := NewSessionManager(&SessionManagerConfig{
+		MaxQueuedPackets: 1,
+	})
+	payload := []byte("Hello world!")
+
+	var out bytes.Buffer
+	socket := MockSocket(nil, io.Discard)
+	resumedSocket := MockSocket(nil, &out)
+
+	// when
+	session, err := manager.Create(socket)
+	_ = socket.Close()
+	_, writeErr := session.Write(payload)
+	resumedSession, resumed := manager.Resume(session.Token(), resumedSocket)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Nil(t, writeErr, "write err should be nil")
+	assert.True(t, resumed, "session should be resumed")
+	assert.Equal(t, session, resumedSession, "sessions should match")
+	assert.True(t, resumedSession.Attached(), "session should be attached")
+	assert.Equal(t, payload, out.Bytes(), "queued payload should be replayed")
+}
+
+func TestSessionQueueDisabled(t *testing.T) {
+	// given
+	manager := NewSessionManager()
+	socket := MockSocket(nil, io.Discard)
+
+	// when
+	session, _ := manager.Create(socket)
+	_ = socket.Close()
+	_, err := session.Write([]byte("Hello world!"))
+
+	// then
+	assert.Equal(t, io.EOF, err, "err should be equal to io.EOF")
+}
+
+func TestSessionExpired(t *testing.T) {
+	// given
+	now := time.Now()
+	manager := NewSessionManager(&SessionManagerConfig{
+		TTL: time.Minute,
+		NowFunc: func() time.Time {
+			return now
+		},
+	})
+	socket := MockSocket(nil, io.Discard)
+
+	// when
+	session, _ := manager.Create(socket)
+	_ = socket.Close()
+	now = now.Add(2 * time.Minute)
+	_, resumed := manager.Resume(session.Token(), MockSocket(nil, io.Discard))
+
+	// then
+	assert.False(t, resumed, "session should not be resumed")
+	assert.Equal(t, 0, manager.Len(), "session should be removed")
+}
+
+func TestSessionWriteBrokenSocket(t *testing.T) {
+	// given
+	manager := NewSessionManager()
+	socket := MockSocket(nil, writerFunc(func(_ []byte) (int, error) {
+		return 0, io.EOF
+	}))
+	session, _ := manager.Create(socket)
+
+	// when
+	result := make(chan error, 1)
+	go func() {
+		_, err := session.Write([]byte("Hello world!"))
+		result <- err
+	}()
+
+	// then
+	select {
+	case err := <-result:
+		assert.NotNil(t, err, "err should not be nil")
+	case <-time.After(time.Second):
+		t.Fatal("write should not block")
+	}
+
+	assert.False(t, session.Attached(), "session should be detached")
+}
+
+func TestSessionCreateClosedSocket(t *testing.T) {
+	// given
+	manager := NewSessionManager()
+	socket := MockSocket(nil, io.Discard)
+	_ = socket.Close()
+
+	// when
+	session, _ := manager.Create(socket)
+
+	// then
+	assert.False(t, session.Attached(), "session with closed socket should be detached")
+}
+
+func TestSessionReplaySlowSocket(t *testing.T) {
+	// given
+	manager := NewSessionManager(&SessionManagerConfig{
+		MaxQueuedPackets: 2,
+	})
+
+	var (
+		out     bytes.Buffer
+		m       sync.Mutex
+		blocked = make(chan struct{})
+		release = make(chan struct{})
+	)
+	resumedSocket := MockSocket(nil, writerFunc(func(b []byte) (int, error) {
+		m.Lock()
+		first := out.Len() == 0
+		m.Unlock()
+
+		if first {
+			close(blocked)
+			<-release
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		return out.Write(b)
+	}))
+
+	socket := MockSocket(nil, io.Discard)
+	session, _ := manager.Create(socket)
+	_ = socket.Close()
+	_, _ = session.Write([]byte("first"))
+
+	// when
+	resumed := make(chan struct{})
+	go func() {
+		manager.Resume(session.Token(), resumedSocket)
+		close(resumed)
+	}()
+	<-blocked
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := session.Write([]byte("second"))
+		written <- err
+	}()
+
+	// then
+	select {
+	case err := <-written:
+		assert.Nil(t, err, "write err should be nil")
+	case <-time.After(time.Second):
+		t.Fatal("write should not be blocked by the replay")
+	}
+
+	close(release)
+	<-resumed
+
+	assert.Equal(t, "firstsecond", out.String(), "order of writes should be preserved")
+}
@@ -1,7 +1,10 @@
 package tinytcp
 
 import (
+	"bytes"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"sync"
@@ -9,24 +12,99 @@ import (
 	"time"
 )
 
+// ErrSocketClosed is returned by the operations performed on a Socket that has already been closed (or recycled).
+// It wraps io.EOF, so the code checking errors with errors.Is(err, io.EOF) treats it as the end of the stream.
+// Operations failing because the connection has been lost on the client side return io.EOF.
+var ErrSocketClosed = fmt.Errorf("socket is closed: %w", io.EOF)
+
+// ErrSocketDraining is the cause of the contexts cancelled when the server starts a graceful shutdown
+// (see PacketFramingHandlerCtx and Server.Shutdown). The socket itself remains open.
+var ErrSocketDraining = errors.New("socket is draining")
+
+// closedChannel is returned by Done() of the sockets closed before Done() has been called.
+var closedChannel = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// cacheLineSize is the padding used to keep the groups of Socket fields on separate cache lines.
+const cacheLineSize = 64
+
 // Socket represents a connected TCP socket.
 // An instance of Socket is only valid inside its designated handler and cannot be stored outside (see SocketRef).
 type Socket struct {
-	remoteAddr    string
-	timestamp     int64
-	conn          net.Conn
-	reader        io.Reader
-	writer        io.Writer
-	meteredReader *meteredReader
-	meteredWriter *meteredWriter
+	// Fields are grouped by the access pattern. Fields read on every Read() and Write() are kept apart from the ones
+	// updated on every packet and from the ones modified by other goroutines, so they don't share cache lines.
+
+	// read-mostly, accessed on every Read() and Write()
+	conn            net.Conn
+	reader          io.Reader
+	writer          io.Writer
+	meteredReader   *meteredReader
+	meteredWriter   *meteredWriter
+	writeBuffer     *writeBuffer
+	clock           Clock
+	peeked          []byte
+	readerUpgrade   func(io.Reader) io.Reader
+	handoff         SocketHandler
+	handshakeOnce   sync.Once
+	closed          uint32
+	framing         bool
+	handshakeFailed bool
+
+	_ [cacheLineSize]byte
+
+	// updated by the packet handler on every packet
+	packetLatency histogramRecorder[time.Duration]
+	packetSize    histogramRecorder[uint64]
+
+	_ [cacheLineSize]byte
+
+	// updated by the housekeeping job and by the goroutines closing the socket
+	lastActivity      int64
+	closeDeadline     int64
+	closeAfter        int32
+	closedAt          int64
+	closeReason       CloseReason
+	closeErr          error
+	handshakeDuration int64
+	readyLatency      int64
+	ready             uint32
+	readyReported     bool
+	recyclable        uint32
+
+	// cold, set once or accessed rarely
+	id         uint64
+	remoteAddr string
+	peerLabels map[string]string
+	timestamp  int64
+	acceptedAt time.Time
+	verifyPeer func(*Socket, tls.ConnectionState) error
+
+	readsResumed chan struct{}
+	readsMutex   sync.Mutex
 
 	closeOnce            sync.Once
+	done                 chan struct{}
+	doneClosed           bool
+	doneMutex            sync.Mutex
+	draining             chan struct{}
+	drainStarted         bool
+	drainHandlers        []func()
+	drainMutex           sync.Mutex
 	closeHandlers        []SocketCloseHandler
 	closeHandlersMutex   sync.RWMutex
 	recycleHandlers      []func()
+	recycling            bool
 	recycleHandlersMutex sync.RWMutex
-	recyclable           uint32
 
+	ref     *SocketRef
+	refOnce sync.Once
+
+	_ [cacheLineSize]byte
+
+	// modified by the sockets list whenever the neighbouring sockets are added or removed
 	prev *Socket
 	next *Socket
 }
@@ -37,17 +115,62 @@ type SocketHandler func(*Socket)
 // SocketCloseHandler represents a signature of function used by Socket to register custom close handlers.
 type SocketCloseHandler func(CloseReason)
 
+// NewSocket creates a standalone Socket wrapping given connection, outside the lifecycle of any server.
+// Sockets created this way are never pooled. It's mostly useful for testing handlers (see tinytcptest package).
+func NewSocket(connection net.Conn) *Socket {
+	socket := &Socket{
+		meteredReader: &meteredReader{},
+		meteredWriter: &meteredWriter{},
+	}
+
+	socket.init(connection, SystemClock())
+	return socket
+}
+
 // Close closes underlying TCP connection and executes all the registered close handlers.
 func (s *Socket) Close(reason ...CloseReason) (err error) {
+	r := CloseReasonServer
+	if reason != nil {
+		r = reason[0]
+	}
+
+	return s.close(r, nil)
+}
+
+// CloseError returns the error that caused the server to abort, if the socket has been closed with CloseReasonAbort.
+func (s *Socket) CloseError() error {
+	if !s.IsClosed() {
+		return nil
+	}
+
+	return s.closeErr
+}
+
+func (s *Socket) close(r CloseReason, closeErr error) (err error) {
 	s.closeOnce.Do(func() {
+		s.closeErr = closeErr
+		atomic.StoreUint32(&s.closed, 1)
+
+		if s.writeBuffer != nil {
+			s.writeBuffer.TryFlush()
+		}
+
 		if e := s.conn.Close(); e != nil {
 			err = e
 		}
 
-		r := CloseReasonServer
-		if reason != nil {
-			r = reason[0]
+		s.closeReason = r
+		atomic.StoreInt64(&s.closedAt, s.clock.Now().UTC().UnixMilli())
+
+		// unblock the framing loop, so it can notice the connection is closed
+		s.ResumeReads()
+
+		s.doneMutex.Lock()
+		s.doneClosed = true
+		if s.done != nil {
+			close(s.done)
 		}
+		s.doneMutex.Unlock()
 
 		s.closeHandlersMutex.RLock()
 		{
@@ -62,13 +185,102 @@ func (s *Socket) Close(reason ...CloseReason) (err error) {
 	return
 }
 
+// IsClosed returns true if the socket has been closed.
+func (s *Socket) IsClosed() bool {
+	return atomic.LoadUint32(&s.closed) == 1
+}
+
+// Done returns a channel that is closed when the socket closes. It allows goroutines pumping data from other sources
+// (eg. tickers or pub-sub subscriptions) to select on the lifetime of the socket.
+func (s *Socket) Done() <-chan struct{} {
+	s.doneMutex.Lock()
+	defer s.doneMutex.Unlock()
+
+	if s.done == nil {
+		if s.doneClosed {
+			return closedChannel
+		}
+
+		s.done = make(chan struct{})
+	}
+
+	return s.done
+}
+
+// Draining returns a channel that is closed when the server starts a graceful shutdown (see Server.Shutdown).
+// Handlers can select on it to inform the client (eg. with a GOAWAY-style message) and finish the work in progress
+// before the connection is closed forcibly.
+func (s *Socket) Draining() <-chan struct{} {
+	s.drainMutex.Lock()
+	defer s.drainMutex.Unlock()
+
+	if s.draining == nil {
+		if s.drainStarted {
+			return closedChannel
+		}
+
+		s.draining = make(chan struct{})
+	}
+
+	return s.draining
+}
+
+// OnDrain registers a handler that is called when the server starts a graceful shutdown (see Server.Shutdown).
+// Handler is called on the goroutine calling Shutdown, so it should not block. If the shutdown has already started,
+// handler is called immediately.
+func (s *Socket) OnDrain(handler func()) {
+	s.drainMutex.Lock()
+
+	if s.drainStarted {
+		s.drainMutex.Unlock()
+		handler()
+		return
+	}
+
+	s.drainHandlers = append(s.drainHandlers, handler)
+	s.drainMutex.Unlock()
+}
+
+// CloseAfter schedules the socket to be closed with given reason after duration d, eg. at the end of a trial session.
+// Scheduled close is performed by the housekeeping job of the server, so its precision is limited by
+// ServerConfig.TickInterval. Calling CloseAfter again reschedules the close, CancelClose cancels it.
+// Standalone sockets (see NewSocket) are never closed this way.
+func (s *Socket) CloseAfter(d time.Duration, reason CloseReason) {
+	atomic.StoreInt32(&s.closeAfter, int32(reason))
+	atomic.StoreInt64(&s.closeDeadline, s.clock.Now().Add(d).UTC().UnixMilli())
+}
+
+// CancelClose cancels the close scheduled by CloseAfter.
+func (s *Socket) CancelClose() {
+	atomic.StoreInt64(&s.closeDeadline, 0)
+}
+
 // Read conforms to the io.Reader interface.
 func (s *Socket) Read(b []byte) (int, error) {
+	if s.IsClosed() {
+		return 0, ErrSocketClosed
+	}
+
+	if err := s.handshake(); err != nil {
+		return 0, err
+	}
+
+	if len(s.peeked) > 0 {
+		n := copy(b, s.peeked)
+		s.peeked = s.peeked[n:]
+		return n, nil
+	}
+
 	n, err := s.reader.Read(b)
 	if err != nil {
 		if isBrokenPipe(err) {
-			_ = s.Close(CloseReasonClient)
-			return n, io.EOF
+			if n > 0 {
+				// let the handler process the data while the socket is still open,
+				// the error is returned again by the next Read()
+				return n, nil
+			}
+
+			return n, s.closeBroken()
 		}
 
 		return n, err
@@ -77,13 +289,60 @@ func (s *Socket) Read(b []byte) (int, error) {
 	return n, nil
 }
 
+// Peek returns the next n bytes without consuming them, they are returned again by the subsequent Read() calls.
+// It allows to inspect the initial bytes sent by the client and route the connection based on its content,
+// eg. to a different handler. Peek blocks until n bytes are available. If fewer bytes are read, it returns them
+// together with an error. Returned slice is only valid until the next call to Read() or Peek().
+// Peeked data has already passed through the reader wrappers, wrappers added later won't affect it.
+func (s *Socket) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.New("negative peek size")
+	}
+
+	if s.IsClosed() {
+		return nil, ErrSocketClosed
+	}
+
+	if err := s.handshake(); err != nil {
+		return nil, err
+	}
+
+	if cap(s.peeked) < n {
+		grown := make([]byte, len(s.peeked), n)
+		copy(grown, s.peeked)
+		s.peeked = grown
+	}
+
+	for len(s.peeked) < n {
+		read, err := s.reader.Read(s.peeked[len(s.peeked):n])
+		s.peeked = s.peeked[:len(s.peeked)+read]
+
+		if err != nil {
+			if isBrokenPipe(err) {
+				return s.peeked, s.closeBroken()
+			}
+
+			return s.peeked, err
+		}
+	}
+
+	return s.peeked[:n], nil
+}
+
 // Write conforms to the io.Writer interface.
 func (s *Socket) Write(b []byte) (int, error) {
+	if s.IsClosed() {
+		return 0, ErrSocketClosed
+	}
+
+	if err := s.handshake(); err != nil {
+		return 0, err
+	}
+
 	n, err := s.writer.Write(b)
 	if err != nil {
 		if isBrokenPipe(err) {
-			_ = s.Close(CloseReasonClient)
-			return n, io.EOF
+			return n, s.closeBroken()
 		}
 
 		return n, err
@@ -92,13 +351,73 @@ func (s *Socket) Write(b []byte) (int, error) {
 	return n, nil
 }
 
+// ReadFrom conforms to the io.ReaderFrom interface. Unless the writer has been wrapped (see WrapWriter), the data
+// is passed to the underlying connection, so *net.TCPConn transfers it within the kernel, eg. with sendfile
+// when r is an *os.File, avoiding the copies in userspace. Otherwise, the data is copied through Write().
+func (s *Socket) ReadFrom(r io.Reader) (int64, error) {
+	if s.IsClosed() {
+		return 0, ErrSocketClosed
+	}
+
+	if err := s.handshake(); err != nil {
+		return 0, err
+	}
+
+	var (
+		n   int64
+		err error
+	)
+
+	if s.writer == io.Writer(s.meteredWriter) {
+		n, err = s.meteredWriter.ReadFrom(r)
+	} else if s.writeBuffer != nil && s.writer == io.Writer(s.writeBuffer) {
+		n, err = s.writeBuffer.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{s.writer}, r)
+	}
+
+	if err != nil {
+		if isBrokenPipe(err) {
+			return n, s.closeBroken()
+		}
+
+		return n, err
+	}
+
+	return n, nil
+}
+
+// Flush writes the data held by the write buffer to the connection (see ServerConfig.WriteBufferSize).
+// It has no effect when the buffered writes are disabled.
+func (s *Socket) Flush() error {
+	if s.writeBuffer == nil {
+		return nil
+	}
+	if s.IsClosed() {
+		return ErrSocketClosed
+	}
+
+	if err := s.writeBuffer.Flush(); err != nil {
+		if isBrokenPipe(err) {
+			return s.closeBroken()
+		}
+
+		return err
+	}
+
+	return nil
+}
+
 // SetDeadline sets deadline for underlying socket.
 func (s *Socket) SetDeadline(deadline time.Time) error {
+	if s.IsClosed() {
+		return ErrSocketClosed
+	}
+
 	err := s.conn.SetDeadline(deadline)
 	if err != nil {
 		if isBrokenPipe(err) {
-			_ = s.Close(CloseReasonClient)
-			return io.EOF
+			return s.closeBroken()
 		}
 
 		return err
@@ -109,11 +428,14 @@ func (s *Socket) SetDeadline(deadline time.Time) error {
 
 // SetReadDeadline sets read deadline for underlying socket.
 func (s *Socket) SetReadDeadline(deadline time.Time) error {
+	if s.IsClosed() {
+		return ErrSocketClosed
+	}
+
 	err := s.conn.SetReadDeadline(deadline)
 	if err != nil {
 		if isBrokenPipe(err) {
-			_ = s.Close(CloseReasonClient)
-			return io.EOF
+			return s.closeBroken()
 		}
 
 		return err
@@ -124,11 +446,14 @@ func (s *Socket) SetReadDeadline(deadline time.Time) error {
 
 // SetWriteDeadline sets read deadline for underlying socket.
 func (s *Socket) SetWriteDeadline(deadline time.Time) error {
+	if s.IsClosed() {
+		return ErrSocketClosed
+	}
+
 	err := s.conn.SetWriteDeadline(deadline)
 	if err != nil {
 		if isBrokenPipe(err) {
-			_ = s.Close(CloseReasonClient)
-			return io.EOF
+			return s.closeBroken()
 		}
 
 		return err
@@ -137,16 +462,47 @@ func (s *Socket) SetWriteDeadline(deadline time.Time) error {
 	return nil
 }
 
+// ID returns a number identifying the socket among all the sockets accepted by the server.
+// Standalone sockets (see NewSocket) have ID of 0.
+func (s *Socket) ID() uint64 {
+	return s.id
+}
+
 // RemoteAddress returns a remote address of the socket.
 func (s *Socket) RemoteAddress() string {
 	return s.remoteAddr
 }
 
+// PeerLabels returns labels assigned to the remote peer by ServerConfig.ClassifyPeer.
+// Returns nil if the peer hasn't been classified. Returned map should not be modified.
+func (s *Socket) PeerLabels() map[string]string {
+	return s.peerLabels
+}
+
 // ConnectedAt returns a unix timestamp indicating the exact moment the socket has connected (UTC, in milliseconds).
 func (s *Socket) ConnectedAt() int64 {
 	return s.timestamp
 }
 
+// AcceptedAt returns the moment the connection has been accepted, with the full precision of the server Clock.
+func (s *Socket) AcceptedAt() time.Time {
+	return s.acceptedAt
+}
+
+// ReadyLatency returns time elapsed between accepting the connection and the moment it became ready to transfer
+// application data, ie. the first Read, Peek or Write call by the handler, after the TLS handshake has completed.
+// It includes the time spent by the socket in the queues of the ForkingStrategy. Returns 0 if the socket
+// is not ready yet.
+func (s *Socket) ReadyLatency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.readyLatency))
+}
+
+// HandshakeDuration returns a duration of the TLS handshake. Returns 0 if the connection doesn't use TLS
+// or the handshake hasn't completed yet.
+func (s *Socket) HandshakeDuration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.handshakeDuration))
+}
+
 // OnClose registers a handler that is called when underlying TCP connection is being closed.
 func (s *Socket) OnClose(handler SocketCloseHandler) {
 	s.closeHandlersMutex.Lock()
@@ -155,26 +511,47 @@ func (s *Socket) OnClose(handler SocketCloseHandler) {
 	s.closeHandlers = append(s.closeHandlers, handler)
 }
 
+// OnRead registers a hook that is called with the number of bytes each time data is read from the underlying
+// connection. Hooks observe the same traffic as the metered counters (see TotalRead), so they are suitable
+// for auditing or custom accounting. Hooks are called synchronously, on the reading goroutine, so they need to be fast.
+// OnRead must not be called concurrently with Read(), preferably right at the start of the handler.
+func (s *Socket) OnRead(hook func(n int)) {
+	s.meteredReader.hooks = append(s.meteredReader.hooks, hook)
+}
+
+// OnWrite registers a hook that is called with the number of bytes each time data is written to the underlying
+// connection (see OnRead). OnWrite must not be called concurrently with Write().
+func (s *Socket) OnWrite(hook func(n int)) {
+	s.meteredWriter.hooks = append(s.meteredWriter.hooks, hook)
+}
+
 // OnRecycle registers a handler that is called when the Socket object is being recycled and put back into pool.
+// If the socket is already being recycled, handler is called immediately.
 func (s *Socket) OnRecycle(handler func()) {
 	s.recycleHandlersMutex.Lock()
-	defer s.recycleHandlersMutex.Unlock()
+
+	if s.recycling {
+		s.recycleHandlersMutex.Unlock()
+		handler()
+		return
+	}
 
 	s.recycleHandlers = append(s.recycleHandlers, handler)
+	s.recycleHandlersMutex.Unlock()
 }
 
 // Recycle closes the socket and marks it as recyclable.
 func (s *Socket) Recycle() error {
 	err := s.Close()
 
-	s.recycleHandlersMutex.RLock()
-	{
-		for i := len(s.recycleHandlers) - 1; i >= 0; i-- {
-			handler := s.recycleHandlers[i]
-			handler()
-		}
+	s.recycleHandlersMutex.Lock()
+	s.recycling = true
+	handlers := s.recycleHandlers
+	s.recycleHandlersMutex.Unlock()
+
+	for i := len(handlers) - 1; i >= 0; i-- {
+		handlers[i]()
 	}
-	s.recycleHandlersMutex.RUnlock()
 
 	atomic.StoreUint32(&s.recyclable, 1)
 	return err
@@ -186,12 +563,9 @@ func (s *Socket) Unwrap() net.Conn {
 }
 
 // UnwrapTLS tries to return underlying tls.Conn instance from Socket.
+// In case of TLS auto-detection mode, it might block until the first byte is received from the client.
 func (s *Socket) UnwrapTLS() (*tls.Conn, bool) {
-	if conn, ok := s.conn.(*tls.Conn); ok {
-		return conn, true
-	}
-
-	return nil, false
+	return unwrapTLSConn(s.conn)
 }
 
 // WrapReader allows to wrap reader object into user defined wrapper.
@@ -199,11 +573,82 @@ func (s *Socket) WrapReader(wrapper func(io.Reader) io.Reader) {
 	s.reader = wrapper(s.reader)
 }
 
+// UpgradeReader wraps reader object into user defined wrapper, just like WrapReader. It's meant to be called from
+// PacketHandler, when the protocol switches to another encoding mid-stream (eg. enables compression).
+// Data already read by PacketFramingHandler past the current packet is passed through the wrapper as well,
+// instead of being interpreted as is. Upgrade takes effect once the PacketHandler returns.
+// Outside PacketFramingHandler it's equivalent to WrapReader. Strategies handling packets asynchronously
+// (eg. Actors or Sharded) are not supported.
+func (s *Socket) UpgradeReader(wrapper func(io.Reader) io.Reader) {
+	if !s.framing {
+		s.WrapReader(wrapper)
+		return
+	}
+
+	previous := s.readerUpgrade
+	if previous == nil {
+		s.readerUpgrade = wrapper
+		return
+	}
+
+	s.readerUpgrade = func(reader io.Reader) io.Reader {
+		return wrapper(previous(reader))
+	}
+}
+
+// Handoff detaches the socket from PacketFramingHandler and passes it to another SocketHandler. It's meant to be called
+// from PacketHandler, when the connection switches to a protocol handled in a completely different way
+// (eg. HTTP upgrade to WebSocket). Data already read by PacketFramingHandler past the current packet is not lost,
+// it's returned by the next Read() calls of the socket. Handoff takes effect once the PacketHandler returns,
+// the new handler is called on the same goroutine. Outside PacketFramingHandler the handler is called immediately.
+// Strategies handling packets asynchronously (eg. Actors or Sharded) are not supported.
+func (s *Socket) Handoff(handler SocketHandler) {
+	if !s.framing {
+		handler(s)
+		return
+	}
+
+	s.handoff = handler
+}
+
 // WrapWriter allows to wrap writer object into user defined wrapper.
 func (s *Socket) WrapWriter(wrapper func(io.Writer) io.Writer) {
 	s.writer = wrapper(s.writer)
 }
 
+// PauseReads stops PacketFramingHandler from reading data off the wire and passing packets to the handler,
+// until ResumeReads is called. It allows to apply backpressure, eg. when a downstream queue is full.
+// Data already received by the kernel stays in its buffers, so the TCP flow control eventually slows the client down.
+// Packets already read into the buffers of the framing loop are held as well. Pause has no effect on reads performed
+// outside PacketFramingHandler. Closing the socket resumes reads.
+func (s *Socket) PauseReads() {
+	s.readsMutex.Lock()
+	defer s.readsMutex.Unlock()
+
+	if s.readsResumed == nil {
+		s.readsResumed = make(chan struct{})
+	}
+}
+
+// ResumeReads resumes reads paused by PauseReads.
+func (s *Socket) ResumeReads() {
+	s.readsMutex.Lock()
+	defer s.readsMutex.Unlock()
+
+	if s.readsResumed != nil {
+		close(s.readsResumed)
+		s.readsResumed = nil
+	}
+}
+
+// ReadsPaused returns true if reads have been paused by PauseReads.
+func (s *Socket) ReadsPaused() bool {
+	s.readsMutex.Lock()
+	defer s.readsMutex.Unlock()
+
+	return s.readsResumed != nil
+}
+
 // TotalRead returns a total number of bytes read through this socket.
 func (s *Socket) TotalRead() uint64 {
 	return s.meteredReader.Total()
@@ -224,40 +669,309 @@ func (s *Socket) WrittenLastSecond() uint64 {
 	return s.meteredWriter.PerSecond()
 }
 
-func (s *Socket) init(conn net.Conn) {
+// ReadRate returns a rate of bytes read from this socket, averaged over different time windows.
+func (s *Socket) ReadRate() Rate {
+	return s.meteredReader.Rate()
+}
+
+// WriteRate returns a rate of bytes written to this socket, averaged over different time windows.
+func (s *Socket) WriteRate() Rate {
+	return s.meteredWriter.Rate()
+}
+
+// PacketLatency returns a distribution of time spent by the packet handler on processing packets received
+// through this socket (see PacketFramingHandler). Buckets are defined by PacketLatencyBuckets.
+func (s *Socket) PacketLatency() Histogram[time.Duration] {
+	return s.packetLatency.Total()
+}
+
+// PacketSize returns a distribution of sizes of packets received through this socket (see PacketFramingHandler).
+// Buckets are defined by PacketSizeBuckets.
+func (s *Socket) PacketSize() Histogram[uint64] {
+	return s.packetSize.Total()
+}
+
+func (s *Socket) init(conn net.Conn, clock Clock) {
+	s.closed = 0
+	s.clock = clock
 	s.remoteAddr = parseRemoteAddress(conn)
-	s.timestamp = time.Now().UTC().UnixMilli()
+	s.acceptedAt = clock.Now()
+	s.timestamp = s.acceptedAt.UTC().UnixMilli()
+	s.lastActivity = s.timestamp
 	s.conn = conn
 	s.meteredReader.reader = conn
 	s.meteredWriter.writer = conn
 	s.reader = s.meteredReader
 	s.writer = s.meteredWriter
+	s.packetLatency.buckets = &PacketLatencyBuckets
+	s.packetSize.buckets = &PacketSizeBuckets
 }
 
 func (s *Socket) reset() {
 	s.remoteAddr = ""
+	s.peerLabels = nil
 	s.conn = nil
 	s.reader = nil
 	s.writer = nil
+	s.framing = false
+	s.readerUpgrade = nil
+	s.handoff = nil
+	s.peeked = nil
+	s.readsResumed = nil
+	s.readsMutex = sync.Mutex{}
 	s.meteredReader.reset()
 	s.meteredWriter.reset()
+	if s.writeBuffer != nil {
+		s.writeBuffer.Free()
+		s.writeBuffer = nil
+	}
+	s.packetLatency.reset()
+	s.packetSize.reset()
+	s.closeReason = CloseReasonServer
+	s.closeErr = nil
+	s.closed = 1 // operations on a pooled socket are invalid
+	s.closedAt = 0
+	s.closeDeadline = 0
+	s.closeAfter = 0
+	s.lastActivity = 0
+	s.id = 0
 	s.recyclable = 0
 	s.closeHandlers = nil
 	s.recycleHandlers = nil
+	s.recycling = false
 	s.closeOnce = sync.Once{}
+	s.done = nil
+	s.doneClosed = false
+	s.doneMutex = sync.Mutex{}
+	s.draining = nil
+	s.drainStarted = false
+	s.drainHandlers = nil
+	s.drainMutex = sync.Mutex{}
 	s.closeHandlersMutex = sync.RWMutex{}
 	s.recycleHandlersMutex = sync.RWMutex{}
+	s.ref = nil
+	s.refOnce = sync.Once{}
+	s.acceptedAt = time.Time{}
+	s.verifyPeer = nil
+	s.handshakeOnce = sync.Once{}
+	s.handshakeFailed = false
+	s.handshakeDuration = 0
+	s.readyLatency = 0
+	s.ready = 0
+	s.readyReported = false
 
 	s.prev = nil
 	s.next = nil
 }
 
+// applyReaderUpgrade applies the wrapper passed to UpgradeReader, feeding it with the data left in the read buffers
+// of the framing loop first.
+func (s *Socket) applyReaderUpgrade(buffered []byte) {
+	wrapper := s.readerUpgrade
+	s.readerUpgrade = nil
+
+	s.unread(buffered)
+	s.reader = wrapper(s.reader)
+}
+
+// unread makes the data left in the read buffers of the framing loop available to the next Read() calls.
+func (s *Socket) unread(buffered []byte) {
+	if len(buffered) > 0 {
+		s.reader = io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), s.reader)
+	}
+}
+
+// runHandoff calls the handler passed to Handoff, once the framing loop has exited.
+func (s *Socket) runHandoff() {
+	handler := s.handoff
+	if handler == nil {
+		return
+	}
+
+	s.handoff = nil
+	handler(s)
+}
+
+// waitForReads blocks while reads are paused (see PauseReads).
+func (s *Socket) waitForReads() {
+	s.readsMutex.Lock()
+	resumed := s.readsResumed
+	s.readsMutex.Unlock()
+
+	if resumed != nil {
+		<-resumed
+	}
+}
+
+// handshake completes the TLS handshake (if the connection uses TLS) and verifies the peer with TLSVerifyPeer,
+// before the first data transfer. It also marks the socket as ready (see ReadyLatency).
+func (s *Socket) handshake() error {
+	var err error
+
+	s.handshakeOnce.Do(func() {
+		err = s.handshakeAndVerify()
+		if err != nil {
+			s.handshakeFailed = true
+			return
+		}
+
+		atomic.StoreInt64(&s.readyLatency, int64(s.clock.Now().Sub(s.acceptedAt)))
+		atomic.StoreUint32(&s.ready, 1)
+	})
+
+	if err != nil {
+		return err
+	}
+	if s.handshakeFailed {
+		return io.EOF
+	}
+
+	return nil
+}
+
+func (s *Socket) handshakeAndVerify() error {
+	conn, ok := unwrapTLSConn(s.conn)
+	if !ok {
+		return nil
+	}
+
+	start := s.clock.Now()
+
+	if err := conn.Handshake(); err != nil {
+		if isBrokenPipe(err) {
+			return s.closeBroken()
+		}
+
+		_ = s.Close()
+		return err
+	}
+
+	atomic.StoreInt64(&s.handshakeDuration, int64(s.clock.Now().Sub(start)))
+
+	if s.verifyPeer == nil {
+		return nil
+	}
+
+	if err := s.verifyPeer(s, conn.ConnectionState()); err != nil {
+		_ = s.Close()
+		return err
+	}
+
+	return nil
+}
+
+// drain notifies the socket that the server is shutting down (see Draining).
+func (s *Socket) drain() {
+	s.drainMutex.Lock()
+
+	if s.drainStarted {
+		s.drainMutex.Unlock()
+		return
+	}
+
+	s.drainStarted = true
+	if s.draining != nil {
+		close(s.draining)
+	}
+
+	handlers := s.drainHandlers
+	s.drainHandlers = nil
+	s.drainMutex.Unlock()
+
+	for _, handler := range handlers {
+		handler()
+	}
+}
+
+// closeIfScheduled closes the socket if the deadline set by CloseAfter has passed.
+func (s *Socket) closeIfScheduled(now int64) {
+	deadline := atomic.LoadInt64(&s.closeDeadline)
+	if deadline == 0 || now < deadline {
+		return
+	}
+
+	atomic.StoreInt64(&s.closeDeadline, 0)
+	_ = s.Close(CloseReason(atomic.LoadInt32(&s.closeAfter)))
+}
+
+// closeBroken handles an operation that failed because the connection is broken. If the socket has been closed
+// locally in the meantime, ErrSocketClosed is returned. Otherwise, the connection has been lost and io.EOF is returned.
+func (s *Socket) closeBroken() error {
+	if s.IsClosed() {
+		return ErrSocketClosed
+	}
+
+	_ = s.Close(CloseReasonClient)
+	return io.EOF
+}
+
+// enableWriteBuffer makes the socket coalesce the writes in a buffer of given size. It needs to be called before
+// the socket is passed to the handler.
+func (s *Socket) enableWriteBuffer(size int) {
+	s.writeBuffer = newWriteBuffer(s.writer, s.clock, size)
+	s.writer = s.writeBuffer
+}
+
+// flushIfDirty flushes the write buffer, if it has been holding the data since before the given time (in unix millis).
+// It's expected to be called only from the housekeeping job.
+func (s *Socket) flushIfDirty(before int64) {
+	if s.writeBuffer == nil || s.IsClosed() {
+		return
+	}
+
+	if _, err := s.writeBuffer.FlushIfDirty(before); err != nil && isBrokenPipe(err) {
+		_ = s.closeBroken()
+	}
+}
+
+// spliceableConn returns the underlying connection if the data can be read from it directly, bypassing Read(),
+// ie. the connection can be spliced, the reader has not been wrapped and there is no data buffered by Peek.
+func (s *Socket) spliceableConn() (io.Reader, bool) {
+	if s.IsClosed() || len(s.peeked) > 0 || s.reader != io.Reader(s.meteredReader) {
+		return nil, false
+	}
+	if s.meteredReader.reader != io.Reader(s.conn) || !isSpliceable(s.conn) {
+		return nil, false
+	}
+
+	return s.conn, true
+}
+
+// sharedRef returns a SocketRef shared by all the callers, created on the first call.
+func (s *Socket) sharedRef() *SocketRef {
+	s.refOnce.Do(func() {
+		s.ref = NewSocketRef(s)
+	})
+
+	return s.ref
+}
+
+// takeReadyMetrics returns the latencies recorded when the socket became ready, exactly once.
+// It's expected to be called only from the housekeeping job.
+func (s *Socket) takeReadyMetrics() (readyLatency time.Duration, handshakeDuration time.Duration, ok bool) {
+	if s.readyReported || atomic.LoadUint32(&s.ready) == 0 {
+		return 0, 0, false
+	}
+
+	s.readyReported = true
+	return s.ReadyLatency(), s.HandshakeDuration(), true
+}
+
 func (s *Socket) isRecyclable() bool {
 	return atomic.LoadUint32(&s.recyclable) == 1
 }
 
-func (s *Socket) updateMetrics(interval time.Duration) (uint64, uint64) {
-	reads := s.meteredReader.Update(interval)
-	writes := s.meteredWriter.Update(interval)
-	return reads, writes
+func (s *Socket) updateMetrics(interval time.Duration, now int64) socketMetricsDelta {
+	delta := socketMetricsDelta{
+		reads:         s.meteredReader.Update(interval),
+		writes:        s.meteredWriter.Update(interval),
+		packetLatency: s.packetLatency.Update(),
+		packetSize:    s.packetSize.Update(),
+	}
+
+	if delta.reads > 0 || delta.writes > 0 {
+		atomic.StoreInt64(&s.lastActivity, now)
+	}
+
+	return delta
 }
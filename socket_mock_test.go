@@ -59,11 +59,24 @@ func (cm *ConnMock) SetWriteDeadline(_ time.Time) error {
 // Socket
 
 func MockSocket(in io.Reader, out io.Writer) *Socket {
+	meteredReader := &meteredReader{reader: in}
+	meteredWriter := &meteredWriter{writer: out}
+
 	return &Socket{
-		remoteAddr: "127.0.0.1",
-		timestamp:  time.Now().UTC().UnixMilli(),
-		conn:       &ConnMock{},
-		reader:     in,
-		writer:     out,
+		remoteAddr:    "127.0.0.1",
+		timestamp:     time.Now().UTC().UnixMilli(),
+		acceptedAt:    time.Now(),
+		conn:          &ConnMock{},
+		reader:        meteredReader,
+		writer:        meteredWriter,
+		meteredReader: meteredReader,
+		meteredWriter: meteredWriter,
+		clock:         SystemClock(),
+		packetLatency: histogramRecorder[time.Duration]{
+			buckets: &PacketLatencyBuckets,
+		},
+		packetSize: histogramRecorder[uint64]{
+			buckets: &PacketSizeBuckets,
+		},
 	}
 }
@@ -0,0 +1,86 @@
+package tinytcp
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// SocketSetupError is passed to the handler set with Server.OnSocketSetupError, when applying the option configured
+// in ServerConfig (eg. ReceiveBufferSize) to the accepted connection fails. Such connections are still served.
+type SocketSetupError struct {
+	// Option is a name of the option that failed, eg. "SO_RCVBUF".
+	Option string
+
+	// RemoteAddr is an address of the client.
+	RemoteAddr net.Addr
+
+	// LocalAddr is an address the client connected to.
+	LocalAddr net.Addr
+
+	// Err is the error returned by the operating system.
+	Err error
+}
+
+func (e *SocketSetupError) Error() string {
+	return "failed to set " + e.Option + " for connection from " + e.RemoteAddr.String() + ": " + e.Err.Error()
+}
+
+func (e *SocketSetupError) Unwrap() error {
+	return e.Err
+}
+
+type tcpOptionsSetter interface {
+	SetNoDelay(noDelay bool) error
+	SetKeepAlive(keepalive bool) error
+	SetKeepAlivePeriod(d time.Duration) error
+	SetReadBuffer(bytes int) error
+	SetWriteBuffer(bytes int) error
+}
+
+// applySocketSetup applies the per-connection options from ServerConfig to the underlying TCP connection,
+// reporting every failed option to onError. Other connections are left unchanged.
+func applySocketSetup(conn net.Conn, config *ServerConfig, onError func(*SocketSetupError)) {
+	if !config.DisableNoDelay && config.KeepAlive == 0 && config.ReceiveBufferSize == 0 && config.SendBufferSize == 0 {
+		return
+	}
+
+	switch c := conn.(type) {
+	case *tls.Conn:
+		conn = c.NetConn()
+	case *autoDetectConn:
+		conn = c.Conn
+	}
+
+	setter, ok := conn.(tcpOptionsSetter)
+	if !ok {
+		return
+	}
+
+	report := func(option string, err error) {
+		if err != nil {
+			onError(&SocketSetupError{
+				Option:     option,
+				RemoteAddr: conn.RemoteAddr(),
+				LocalAddr:  conn.LocalAddr(),
+				Err:        err,
+			})
+		}
+	}
+
+	if config.DisableNoDelay {
+		report("TCP_NODELAY", setter.SetNoDelay(false))
+	}
+	if config.KeepAlive > 0 {
+		report("SO_KEEPALIVE", setter.SetKeepAlive(true))
+		report("TCP_KEEPINTVL", setter.SetKeepAlivePeriod(config.KeepAlive))
+	} else if config.KeepAlive < 0 {
+		report("SO_KEEPALIVE", setter.SetKeepAlive(false))
+	}
+	if config.ReceiveBufferSize > 0 {
+		report("SO_RCVBUF", setter.SetReadBuffer(config.ReceiveBufferSize))
+	}
+	if config.SendBufferSize > 0 {
+		report("SO_SNDBUF", setter.SetWriteBuffer(config.SendBufferSize))
+	}
+}
@@ -0,0 +1,76 @@
+package tinytcp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSocketSetup(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:        -1,
+		KeepAlive:         30 * time.Second,
+		DisableNoDelay:    true,
+		ReceiveBufferSize: 64 * 1024,
+		SendBufferSize:    64 * 1024,
+	})
+
+	setupErrors := make(chan *SocketSetupError, 8)
+	server.OnSocketSetupError(func(err *SocketSetupError) {
+		setupErrors <- err
+	})
+
+	accepted := make(chan struct{}, 1)
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {
+		accepted <- struct{}{}
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	<-accepted
+
+	// then
+	assert.Empty(t, setupErrors, "options should be applied without errors")
+}
+
+func TestSocketSetupError(t *testing.T) {
+	// given
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "err should be nil")
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+
+	_ = conn.Close()
+
+	var setupErrors []*SocketSetupError
+
+	// when
+	applySocketSetup(conn, &ServerConfig{ReceiveBufferSize: 1024, SendBufferSize: 1024}, func(err *SocketSetupError) {
+		setupErrors = append(setupErrors, err)
+	})
+
+	// then
+	if assert.Len(t, setupErrors, 2, "both options should fail") {
+		assert.Equal(t, "SO_RCVBUF", setupErrors[0].Option, "option should match")
+		assert.Equal(t, "SO_SNDBUF", setupErrors[1].Option, "option should match")
+		assert.Equal(t, conn.RemoteAddr(), setupErrors[0].RemoteAddr, "remote address should match")
+		assert.True(t, errors.Is(setupErrors[0], net.ErrClosed), "error should be unwrapped")
+	}
+}
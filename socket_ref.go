@@ -36,7 +36,7 @@ func (r *SocketRef) Read(b []byte) (int, error) {
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return 0, io.EOF
+		return 0, ErrSocketClosed
 	}
 
 	return r.s.Read(b)
@@ -48,31 +48,79 @@ func (r *SocketRef) Write(b []byte) (int, error) {
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return 0, io.EOF
+		return 0, ErrSocketClosed
 	}
 
 	return r.s.Write(b)
 }
 
+// ReadFrom writes the data read from r into a socket, only if it hasn't been recycled yet (see Socket.ReadFrom).
+func (r *SocketRef) ReadFrom(reader io.Reader) (int64, error) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return 0, ErrSocketClosed
+	}
+
+	return r.s.ReadFrom(reader)
+}
+
+// Flush flushes the write buffer of a socket only if it hasn't been recycled yet (see Socket.Flush).
+func (r *SocketRef) Flush() error {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return ErrSocketClosed
+	}
+
+	return r.s.Flush()
+}
+
 // Close closes a socket only if it hasn't been recycled yet.
 func (r *SocketRef) Close(reason ...CloseReason) error {
 	r.m.RLock()
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return io.EOF
+		return ErrSocketClosed
 	}
 
 	return r.s.Close(reason...)
 }
 
+// CloseAfter schedules a close of a socket only if it hasn't been recycled yet (see Socket.CloseAfter).
+func (r *SocketRef) CloseAfter(d time.Duration, reason CloseReason) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.CloseAfter(d, reason)
+}
+
+// CancelClose cancels a scheduled close of a socket only if it hasn't been recycled yet (see Socket.CancelClose).
+func (r *SocketRef) CancelClose() {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.CancelClose()
+}
+
 // SetDeadline sets deadline of a socket only if it hasn't been recycled yet.
 func (r *SocketRef) SetDeadline(deadline time.Time) error {
 	r.m.RLock()
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return io.EOF
+		return ErrSocketClosed
 	}
 
 	return r.s.SetDeadline(deadline)
@@ -84,7 +132,7 @@ func (r *SocketRef) SetReadDeadline(deadline time.Time) error {
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return io.EOF
+		return ErrSocketClosed
 	}
 
 	return r.s.SetReadDeadline(deadline)
@@ -96,12 +144,48 @@ func (r *SocketRef) SetWriteDeadline(deadline time.Time) error {
 	defer r.m.RUnlock()
 
 	if r.s == nil {
-		return io.EOF
+		return ErrSocketClosed
 	}
 
 	return r.s.SetWriteDeadline(deadline)
 }
 
+// PauseReads pauses reads of a socket only if it hasn't been recycled yet (see Socket.PauseReads).
+func (r *SocketRef) PauseReads() {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.PauseReads()
+}
+
+// ResumeReads resumes reads of a socket only if it hasn't been recycled yet (see Socket.ResumeReads).
+func (r *SocketRef) ResumeReads() {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.ResumeReads()
+}
+
+// ID returns a number identifying the socket among all the sockets accepted by the server.
+func (r *SocketRef) ID() uint64 {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return 0
+	}
+
+	return r.s.ID()
+}
+
 // RemoteAddress returns a remote address of the socket.
 func (r *SocketRef) RemoteAddress() string {
 	r.m.RLock()
@@ -114,6 +198,18 @@ func (r *SocketRef) RemoteAddress() string {
 	return r.s.RemoteAddress()
 }
 
+// PeerLabels returns labels assigned to the remote peer by ServerConfig.ClassifyPeer.
+func (r *SocketRef) PeerLabels() map[string]string {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return nil
+	}
+
+	return r.s.PeerLabels()
+}
+
 // ConnectedAt returns a unix timestamp indicating the exact moment the socket has connected (UTC, in milliseconds).
 func (r *SocketRef) ConnectedAt() int64 {
 	r.m.RLock()
@@ -126,6 +222,94 @@ func (r *SocketRef) ConnectedAt() int64 {
 	return r.s.ConnectedAt()
 }
 
+// AcceptedAt returns the moment the connection has been accepted, with the full precision of the server Clock.
+func (r *SocketRef) AcceptedAt() time.Time {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return time.Time{}
+	}
+
+	return r.s.AcceptedAt()
+}
+
+// ReadyLatency returns time elapsed between accepting the connection and the moment it became ready to transfer
+// application data (see Socket.ReadyLatency).
+func (r *SocketRef) ReadyLatency() time.Duration {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return 0
+	}
+
+	return r.s.ReadyLatency()
+}
+
+// HandshakeDuration returns a duration of the TLS handshake. Returns 0 if the connection doesn't use TLS
+// or the handshake hasn't completed yet.
+func (r *SocketRef) HandshakeDuration() time.Duration {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return 0
+	}
+
+	return r.s.HandshakeDuration()
+}
+
+// IsClosed returns true if the socket has been closed or recycled.
+func (r *SocketRef) IsClosed() bool {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return true
+	}
+
+	return r.s.IsClosed()
+}
+
+// Done returns a channel that is closed when the socket closes (see Socket.Done).
+// If the socket has already been recycled, the returned channel is closed.
+func (r *SocketRef) Done() <-chan struct{} {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return closedChannel
+	}
+
+	return r.s.Done()
+}
+
+// Draining returns a channel that is closed when the server starts a graceful shutdown (see Socket.Draining).
+// If the socket has already been recycled, the returned channel is nil.
+func (r *SocketRef) Draining() <-chan struct{} {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return nil
+	}
+
+	return r.s.Draining()
+}
+
+// OnDrain registers a handler that is called when the server starts a graceful shutdown (see Socket.OnDrain).
+func (r *SocketRef) OnDrain(handler func()) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.OnDrain(handler)
+}
+
 // OnClose registers a handler that is called when underlying TCP connection is being closed.
 func (r *SocketRef) OnClose(handler SocketCloseHandler) {
 	r.m.RLock()
@@ -135,7 +319,7 @@ func (r *SocketRef) OnClose(handler SocketCloseHandler) {
 		return
 	}
 
-	r.OnClose(handler)
+	r.s.OnClose(handler)
 }
 
 // OnRecycle registers a handler that is called when the Socket object is being recycled and put back into pool.
@@ -147,7 +331,7 @@ func (r *SocketRef) OnRecycle(handler func()) {
 		return
 	}
 
-	r.OnRecycle(handler)
+	r.s.OnRecycle(handler)
 }
 
 // Unwrap returns underlying net.Conn instance from Socket.
@@ -198,6 +382,19 @@ func (r *SocketRef) WrapWriter(wrapper func(io.Writer) io.Writer) {
 	r.s.WrapWriter(wrapper)
 }
 
+// EnableCapture starts copying all the raw data read from and written to the socket into sink, in hexdump format.
+// It must not be called concurrently with Read() or Write() (see Socket.EnableCapture).
+func (r *SocketRef) EnableCapture(sink io.Writer) {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return
+	}
+
+	r.s.EnableCapture(sink)
+}
+
 // TotalRead returns a total number of bytes read through this socket.
 func (r *SocketRef) TotalRead() uint64 {
 	r.m.RLock()
@@ -246,6 +443,56 @@ func (r *SocketRef) WrittenLastSecond() uint64 {
 	return r.s.WrittenLastSecond()
 }
 
+// ReadRate returns a rate of bytes read from this socket, averaged over different time windows.
+func (r *SocketRef) ReadRate() Rate {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return Rate{}
+	}
+
+	return r.s.ReadRate()
+}
+
+// WriteRate returns a rate of bytes written to this socket, averaged over different time windows.
+func (r *SocketRef) WriteRate() Rate {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return Rate{}
+	}
+
+	return r.s.WriteRate()
+}
+
+// PacketLatency returns a distribution of time spent by the packet handler on processing packets received
+// through this socket (see PacketFramingHandler). Buckets are defined by PacketLatencyBuckets.
+func (r *SocketRef) PacketLatency() Histogram[time.Duration] {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return Histogram[time.Duration]{}
+	}
+
+	return r.s.PacketLatency()
+}
+
+// PacketSize returns a distribution of sizes of packets received through this socket (see PacketFramingHandler).
+// Buckets are defined by PacketSizeBuckets.
+func (r *SocketRef) PacketSize() Histogram[uint64] {
+	r.m.RLock()
+	defer r.m.RUnlock()
+
+	if r.s == nil {
+		return Histogram[uint64]{}
+	}
+
+	return r.s.PacketSize()
+}
+
 func (r *SocketRef) onRecycle() {
 	r.m.Lock()
 	defer r.m.Unlock()
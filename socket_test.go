@@ -2,9 +2,15 @@ package tinytcp
 
 import (
 	"bytes"
+	"crypto/tls"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+	"unsafe"
 )
 
 func TestSocketInput(t *testing.T) {
@@ -43,6 +49,267 @@ func TestSocketInputEOF(t *testing.T) {
 	assert.Truef(t, closeHandlerCalled, "close handler should be called")
 }
 
+func TestSocketPeek(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("GET / HTTP/1.1"))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	prefix, peekErr := socket.Peek(3)
+	data, readErr := io.ReadAll(socket)
+
+	// then
+	assert.Nil(t, peekErr, "peek err should be nil")
+	assert.Equal(t, "GET", string(prefix), "peeked data should match")
+	assert.Nil(t, readErr, "read err should be nil")
+	assert.Equal(t, "GET / HTTP/1.1", string(data), "peeked data should not be consumed")
+}
+
+func TestSocketPeekShort(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("ab"))
+	socket := MockSocket(in, io.Discard)
+
+	// when
+	prefix, err := socket.Peek(4)
+
+	// then
+	assert.Equal(t, io.EOF, err, "err should be io.EOF")
+	assert.Equal(t, "ab", string(prefix), "available data should be returned")
+}
+
+func TestSocketReadWriteHooks(t *testing.T) {
+	// given
+	in := bytes.NewBuffer([]byte("Hello world!"))
+	socket := MockSocket(in, io.Discard)
+
+	var read, written int
+	socket.OnRead(func(n int) {
+		read += n
+	})
+	socket.OnWrite(func(n int) {
+		written += n
+	})
+
+	// when
+	_, _ = socket.Write([]byte("Hi"))
+	_, _ = io.ReadAll(socket)
+
+	// then
+	assert.Equal(t, 12, read, "read bytes should be reported")
+	assert.Equal(t, 2, written, "written bytes should be reported")
+}
+
+func TestSocketCloseAfter(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+	now := time.Now().UTC()
+
+	var closeReason CloseReason = -1
+	socket.OnClose(func(reason CloseReason) {
+		closeReason = reason
+	})
+
+	// when
+	socket.CloseAfter(30*time.Second, CloseReasonClient)
+	socket.closeIfScheduled(now.Add(10 * time.Second).UnixMilli())
+	closedEarly := closeReason != -1
+	socket.closeIfScheduled(now.Add(time.Minute).UnixMilli())
+
+	// then
+	assert.False(t, closedEarly, "socket should not be closed before the deadline")
+	assert.Equal(t, CloseReasonClient, closeReason, "socket should be closed with the scheduled reason")
+}
+
+func TestSocketCancelClose(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+
+	var closed bool
+	socket.OnClose(func(_ CloseReason) {
+		closed = true
+	})
+
+	// when
+	socket.CloseAfter(time.Second, CloseReasonServer)
+	socket.CancelClose()
+	socket.closeIfScheduled(time.Now().Add(time.Minute).UTC().UnixMilli())
+
+	// then
+	assert.False(t, closed, "socket should not be closed")
+}
+
+func TestSocketClosed(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+
+	// when
+	_ = socket.Close()
+	_, readErr := socket.Read(make([]byte, 1))
+	_, writeErr := socket.Write([]byte("Hi"))
+	deadlineErr := socket.SetDeadline(time.Now())
+
+	// then
+	assert.Equal(t, ErrSocketClosed, readErr, "read err should be ErrSocketClosed")
+	assert.Equal(t, ErrSocketClosed, writeErr, "write err should be ErrSocketClosed")
+	assert.Equal(t, ErrSocketClosed, deadlineErr, "deadline err should be ErrSocketClosed")
+	assert.ErrorIs(t, readErr, io.EOF, "ErrSocketClosed should wrap io.EOF")
+}
+
+func TestSocketDone(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+	done := socket.Done()
+
+	// when
+	var closedBefore bool
+	select {
+	case <-done:
+		closedBefore = true
+	default:
+	}
+
+	_ = socket.Close()
+
+	// then
+	assert.False(t, closedBefore, "done channel should not be closed before close")
+	_, open := <-done
+	assert.False(t, open, "done channel should be closed")
+	_, open = <-socket.Done()
+	assert.False(t, open, "done channel should be closed after close")
+}
+
+func TestSocketDrain(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+	draining := socket.Draining()
+
+	var calls int
+	socket.OnDrain(func() {
+		calls++
+	})
+
+	// when
+	socket.drain()
+	socket.drain()
+
+	socket.OnDrain(func() {
+		calls++
+	})
+
+	// then
+	_, open := <-draining
+	assert.False(t, open, "draining channel should be closed")
+	_, open = <-socket.Draining()
+	assert.False(t, open, "draining channel should be closed after drain")
+	assert.Equal(t, 2, calls, "handlers should be called once")
+}
+
+func TestSocketReadyLatency(t *testing.T) {
+	// given
+	socket := MockSocket(bytes.NewBufferString("Hello world!"), io.Discard)
+
+	// when
+	_, _, notReady := socket.takeReadyMetrics()
+	time.Sleep(5 * time.Millisecond)
+	_, err := socket.Read(make([]byte, 5))
+	readyLatency, handshakeDuration, ok := socket.takeReadyMetrics()
+	_, _, okAgain := socket.takeReadyMetrics()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.False(t, notReady, "socket should not be ready before the first read")
+	assert.True(t, ok, "socket should be ready after the first read")
+	assert.False(t, okAgain, "ready metrics should be taken once")
+	assert.GreaterOrEqual(t, readyLatency, 5*time.Millisecond, "ready latency should include the wait")
+	assert.Equal(t, readyLatency, socket.ReadyLatency(), "ready latency should match")
+	assert.Equal(t, time.Duration(0), handshakeDuration, "plain socket should have no handshake")
+}
+
+func TestSocketHandshakeDuration(t *testing.T) {
+	// given
+	cert := generateTestCertificate(t)
+	serverConn, clientConn := net.Pipe()
+
+	socket := NewSocket(tls.Server(serverConn, &tls.Config{Certificates: []tls.Certificate{cert}}))
+	defer socket.Close()
+	defer clientConn.Close()
+
+	go func() {
+		client := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true})
+		_, _ = client.Write([]byte("Hello world!"))
+	}()
+
+	// when
+	_, err := io.ReadFull(socket, make([]byte, 12))
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Greater(t, socket.HandshakeDuration(), time.Duration(0), "handshake duration should be recorded")
+	assert.GreaterOrEqual(t, socket.ReadyLatency(), socket.HandshakeDuration(), "ready latency should include handshake")
+	assert.False(t, socket.AcceptedAt().IsZero(), "accept timestamp should be set")
+}
+
+func TestSocketLayout(t *testing.T) {
+	// given
+	var socket Socket
+
+	// when
+	readMostlyEnd := int(unsafe.Offsetof(socket.handshakeFailed) + unsafe.Sizeof(socket.handshakeFailed))
+	perPacketStart := int(unsafe.Offsetof(socket.packetLatency))
+	perPacketEnd := int(unsafe.Offsetof(socket.packetSize) + unsafe.Sizeof(socket.packetSize))
+	sharedStart := int(unsafe.Offsetof(socket.lastActivity))
+	coldEnd := int(unsafe.Offsetof(socket.refOnce) + unsafe.Sizeof(socket.refOnce))
+	linksStart := int(unsafe.Offsetof(socket.prev))
+
+	// then
+	assert.GreaterOrEqual(t, perPacketStart-readMostlyEnd, cacheLineSize, "per-packet fields should be padded")
+	assert.GreaterOrEqual(t, sharedStart-perPacketEnd, cacheLineSize, "shared fields should be padded")
+	assert.GreaterOrEqual(t, linksStart-coldEnd, cacheLineSize, "list links should be padded")
+}
+
+func TestSocketRefCloseState(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+	ref := NewSocketRef(socket)
+
+	var closeHandlerCalled bool
+	ref.OnClose(func(_ CloseReason) {
+		closeHandlerCalled = true
+	})
+
+	// when
+	closedBefore := ref.IsClosed()
+	_ = socket.Recycle()
+	_, open := <-ref.Done()
+
+	// then
+	assert.False(t, closedBefore, "socket should not be closed before recycle")
+	assert.True(t, ref.IsClosed(), "socket should be closed after recycle")
+	assert.False(t, open, "done channel should be closed")
+	assert.True(t, closeHandlerCalled, "close handler should be called")
+}
+
+func TestSocketRefCreatedDuringRecycle(t *testing.T) {
+	// given
+	socket := MockSocket(&bytes.Buffer{}, io.Discard)
+
+	var ref *SocketRef
+	socket.OnRecycle(func() {
+		// eg. IterateRefs racing with the recycle
+		ref = socket.sharedRef()
+	})
+
+	// when
+	_ = socket.Recycle()
+	lateRef := NewSocketRef(socket)
+
+	// then
+	assert.Nil(t, ref.s, "ref created during recycle should be invalidated")
+	assert.Nil(t, lateRef.s, "ref created after recycle should be invalidated")
+	assert.Equal(t, "", lateRef.RemoteAddress(), "invalidated ref should not expose the socket")
+}
+
 func TestSocketOutput(t *testing.T) {
 	// given
 	payload := []byte("Hello world")
@@ -91,3 +358,74 @@ type eofWriter struct {
 func (ew *eofWriter) Write(_ []byte) (int, error) {
 	return 0, io.EOF
 }
+
+func TestSocketReadFromFile(t *testing.T) {
+	// given
+	payload := bytes.Repeat([]byte("Hello world!"), 1024)
+	path := filepath.Join(t.TempDir(), "payload")
+	assert.Nil(t, os.WriteFile(path, payload, 0o600), "err should be nil")
+
+	file, err := os.Open(path)
+	assert.Nil(t, err, "err should be nil")
+	defer file.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "err should be nil")
+	defer listener.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := net.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err, "err should be nil")
+	socket := NewSocket(conn)
+
+	// when
+	n, err := socket.ReadFrom(file)
+	_ = socket.Close()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, int64(len(payload)), n, "n should match")
+	assert.Equal(t, payload, <-received, "payloads should match")
+	assert.Equal(t, uint64(len(payload)), socket.meteredWriter.current, "written bytes should be metered")
+}
+
+func TestSocketReadFromWrappedWriter(t *testing.T) {
+	// given
+	var out bytes.Buffer
+	socket := MockSocket(&bytes.Buffer{}, &out)
+
+	var wrapperCalls int
+	socket.WrapWriter(func(w io.Writer) io.Writer {
+		return writerFunc(func(b []byte) (int, error) {
+			wrapperCalls++
+			return w.Write(b)
+		})
+	})
+
+	// when
+	n, err := socket.ReadFrom(bytes.NewBufferString("Hello world!"))
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, int64(12), n, "n should match")
+	assert.Equal(t, "Hello world!", out.String(), "payloads should match")
+	assert.Greater(t, wrapperCalls, 0, "data should pass through the wrapper")
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
@@ -0,0 +1,131 @@
+package tinytcp
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// writeBuffer coalesces small writes to the socket (see ServerConfig.WriteBufferSize). Data is written
+// to the underlying writer when the buffer fills up, on Flush(), or by the housekeeping job, once the buffer
+// has been dirty for longer than ServerConfig.WriteFlushDelay.
+type writeBuffer struct {
+	writer     io.Writer
+	clock      Clock
+	allocator  Allocator
+	buffer     []byte
+	dirtySince int64
+	m          sync.Mutex
+}
+
+func newWriteBuffer(writer io.Writer, clock Clock, size int) *writeBuffer {
+	allocator := PoolAllocator()
+
+	return &writeBuffer{
+		writer:    writer,
+		clock:     clock,
+		allocator: allocator,
+		buffer:    allocator.Allocate(size)[:0],
+	}
+}
+
+func (b *writeBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if len(b.buffer)+len(p) > cap(b.buffer) {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+
+		if len(p) >= cap(b.buffer) {
+			// no point in copying the data that wouldn't fit anyway
+			return b.writer.Write(p)
+		}
+	}
+
+	if len(b.buffer) == 0 {
+		atomic.StoreInt64(&b.dirtySince, b.clock.Now().UTC().UnixMilli())
+	}
+
+	b.buffer = append(b.buffer, p...)
+	return len(p), nil
+}
+
+// ReadFrom flushes the buffer and lets the underlying writer transfer the data on its own (see Socket.ReadFrom).
+func (b *writeBuffer) ReadFrom(r io.Reader) (int64, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if err := b.flush(); err != nil {
+		return 0, err
+	}
+
+	if readerFrom, ok := b.writer.(io.ReaderFrom); ok {
+		return readerFrom.ReadFrom(r)
+	}
+
+	return io.Copy(writerOnly{b.writer}, r)
+}
+
+// Flush writes the buffered data to the underlying writer.
+func (b *writeBuffer) Flush() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	return b.flush()
+}
+
+// FlushIfDirty flushes the buffer if it has been holding the data since before the given time (in unix millis).
+// The buffer is skipped if it's being written to concurrently, the writer flushes it anyway once it's full.
+func (b *writeBuffer) FlushIfDirty(before int64) (bool, error) {
+	dirtySince := atomic.LoadInt64(&b.dirtySince)
+	if dirtySince == 0 || dirtySince > before {
+		return false, nil
+	}
+
+	if !b.m.TryLock() {
+		return false, nil
+	}
+	defer b.m.Unlock()
+
+	return true, b.flush()
+}
+
+// TryFlush flushes the buffer, unless it's being written to concurrently. Used when the socket is closed,
+// as the concurrent write might be blocked until the connection is closed.
+func (b *writeBuffer) TryFlush() {
+	if !b.m.TryLock() {
+		return
+	}
+	defer b.m.Unlock()
+
+	_ = b.flush()
+}
+
+func (b *writeBuffer) Free() {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.buffer != nil {
+		b.allocator.Free(b.buffer)
+		b.buffer = nil
+	}
+}
+
+func (b *writeBuffer) flush() error {
+	if len(b.buffer) == 0 {
+		return nil
+	}
+
+	n, err := b.writer.Write(b.buffer)
+	if n > 0 {
+		// keep the data that hasn't been written
+		b.buffer = b.buffer[:copy(b.buffer, b.buffer[n:len(b.buffer)])]
+	}
+	if len(b.buffer) == 0 {
+		atomic.StoreInt64(&b.dirtySince, 0)
+	}
+
+	return err
+}
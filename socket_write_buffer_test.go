@@ -0,0 +1,131 @@
+package tinytcp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWriteBufferCoalescing(t *testing.T) {
+	// given
+	var out bytes.Buffer
+	var writes int
+
+	buffer := newWriteBuffer(writerFunc(func(b []byte) (int, error) {
+		writes++
+		return out.Write(b)
+	}), SystemClock(), 64)
+	defer buffer.Free()
+
+	// when
+	_, _ = buffer.Write([]byte("Hello"))
+	_, _ = buffer.Write([]byte(" world"))
+	writesBeforeFlush := writes
+	_ = buffer.Flush()
+
+	// then
+	assert.Equal(t, 0, writesBeforeFlush, "small writes should be buffered")
+	assert.Equal(t, 1, writes, "buffered writes should be written at once")
+	assert.Equal(t, "Hello world", out.String(), "data should match")
+}
+
+func TestWriteBufferFull(t *testing.T) {
+	// given
+	var out bytes.Buffer
+
+	buffer := newWriteBuffer(&out, SystemClock(), 64)
+	defer buffer.Free()
+
+	// when
+	_, _ = buffer.Write(bytes.Repeat([]byte{'a'}, 60))
+	_, _ = buffer.Write(bytes.Repeat([]byte{'b'}, 10))
+	flushedWhenFull := out.Len()
+	_, _ = buffer.Write(bytes.Repeat([]byte{'c'}, 100))
+
+	// then
+	assert.Equal(t, 60, flushedWhenFull, "buffer should be flushed when the data doesn't fit")
+	assert.Equal(t, 170, out.Len(), "large write should bypass the buffer")
+}
+
+func TestWriteBufferFlushIfDirty(t *testing.T) {
+	// given
+	var out bytes.Buffer
+
+	buffer := newWriteBuffer(&out, SystemClock(), 64)
+	defer buffer.Free()
+
+	_, _ = buffer.Write([]byte("Hello"))
+	now := time.Now().UTC().UnixMilli()
+
+	// when
+	flushedEarly, _ := buffer.FlushIfDirty(now - 1000)
+	flushed, err := buffer.FlushIfDirty(now)
+	flushedClean, _ := buffer.FlushIfDirty(now)
+
+	// then
+	assert.False(t, flushedEarly, "buffer should not be flushed before the delay")
+	assert.True(t, flushed, "buffer should be flushed after the delay")
+	assert.Nil(t, err, "err should be nil")
+	assert.False(t, flushedClean, "clean buffer should not be flushed")
+	assert.Equal(t, "Hello", out.String(), "data should match")
+}
+
+func TestServerWriteBufferHousekeepingFlush(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:      -1,
+		TickInterval:    10 * time.Millisecond,
+		WriteBufferSize: 1024,
+	})
+
+	written := make(chan struct{})
+	server.ForkingStrategy(GoroutinePerConnection(func(socket *Socket) {
+		_, _ = socket.Write([]byte("Hello"))
+		close(written)
+
+		<-socket.Done()
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	<-written
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	response := make([]byte, 5)
+	n, err := conn.Read(response)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, "Hello", string(response[:n]), "buffered data should be flushed by the housekeeping job")
+}
+
+func TestSocketWriteBufferFlushOnClose(t *testing.T) {
+	// given
+	var out bytes.Buffer
+
+	socket := MockSocket(bytes.NewReader(nil), &out)
+	socket.enableWriteBuffer(64)
+
+	// when
+	_, _ = socket.Write([]byte("Hello"))
+	bufferedLen := out.Len()
+	_ = socket.Close()
+
+	// then
+	assert.Equal(t, 0, bufferedLen, "data should be buffered")
+	assert.Equal(t, "Hello", out.String(), "data should be flushed on close")
+}
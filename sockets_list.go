@@ -9,14 +9,27 @@ type socketsList struct {
 	head    *Socket
 	tail    *Socket
 	size    int
+	peak    int
 	maxSize int
-	m       sync.RWMutex
-	pool    sync.Pool
+	lastID  uint64
+	closed  bool
+
+	// reads and writes count the traffic of all the registered sockets, summed up by the housekeeping job
+	reads  shardedCounter
+	writes shardedCounter
+
+	// writeBufferSize enables buffered writes of the new sockets (see ServerConfig.WriteBufferSize)
+	writeBufferSize int
+
+	clock Clock
+	m     sync.RWMutex
+	pool  sync.Pool
 }
 
-func newSocketsList(maxSize int) *socketsList {
+func newSocketsList(maxSize int, clock Clock) *socketsList {
 	return &socketsList{
 		maxSize: maxSize,
+		clock:   clock,
 		pool: sync.Pool{
 			New: func() any {
 				return &Socket{
@@ -32,8 +45,7 @@ func (s *socketsList) New(connection net.Conn) *Socket {
 	socket := s.newSocket(connection)
 
 	if registered := s.registerSocket(socket); !registered {
-		// instantly terminate the connection if it can't be added to the pool
-		_ = connection.Close()
+		// connection is closed (or tarpitted) by the caller
 		s.recycleSocket(socket)
 		return nil
 	}
@@ -48,7 +60,17 @@ func (s *socketsList) Len() int {
 	return s.size
 }
 
-func (s *socketsList) Cleanup() {
+// Peak returns the highest number of sockets held by the list at once.
+func (s *socketsList) Peak() int {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	return s.peak
+}
+
+// Cleanup removes all the recyclable sockets from the list and puts them back into the pool.
+// If onRecycle is not nil, it's called for each socket right before it's recycled.
+func (s *socketsList) Cleanup(onRecycle func(*Socket)) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
@@ -67,6 +89,10 @@ func (s *socketsList) Cleanup() {
 				socket.next.prev = socket.prev
 			}
 
+			if onRecycle != nil {
+				onRecycle(socket)
+			}
+
 			s.recycleSocket(socket)
 			s.size--
 		}
@@ -84,23 +110,42 @@ func (s *socketsList) Iterate(fn func(s *Socket)) {
 	}
 }
 
-func (s *socketsList) Reset() {
+// Reset closes all the sockets. New sockets are rejected, until Open is called.
+func (s *socketsList) Reset(abortErr error) {
 	s.m.Lock()
 	defer s.m.Unlock()
 
+	reason := CloseReasonServer
+	if abortErr != nil {
+		reason = CloseReasonAbort
+	}
+
+	// sockets might still be used by their handlers, so they're only closed and never returned to the pool
 	for socket := s.head; socket != nil; socket = socket.next {
-		_ = socket.Recycle()
-		s.recycleSocket(socket)
+		_ = socket.close(reason, abortErr)
 	}
 
 	s.head = nil
 	s.tail = nil
 	s.size = 0
+	s.closed = true
+}
+
+// Open allows registering the sockets again after Reset.
+func (s *socketsList) Open() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.closed = false
 }
 
 func (s *socketsList) newSocket(connection net.Conn) *Socket {
 	socket := s.pool.Get().(*Socket)
-	socket.init(connection)
+	socket.init(connection, s.clock)
+	if s.writeBufferSize > 0 {
+		socket.enableWriteBuffer(s.writeBufferSize)
+	}
+
 	return socket
 }
 
@@ -108,7 +153,7 @@ func (s *socketsList) registerSocket(socket *Socket) bool {
 	s.m.Lock()
 	defer s.m.Unlock()
 
-	if s.maxSize >= 0 && s.size >= s.maxSize {
+	if s.closed || (s.maxSize >= 0 && s.size >= s.maxSize) {
 		return false
 	}
 
@@ -122,6 +167,14 @@ func (s *socketsList) registerSocket(socket *Socket) bool {
 	}
 
 	s.size++
+	if s.size > s.peak {
+		s.peak = s.size
+	}
+
+	s.lastID++
+	socket.id = s.lastID
+	socket.meteredReader.shared = s.reads.Shard(socket.id)
+	socket.meteredWriter.shared = s.writes.Shard(socket.id)
 
 	return true
 }
@@ -8,7 +8,7 @@ import (
 
 func TestSocketsListSimple(t *testing.T) {
 	// given
-	list := newSocketsList(-1)
+	list := newSocketsList(-1, SystemClock())
 	connections := []net.Conn{&ConnMock{}, &ConnMock{}, &ConnMock{}}
 	sockets := make([]*Socket, len(connections))
 
@@ -17,7 +17,7 @@ func TestSocketsListSimple(t *testing.T) {
 		sockets[i] = list.New(conn)
 	}
 
-	list.Cleanup()
+	list.Cleanup(nil)
 
 	// then
 	assert.Equal(t, len(sockets), list.Len(), "sockets count should match")
@@ -25,7 +25,7 @@ func TestSocketsListSimple(t *testing.T) {
 
 func TestSocketsListCleanup(t *testing.T) {
 	// given
-	list := newSocketsList(-1)
+	list := newSocketsList(-1, SystemClock())
 	connections := []net.Conn{&ConnMock{}, &ConnMock{}, &ConnMock{}}
 	sockets := make([]*Socket, len(connections))
 
@@ -35,7 +35,7 @@ func TestSocketsListCleanup(t *testing.T) {
 	}
 
 	_ = sockets[0].Recycle()
-	list.Cleanup()
+	list.Cleanup(nil)
 
 	// then
 	assert.Equal(t, len(sockets)-1, list.Len(), "sockets count should match")
@@ -43,7 +43,7 @@ func TestSocketsListCleanup(t *testing.T) {
 
 func TestSocketsListLimit(t *testing.T) {
 	// given
-	list := newSocketsList(0)
+	list := newSocketsList(0, SystemClock())
 	connection := &ConnMock{}
 
 	// when
@@ -52,3 +52,58 @@ func TestSocketsListLimit(t *testing.T) {
 	// then
 	assert.Nil(t, socket, "socket should not be returned")
 }
+
+func TestSocketsListPeak(t *testing.T) {
+	// given
+	list := newSocketsList(-1, SystemClock())
+	connections := []net.Conn{&ConnMock{}, &ConnMock{}, &ConnMock{}}
+	sockets := make([]*Socket, len(connections))
+
+	// when
+	for i, conn := range connections {
+		sockets[i] = list.New(conn)
+	}
+
+	_ = sockets[0].Recycle()
+	_ = sockets[1].Recycle()
+	list.Cleanup(nil)
+
+	// then
+	assert.Equal(t, 1, list.Len(), "sockets count should match")
+	assert.Equal(t, 3, list.Peak(), "peak sockets count should match")
+}
+
+func TestSocketsListResetDoesNotRecycle(t *testing.T) {
+	// given
+	list := newSocketsList(-1, SystemClock())
+	connection := &ConnMock{}
+	socket := list.New(connection)
+
+	// when
+	list.Reset(nil)
+	list.Open()
+	next := list.New(&ConnMock{})
+
+	// then
+	assert.True(t, socket.IsClosed(), "socket should be closed")
+	assert.Equal(t, connection, socket.Unwrap(), "socket still used by its handler should not be reset")
+	assert.NotSame(t, socket, next, "socket still used by its handler should not be reused")
+}
+
+func TestSocketsListTraffic(t *testing.T) {
+	// given
+	list := newSocketsList(-1, SystemClock())
+	first := list.New(&ConnMock{})
+	second := list.New(&ConnMock{})
+
+	// when
+	first.meteredReader.add(100)
+	second.meteredReader.add(50)
+	second.meteredWriter.add(10)
+	_ = first.Recycle()
+	list.Cleanup(nil)
+
+	// then
+	assert.Equal(t, uint64(150), list.reads.Swap(), "reads of all the sockets should be counted")
+	assert.Equal(t, uint64(10), list.writes.Swap(), "writes of all the sockets should be counted")
+}
@@ -0,0 +1,4 @@
+/*
+Package statsdtinytcp provides a metrics handler sending tinytcp server metrics over UDP, using StatsD protocol.
+*/
+package statsdtinytcp
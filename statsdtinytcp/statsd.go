@@ -0,0 +1,160 @@
+package statsdtinytcp
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// Config specifies an optional config for NewHandler.
+type Config struct {
+	// Address is an address of StatsD server (default: "127.0.0.1:8125").
+	Address string
+
+	// Prefix is prepended to the names of all the metrics (default: "tinytcp.").
+	Prefix string
+
+	// Tags are attached to all the metrics, in DogStatsD format (eg. "env:prod").
+	// Tags are supported by Datadog agent and Telegraf, but not by the original StatsD server.
+	Tags []string
+
+	// MaxPacketSize is a maximal size of a single UDP datagram. Metrics are batched into as few datagrams as possible
+	// (default: 1432).
+	MaxPacketSize int
+
+	// OnError is a handler called when metrics cannot be sent.
+	OnError func(error)
+}
+
+type handler struct {
+	config   *Config
+	conn     net.Conn
+	tags     string
+	previous tinytcp.ServerMetrics
+	buffer   []byte
+	m        sync.Mutex
+}
+
+func mergeConfig(provided *Config) *Config {
+	config := &Config{
+		Address:       "127.0.0.1:8125",
+		Prefix:        "tinytcp.",
+		MaxPacketSize: 1432,
+		OnError:       func(_ error) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Address != "" {
+		config.Address = provided.Address
+	}
+	if provided.Prefix != "" {
+		config.Prefix = provided.Prefix
+	}
+	if provided.Tags != nil {
+		config.Tags = provided.Tags
+	}
+	if provided.MaxPacketSize > 0 {
+		config.MaxPacketSize = provided.MaxPacketSize
+	}
+	if provided.OnError != nil {
+		config.OnError = provided.OnError
+	}
+
+	return config
+}
+
+// NewHandler creates a metrics handler for tinytcp.Server. It can be registered using OnMetricsUpdate method.
+// Totals are sent as counters (increments since the previous update), and the current state is sent as gauges.
+func NewHandler(config ...*Config) (func(metrics tinytcp.ServerMetrics), error) {
+	var providedConfig *Config
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeConfig(providedConfig)
+
+	conn, err := net.Dial("udp", c.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &handler{
+		config: c,
+		conn:   conn,
+		buffer: make([]byte, 0, c.MaxPacketSize),
+	}
+
+	if len(c.Tags) > 0 {
+		h.tags = "|#" + strings.Join(c.Tags, ",")
+	}
+
+	return h.update, nil
+}
+
+func (h *handler) update(metrics tinytcp.ServerMetrics) {
+	h.m.Lock()
+	defer h.m.Unlock()
+
+	h.counter("read", metrics.TotalRead, h.previous.TotalRead)
+	h.counter("written", metrics.TotalWritten, h.previous.TotalWritten)
+	h.counter("connections.accepted", metrics.TotalAccepted, h.previous.TotalAccepted)
+	h.counter("connections.rejected", metrics.TotalRejected, h.previous.TotalRejected)
+	h.counter("connections.closed.server", metrics.TotalClosedByServer, h.previous.TotalClosedByServer)
+	h.counter("connections.closed.client", metrics.TotalClosedByClient, h.previous.TotalClosedByClient)
+	h.gauge("read_last_second", metrics.ReadLastSecond)
+	h.gauge("written_last_second", metrics.WrittenLastSecond)
+	h.gauge("connections", uint64(metrics.Connections))
+	h.gauge("goroutines", uint64(metrics.Goroutines))
+	h.flush()
+
+	h.previous = metrics
+}
+
+func (h *handler) counter(name string, value, previous uint64) {
+	if value < previous {
+		// server has been restarted
+		previous = 0
+	}
+
+	h.write(name, value-previous, "c")
+}
+
+func (h *handler) gauge(name string, value uint64) {
+	h.write(name, value, "g")
+}
+
+func (h *handler) write(name string, value uint64, metricType string) {
+	size := len(h.config.Prefix) + len(name) + 1 + 20 + 1 + len(metricType) + len(h.tags) + 1
+	if len(h.buffer)+size > h.config.MaxPacketSize {
+		h.flush()
+	}
+
+	if len(h.buffer) > 0 {
+		h.buffer = append(h.buffer, '\n')
+	}
+
+	h.buffer = append(h.buffer, h.config.Prefix...)
+	h.buffer = append(h.buffer, name...)
+	h.buffer = append(h.buffer, ':')
+	h.buffer = strconv.AppendUint(h.buffer, value, 10)
+	h.buffer = append(h.buffer, '|')
+	h.buffer = append(h.buffer, metricType...)
+	h.buffer = append(h.buffer, h.tags...)
+}
+
+func (h *handler) flush() {
+	if len(h.buffer) == 0 {
+		return
+	}
+
+	if _, err := h.conn.Write(h.buffer); err != nil {
+		h.config.OnError(err)
+	}
+
+	h.buffer = h.buffer[:0]
+}
@@ -0,0 +1,74 @@
+package statsdtinytcp
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	// given
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	handler, err := NewHandler(&Config{
+		Address: conn.LocalAddr().String(),
+		Tags:    []string{"env:test"},
+	})
+	assert.Nil(t, err, "err should be nil")
+
+	// when
+	handler(tinytcp.ServerMetrics{TotalRead: 100, Connections: 2})
+	first := receive(t, conn)
+
+	handler(tinytcp.ServerMetrics{TotalRead: 150, Connections: 1})
+	second := receive(t, conn)
+
+	// then
+	assert.Contains(t, first, "tinytcp.read:100|c|#env:test", "counter should be sent")
+	assert.Contains(t, first, "tinytcp.connections:2|g|#env:test", "gauge should be sent")
+	assert.Contains(t, second, "tinytcp.read:50|c|#env:test", "counter should be sent as increment")
+	assert.Contains(t, second, "tinytcp.connections:1|g|#env:test", "gauge should be sent as is")
+}
+
+func TestHandlerBatching(t *testing.T) {
+	// given
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	handler, err := NewHandler(&Config{
+		Address:       conn.LocalAddr().String(),
+		MaxPacketSize: 128,
+	})
+	assert.Nil(t, err, "err should be nil")
+
+	// when
+	handler(tinytcp.ServerMetrics{})
+
+	var lines int
+	for lines < 10 {
+		packet := receive(t, conn)
+		assert.LessOrEqual(t, len(packet), 128, "packet should not exceed the limit")
+
+		lines += len(strings.Split(packet, "\n"))
+	}
+
+	// then
+	assert.Equal(t, 10, lines, "all metrics should be sent")
+}
+
+func receive(t *testing.T, conn net.PacketConn) string {
+	buffer := make([]byte, 2048)
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buffer)
+	assert.Nil(t, err, "err should be nil")
+
+	return string(buffer[:n])
+}
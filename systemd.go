@@ -0,0 +1,101 @@
+package tinytcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// systemdFirstFD is the first file descriptor passed by systemd (SD_LISTEN_FDS_START).
+const systemdFirstFD = 3
+
+type systemdFD struct {
+	fd   int
+	name string
+}
+
+// NewSystemdListener returns a Listener that uses a socket passed by systemd (socket activation).
+// If name is specified, the socket is chosen by its FileDescriptorName, otherwise the first passed socket is used.
+// Using socket activation allows zero-downtime restarts, as systemd keeps the socket open between the restarts.
+// The socket is resolved on the first Listen() and kept open, so the server can be restarted with the same Listener.
+func NewSystemdListener(name ...string) Listener {
+	return &netListener{
+		listenFunc: fileListenFunc(func() (*os.File, error) {
+			return resolveSystemdFile(name...)
+		}),
+	}
+}
+
+// fileListenFunc returns a listenFunc creating the listeners from the file returned by resolve.
+// The file is resolved only once and never closed, as net.FileListener duplicates the descriptor, and closing
+// the listener leaves the original descriptor intact for the subsequent Listen() calls.
+// listenFunc is always called under netListener's lock, so the file doesn't need to be guarded separately.
+func fileListenFunc(resolve func() (*os.File, error)) func() (net.Listener, error) {
+	var file *os.File
+
+	return func() (net.Listener, error) {
+		if file == nil {
+			f, err := resolve()
+			if err != nil {
+				return nil, err
+			}
+
+			file = f
+		}
+
+		return net.FileListener(file)
+	}
+}
+
+func resolveSystemdFile(name ...string) (*os.File, error) {
+	fds, err := parseSystemdFDs(os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS"), os.Getenv("LISTEN_FDNAMES"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fd := range fds {
+		if name != nil && fd.name != name[0] {
+			continue
+		}
+
+		return os.NewFile(uintptr(fd.fd), fd.name), nil
+	}
+
+	return nil, errors.New("no matching socket passed by systemd")
+}
+
+func parseSystemdFDs(pid, fds, names string) ([]systemdFD, error) {
+	if pid == "" || fds == "" {
+		return nil, errors.New("no sockets passed by systemd")
+	}
+
+	listenPID, err := strconv.Atoi(pid)
+	if err != nil {
+		return nil, errors.New("invalid LISTEN_PID")
+	}
+	if listenPID != os.Getpid() {
+		return nil, errors.New("sockets passed by systemd are meant for another process")
+	}
+
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 0 {
+		return nil, errors.New("invalid LISTEN_FDS")
+	}
+
+	var fdNames []string
+	if names != "" {
+		fdNames = strings.Split(names, ":")
+	}
+
+	result := make([]systemdFD, count)
+	for i := range result {
+		result[i].fd = systemdFirstFD + i
+		if i < len(fdNames) {
+			result[i].name = fdNames[i]
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestParseSystemdFDs(t *testing.T) {
+	// given
+	pid := strconv.Itoa(os.Getpid())
+
+	// when
+	fds, err := parseSystemdFDs(pid, "2", "http:admin")
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, []systemdFD{{fd: 3, name: "http"}, {fd: 4, name: "admin"}}, fds, "fds should match")
+}
+
+func TestParseSystemdFDsOtherProcess(t *testing.T) {
+	// given
+	pid := strconv.Itoa(os.Getpid() + 1)
+
+	// when
+	_, err := parseSystemdFDs(pid, "1", "")
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
+
+func TestFileListenFuncRestart(t *testing.T) {
+	// given
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer tcpListener.Close()
+
+	file, err := tcpListener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("failed to get file: %v", err)
+	}
+
+	var resolved int
+	listener := &netListener{
+		listenFunc: fileListenFunc(func() (*os.File, error) {
+			resolved++
+			return file, nil
+		}),
+	}
+
+	// when
+	firstErr := listener.Listen()
+	_ = listener.Close()
+	secondErr := listener.Listen()
+	defer listener.Close()
+
+	// then
+	assert.Nil(t, firstErr, "first Listen should succeed")
+	assert.Nil(t, secondErr, "second Listen should succeed")
+	assert.Equal(t, 1, resolved, "file should be resolved only once")
+
+	go func() {
+		conn, err := net.Dial("tcp", tcpListener.Addr().String())
+		if err == nil {
+			_ = conn.Close()
+		}
+	}()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err, "listener should accept connections after the restart")
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,137 @@
+package tinytcp
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// TarpitConfig holds a configuration of tarpitting (see ServerConfig.Tarpit).
+// Tarpitted connections are accepted, but never served. Server doesn't read from them, and shrinks their receive
+// buffers, so the TCP window of the peer quickly drops to zero. Connections are held until Duration passes,
+// slowing down scanners and brute-forcers that would otherwise immediately retry.
+type TarpitConfig struct {
+	// Match is an optional function selecting connections to be tarpitted right after accept, eg. from banned peers.
+	Match func(conn net.Conn) bool
+
+	// Rejected enables tarpitting of connections rejected due to MaxClients limit or by the AdmissionController,
+	// instead of closing them immediately.
+	Rejected bool
+
+	// MaxConnections is a maximal number of connections held in the tarpit at once. Connections over the limit
+	// are closed immediately (default: 1024).
+	MaxConnections int
+
+	// Duration is the time for which connections are held in the tarpit before being closed (default: 5m).
+	// Connections are released by the housekeeping job of the server, so its precision is limited by
+	// ServerConfig.TickInterval.
+	Duration time.Duration
+}
+
+func mergeTarpitConfig(provided *TarpitConfig) *TarpitConfig {
+	config := &TarpitConfig{
+		MaxConnections: 1024,
+		Duration:       5 * time.Minute,
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Match != nil {
+		config.Match = provided.Match
+	}
+	if provided.Rejected {
+		config.Rejected = true
+	}
+	if provided.MaxConnections > 0 {
+		config.MaxConnections = provided.MaxConnections
+	}
+	if provided.Duration > 0 {
+		config.Duration = provided.Duration
+	}
+
+	return config
+}
+
+type receiveBufferSetter interface {
+	SetReadBuffer(bytes int) error
+}
+
+// tarpit holds the tarpitted connections, along with their release deadlines (in unix millis). Connections are
+// released by the housekeeping job, instead of goroutines or timers, so holding a connection is cheap.
+type tarpit struct {
+	config      *TarpitConfig
+	clock       Clock
+	connections map[net.Conn]int64
+	m           sync.Mutex
+}
+
+func newTarpit(config *TarpitConfig, clock Clock) *tarpit {
+	return &tarpit{
+		config:      config,
+		clock:       clock,
+		connections: make(map[net.Conn]int64),
+	}
+}
+
+func (t *tarpit) matches(conn net.Conn) bool {
+	return t.config.Match != nil && t.config.Match(conn)
+}
+
+// hold puts the connection into the tarpit. It returns false if the tarpit is full, and the connection
+// should be closed by the caller.
+func (t *tarpit) hold(conn net.Conn) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	if len(t.connections) >= t.config.MaxConnections {
+		return false
+	}
+
+	if c, ok := conn.(receiveBufferSetter); ok {
+		// kernel rounds it up to the minimal size
+		_ = c.SetReadBuffer(1)
+	}
+
+	t.connections[conn] = t.clock.Now().Add(t.config.Duration).UnixMilli()
+
+	return true
+}
+
+// ReleaseExpired closes the connections held past their deadline. It's called by the housekeeping job.
+func (t *tarpit) ReleaseExpired(now int64) {
+	var expired []net.Conn
+
+	t.m.Lock()
+	for conn, deadline := range t.connections {
+		if now >= deadline {
+			expired = append(expired, conn)
+			delete(t.connections, conn)
+		}
+	}
+	t.m.Unlock()
+
+	for _, conn := range expired {
+		_ = conn.Close()
+	}
+}
+
+func (t *tarpit) Len() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	return len(t.connections)
+}
+
+// Reset closes all the held connections.
+func (t *tarpit) Reset() {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	for conn := range t.connections {
+		_ = conn.Close()
+	}
+
+	t.connections = make(map[net.Conn]int64)
+}
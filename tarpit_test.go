@@ -0,0 +1,106 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTarpitCap(t *testing.T) {
+	// given
+	tp := newTarpit(mergeTarpitConfig(&TarpitConfig{
+		MaxConnections: 1,
+		Duration:       time.Hour,
+	}), SystemClock())
+
+	first, _ := net.Pipe()
+	second, _ := net.Pipe()
+
+	// when
+	firstHeld := tp.hold(first)
+	secondHeld := tp.hold(second)
+	held := tp.Len()
+
+	tp.Reset()
+
+	// then
+	assert.True(t, firstHeld, "first connection should be held")
+	assert.False(t, secondHeld, "second connection should exceed the cap")
+	assert.Equal(t, 1, held, "one connection should be held")
+	assert.Equal(t, 0, tp.Len(), "tarpit should be empty after reset")
+}
+
+func TestTarpitReleaseExpired(t *testing.T) {
+	// given
+	tp := newTarpit(mergeTarpitConfig(&TarpitConfig{
+		Duration: time.Minute,
+	}), SystemClock())
+
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	now := time.Now()
+	tp.hold(conn)
+
+	// when
+	tp.ReleaseExpired(now.Add(59 * time.Second).UnixMilli())
+	heldBeforeDeadline := tp.Len()
+
+	tp.ReleaseExpired(now.Add(time.Minute + time.Second).UnixMilli())
+
+	// then
+	_, err := peer.Read(make([]byte, 1))
+
+	assert.Equal(t, 1, heldBeforeDeadline, "connection should be held until the deadline")
+	assert.Equal(t, 0, tp.Len(), "connection should be released after the deadline")
+	assert.Equal(t, io.EOF, err, "released connection should be closed")
+}
+
+func TestServerTarpit(t *testing.T) {
+	// given
+	server := NewServer("127.0.0.1:0", &ServerConfig{
+		MaxClients:   -1,
+		TickInterval: 50 * time.Millisecond,
+		Tarpit: &TarpitConfig{
+			Match: func(_ net.Conn) bool {
+				return true
+			},
+			Duration: 200 * time.Millisecond,
+		},
+	})
+
+	var handled uint32
+	server.ForkingStrategy(GoroutinePerConnection(func(_ *Socket) {
+		atomic.StoreUint32(&handled, 1)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	// when
+	conn, err := net.Dial("tcp", server.listener.Addr().String())
+	assert.Nil(t, err, "err should be nil")
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, heldErr := conn.Read(make([]byte, 1))
+
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, closedErr := conn.Read(make([]byte, 1))
+
+	// then
+	assert.ErrorIs(t, heldErr, os.ErrDeadlineExceeded, "connection should be held open")
+	assert.Equal(t, io.EOF, closedErr, "connection should be closed after the duration")
+	assert.Equal(t, uint32(0), atomic.LoadUint32(&handled), "connection should not be handled")
+	assert.Equal(t, uint64(1), atomic.LoadUint64(&server.tarpittedConnections), "connection should be counted")
+}
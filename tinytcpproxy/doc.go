@@ -0,0 +1,4 @@
+/*
+Package tinytcpproxy provides a SocketHandler forwarding TCP traffic to an upstream server.
+*/
+package tinytcpproxy
@@ -0,0 +1,234 @@
+package tinytcpproxy
+
+import (
+	"crypto/tls"
+	"errors"
+	"github.com/mkorman9/tinytcp"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config specifies an optional config for ProxyTo.
+type Config struct {
+	// Network is a network parameter to pass when dialing upstream (default: "tcp").
+	Network string
+
+	// DialTimeout is a maximum amount of time to wait for the connection with upstream (default: 10s).
+	DialTimeout time.Duration
+
+	// IdleTimeout specifies the time after which the connection is closed if no data is transferred
+	// in either direction. The value of 0 or less, means that the timeout is infinite (default: 0).
+	IdleTimeout time.Duration
+
+	// TLSConfig enables TLS when connecting with upstream.
+	TLSConfig *tls.Config
+
+	// BufferSize sets a size of buffers used to copy data between the connections when IdleTimeout is set
+	// (default: 32KiB). Without IdleTimeout, data is copied by tinytcp.PipeSocket.
+	BufferSize int
+
+	// OnError is a handler called when the proxy encounters an error other than EOF or a timeout.
+	OnError func(*tinytcp.Socket, error)
+
+	// OnClose is a handler called after both directions of the proxied connection are closed.
+	OnClose func(*tinytcp.Socket, Metrics)
+}
+
+// Metrics contains a number of bytes transferred through a single proxied connection.
+type Metrics struct {
+	// Upstream is a total number of bytes sent from client to upstream.
+	Upstream uint64
+
+	// Downstream is a total number of bytes sent from upstream to client.
+	Downstream uint64
+}
+
+type deadlineReadWriter interface {
+	io.ReadWriter
+	SetReadDeadline(time.Time) error
+	SetWriteDeadline(time.Time) error
+}
+
+func mergeConfig(provided *Config) *Config {
+	config := &Config{
+		Network:     "tcp",
+		DialTimeout: 10 * time.Second,
+		BufferSize:  32 * 1024, // 32 KiB
+		OnError:     func(_ *tinytcp.Socket, _ error) {},
+		OnClose:     func(_ *tinytcp.Socket, _ Metrics) {},
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Network != "" {
+		config.Network = provided.Network
+	}
+	if provided.DialTimeout > 0 {
+		config.DialTimeout = provided.DialTimeout
+	}
+	if provided.IdleTimeout > 0 {
+		config.IdleTimeout = provided.IdleTimeout
+	}
+	if provided.TLSConfig != nil {
+		config.TLSConfig = provided.TLSConfig
+	}
+	if provided.BufferSize > 0 {
+		config.BufferSize = provided.BufferSize
+	}
+	if provided.OnError != nil {
+		config.OnError = provided.OnError
+	}
+	if provided.OnClose != nil {
+		config.OnClose = provided.OnClose
+	}
+
+	return config
+}
+
+// ProxyTo returns a SocketHandler that forwards all the traffic between the socket and given upstream address.
+// Each accepted socket opens a new connection with upstream, and data is copied in both directions
+// until either side closes the connection. Combined with TLS mode of the server, it can act as a TLS terminator.
+func ProxyTo(upstream string, config ...*Config) tinytcp.SocketHandler {
+	var providedConfig *Config
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeConfig(providedConfig)
+
+	// copy buffers are pooled to avoid memory allocation in hot path
+	bufferPool := sync.Pool{
+		New: func() any {
+			return make([]byte, c.BufferSize)
+		},
+	}
+
+	return func(socket *tinytcp.Socket) {
+		conn, err := dial(upstream, c)
+		if err != nil {
+			c.OnError(socket, err)
+			return
+		}
+
+		if c.IdleTimeout <= 0 {
+			// no deadlines to maintain, so the data can be spliced within the kernel
+			toUpstream, toSocket, err := tinytcp.PipeSocket(socket, conn)
+			if err != nil {
+				c.OnError(socket, err)
+			}
+
+			c.OnClose(socket, Metrics{Upstream: uint64(toUpstream), Downstream: uint64(toSocket)})
+			return
+		}
+
+		var (
+			metrics        Metrics
+			downstreamDone = make(chan struct{})
+			lastActivity   = time.Now().UnixNano()
+		)
+
+		go func() {
+			defer close(downstreamDone)
+
+			buffer := bufferPool.Get().([]byte)
+			defer bufferPool.Put(buffer)
+
+			n, err := copyStream(socket, conn, buffer, c.IdleTimeout, &lastActivity)
+			metrics.Downstream = n
+			if err != nil {
+				c.OnError(socket, err)
+			}
+
+			_ = socket.Close()
+		}()
+
+		buffer := bufferPool.Get().([]byte)
+		defer bufferPool.Put(buffer)
+
+		n, err := copyStream(conn, socket, buffer, c.IdleTimeout, &lastActivity)
+		metrics.Upstream = n
+		if err != nil {
+			c.OnError(socket, err)
+		}
+
+		_ = conn.Close()
+		<-downstreamDone
+
+		c.OnClose(socket, metrics)
+	}
+}
+
+func dial(upstream string, config *Config) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout: config.DialTimeout,
+	}
+
+	if config.TLSConfig != nil {
+		return tls.DialWithDialer(dialer, config.Network, upstream, config.TLSConfig)
+	}
+
+	return dialer.Dial(config.Network, upstream)
+}
+
+// copyStream copies the data from src to dst. lastActivity (unix nanos) is shared by both directions of the connection,
+// so a direction that has been idle for idleTimeout keeps waiting as long as the other one transfers data.
+func copyStream(dst, src deadlineReadWriter, buffer []byte, idleTimeout time.Duration, lastActivity *int64) (uint64, error) {
+	var total uint64
+
+	for {
+		if idleTimeout > 0 {
+			deadline := time.Unix(0, atomic.LoadInt64(lastActivity)).Add(idleTimeout)
+			if err := src.SetReadDeadline(deadline); err != nil {
+				return total, filterError(err)
+			}
+		}
+
+		n, err := src.Read(buffer)
+		if n > 0 {
+			atomic.StoreInt64(lastActivity, time.Now().UnixNano())
+
+			if idleTimeout > 0 {
+				if e := dst.SetWriteDeadline(time.Now().Add(idleTimeout)); e != nil {
+					return total, filterError(e)
+				}
+			}
+
+			if e := tinytcp.WriteBytes(dst, buffer[:n]); e != nil {
+				return total, filterError(e)
+			}
+
+			atomic.StoreInt64(lastActivity, time.Now().UnixNano())
+			total += uint64(n)
+		}
+
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) && isActive(lastActivity, idleTimeout) {
+				// the other direction is still transferring data
+				continue
+			}
+
+			return total, filterError(err)
+		}
+	}
+}
+
+func isActive(lastActivity *int64, idleTimeout time.Duration) bool {
+	return time.Since(time.Unix(0, atomic.LoadInt64(lastActivity))) < idleTimeout
+}
+
+// filterError drops errors that are expected when either side of the connection is closed or times out.
+func filterError(err error) error {
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, tinytcp.ErrSocketClosed) ||
+		errors.Is(err, os.ErrDeadlineExceeded) {
+		return nil
+	}
+
+	return err
+}
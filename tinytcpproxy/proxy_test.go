@@ -0,0 +1,142 @@
+package tinytcpproxy
+
+import (
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProxyTo(t *testing.T) {
+	// given
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer upstream.Close()
+
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	payload := []byte("Hello world!")
+	metricsChannel := make(chan Metrics, 1)
+
+	handler := ProxyTo(upstream.Addr().String(), &Config{
+		OnClose: func(_ *tinytcp.Socket, metrics Metrics) {
+			metricsChannel <- metrics
+		},
+	})
+
+	server, client := net.Pipe()
+	go handler(tinytcp.NewSocket(server))
+
+	// when
+	_, _ = client.Write(payload)
+
+	buffer := make([]byte, len(payload))
+	_, err = io.ReadFull(client, buffer)
+	_ = client.Close()
+
+	metrics := <-metricsChannel
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, payload, buffer, "payloads should match")
+	assert.Equal(t, uint64(len(payload)), metrics.Upstream, "upstream bytes should match")
+	assert.Equal(t, uint64(len(payload)), metrics.Downstream, "downstream bytes should match")
+}
+
+func TestProxyToUpstreamClosesFirst(t *testing.T) {
+	// given
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer upstream.Close()
+
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+
+		_, _ = conn.Write([]byte("Bye"))
+		_ = conn.Close()
+	}()
+
+	errorsChannel := make(chan error, 2)
+	closed := make(chan struct{})
+
+	handler := ProxyTo(upstream.Addr().String(), &Config{
+		IdleTimeout: time.Second,
+		OnError: func(_ *tinytcp.Socket, err error) {
+			errorsChannel <- err
+		},
+		OnClose: func(_ *tinytcp.Socket, _ Metrics) {
+			close(closed)
+		},
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go handler(tinytcp.NewSocket(server))
+
+	// when
+	buffer := make([]byte, 3)
+	_, err = io.ReadFull(client, buffer)
+
+	<-closed
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, "Bye", string(buffer), "payload should match")
+	assert.Empty(t, errorsChannel, "closing by upstream should not be reported as error")
+}
+
+func TestProxyToIdleTimeoutOneWayStream(t *testing.T) {
+	// given
+	const chunks = 10
+
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer upstream.Close()
+
+	go func() {
+		conn, err := upstream.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for i := 0; i < chunks; i++ {
+			_, _ = conn.Write([]byte{'x'})
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	handler := ProxyTo(upstream.Addr().String(), &Config{
+		IdleTimeout: 50 * time.Millisecond,
+	})
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go handler(tinytcp.NewSocket(server))
+
+	// when
+	received, err := io.ReadAll(client)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, chunks, len(received), "stream active in one direction should not time out")
+}
@@ -0,0 +1,221 @@
+package tinytcptest
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// ChaosConfig holds a configuration for NewChaosListener.
+// All the probabilities are expressed as numbers between 0 and 1, and apply to every single Read() or Write() call.
+type ChaosConfig struct {
+	// Latency is a delay added before every Read() and Write() (default: 0).
+	Latency time.Duration
+
+	// LatencyJitter is a maximal random delay added on top of Latency (default: 0).
+	LatencyJitter time.Duration
+
+	// PartialWriteProbability is a probability of Write() being split into two writes of random sizes,
+	// separated by Latency, so the other side receives the data in fragments (default: 0).
+	PartialWriteProbability float64
+
+	// ShortReadProbability is a probability of Read() returning fewer bytes than requested (default: 0).
+	ShortReadProbability float64
+
+	// ResetProbability is a probability of the connection being abruptly closed. Interrupted call returns
+	// syscall.ECONNRESET (default: 0).
+	ResetProbability float64
+
+	// BitFlipProbability is a probability of flipping a random bit in the data returned by Read() (default: 0).
+	BitFlipProbability float64
+
+	// Seed is a seed of the random number generator, allowing to reproduce the failures (default: current time).
+	Seed int64
+}
+
+func mergeChaosConfig(provided *ChaosConfig) *ChaosConfig {
+	config := &ChaosConfig{
+		Seed: time.Now().UnixNano(),
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Latency > 0 {
+		config.Latency = provided.Latency
+	}
+	if provided.LatencyJitter > 0 {
+		config.LatencyJitter = provided.LatencyJitter
+	}
+	if provided.PartialWriteProbability > 0 {
+		config.PartialWriteProbability = provided.PartialWriteProbability
+	}
+	if provided.ShortReadProbability > 0 {
+		config.ShortReadProbability = provided.ShortReadProbability
+	}
+	if provided.ResetProbability > 0 {
+		config.ResetProbability = provided.ResetProbability
+	}
+	if provided.BitFlipProbability > 0 {
+		config.BitFlipProbability = provided.BitFlipProbability
+	}
+	if provided.Seed != 0 {
+		config.Seed = provided.Seed
+	}
+
+	return config
+}
+
+// ChaosListener wraps a tinytcp.Listener and injects faults into all the accepted connections: latency,
+// fragmented writes, short reads, connection resets and corrupted data. It allows to test resilience of handlers
+// without any external tooling. It can be passed to the server with Server.Listener().
+type ChaosListener struct {
+	listener tinytcp.Listener
+	config   *ChaosConfig
+	random   *rand.Rand
+	m        sync.Mutex
+}
+
+// NewChaosListener creates new ChaosListener wrapping given listener (eg. PipeListener).
+func NewChaosListener(listener tinytcp.Listener, config ...*ChaosConfig) *ChaosListener {
+	var providedConfig *ChaosConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeChaosConfig(providedConfig)
+
+	return &ChaosListener{
+		listener: listener,
+		config:   c,
+		random:   rand.New(rand.NewSource(c.Seed)),
+	}
+}
+
+// Listen conforms to the tinytcp.Listener interface.
+func (l *ChaosListener) Listen() error {
+	return l.listener.Listen()
+}
+
+// Accept conforms to the net.Listener interface.
+func (l *ChaosListener) Accept() (net.Conn, error) {
+	conn, err := l.listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	l.m.Lock()
+	seed := l.random.Int63()
+	l.m.Unlock()
+
+	return &chaosConn{
+		Conn:   conn,
+		config: l.config,
+		random: rand.New(rand.NewSource(seed)),
+	}, nil
+}
+
+// Addr conforms to the net.Listener interface.
+func (l *ChaosListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// Close conforms to the net.Listener interface.
+func (l *ChaosListener) Close() error {
+	return l.listener.Close()
+}
+
+type chaosConn struct {
+	net.Conn
+	config *ChaosConfig
+	random *rand.Rand
+	m      sync.Mutex
+}
+
+func (c *chaosConn) Read(b []byte) (int, error) {
+	c.delay()
+
+	if c.chance(c.config.ResetProbability) {
+		return 0, c.reset()
+	}
+
+	if len(b) > 1 && c.chance(c.config.ShortReadProbability) {
+		b = b[:1+c.intn(len(b)-1)]
+	}
+
+	n, err := c.Conn.Read(b)
+
+	if n > 0 && c.chance(c.config.BitFlipProbability) {
+		bit := c.intn(n * 8)
+		b[bit/8] ^= 1 << (bit % 8)
+	}
+
+	return n, err
+}
+
+func (c *chaosConn) Write(b []byte) (int, error) {
+	c.delay()
+
+	if c.chance(c.config.ResetProbability) {
+		return 0, c.reset()
+	}
+
+	if len(b) > 1 && c.chance(c.config.PartialWriteProbability) {
+		split := 1 + c.intn(len(b)-1)
+
+		n, err := c.Conn.Write(b[:split])
+		if err != nil {
+			return n, err
+		}
+
+		c.delay()
+
+		m, err := c.Conn.Write(b[split:])
+		return n + m, err
+	}
+
+	return c.Conn.Write(b)
+}
+
+func (c *chaosConn) delay() {
+	d := c.config.Latency
+	if c.config.LatencyJitter > 0 {
+		d += time.Duration(c.intn(int(c.config.LatencyJitter)))
+	}
+
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (c *chaosConn) reset() error {
+	if tcpConn, ok := c.Conn.(*net.TCPConn); ok {
+		// send RST instead of FIN
+		_ = tcpConn.SetLinger(0)
+	}
+
+	_ = c.Conn.Close()
+	return syscall.ECONNRESET
+}
+
+func (c *chaosConn) chance(probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.random.Float64() < probability
+}
+
+func (c *chaosConn) intn(n int) int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.random.Intn(n)
+}
@@ -0,0 +1,99 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestChaosListenerBitFlip(t *testing.T) {
+	// given
+	payload := []byte("Hello world!")
+	received := make(chan []byte, 1)
+
+	listener := NewPipeListener()
+	server := startChaosServer(t, NewChaosListener(listener, &ChaosConfig{
+		BitFlipProbability: 1,
+	}), func(socket *tinytcp.Socket) {
+		buffer := make([]byte, len(payload))
+		_, _ = io.ReadFull(socket, buffer)
+		received <- buffer
+	})
+	defer server.Stop()
+
+	// when
+	client := listener.Connect()
+	_, _ = client.Write(payload)
+
+	// then
+	assert.NotEqual(t, payload, <-received, "payload should be corrupted")
+}
+
+func TestChaosListenerReset(t *testing.T) {
+	// given
+	readErr := make(chan error, 1)
+
+	listener := NewPipeListener()
+	server := startChaosServer(t, NewChaosListener(listener, &ChaosConfig{
+		ResetProbability: 1,
+	}), func(socket *tinytcp.Socket) {
+		_, err := socket.Read(make([]byte, 1))
+		readErr <- err
+	})
+	defer server.Stop()
+
+	// when
+	client := listener.Connect()
+	_, clientErr := client.Read(make([]byte, 1))
+
+	// then
+	assert.NotNil(t, <-readErr, "server read should fail")
+	assert.Equal(t, io.EOF, clientErr, "client should be disconnected")
+}
+
+func TestChaosListenerPartialWrites(t *testing.T) {
+	// given
+	payload := bytes.Repeat([]byte("x"), 64)
+
+	listener := NewPipeListener()
+	server := startChaosServer(t, NewChaosListener(listener, &ChaosConfig{
+		PartialWriteProbability: 1,
+	}), func(socket *tinytcp.Socket) {
+		_, _ = socket.Write(payload)
+	})
+	defer server.Stop()
+
+	// when
+	client := listener.Connect()
+	first := make([]byte, len(payload))
+	n, _ := client.Read(first)
+	rest := make([]byte, len(payload)-n)
+	_, err := io.ReadFull(client, rest)
+
+	// then
+	assert.Less(t, n, len(payload), "payload should be fragmented")
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, payload, append(first[:n], rest...), "payload should be delivered intact")
+}
+
+func startChaosServer(t *testing.T, listener *ChaosListener, handler tinytcp.SocketHandler) *tinytcp.Server {
+	t.Helper()
+
+	server := tinytcp.NewServer("pipe")
+	server.Listener(listener)
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(handler))
+
+	started := make(chan struct{})
+	server.OnStart(func() {
+		close(started)
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	<-started
+
+	return server
+}
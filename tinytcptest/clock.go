@@ -0,0 +1,142 @@
+package tinytcptest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// FakeClock is a deterministic implementation of tinytcp.Clock. Time only moves forward when Advance() is called,
+// which fires all the timers that are due. It can be passed to the server with ServerConfig.Clock.
+// Note that Server.Shutdown polls the connections using the clock, so it won't return until the clock is advanced
+// after the connections are closed. Wait for Shutdown to schedule its timer (see Timers) and call Advance():
+//
+//	go func() { done <- server.Shutdown(context.Background()) }()
+//	// wait until clock.Timers() reports the poll timer, then close the connections
+//	clock.Advance(time.Second)
+//	err := <-done
+type FakeClock struct {
+	now    time.Time
+	timers []*fakeTimer
+	m      sync.Mutex
+}
+
+// NewFakeClock creates new FakeClock, set to the given time (default: 2000-01-01 00:00:00 UTC).
+func NewFakeClock(now ...time.Time) *FakeClock {
+	t := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if now != nil {
+		t = now[0]
+	}
+
+	return &FakeClock{
+		now: t,
+	}
+}
+
+// Now conforms to the tinytcp.Clock interface.
+func (c *FakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+// NewTimer conforms to the tinytcp.Clock interface.
+func (c *FakeClock) NewTimer(d time.Duration) tinytcp.Timer {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	t := &fakeTimer{
+		clock: c,
+		c:     make(chan time.Time, 1),
+	}
+	c.schedule(t, d)
+
+	return t
+}
+
+// Advance moves the clock forward by d and fires all the timers that are due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.now = c.now.Add(d)
+
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if !t.deadline.After(c.now) {
+			t.fire(c.now)
+			continue
+		}
+
+		active = append(active, t)
+	}
+	c.timers = active
+}
+
+// Timers returns the number of active timers. Tests can wait for it before calling Advance(), to make sure
+// the code under test has already scheduled its timer.
+func (c *FakeClock) Timers() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.timers)
+}
+
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = c.now.Add(d)
+
+	if d <= 0 {
+		t.fire(c.now)
+		return
+	}
+
+	c.timers = append(c.timers, t)
+}
+
+// unschedule removes the timer from the list of active timers. Returns true if the timer was active.
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+
+	return active
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
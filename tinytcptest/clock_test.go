@@ -0,0 +1,222 @@
+package tinytcptest
+
+import (
+	"context"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFakeClockTimer(t *testing.T) {
+	// given
+	clock := NewFakeClock()
+	timer := clock.NewTimer(time.Second)
+
+	// when
+	clock.Advance(999 * time.Millisecond)
+	firedEarly := len(timer.C()) > 0
+
+	clock.Advance(time.Millisecond)
+	firedOnTime := len(timer.C()) > 0
+
+	// then
+	assert.False(t, firedEarly, "timer should not fire before deadline")
+	assert.True(t, firedOnTime, "timer should fire on deadline")
+	assert.Equal(t, 0, clock.Timers(), "fired timer should not be active")
+}
+
+func TestFakeClockServerHousekeeping(t *testing.T) {
+	// given
+	clock := NewFakeClock()
+	server := tinytcp.NewServer("pipe", &tinytcp.ServerConfig{
+		Clock:        clock,
+		TickInterval: time.Minute,
+	})
+	server.Listener(NewPipeListener())
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(_ *tinytcp.Socket) {}))
+
+	var updates int32
+	server.OnMetricsUpdate(func(_ tinytcp.ServerMetrics) {
+		atomic.AddInt32(&updates, 1)
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 1
+	}, time.Second, time.Millisecond, "housekeeping job should schedule its timer")
+
+	// when
+	clock.Advance(time.Minute)
+
+	// then
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&updates) == 1
+	}, time.Second, time.Millisecond, "metrics should be updated after tick interval")
+}
+
+func TestFakeClockServerShutdown(t *testing.T) {
+	// given
+	clock := NewFakeClock()
+	listener := NewPipeListener()
+	server := tinytcp.NewServer("pipe", &tinytcp.ServerConfig{
+		Clock:        clock,
+		TickInterval: time.Minute,
+	})
+	server.Listener(listener)
+
+	connected := make(chan struct{})
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(socket *tinytcp.Socket) {
+		close(connected)
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 1
+	}, time.Second, time.Millisecond, "housekeeping job should schedule its timer")
+
+	conn := listener.Connect()
+	<-connected
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 2
+	}, time.Second, time.Millisecond, "shutdown should schedule its poll timer")
+
+	// when
+	_ = conn.Close()
+	time.Sleep(10 * time.Millisecond)
+	returnedEarly := len(shutdown) > 0
+
+	clock.Advance(time.Second)
+
+	// then
+	assert.False(t, returnedEarly, "shutdown should wait for the clock to poll the connections")
+	select {
+	case err := <-shutdown:
+		assert.Nil(t, err, "err should be nil")
+	case <-time.After(time.Second):
+		t.Fatal("shutdown should complete once the clock advances")
+	}
+}
+
+func TestFakeClockServerShutdownCancelled(t *testing.T) {
+	// given
+	clock := NewFakeClock()
+	listener := NewPipeListener()
+	server := tinytcp.NewServer("pipe", &tinytcp.ServerConfig{
+		Clock:        clock,
+		TickInterval: time.Minute,
+	})
+	server.Listener(listener)
+
+	connected := make(chan struct{})
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(socket *tinytcp.Socket) {
+		close(connected)
+		_, _ = io.Copy(io.Discard, socket)
+	}))
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == tinytcp.ServerRunning
+	}, time.Second, time.Millisecond, "server should be running")
+
+	conn := listener.Connect()
+	defer conn.Close()
+	<-connected
+
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return server.State() == tinytcp.ServerDraining
+	}, time.Second, time.Millisecond, "server should be draining")
+
+	// when
+	cancel()
+
+	// then
+	select {
+	case err := <-shutdown:
+		assert.Nil(t, err, "err should be nil")
+	case <-time.After(time.Second):
+		t.Fatal("cancelled shutdown should not wait for the clock")
+	}
+	assert.Equal(t, tinytcp.ServerStopped, server.State(), "server should be stopped")
+}
+
+func TestFakeClockPacketLatency(t *testing.T) {
+	// given
+	clock := NewFakeClock()
+	listener := NewPipeListener()
+	server := tinytcp.NewServer("pipe", &tinytcp.ServerConfig{
+		Clock:        clock,
+		TickInterval: time.Minute,
+	})
+	server.Listener(listener)
+
+	handled := make(chan struct{}, 2)
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		func(_ *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(_ []byte) {
+				clock.Advance(5 * time.Millisecond)
+				handled <- struct{}{}
+			}
+		},
+	)))
+
+	latency := make(chan time.Duration, 1)
+	server.OnMetricsUpdate(func(metrics tinytcp.ServerMetrics) {
+		latency <- metrics.PacketLatency.Sum
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 1
+	}, time.Second, time.Millisecond, "housekeeping job should schedule its timer")
+
+	conn := listener.Connect()
+	defer conn.Close()
+
+	_, _ = conn.Write([]byte("first\nsecond\n"))
+	<-handled
+	<-handled
+
+	// when
+	clock.Advance(time.Minute)
+
+	// then
+	select {
+	case sum := <-latency:
+		assert.Equal(t, 10*time.Millisecond, sum, "packet latency should be measured with the clock")
+	case <-time.After(time.Second):
+		t.Fatal("metrics should be updated after tick interval")
+	}
+}
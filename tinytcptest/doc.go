@@ -0,0 +1,4 @@
+/*
+Package tinytcptest provides utilities for testing applications built on top of tinytcp.
+*/
+package tinytcptest
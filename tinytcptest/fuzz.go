@@ -0,0 +1,208 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// maxFuzzPackets limits the number of packets extracted from a single input, so a protocol that keeps extracting
+// empty packets without consuming any data is reported instead of looping forever.
+const maxFuzzPackets = 1 << 16
+
+const maxFuzzChunks = 64
+
+// FuzzFraming runs a fuzz test of given FramingProtocol. Protocol is fed with random inputs, split into chunks
+// of random sizes, just like the data arriving from the network. Test fails if ExtractPacket:
+//   - panics,
+//   - returns rest that is not a suffix of the source, or packet that is not a part of the consumed data,
+//   - reports an extracted packet without consuming any data (no progress),
+//   - extracts different packets depending on how the input has been split into chunks (desync).
+//
+// Corpus is seeded with given inputs. Use SeparatorCorpus or LengthPrefixedCorpus to generate valid ones.
+// FuzzFraming should be called from a fuzz target, eg.
+//
+//	func FuzzMyProtocol(f *testing.F) {
+//		tinytcptest.FuzzFraming(f, MyProtocol(), tinytcptest.SeparatorCorpus([]byte{'\n'})...)
+//	}
+func FuzzFraming(f *testing.F, protocol tinytcp.FramingProtocol, corpus ...[]byte) {
+	f.Helper()
+
+	for i, input := range corpus {
+		f.Add(input, uint8(i))
+	}
+	f.Add([]byte{}, uint8(0))
+
+	f.Fuzz(func(t *testing.T, input []byte, chunk uint8) {
+		// number of chunks is bounded, as every chunk triggers an extraction attempt over the whole buffered data
+		chunkSize := int(chunk) + 1
+		if minChunkSize := len(input) / maxFuzzChunks; chunkSize < minChunkSize {
+			chunkSize = minChunkSize
+		}
+
+		expected, err := extractAll(protocol, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := extractChunked(protocol, input, chunkSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(expected) != len(actual) {
+			t.Fatalf(
+				"extracted %d packets from the whole input, but %d from chunks of %d bytes",
+				len(expected),
+				len(actual),
+				chunkSize,
+			)
+		}
+
+		for i := range expected {
+			if !bytes.Equal(expected[i], actual[i]) {
+				t.Fatalf("packet %d differs when input is split into chunks of %d bytes", i, chunkSize)
+			}
+		}
+	})
+}
+
+// SeparatorCorpus generates a set of valid inputs for a protocol splitting packets by given separator
+// (see tinytcp.SplitBySeparator).
+func SeparatorCorpus(separator []byte) [][]byte {
+	var corpus [][]byte
+
+	for _, packets := range corpusPackets() {
+		var input []byte
+		for _, packet := range packets {
+			input = append(input, bytes.ReplaceAll(packet, separator, nil)...)
+			input = append(input, separator...)
+		}
+
+		corpus = append(corpus, input)
+	}
+
+	return corpus
+}
+
+// LengthPrefixedCorpus generates a set of valid inputs for a protocol prefixing packets with their length
+// (see tinytcp.LengthPrefixedFraming).
+func LengthPrefixedCorpus(prefix tinytcp.PrefixType) [][]byte {
+	var corpus [][]byte
+
+	for _, packets := range corpusPackets() {
+		var input bytes.Buffer
+		for _, packet := range packets {
+			_ = tinytcp.WritePrefixedByteArray(&input, packet, prefix)
+		}
+
+		corpus = append(corpus, input.Bytes())
+	}
+
+	return corpus
+}
+
+func corpusPackets() [][][]byte {
+	return [][][]byte{
+		{[]byte("a")},
+		{[]byte("Hello world!"), []byte("second packet")},
+		{{}, []byte("after empty packet"), {}},
+		{bytes.Repeat([]byte{0xff}, 300), bytes.Repeat([]byte{0x80}, 128)},
+		{bytes.Repeat([]byte("x"), 4096)},
+	}
+}
+
+func extractAll(protocol tinytcp.FramingProtocol, input []byte) ([][]byte, error) {
+	var (
+		packets [][]byte
+		source  = input
+	)
+
+	for {
+		packet, rest, extracted, err := extract(protocol, source)
+		if err != nil {
+			return nil, err
+		}
+		if !extracted {
+			return packets, nil
+		}
+
+		packets = append(packets, packet)
+		if len(packets) > maxFuzzPackets {
+			return nil, fmt.Errorf("more than %d packets extracted from %d bytes", maxFuzzPackets, len(input))
+		}
+
+		source = rest
+	}
+}
+
+func extractChunked(protocol tinytcp.FramingProtocol, input []byte, chunkSize int) ([][]byte, error) {
+	var (
+		packets [][]byte
+		buffer  []byte
+	)
+
+	for len(input) > 0 {
+		n := chunkSize
+		if n > len(input) {
+			n = len(input)
+		}
+
+		buffer = append(buffer, input[:n]...)
+		input = input[n:]
+
+		for {
+			packet, rest, extracted, err := extract(protocol, buffer)
+			if err != nil {
+				return nil, err
+			}
+			if !extracted {
+				break
+			}
+
+			packets = append(packets, packet)
+			if len(packets) > maxFuzzPackets {
+				return nil, fmt.Errorf("more than %d packets extracted", maxFuzzPackets)
+			}
+
+			buffer = rest
+		}
+
+		// detach the fragmented packet from the source, like the framer does with its receive buffer
+		buffer = append([]byte(nil), buffer...)
+	}
+
+	return packets, nil
+}
+
+// extract calls ExtractPacket and validates its result. Returned packet is a copy.
+func extract(protocol tinytcp.FramingProtocol, source []byte) (packet []byte, rest []byte, extracted bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ExtractPacket panicked on %d bytes of input: %v", len(source), r)
+		}
+	}()
+
+	packet, rest, extracted = protocol.ExtractPacket(source)
+	if !extracted {
+		// rest is ignored by tinytcp when no packet is extracted, the source is retained as a whole
+		return nil, source, false, nil
+	}
+
+	if len(rest) > len(source) || !bytes.Equal(rest, source[len(source)-len(rest):]) {
+		return nil, nil, false, fmt.Errorf("rest is not a suffix of the source")
+	}
+
+	consumed := source[:len(source)-len(rest)]
+
+	if len(consumed) == 0 {
+		return nil, nil, false, fmt.Errorf("packet extracted without consuming any data")
+	}
+	if !bytes.Contains(consumed, packet) {
+		return nil, nil, false, fmt.Errorf("packet is not a part of the consumed data")
+	}
+
+	return append([]byte{}, packet...), rest, true, nil
+}
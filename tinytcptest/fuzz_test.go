@@ -0,0 +1,22 @@
+package tinytcptest
+
+import (
+	"github.com/mkorman9/tinytcp"
+	"testing"
+)
+
+func FuzzSplitBySeparator(f *testing.F) {
+	FuzzFraming(f, tinytcp.SplitBySeparator([]byte("\r\n")), SeparatorCorpus([]byte("\r\n"))...)
+}
+
+func FuzzLengthPrefixedVarInt(f *testing.F) {
+	FuzzFraming(f, tinytcp.LengthPrefixedFraming(tinytcp.PrefixVarInt), LengthPrefixedCorpus(tinytcp.PrefixVarInt)...)
+}
+
+func FuzzLengthPrefixedVarLong(f *testing.F) {
+	FuzzFraming(f, tinytcp.LengthPrefixedFraming(tinytcp.PrefixVarLong), LengthPrefixedCorpus(tinytcp.PrefixVarLong)...)
+}
+
+func FuzzLengthPrefixedInt64(f *testing.F) {
+	FuzzFraming(f, tinytcp.LengthPrefixedFraming(tinytcp.PrefixInt64_BE), LengthPrefixedCorpus(tinytcp.PrefixInt64_BE)...)
+}
@@ -0,0 +1,101 @@
+package tinytcptest
+
+import (
+	"net"
+	"sync"
+)
+
+// PipeListener is an in-memory implementation of tinytcp.Listener.
+// It allows to start a tinytcp server in tests without binding to real network ports.
+// Connections are created with Connect() and backed by net.Pipe().
+type PipeListener struct {
+	acceptQueue chan net.Conn
+	closed      chan struct{}
+	m           sync.Mutex
+}
+
+// NewPipeListener creates new PipeListener. It can be passed to the server with Server.Listener().
+func NewPipeListener() *PipeListener {
+	closed := make(chan struct{})
+	close(closed)
+
+	return &PipeListener{
+		acceptQueue: make(chan net.Conn),
+		closed:      closed,
+	}
+}
+
+// Listen conforms to the tinytcp.Listener interface.
+func (l *PipeListener) Listen() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	select {
+	case <-l.closed:
+		l.closed = make(chan struct{})
+	default:
+	}
+
+	return nil
+}
+
+// Accept conforms to the net.Listener interface.
+func (l *PipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.acceptQueue:
+		return conn, nil
+	case <-l.closedChannel():
+		return nil, net.ErrClosed
+	}
+}
+
+// Addr conforms to the net.Listener interface.
+func (l *PipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// Close conforms to the net.Listener interface.
+func (l *PipeListener) Close() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	select {
+	case <-l.closed:
+	default:
+		close(l.closed)
+	}
+
+	return nil
+}
+
+// Connect creates new in-memory connection, passes its server side to the server and returns the client side.
+// It blocks until the connection is accepted. If the listener is closed, returned connection is closed too.
+func (l *PipeListener) Connect() net.Conn {
+	server, client := net.Pipe()
+
+	select {
+	case l.acceptQueue <- server:
+	case <-l.closedChannel():
+		_ = server.Close()
+		_ = client.Close()
+	}
+
+	return client
+}
+
+func (l *PipeListener) closedChannel() <-chan struct{} {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.closed
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string {
+	return "pipe"
+}
+
+func (pipeAddr) String() string {
+	return "pipe"
+}
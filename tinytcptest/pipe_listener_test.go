@@ -0,0 +1,43 @@
+package tinytcptest
+
+import (
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestPipeListener(t *testing.T) {
+	// given
+	payload := []byte("Hello world!")
+	listener := NewPipeListener()
+
+	server := tinytcp.NewServer("pipe")
+	server.Listener(listener)
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(func(socket *tinytcp.Socket) {
+		_, _ = socket.Write(payload)
+	}))
+
+	started := make(chan struct{})
+	server.OnStart(func() {
+		close(started)
+	})
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start()
+	}()
+	<-started
+
+	// when
+	client := listener.Connect()
+	buffer := make([]byte, len(payload))
+	_, err := io.ReadFull(client, buffer)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, payload, buffer, "payloads should match")
+
+	_ = server.Stop()
+	assert.Nil(t, <-stopped, "server should stop without error")
+}
@@ -0,0 +1,244 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// Direction denotes the direction of the recorded data.
+type Direction string
+
+const (
+	// Inbound denotes the data read from the socket.
+	Inbound Direction = "in"
+
+	// Outbound denotes the data written to the socket.
+	Outbound Direction = "out"
+)
+
+// RecordedChunk is a single chunk of data, read from or written to the socket with a single call.
+type RecordedChunk struct {
+	// Offset is the time elapsed since the start of recording.
+	Offset time.Duration `json:"offset"`
+
+	// Direction denotes whether the chunk has been read or written.
+	Direction Direction `json:"direction"`
+
+	// Data holds the recorded bytes.
+	Data []byte `json:"data"`
+}
+
+// Recording holds the byte stream of a single connection, in both directions, along with its timing.
+// It's serializable to JSON, so the traffic captured in production can be stored and replayed in tests (see Replay).
+type Recording struct {
+	Chunks []RecordedChunk `json:"chunks"`
+}
+
+// LoadRecording reads the recording saved with Recording.Save.
+func LoadRecording(r io.Reader) (*Recording, error) {
+	var recording Recording
+	if err := json.NewDecoder(r).Decode(&recording); err != nil {
+		return nil, err
+	}
+
+	return &recording, nil
+}
+
+// Save writes the recording to w as JSON.
+func (r *Recording) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// Inbound returns all the inbound data, concatenated.
+func (r *Recording) Inbound() []byte {
+	return r.concat(Inbound)
+}
+
+// Outbound returns all the outbound data, concatenated.
+func (r *Recording) Outbound() []byte {
+	return r.concat(Outbound)
+}
+
+func (r *Recording) concat(direction Direction) []byte {
+	var buffer bytes.Buffer
+
+	for _, chunk := range r.Chunks {
+		if chunk.Direction == direction {
+			buffer.Write(chunk.Data)
+		}
+	}
+
+	return buffer.Bytes()
+}
+
+// Recorder records the byte stream of a live connection (see Record).
+type Recorder struct {
+	start     time.Time
+	recording Recording
+	m         sync.Mutex
+}
+
+// Record starts recording all the data read from and written to the socket. It should be called right at the start
+// of the handler, before any other wrappers (like compression) are applied, so the raw stream is recorded.
+// It must not be called concurrently with Read() or Write().
+func Record(socket *tinytcp.Socket) *Recorder {
+	recorder := &Recorder{
+		start: time.Now(),
+	}
+
+	socket.WrapReader(func(reader io.Reader) io.Reader {
+		return &recordingReader{reader: reader, recorder: recorder}
+	})
+	socket.WrapWriter(func(writer io.Writer) io.Writer {
+		return &recordingWriter{writer: writer, recorder: recorder}
+	})
+
+	return recorder
+}
+
+// Recording returns a copy of the data recorded so far.
+func (r *Recorder) Recording() *Recording {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	chunks := make([]RecordedChunk, len(r.recording.Chunks))
+	copy(chunks, r.recording.Chunks)
+
+	return &Recording{Chunks: chunks}
+}
+
+func (r *Recorder) record(direction Direction, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+
+	chunk := RecordedChunk{
+		Offset:    time.Since(r.start),
+		Direction: direction,
+		Data:      append([]byte(nil), b...),
+	}
+
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	r.recording.Chunks = append(r.recording.Chunks, chunk)
+}
+
+type recordingReader struct {
+	reader   io.Reader
+	recorder *Recorder
+}
+
+func (r *recordingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.recorder.record(Inbound, b[:n])
+	return n, err
+}
+
+type recordingWriter struct {
+	writer   io.Writer
+	recorder *Recorder
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	n, err := w.writer.Write(b)
+	w.recorder.record(Outbound, b[:n])
+	return n, err
+}
+
+// ReplayConfig holds a configuration for Replay.
+type ReplayConfig struct {
+	// TimeScale is a multiplier of the recorded delays between inbound chunks, eg. 1 replays the traffic in real time,
+	// and 0.5 replays it twice as fast. The value of 0 ignores the timing (default: 0).
+	TimeScale float64
+}
+
+func mergeReplayConfig(provided *ReplayConfig) *ReplayConfig {
+	config := &ReplayConfig{}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.TimeScale > 0 {
+		config.TimeScale = provided.TimeScale
+	}
+
+	return config
+}
+
+// Replay runs handler against a socket that replays the inbound data of the recording, preserving its chunks,
+// and then reports the connection as closed by the client. Returns all the data written by the handler,
+// which can be compared with recording.Outbound() to catch regressions.
+func Replay(recording *Recording, handler tinytcp.SocketHandler, config ...*ReplayConfig) []byte {
+	var providedConfig *ReplayConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeReplayConfig(providedConfig)
+
+	var chunks []RecordedChunk
+	for _, chunk := range recording.Chunks {
+		if chunk.Direction == Inbound {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	output := &syncBuffer{}
+	socket := NewSocket(&replayReader{chunks: chunks, timeScale: c.TimeScale, start: time.Now()}, output)
+
+	handler(socket)
+	_ = socket.Close()
+
+	return output.Bytes()
+}
+
+type replayReader struct {
+	chunks    []RecordedChunk
+	timeScale float64
+	start     time.Time
+}
+
+func (r *replayReader) Read(b []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	if r.timeScale > 0 {
+		due := r.start.Add(time.Duration(float64(r.chunks[0].Offset) * r.timeScale))
+		time.Sleep(time.Until(due))
+	}
+
+	n := copy(b, r.chunks[0].Data)
+	if n < len(r.chunks[0].Data) {
+		r.chunks[0].Data = r.chunks[0].Data[n:]
+	} else {
+		r.chunks = r.chunks[1:]
+	}
+
+	return n, nil
+}
+
+type syncBuffer struct {
+	buffer bytes.Buffer
+	m      sync.Mutex
+}
+
+func (s *syncBuffer) Write(b []byte) (int, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.buffer.Write(b)
+}
+
+func (s *syncBuffer) Bytes() []byte {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return append([]byte(nil), s.buffer.Bytes()...)
+}
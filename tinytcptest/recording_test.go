@@ -0,0 +1,40 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	// given
+	handler := tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(packet []byte) {
+				_, _ = socket.Write(append(bytes.ToUpper(packet), '\n'))
+			}
+		},
+	)
+
+	var output bytes.Buffer
+	socket := NewScriptedSocket(&output, []byte("hello\nwor"), []byte("ld\n"))
+
+	// when
+	recorder := Record(socket)
+	handler(socket)
+
+	var saved bytes.Buffer
+	saveErr := recorder.Recording().Save(&saved)
+	recording, loadErr := LoadRecording(&saved)
+
+	replayed := Replay(recording, handler)
+
+	// then
+	assert.Nil(t, saveErr, "saveErr should be nil")
+	assert.Nil(t, loadErr, "loadErr should be nil")
+	assert.Equal(t, []byte("hello\nworld\n"), recording.Inbound(), "inbound data should be recorded")
+	assert.Equal(t, []byte("HELLO\nWORLD\n"), recording.Outbound(), "outbound data should be recorded")
+	assert.Equal(t, recording.Outbound(), replayed, "replayed output should match the recording")
+}
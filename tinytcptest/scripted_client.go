@@ -0,0 +1,259 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/mkorman9/tinytcp"
+)
+
+// Matcher matches the data received from the server (see Expect).
+type Matcher struct {
+	description string
+	match       func(data []byte) (n int, ok bool)
+}
+
+// MatchFunc creates a custom Matcher. Function receives all the data buffered so far, and returns the number of bytes
+// consumed by the match, or false if the data doesn't match (yet). Description is used in error messages.
+func MatchFunc(description string, match func(data []byte) (n int, ok bool)) Matcher {
+	return Matcher{
+		description: description,
+		match:       match,
+	}
+}
+
+// Exact matches exactly given bytes.
+func Exact(expected []byte) Matcher {
+	return MatchFunc(fmt.Sprintf("%q", expected), func(data []byte) (int, bool) {
+		if bytes.HasPrefix(data, expected) {
+			return len(expected), true
+		}
+
+		return 0, false
+	})
+}
+
+// ExactString matches exactly given string.
+func ExactString(expected string) Matcher {
+	return Exact([]byte(expected))
+}
+
+// Regexp matches a regular expression, anchored at the start of the received data.
+// As the data arrives in chunks, the expression should end with a delimiter (eg. `^OK \d+\r\n`), otherwise it might
+// match before the whole response arrives.
+func Regexp(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+
+	return MatchFunc("/"+expr+"/", func(data []byte) (int, bool) {
+		location := re.FindIndex(data)
+		if location == nil || location[0] != 0 {
+			return 0, false
+		}
+
+		return location[1], true
+	})
+}
+
+// Length matches any n bytes.
+func Length(n int) Matcher {
+	return MatchFunc(fmt.Sprintf("any %d bytes", n), func(data []byte) (int, bool) {
+		if len(data) >= n {
+			return n, true
+		}
+
+		return 0, false
+	})
+}
+
+// Step is a single step of the script executed by ScriptedClient.
+type Step struct {
+	send        []byte
+	expect      *Matcher
+	expectClose bool
+	timeout     time.Duration
+}
+
+// Send returns a Step writing given data to the server.
+func Send(data []byte) Step {
+	return Step{send: data}
+}
+
+// SendString returns a Step writing given string to the server.
+func SendString(data string) Step {
+	return Send([]byte(data))
+}
+
+// Expect returns a Step waiting for the data matching given Matcher.
+func Expect(matcher Matcher) Step {
+	return Step{expect: &matcher}
+}
+
+// ExpectString returns a Step waiting for exactly given string.
+func ExpectString(expected string) Step {
+	return Expect(ExactString(expected))
+}
+
+// ExpectClose returns a Step waiting for the server to close the connection, without sending any more data.
+func ExpectClose() Step {
+	return Step{expectClose: true}
+}
+
+// WithTimeout overrides the timeout of the step (see ScriptedClientConfig.Timeout).
+func (s Step) WithTimeout(timeout time.Duration) Step {
+	s.timeout = timeout
+	return s
+}
+
+// ScriptedClientConfig holds a configuration for NewScriptedClient and DialScriptedClient.
+type ScriptedClientConfig struct {
+	// Timeout is a default time to wait for the expected data in each step (default: 5s).
+	Timeout time.Duration
+
+	// DialOptions are options used to establish the connection by DialScriptedClient.
+	DialOptions *tinytcp.DialOptions
+}
+
+func mergeScriptedClientConfig(provided *ScriptedClientConfig) *ScriptedClientConfig {
+	config := &ScriptedClientConfig{
+		Timeout: 5 * time.Second,
+	}
+
+	if provided == nil {
+		return config
+	}
+
+	if provided.Timeout > 0 {
+		config.Timeout = provided.Timeout
+	}
+	if provided.DialOptions != nil {
+		config.DialOptions = provided.DialOptions
+	}
+
+	return config
+}
+
+// ScriptedClient executes declarative send/expect scripts against a server, making end-to-end protocol tests concise:
+//
+//	err := client.Run(
+//		tinytcptest.SendString("PING\n"),
+//		tinytcptest.ExpectString("PONG\n"),
+//		tinytcptest.SendString("QUIT\n"),
+//		tinytcptest.ExpectClose(),
+//	)
+type ScriptedClient struct {
+	conn    net.Conn
+	config  *ScriptedClientConfig
+	buffer  []byte
+	readBuf []byte
+}
+
+// NewScriptedClient creates new ScriptedClient using given connection, eg. the one returned by PipeListener.Connect().
+func NewScriptedClient(conn net.Conn, config ...*ScriptedClientConfig) *ScriptedClient {
+	var providedConfig *ScriptedClientConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+
+	return &ScriptedClient{
+		conn:    conn,
+		config:  mergeScriptedClientConfig(providedConfig),
+		readBuf: make([]byte, 4096),
+	}
+}
+
+// DialScriptedClient connects to the server under given address and creates new ScriptedClient.
+func DialScriptedClient(address string, config ...*ScriptedClientConfig) (*ScriptedClient, error) {
+	var providedConfig *ScriptedClientConfig
+	if config != nil {
+		providedConfig = config[0]
+	}
+	c := mergeScriptedClientConfig(providedConfig)
+
+	client, err := tinytcp.DialContext(context.Background(), address, c.DialOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewScriptedClient(client.Unwrap(), c), nil
+}
+
+// Run executes given steps in order. It stops on the first failed step, and returns an error describing the step,
+// the expectation and the data received so far. Data received past the last match is kept for the next Run.
+func (c *ScriptedClient) Run(steps ...Step) error {
+	for i, step := range steps {
+		if err := c.runStep(step); err != nil {
+			return fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Close closes the connection.
+func (c *ScriptedClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *ScriptedClient) runStep(step Step) error {
+	if step.send != nil {
+		if err := tinytcp.WriteBytes(c.conn, step.send); err != nil {
+			return fmt.Errorf("failed to send %q: %w", step.send, err)
+		}
+	}
+
+	timeout := c.config.Timeout
+	if step.timeout > 0 {
+		timeout = step.timeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	if step.expect != nil {
+		for {
+			if n, ok := step.expect.match(c.buffer); ok {
+				c.buffer = c.buffer[n:]
+				return nil
+			}
+
+			if err := c.receive(deadline); err != nil {
+				return fmt.Errorf("expected %s, received %q: %w", step.expect.description, c.buffer, err)
+			}
+		}
+	}
+
+	if step.expectClose {
+		for len(c.buffer) == 0 {
+			if err := c.receive(deadline); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+
+				return fmt.Errorf("expected close: %w", err)
+			}
+		}
+
+		return fmt.Errorf("expected close, received %q", c.buffer)
+	}
+
+	return nil
+}
+
+func (c *ScriptedClient) receive(deadline time.Time) error {
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+
+	n, err := c.conn.Read(c.readBuf)
+	c.buffer = append(c.buffer, c.readBuf[:n]...)
+
+	if n > 0 {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,79 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestScriptedClient(t *testing.T) {
+	// given
+	listener := NewPipeListener()
+
+	server := tinytcp.NewServer("pipe")
+	server.Listener(listener)
+	server.ForkingStrategy(tinytcp.GoroutinePerConnection(tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		func(socket *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(packet []byte) {
+				if bytes.Equal(packet, []byte("QUIT")) {
+					_ = socket.Close()
+					return
+				}
+
+				_, _ = socket.Write(append(bytes.ToUpper(packet), '\n'))
+			}
+		},
+	)))
+
+	started := make(chan struct{})
+	server.OnStart(func() {
+		close(started)
+	})
+
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+	<-started
+
+	client := NewScriptedClient(listener.Connect())
+	defer client.Close()
+
+	// when
+	err := client.Run(
+		SendString("hello\n"),
+		ExpectString("HELLO\n"),
+		SendString("id 42\n"),
+		Expect(Regexp(`^ID \d+\n`)),
+		Send([]byte{'a', 'b', '\n'}),
+		Expect(Length(3)),
+		SendString("QUIT\n"),
+		ExpectClose(),
+	)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+}
+
+func TestScriptedClientMismatch(t *testing.T) {
+	// given
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	client := NewScriptedClient(conn)
+	defer client.Close()
+
+	// when
+	err := client.Run(
+		ExpectString("HELLO\n").WithTimeout(10 * time.Millisecond),
+	)
+
+	// then
+	assert.ErrorContains(t, err, `step 1: expected "HELLO\n"`, "error should describe the step")
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded, "step should time out")
+}
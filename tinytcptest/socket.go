@@ -0,0 +1,104 @@
+package tinytcptest
+
+import (
+	"github.com/mkorman9/tinytcp"
+	"io"
+	"net"
+	"time"
+)
+
+// NewSocket creates a tinytcp.Socket that reads its input from r and writes its output to w.
+// Nil reader behaves like a connection closed by the client, nil writer discards all the output.
+// It allows to unit-test SocketHandlers and PacketHandlers without any network connection.
+func NewSocket(r io.Reader, w io.Writer) *tinytcp.Socket {
+	if r == nil {
+		r = eofReader{}
+	}
+	if w == nil {
+		w = io.Discard
+	}
+
+	return tinytcp.NewSocket(&mockConn{
+		reader: r,
+		writer: w,
+	})
+}
+
+// NewScriptedSocket creates a tinytcp.Socket that replays given chunks of data, one chunk per Read() call,
+// and then reports the connection as closed by the client. Output is written to w.
+// It allows to deterministically test how handlers deal with fragmented packets.
+func NewScriptedSocket(w io.Writer, chunks ...[]byte) *tinytcp.Socket {
+	return NewSocket(&scriptedReader{chunks: chunks}, w)
+}
+
+type scriptedReader struct {
+	chunks [][]byte
+}
+
+func (r *scriptedReader) Read(b []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(b, r.chunks[0])
+	if n < len(r.chunks[0]) {
+		r.chunks[0] = r.chunks[0][n:]
+	} else {
+		r.chunks = r.chunks[1:]
+	}
+
+	return n, nil
+}
+
+type eofReader struct{}
+
+func (eofReader) Read(_ []byte) (int, error) {
+	return 0, io.EOF
+}
+
+type mockConn struct {
+	reader io.Reader
+	writer io.Writer
+}
+
+func (c *mockConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *mockConn) Write(b []byte) (int, error) {
+	return c.writer.Write(b)
+}
+
+func (c *mockConn) Close() error {
+	return nil
+}
+
+func (c *mockConn) LocalAddr() net.Addr {
+	return mockAddr{}
+}
+
+func (c *mockConn) RemoteAddr() net.Addr {
+	return mockAddr{}
+}
+
+func (c *mockConn) SetDeadline(_ time.Time) error {
+	return nil
+}
+
+func (c *mockConn) SetReadDeadline(_ time.Time) error {
+	return nil
+}
+
+func (c *mockConn) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+type mockAddr struct{}
+
+func (mockAddr) Network() string {
+	return "tcp"
+}
+
+func (mockAddr) String() string {
+	return "127.0.0.1:1234"
+}
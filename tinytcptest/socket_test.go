@@ -0,0 +1,45 @@
+package tinytcptest
+
+import (
+	"bytes"
+	"github.com/mkorman9/tinytcp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewSocket(t *testing.T) {
+	// given
+	payload := []byte("Hello world!")
+	var out bytes.Buffer
+	socket := NewSocket(bytes.NewReader(payload), &out)
+
+	// when
+	buffer := make([]byte, len(payload))
+	n, err := socket.Read(buffer)
+	_, _ = socket.Write(buffer[:n])
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, payload, out.Bytes(), "payloads should match")
+	assert.Equal(t, "127.0.0.1", socket.RemoteAddress(), "remote address should match")
+}
+
+func TestScriptedSocket(t *testing.T) {
+	// given
+	socket := NewScriptedSocket(nil, []byte("Hel"), []byte("lo\nwor"), []byte("ld\n"))
+
+	// when
+	var packets []string
+
+	tinytcp.PacketFramingHandler(
+		tinytcp.SplitBySeparator([]byte{'\n'}),
+		func(_ *tinytcp.Socket) tinytcp.PacketHandler {
+			return func(packet []byte) {
+				packets = append(packets, string(packet))
+			}
+		},
+	)(socket)
+
+	// then
+	assert.Equal(t, []string{"Hello", "world"}, packets, "packets should match")
+}
@@ -0,0 +1,101 @@
+package tinytcp
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+)
+
+// tlsRecordTypeHandshake is the first byte of every TLS ClientHello record.
+const tlsRecordTypeHandshake = 0x16
+
+type autoDetectListener struct {
+	net.Listener
+	config *tls.Config
+}
+
+func (l *autoDetectListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &autoDetectConn{
+		Conn:   conn,
+		config: l.config,
+	}, nil
+}
+
+// autoDetectConn postpones the decision whether to use TLS until the first byte sent by the client is known.
+// Detection is performed lazily, on the first call to Read() or Write(), so it never blocks the accept loop.
+type autoDetectConn struct {
+	net.Conn
+	config *tls.Config
+
+	detectOnce sync.Once
+	conn       net.Conn
+	err        error
+}
+
+func (c *autoDetectConn) Read(b []byte) (int, error) {
+	if err := c.detect(); err != nil {
+		return 0, err
+	}
+
+	return c.conn.Read(b)
+}
+
+func (c *autoDetectConn) Write(b []byte) (int, error) {
+	if err := c.detect(); err != nil {
+		return 0, err
+	}
+
+	return c.conn.Write(b)
+}
+
+func (c *autoDetectConn) unwrapTLS() (*tls.Conn, bool) {
+	if err := c.detect(); err != nil {
+		return nil, false
+	}
+
+	conn, ok := c.conn.(*tls.Conn)
+	return conn, ok
+}
+
+func (c *autoDetectConn) detect() error {
+	c.detectOnce.Do(func() {
+		prefixed := &prefixedConn{Conn: c.Conn}
+
+		n, err := c.Conn.Read(prefixed.prefix[:])
+		if err != nil {
+			c.err = err
+			return
+		}
+		prefixed.prefixLen = n
+
+		if prefixed.prefix[0] == tlsRecordTypeHandshake {
+			c.conn = tls.Server(prefixed, c.config)
+		} else {
+			c.conn = prefixed
+		}
+	})
+
+	return c.err
+}
+
+// prefixedConn returns the already consumed prefix before reading from the underlying connection.
+type prefixedConn struct {
+	net.Conn
+	prefix    [1]byte
+	prefixLen int
+}
+
+func (c *prefixedConn) Read(b []byte) (int, error) {
+	if c.prefixLen > 0 && len(b) > 0 {
+		b[0] = c.prefix[0]
+		c.prefixLen = 0
+		return 1, nil
+	}
+
+	return c.Conn.Read(b)
+}
@@ -0,0 +1,47 @@
+package tinytcp
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestAutoDetectPlaintext(t *testing.T) {
+	// given
+	server, client := net.Pipe()
+	conn := &autoDetectConn{Conn: server, config: &tls.Config{}}
+	payload := []byte("Hello world!")
+
+	go func() {
+		_, _ = client.Write(payload)
+	}()
+
+	// when
+	buffer := make([]byte, len(payload))
+	n, err := io.ReadFull(conn, buffer)
+	_, isTLS := conn.unwrapTLS()
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	assert.Equal(t, len(payload), n, "n should equal to bytes read")
+	assert.Equal(t, payload, buffer, "payloads should match")
+	assert.False(t, isTLS, "connection should not be detected as TLS")
+}
+
+func TestAutoDetectTLS(t *testing.T) {
+	// given
+	server, client := net.Pipe()
+	conn := &autoDetectConn{Conn: server, config: &tls.Config{}}
+
+	go func() {
+		_, _ = client.Write([]byte{tlsRecordTypeHandshake})
+	}()
+
+	// when
+	_, isTLS := conn.unwrapTLS()
+
+	// then
+	assert.True(t, isTLS, "connection should be detected as TLS")
+}
@@ -0,0 +1,112 @@
+package tinytcp
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+func isUnixNetwork(network string) bool {
+	return network == "unix" || network == "unixpacket"
+}
+
+// isAbstractUnixSocket reports whether address denotes a Linux abstract socket, which has no file in the filesystem.
+func isAbstractUnixSocket(address string) bool {
+	return strings.HasPrefix(address, "@")
+}
+
+// removeStaleUnixSocket removes a socket file left by a process that has not cleaned up after itself.
+// Socket files still accepting connections and files of other types are never removed.
+func removeStaleUnixSocket(network, address string) error {
+	if isAbstractUnixSocket(address) {
+		return nil
+	}
+
+	info, err := os.Stat(address)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.New("file " + address + " exists and is not a socket")
+	}
+
+	if conn, err := net.Dial(network, address); err == nil {
+		_ = conn.Close()
+		return errors.New("socket " + address + " is already in use")
+	}
+
+	return os.Remove(address)
+}
+
+func setupUnixSocket(address string, config *ServerConfig) error {
+	if isAbstractUnixSocket(address) {
+		return nil
+	}
+
+	if config.UnixSocketMode != 0 {
+		if err := os.Chmod(address, config.UnixSocketMode); err != nil {
+			return err
+		}
+	}
+
+	if config.UnixSocketOwner != "" || config.UnixSocketGroup != "" {
+		uid, gid := -1, -1
+
+		if config.UnixSocketOwner != "" {
+			id, err := lookupUserID(config.UnixSocketOwner)
+			if err != nil {
+				return err
+			}
+
+			uid = id
+		}
+		if config.UnixSocketGroup != "" {
+			id, err := lookupGroupID(config.UnixSocketGroup)
+			if err != nil {
+				return err
+			}
+
+			gid = id
+		}
+
+		if err := os.Chown(address, uid, gid); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func lookupUserID(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(u.Uid)
+}
+
+func lookupGroupID(name string) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(g.Gid)
+}
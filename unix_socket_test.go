@@ -0,0 +1,61 @@
+package tinytcp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveStaleUnixSocket(t *testing.T) {
+	// given
+	address := filepath.Join(t.TempDir(), "stale.sock")
+
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = listener.Close()
+
+	// when
+	err = removeStaleUnixSocket("unix", address)
+
+	// then
+	assert.Nil(t, err, "err should be nil")
+	_, err = os.Stat(address)
+	assert.True(t, os.IsNotExist(err), "stale socket file should be removed")
+}
+
+func TestRemoveStaleUnixSocketInUse(t *testing.T) {
+	// given
+	address := filepath.Join(t.TempDir(), "active.sock")
+
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer listener.Close()
+
+	// when
+	err = removeStaleUnixSocket("unix", address)
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+	_, err = os.Stat(address)
+	assert.Nil(t, err, "active socket file should not be removed")
+}
+
+func TestRemoveStaleUnixSocketRegularFile(t *testing.T) {
+	// given
+	address := filepath.Join(t.TempDir(), "file.sock")
+	_ = os.WriteFile(address, nil, 0644)
+
+	// when
+	err := removeStaleUnixSocket("unix", address)
+
+	// then
+	assert.NotNil(t, err, "err should not be nil")
+}
@@ -1,6 +1,7 @@
 package tinytcp
 
 import (
+	"crypto/tls"
 	"errors"
 	"io"
 	"net"
@@ -63,8 +64,37 @@ func (p PrefixType) Size() int {
 	return -1
 }
 
+// Flags represents a set of boolean flags packed into bits. Bit 0 is the least significant one.
+// Flags are read and written using fixed-width helpers (eg. ReadFlags8, WriteFlags16).
+type Flags uint64
+
+// NewFlags packs given booleans into Flags, starting from bit 0.
+func NewFlags(values ...bool) Flags {
+	var f Flags
+	for i, value := range values {
+		f = f.Set(i, value)
+	}
+
+	return f
+}
+
+// Has returns true if given bit is set.
+func (f Flags) Has(bit int) bool {
+	return f&(1<<bit) != 0
+}
+
+// Set returns a copy of flags with given bit set to value.
+func (f Flags) Set(bit int, value bool) Flags {
+	if value {
+		return f | (1 << bit)
+	}
+
+	return f &^ (1 << bit)
+}
+
 // CloseReason denotes a reason that Close() function has been called for.
 // Close() can be triggered either by server, or by client (connection reset by peer).
+// The same values are used by both Socket and Client.
 type CloseReason int
 
 const (
@@ -73,15 +103,33 @@ const (
 
 	// CloseReasonClient means the connection has been either closed by client or has been lost for other reasons.
 	CloseReasonClient
+
+	// CloseReasonAbort means the connection has been closed because the server has been aborted (see Server.Abort).
+	// The error that caused the abort can be retrieved with Socket.CloseError().
+	CloseReasonAbort
 )
 
+// String returns a name of the close reason.
+func (r CloseReason) String() string {
+	switch r {
+	case CloseReasonServer:
+		return "server"
+	case CloseReasonClient:
+		return "client"
+	case CloseReasonAbort:
+		return "abort"
+	default:
+		return "unknown"
+	}
+}
+
 const (
 	segmentBits = 0x7F
 	continueBit = 0x80
 )
 
 func isBrokenPipe(err error) bool {
-	return err == io.EOF ||
+	return errors.Is(err, io.EOF) ||
 		errors.Is(err, syscall.ECONNRESET) ||
 		strings.Contains(err.Error(), "use of closed network connection") ||
 		strings.Contains(err.Error(), "wsarecv: An existing connection was forcibly closed by the remote host.") ||
@@ -119,3 +167,37 @@ func resolveNetworkPort(address net.Addr) int {
 
 	return port
 }
+
+func unwrapTLSConn(connection net.Conn) (*tls.Conn, bool) {
+	switch conn := connection.(type) {
+	case *tls.Conn:
+		return conn, true
+	case *autoDetectConn:
+		return conn.unwrapTLS()
+	case *webSocketConn:
+		return conn.unwrapTLS()
+	}
+
+	return nil, false
+}
+
+// writerOnly hides all the methods of the writer other than Write, eg. to prevent io.Copy from calling ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
+
+// readerOnly hides all the methods of the reader other than Read, eg. to prevent io.Copy from calling WriteTo.
+type readerOnly struct {
+	io.Reader
+}
+
+func countTrue(values ...bool) int {
+	var count int
+	for _, v := range values {
+		if v {
+			count++
+		}
+	}
+
+	return count
+}
@@ -2,7 +2,12 @@ package tinytcp
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+	"math"
+	"net"
+	"strings"
+	"time"
 )
 
 // WriteBytes writes a byte into given writer.
@@ -66,6 +71,57 @@ func WriteInt64(writer io.Writer, value int64, byteOrder ...binary.ByteOrder) er
 	return binary.Write(writer, order, value)
 }
 
+// WriteUint16 writes uint16 into given writer.
+func WriteUint16(writer io.Writer, value uint16, byteOrder ...binary.ByteOrder) error {
+	return WriteInt16(writer, int16(value), byteOrder...)
+}
+
+// WriteUint32 writes uint32 into given writer.
+func WriteUint32(writer io.Writer, value uint32, byteOrder ...binary.ByteOrder) error {
+	return WriteInt32(writer, int32(value), byteOrder...)
+}
+
+// WriteUint64 writes uint64 into given writer.
+func WriteUint64(writer io.Writer, value uint64, byteOrder ...binary.ByteOrder) error {
+	return WriteInt64(writer, int64(value), byteOrder...)
+}
+
+// WriteTime writes time encoded as int64 number of units elapsed since Unix epoch (eg. time.Millisecond).
+// Unit must be a divisor of time.Second. Precision finer than unit is truncated.
+func WriteTime(writer io.Writer, value time.Time, unit time.Duration, byteOrder ...binary.ByteOrder) error {
+	if unit <= 0 || time.Second%unit != 0 {
+		return errors.New("invalid time unit")
+	}
+
+	perSecond := int64(time.Second / unit)
+	return WriteInt64(writer, value.Unix()*perSecond+int64(value.Nanosecond())/int64(unit), byteOrder...)
+}
+
+// WriteUUID writes 16-byte UUID into given writer.
+func WriteUUID(writer io.Writer, value [16]byte) error {
+	return WriteBytes(writer, value[:])
+}
+
+// WriteIP writes IP address encoded on 16 bytes into given writer. IPv4 addresses are written as IPv4-mapped.
+func WriteIP(writer io.Writer, value net.IP) error {
+	ip := value.To16()
+	if ip == nil {
+		return errors.New("invalid IP address")
+	}
+
+	return WriteBytes(writer, ip)
+}
+
+// WriteIPv4 writes IPv4 address encoded on 4 bytes into given writer.
+func WriteIPv4(writer io.Writer, value net.IP) error {
+	ip := value.To4()
+	if ip == nil {
+		return errors.New("not an IPv4 address")
+	}
+
+	return WriteBytes(writer, ip)
+}
+
 // WriteFloat32 writes float32 into given writer.
 func WriteFloat32(writer io.Writer, value float32, byteOrder ...binary.ByteOrder) error {
 	var order binary.ByteOrder = binary.BigEndian
@@ -131,3 +187,202 @@ func WriteVarLong(writer io.Writer, value int64) error {
 
 	return nil
 }
+
+// WriteSVarInt writes zigzag-encoded var int into given writer (compatible with protobuf sint32).
+func WriteSVarInt(writer io.Writer, value int32) error {
+	return WriteVarInt(writer, int(uint32((value<<1)^(value>>31))))
+}
+
+// WriteSVarLong writes zigzag-encoded var long into given writer (compatible with protobuf sint64).
+func WriteSVarLong(writer io.Writer, value int64) error {
+	return writeUvarint(writer, uint64((value<<1)^(value>>63)))
+}
+
+// WriteByteArray writes VarInt-prefixed byte array into given writer.
+func WriteByteArray(writer io.Writer, value []byte) error {
+	return WritePrefixedByteArray(writer, value, PrefixVarInt)
+}
+
+// WriteString writes VarInt-prefixed string into given writer.
+func WriteString(writer io.Writer, value string) error {
+	return WriteByteArray(writer, []byte(value))
+}
+
+// WritePrefixedByteArray writes byte array prefixed with its length encoded as specified by prefix.
+// ErrLengthExceeded is returned if the length does not fit into the prefix.
+func WritePrefixedByteArray(writer io.Writer, value []byte, prefix PrefixType) error {
+	err := writeLength(writer, len(value), prefix)
+	if err != nil {
+		return err
+	}
+
+	return WriteBytes(writer, value)
+}
+
+// WritePrefixedString writes string prefixed with its length encoded as specified by prefix.
+// ErrLengthExceeded is returned if the length does not fit into the prefix.
+func WritePrefixedString(writer io.Writer, value string, prefix PrefixType) error {
+	return WritePrefixedByteArray(writer, []byte(value), prefix)
+}
+
+// WriteCString writes NUL-terminated string into given writer.
+// Returns error if the string itself contains NUL.
+func WriteCString(writer io.Writer, value string) error {
+	if strings.IndexByte(value, 0) >= 0 {
+		return errors.New("string contains NUL")
+	}
+
+	return WriteBytes(writer, append([]byte(value), 0))
+}
+
+func writeLength(writer io.Writer, length int, prefix PrefixType) error {
+	switch prefix {
+	case PrefixVarInt:
+		if length > math.MaxInt32 {
+			return ErrLengthExceeded
+		}
+		return WriteVarInt(writer, length)
+	case PrefixVarLong:
+		return WriteVarLong(writer, int64(length))
+	case PrefixInt16_BE, PrefixInt16_LE:
+		if length > math.MaxUint16 {
+			return ErrLengthExceeded
+		}
+		return WriteUint16(writer, uint16(length), prefixByteOrder(prefix))
+	case PrefixInt32_BE, PrefixInt32_LE:
+		if length > math.MaxUint32 {
+			return ErrLengthExceeded
+		}
+		return WriteUint32(writer, uint32(length), prefixByteOrder(prefix))
+	case PrefixInt64_BE, PrefixInt64_LE:
+		return WriteInt64(writer, int64(length), prefixByteOrder(prefix))
+	}
+
+	return errors.New("invalid prefix type")
+}
+
+func prefixByteOrder(prefix PrefixType) binary.ByteOrder {
+	switch prefix {
+	case PrefixInt16_LE, PrefixInt32_LE, PrefixInt64_LE:
+		return binary.LittleEndian
+	default:
+		return binary.BigEndian
+	}
+}
+
+// WriteBitSet writes a set of bits packed into ceil(len(bits)/8) bytes, starting from the least significant bit
+// of the first byte. Unused bits of the last byte are zeroed.
+func WriteBitSet(writer io.Writer, bits []bool) error {
+	buff := make([]byte, (len(bits)+7)/8)
+	for i, bit := range bits {
+		if bit {
+			buff[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	return WriteBytes(writer, buff)
+}
+
+// WriteFlags8 writes 8-bit flags into given writer. Returns error if any of the higher bits is set.
+func WriteFlags8(writer io.Writer, flags Flags) error {
+	if flags > math.MaxUint8 {
+		return errors.New("flags do not fit into 8 bits")
+	}
+
+	return WriteByte(writer, byte(flags))
+}
+
+// WriteFlags16 writes 16-bit flags into given writer. Returns error if any of the higher bits is set.
+func WriteFlags16(writer io.Writer, flags Flags, byteOrder ...binary.ByteOrder) error {
+	if flags > math.MaxUint16 {
+		return errors.New("flags do not fit into 16 bits")
+	}
+
+	return WriteUint16(writer, uint16(flags), byteOrder...)
+}
+
+// WriteFlags32 writes 32-bit flags into given writer. Returns error if any of the higher bits is set.
+func WriteFlags32(writer io.Writer, flags Flags, byteOrder ...binary.ByteOrder) error {
+	if flags > math.MaxUint32 {
+		return errors.New("flags do not fit into 32 bits")
+	}
+
+	return WriteUint32(writer, uint32(flags), byteOrder...)
+}
+
+// WriteSlice writes a slice of values, each encoded by encode function, prefixed with the number of elements.
+// Prefix is VarInt by default.
+func WriteSlice[T any](
+	writer io.Writer,
+	values []T,
+	encode func(io.Writer, T) error,
+	prefix ...PrefixType,
+) error {
+	err := writeCount(writer, len(values), prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		err = encode(writer, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteMap writes a map of key-value pairs, encoded by encodeKey and encodeValue functions,
+// prefixed with the number of pairs. Prefix is VarInt by default.
+// Note that the pairs are written in random order, so the output is not deterministic.
+func WriteMap[K comparable, V any](
+	writer io.Writer,
+	values map[K]V,
+	encodeKey func(io.Writer, K) error,
+	encodeValue func(io.Writer, V) error,
+	prefix ...PrefixType,
+) error {
+	err := writeCount(writer, len(values), prefix)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		err = encodeKey(writer, key)
+		if err != nil {
+			return err
+		}
+
+		err = encodeValue(writer, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeCount(writer io.Writer, count int, prefix []PrefixType) error {
+	p := PrefixVarInt
+	if len(prefix) > 0 {
+		p = prefix[0]
+	}
+
+	return writeLength(writer, count, p)
+}
+
+// writeUvarint writes unsigned var long, which might have its most significant bit set.
+func writeUvarint(writer io.Writer, value uint64) error {
+	var buff [10]byte
+	i := 0
+
+	for value >= continueBit {
+		buff[i] = byte(value&segmentBits) | continueBit
+		value >>= 7
+		i++
+	}
+	buff[i] = byte(value)
+
+	return WriteBytes(writer, buff[:i+1])
+}